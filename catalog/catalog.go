@@ -0,0 +1,590 @@
+// Package catalog records the files imported into a target tree.
+//
+// The catalog is an append-only journal of JSON records, one per line,
+// so that several processes (e.g. one per file dropped onto the desktop icon)
+// can safely add to it at the same time.
+// The current state is rebuilt from the journal when the catalog is opened.
+package catalog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/text/unicode/norm"
+)
+
+// FileName is the name of the catalog journal file.
+const FileName = "catalog.jsonl"
+
+// Operator is recorded as the operator of new sessions instead of the OS user name if not empty,
+// e.g. when several people share an account.
+var Operator string
+
+// CurrentOperator returns Operator or else the OS user name, empty if unknown.
+func CurrentOperator() string {
+	if Operator != "" {
+		return Operator
+	} else if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return ""
+}
+
+// Record is a single line in the catalog journal.
+// Exactly one of the pointer fields is set.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Session *Session  `json:"session,omitempty"`
+	File    *File     `json:"file,omitempty"`
+	Move    *Move     `json:"move,omitempty"`
+	Remove  *Remove   `json:"remove,omitempty"`
+	// Deployment is the complete current state of a deployment, replacing any earlier record of it.
+	Deployment *Deployment `json:"deployment,omitempty"`
+}
+
+// Session describes a single run of the application.
+type Session struct {
+	// ID includes the host name so that the sessions of different installations
+	// (e.g. in catalogs merged from other sites) don't conflict.
+	ID      string    `json:"id"`
+	Started time.Time `json:"started"`
+	Host    string    `json:"host,omitempty"`
+	// Operator is the person (by default the OS user) running the session.
+	Operator string `json:"operator,omitempty"`
+	Command  string `json:"command,omitempty"`
+	Source   string `json:"source,omitempty"`
+	// Card is the device from which the session imported files, nil if unknown or not importing.
+	Card *Card `json:"card,omitempty"`
+}
+
+// Card identifies the device (e.g. an SD card in a reader) containing the source of a session.
+type Card struct {
+	// Device is the device (e.g. /dev/sdb1) or volume (e.g. E:\) of the source file system.
+	Device string `json:"device,omitempty"`
+	// Volume is the file system UUID or volume serial number (e.g. 1A2B-3C4D for FAT),
+	// which is set when the card is formatted.
+	Volume string `json:"volume,omitempty"`
+	Label  string `json:"label,omitempty"`
+	// Serial is the hardware serial number of the card, only readable in some (e.g. built-in) readers.
+	Serial string `json:"serial,omitempty"`
+	// Reader identifies the card reader, e.g. by its USB vendor, model, and serial number.
+	Reader string `json:"reader,omitempty"`
+}
+
+// String describes the card for people.
+func (c *Card) String() string {
+	var parts []string
+	for _, part := range [][2]string{
+		{"volume", c.Volume}, {"label", c.Label}, {"serial", c.Serial}, {"device", c.Device}, {"reader", c.Reader},
+	} {
+		if part[1] != "" {
+			parts = append(parts, part[0]+" "+part[1])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Kinds of derivative files (see File.Derivation).
+const (
+	// DerivationLowRes is a lower resolution copy of the same capture,
+	// e.g. emailed by a cellular camera or uploaded by the phone app.
+	DerivationLowRes = "low-res"
+	// DerivationThumbnail is a small preview image.
+	DerivationThumbnail = "thumbnail"
+	// DerivationTranscode is a video converted to another format or quality.
+	DerivationTranscode = "transcode"
+	// DerivationCrop is a cropped (or otherwise edited) image.
+	DerivationCrop = "crop"
+)
+
+// Derivations are the kinds of derivative files.
+var Derivations = []string{DerivationLowRes, DerivationThumbnail, DerivationTranscode, DerivationCrop}
+
+// File describes a file in the target tree.
+type File struct {
+	// Path is relative to the root, with forward slashes.
+	Path string `json:"path"`
+	// Root is the absolute path of the pool root containing the file, empty for the target root.
+	Root   string `json:"root,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Camera identifies the camera that captured the file when it isn't the source directory,
+	// e.g. when recognized by the scene of a deployment on a card mixing several cameras' files.
+	Camera string `json:"camera,omitempty"`
+	// Original is the path of the file from which this file was derived (see Derivation),
+	// empty for an original.
+	Original string `json:"original,omitempty"`
+	// Derivation is how this file was derived from its Original (e.g. DerivationLowRes).
+	Derivation string `json:"derivation,omitempty"`
+	// Hash of the file contents as algorithm:hex.
+	Hash string `json:"hash,omitempty"`
+	// SourceHash is the hash (as algorithm:hex) of the source file when its archived copy was modified
+	// on import (e.g. EXIF time zone, GPS position, or orientation), empty if copied unchanged.
+	SourceHash string    `json:"sourceHash,omitempty"`
+	Captured   time.Time `json:"captured"`
+	Imported   time.Time `json:"imported"`
+	Session    string    `json:"session,omitempty"`
+	// Offloaded is the URL of the remote copy of a file
+	// that has been replaced by a stub placeholder.
+	Offloaded string `json:"offloaded,omitempty"`
+	// Tags describe the contents of the file, e.g. from a classifier plugin.
+	Tags []string `json:"tags,omitempty"`
+	// Confidence of the classifier in the tags, from 0 to 1, zero if unknown.
+	Confidence float64 `json:"confidence,omitempty"`
+	// Labels are tags added by a person reviewing the file.
+	Labels []string `json:"labels,omitempty"`
+	// Rating is a star rating from 1 to 5 by a person reviewing the file, zero if unrated.
+	Rating int `json:"rating,omitempty"`
+	// Orientation is the EXIF Orientation (1 through 8, 1 is upright) of an image, zero if unknown.
+	// Viewers should rotate and flip the image accordingly.
+	Orientation int `json:"orientation,omitempty"`
+	// Exposure is the exposure problem of an image (dark, bright, or flat), empty if none or unchecked.
+	Exposure string `json:"exposure,omitempty"`
+	// Burst is the path of the first frame of the burst containing the file, empty if none.
+	Burst string `json:"burst,omitempty"`
+	// Score is the sharpness and exposure score of a burst frame.
+	Score float64 `json:"score,omitempty"`
+	// Best marks the best frame of a burst.
+	Best bool `json:"best,omitempty"`
+	// Event is the path (relative to the root of the first clip) of the video stitched from
+	// the consecutive clips including the file, empty if none.
+	Event string `json:"event,omitempty"`
+	// Battery is the battery level reported by the camera, nil if unknown.
+	Battery *Battery `json:"battery,omitempty"`
+	// Weather is the weather at the time and place of capture, nil if unknown.
+	Weather *Weather `json:"weather,omitempty"`
+	// Position is where the file was captured, e.g. from a GPX track or drone telemetry, nil if unknown.
+	Position *Position `json:"position,omitempty"`
+	// Verified is when the contents of the file were last found to match its hash (see scrub),
+	// zero if never.
+	Verified time.Time `json:"verified,omitempty"`
+}
+
+// Derivative returns whether the file was derived from another (see Original).
+func (f *File) Derivative() bool {
+	return f.Original != ""
+}
+
+// AllTags returns the tags (from classification) and labels (from review) of the file.
+func (f *File) AllTags() []string {
+	return append(append([]string{}, f.Tags...), f.Labels...)
+}
+
+// HasTag returns whether the file has the tag or label, ignoring case.
+func (f *File) HasTag(tag string) bool {
+	for _, t := range f.AllTags() {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Battery is a battery level as reported by a camera, either as a percentage or in volts.
+type Battery struct {
+	Percent float64 `json:"percent,omitempty"`
+	Volts   float64 `json:"volts,omitempty"`
+}
+
+// Weather is the hourly weather at a place.
+type Weather struct {
+	// Temperature in degrees Celsius.
+	Temperature float64 `json:"temperature"`
+	// Precipitation (rain and snow) in millimeters.
+	Precipitation float64 `json:"precipitation"`
+	// Snowfall in centimeters.
+	Snowfall float64 `json:"snowfall"`
+}
+
+// Position is a GPS position in decimal degrees.
+type Position struct {
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+}
+
+// Move records the renaming of a file within the target tree.
+type Move struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Remove records the removal of a file from the target tree.
+type Remove struct {
+	Path string `json:"path"`
+}
+
+// Deployment records the placement of a camera in the field.
+type Deployment struct {
+	ID string `json:"id"`
+	// Camera is the source directory of the camera's files (i.e. its card).
+	Camera   string    `json:"camera"`
+	Location string    `json:"location,omitempty"`
+	Position *Position `json:"position,omitempty"`
+	// Installed and Pulled are camera clock times like capture times.
+	Installed time.Time `json:"installed"`
+	// Pulled is zero while the camera is in the field.
+	Pulled time.Time `json:"pulled,omitempty"`
+	// Settings describes the camera settings, e.g. 3 photos, 10s delay, high sensitivity.
+	Settings string `json:"settings,omitempty"`
+	// Fingerprints of reference frames of the camera's scene (e.g. by day and by night)
+	// by which its files are recognized.
+	Fingerprints []string `json:"fingerprints,omitempty"`
+	// Removed marks a deployment recorded in error.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// Active returns whether the camera was deployed at the time.
+func (d *Deployment) Active(when time.Time) bool {
+	return !when.Before(d.Installed) && (d.Pulled.IsZero() || when.Before(d.Pulled))
+}
+
+// Catalog is the current state of the catalog journal.
+// It is safe for concurrent use.
+type Catalog struct {
+	mutex    sync.Mutex
+	path     string
+	journal  *os.File
+	session  *Session
+	sessions map[string]*Session
+	files    map[string]*File
+	// deployments by ID.
+	deployments map[string]*Deployment
+}
+
+// Open loads the catalog journal from the specified directory, creating it if necessary.
+func Open(dir string) (*Catalog, error) {
+	if err := os.MkdirAll(dir, 0766); err != nil {
+		return nil, fmt.Errorf("make catalog dir: %w", err)
+	}
+	c := &Catalog{
+		path:        filepath.Join(dir, FileName),
+		sessions:    make(map[string]*Session),
+		files:       make(map[string]*File),
+		deployments: make(map[string]*Deployment),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	var err error
+	if c.journal, err = os.OpenFile(c.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666); err != nil {
+		return nil, fmt.Errorf("open catalog journal: %w", err)
+	}
+	return c, nil
+}
+
+// Close the catalog journal.
+func (c *Catalog) Close() error {
+	return c.journal.Close()
+}
+
+func (c *Catalog) load() error {
+	file, err := os.Open(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("open catalog journal: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// Most likely a partial line being written by another process.
+			log.Warn().Err(err).Int("line", line).Msg("Skipping bad catalog record")
+			continue
+		}
+		c.apply(&record)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read catalog journal: %w", err)
+	}
+	return nil
+}
+
+// apply a record to the current state.
+func (c *Catalog) apply(record *Record) {
+	switch {
+	case record.Session != nil:
+		c.sessions[record.Session.ID] = record.Session
+	case record.File != nil:
+		c.files[key(record.File.Path)] = record.File
+	case record.Move != nil:
+		if file, found := c.files[key(record.Move.From)]; found {
+			delete(c.files, key(record.Move.From))
+			file.Path = record.Move.To
+			c.files[key(file.Path)] = file
+		}
+	case record.Remove != nil:
+		delete(c.files, key(record.Remove.Path))
+	case record.Deployment != nil:
+		if record.Deployment.Removed {
+			delete(c.deployments, record.Deployment.ID)
+		} else {
+			c.deployments[record.Deployment.ID] = record.Deployment
+		}
+	}
+}
+
+// key returns the file map key for a path.
+// Paths are normalized so that names that differ only in Unicode normalization
+// (e.g. when copied from macOS) are the same file.
+func key(path string) string {
+	return norm.NFC.String(path)
+}
+
+// add a record to the journal and the current state.
+func (c *Catalog) add(record *Record) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	record.Time = time.Now()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal catalog record: %w", err)
+	}
+	// A single write per record so concurrent appends don't interleave.
+	if _, err := c.journal.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write catalog record: %w", err)
+	}
+	c.apply(record)
+	return nil
+}
+
+// StartSession records the start of a session importing from the card (nil if unknown or not importing).
+// Files subsequently added to the catalog are marked with the session ID.
+func (c *Catalog) StartSession(command, source string, card *Card) (*Session, error) {
+	now := time.Now()
+	host, _ := os.Hostname()
+	id := now.Format("20060102-150405") + "-"
+	if name := strings.Map(hostRune, host); name != "" {
+		id += name + "-"
+	}
+	session := &Session{
+		ID:       id + strconv.Itoa(os.Getpid()),
+		Started:  now,
+		Host:     host,
+		Operator: CurrentOperator(),
+		Command:  command,
+		Source:   source,
+		Card:     card,
+	}
+	c.mutex.Lock()
+	c.session = session
+	c.mutex.Unlock()
+	return session, c.add(&Record{Session: session})
+}
+
+// hostRune keeps the letters, digits, and hyphens of a host name for session IDs.
+func hostRune(r rune) rune {
+	if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+		return r
+	}
+	return -1
+}
+
+// AddSession records a session from another catalog, e.g. when files are moved between catalogs.
+// Sessions already in the catalog are not recorded again.
+func (c *Catalog) AddSession(session *Session) error {
+	if c.Session(session.ID) != nil {
+		return nil
+	}
+	return c.add(&Record{Session: session})
+}
+
+// AddFile records a file in the target tree.
+func (c *Catalog) AddFile(file *File) error {
+	c.mutex.Lock()
+	if c.session != nil && file.Session == "" {
+		file.Session = c.session.ID
+	}
+	c.mutex.Unlock()
+	return c.add(&Record{File: file})
+}
+
+// MoveFile records the renaming of a file in the target tree.
+func (c *Catalog) MoveFile(from, to string) error {
+	return c.add(&Record{Move: &Move{From: from, To: to}})
+}
+
+// RemoveFile records the removal of a file from the target tree.
+func (c *Catalog) RemoveFile(path string) error {
+	return c.add(&Record{Remove: &Remove{Path: path}})
+}
+
+// File returns the catalog entry for a path relative to the target root, or nil.
+func (c *Catalog) File(path string) *File {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.files[key(path)]
+}
+
+// History returns the journal records of the file currently at the path, oldest first:
+// the file records (each a complete entry) and the moves of the file to its current path.
+// The journal is read again, so other records are not kept in memory.
+func (c *Catalog) History(path string) ([]*Record, error) {
+	file, err := os.Open(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("open catalog journal: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	histories := make(map[string][]*Record)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		switch {
+		case record.File != nil:
+			histories[key(record.File.Path)] = append(histories[key(record.File.Path)], &record)
+		case record.Move != nil:
+			if history, found := histories[key(record.Move.From)]; found {
+				delete(histories, key(record.Move.From))
+				histories[key(record.Move.To)] = append(history, &record)
+			}
+		case record.Remove != nil:
+			delete(histories, key(record.Remove.Path))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read catalog journal: %w", err)
+	}
+	return histories[key(path)], nil
+}
+
+// Rehashed returns the earlier hashes of files whose contents were rewritten since they were cataloged
+// (e.g. by fix-time), mapped to the current paths of the files.
+// The journal is read again, so other records are not kept in memory.
+func (c *Catalog) Rehashed() (map[string]string, error) {
+	file, err := os.Open(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("open catalog journal: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	hashes := make(map[string][]string)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		switch {
+		case record.File != nil:
+			path := key(record.File.Path)
+			if earlier := hashes[path]; len(earlier) == 0 || earlier[len(earlier)-1] != record.File.Hash {
+				hashes[path] = append(earlier, record.File.Hash)
+			}
+		case record.Move != nil:
+			if earlier, found := hashes[key(record.Move.From)]; found {
+				delete(hashes, key(record.Move.From))
+				hashes[key(record.Move.To)] = earlier
+			}
+		case record.Remove != nil:
+			delete(hashes, key(record.Remove.Path))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read catalog journal: %w", err)
+	}
+	rehashed := make(map[string]string)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for path, earlier := range hashes {
+		current := c.files[path]
+		if current == nil {
+			continue
+		}
+		for _, hash := range earlier {
+			if hash != current.Hash {
+				rehashed[hash] = current.Path
+			}
+		}
+	}
+	return rehashed, nil
+}
+
+// Session returns the session with the specified ID, or nil.
+func (c *Catalog) Session(id string) *Session {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.sessions[id]
+}
+
+// Files returns all file entries ordered by path.
+func (c *Catalog) Files() []*File {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	files := make([]*File, 0, len(c.files))
+	for _, file := range c.files {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// Originals returns the file entries which aren't derivatives (see File.Derivative) ordered by path,
+// so that each capture is counted once.
+func (c *Catalog) Originals() []*File {
+	files := c.Files()
+	originals := files[:0]
+	for _, file := range files {
+		if !file.Derivative() {
+			originals = append(originals, file)
+		}
+	}
+	return originals
+}
+
+// Derivatives returns the file entries derived from the file at the path ordered by path.
+func (c *Catalog) Derivatives(path string) []*File {
+	var derivatives []*File
+	for _, file := range c.Files() {
+		if file.Original != "" && key(file.Original) == key(path) {
+			derivatives = append(derivatives, file)
+		}
+	}
+	return derivatives
+}
+
+// AddDeployment records a deployment, replacing any earlier record with the same ID.
+func (c *Catalog) AddDeployment(deployment *Deployment) error {
+	return c.add(&Record{Deployment: deployment})
+}
+
+// Deployment returns the deployment with the specified ID, or nil.
+func (c *Catalog) Deployment(id string) *Deployment {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.deployments[id]
+}
+
+// Deployments returns all deployments ordered by camera and installation time.
+func (c *Catalog) Deployments() []*Deployment {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	deployments := make([]*Deployment, 0, len(c.deployments))
+	for _, deployment := range c.deployments {
+		deployments = append(deployments, deployment)
+	}
+	sort.Slice(deployments, func(i, j int) bool {
+		if deployments[i].Camera != deployments[j].Camera {
+			return deployments[i].Camera < deployments[j].Camera
+		}
+		return deployments[i].Installed.Before(deployments[j].Installed)
+	})
+	return deployments
+}
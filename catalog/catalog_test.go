@@ -0,0 +1,39 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizedPaths(t *testing.T) {
+	dir := t.TempDir()
+	cat, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nfd, nfc := "2024/05-01-06:30:00-Che\u0300vre.JPG", "2024/05-01-06:30:00-Ch\u00e8vre.JPG"
+	if err := cat.AddFile(&File{Path: nfd, Hash: "sha256:00", Captured: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if cat.File(nfc) == nil {
+		t.Errorf("file added in form D not found in form C")
+	}
+	moved := "2024/05-01-07:30:00-Che\u0300vre.JPG"
+	if err := cat.MoveFile(nfc, moved); err != nil {
+		t.Fatal(err)
+	} else if cat.File(nfd) != nil || cat.File(moved) == nil {
+		t.Errorf("file moved by form C path not moved")
+	}
+	_ = cat.Close()
+
+	// The journal is replayed with the same normalization.
+	if cat, err = Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cat.Close() }()
+	if file := cat.File("2024/05-01-07:30:00-Ch\u00e8vre.JPG"); file == nil {
+		t.Errorf("reopened catalog lacks moved file")
+	} else if len(cat.Files()) != 1 {
+		t.Errorf("reopened catalog has %d files, want 1", len(cat.Files()))
+	}
+}
@@ -0,0 +1,201 @@
+// Package chain keeps a tamper-evident record of imported files, e.g. for footage used as evidence.
+//
+// The chain is an append-only journal of JSON links, one per line, each recording the path and hash
+// of an imported file. Each link's digest covers the digest of the previous link, so altering,
+// removing, or reordering a link breaks every following link, and each digest is signed with
+// an Ed25519 private key so that the chain can't simply be rebuilt without the key.
+// Verifying the chain with the public key (and the archived files against the hashes in the chain)
+// demonstrates that the files it records haven't been altered since they were imported.
+//
+// Links removed from the end of the chain leave no trace, as no link follows them,
+// so the chain alone can't show that it is complete. That takes a record of its last link
+// kept elsewhere: published, or timestamped by an RFC 3161 time stamp authority (see Timestamp),
+// which also proves that the chain existed at that time.
+//
+// A chain must only be appended by one process at a time.
+package chain
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the chain journal file.
+const FileName = "chain.jsonl"
+
+// Link is a single line in the chain journal.
+type Link struct {
+	// Seq is the position of the link in the chain, starting at one.
+	Seq  int       `json:"seq"`
+	Time time.Time `json:"time"`
+	// Path of the file relative to its root, with forward slashes.
+	Path string `json:"path"`
+	// Hash of the file contents as algorithm:hex.
+	Hash string `json:"hash"`
+	// Prev is the digest of the previous link, empty for the first link.
+	Prev string `json:"prev,omitempty"`
+	// Digest is the hex SHA-256 digest of the other fields (see digest).
+	Digest string `json:"digest"`
+	// Signature is the base64 Ed25519 signature of the digest.
+	Signature string `json:"sig"`
+}
+
+// digest returns the digest of the link fields other than the digest and signature.
+func (l *Link) digest() string {
+	sum := sha256.Sum256([]byte(l.Prev + "\n" + strconv.Itoa(l.Seq) + "\n" +
+		l.Time.UTC().Format(time.RFC3339Nano) + "\n" + l.Path + "\n" + l.Hash))
+	return hex.EncodeToString(sum[:])
+}
+
+// Chain appends links to a chain journal.
+// It is safe for concurrent use.
+type Chain struct {
+	mutex   sync.Mutex
+	key     ed25519.PrivateKey
+	journal *os.File
+	head    *Link
+	added   int
+}
+
+// Open the chain journal in the specified directory for appending links signed with the key,
+// creating it if necessary.
+func Open(dir string, key ed25519.PrivateKey) (*Chain, error) {
+	path := filepath.Join(dir, FileName)
+	head, err := Head(path)
+	if err != nil {
+		return nil, err
+	}
+	journal, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("open chain journal: %w", err)
+	}
+	return &Chain{key: key, journal: journal, head: head}, nil
+}
+
+// Close the chain journal.
+func (c *Chain) Close() error {
+	return c.journal.Close()
+}
+
+// Append a link for a file to the chain.
+func (c *Chain) Append(path, hash string) (*Link, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	link := &Link{Seq: 1, Time: time.Now().UTC(), Path: path, Hash: hash}
+	if c.head != nil {
+		link.Seq, link.Prev = c.head.Seq+1, c.head.Digest
+	}
+	link.Digest = link.digest()
+	digest, _ := hex.DecodeString(link.Digest)
+	link.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(c.key, digest))
+	data, err := json.Marshal(link)
+	if err != nil {
+		return nil, fmt.Errorf("marshal chain link: %w", err)
+	}
+	// A single write per link so that a crash can at worst leave a partial last line.
+	if _, err := c.journal.Write(append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("write chain link: %w", err)
+	}
+	c.head = link
+	c.added++
+	return link, nil
+}
+
+// Appended returns the number of links appended since the chain was opened.
+func (c *Chain) Appended() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.added
+}
+
+// Head returns the last link of the chain journal at the specified path, nil if there are none.
+// The link is not verified.
+func Head(path string) (*Link, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("open chain journal: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	// Links are short, so the last one is well within the tail of the journal.
+	const tail = 1 << 16
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("seek chain journal: %w", err)
+	}
+	if offset > tail {
+		offset -= tail
+	} else {
+		offset = 0
+	}
+	data := make([]byte, tail)
+	n, err := file.ReadAt(data, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read chain journal: %w", err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data[:n]), []byte{'\n'})
+	last := lines[len(lines)-1]
+	if len(last) == 0 {
+		return nil, nil
+	}
+	link := &Link{}
+	if err := json.Unmarshal(last, link); err != nil {
+		return nil, fmt.Errorf("last chain link: %w", err)
+	}
+	return link, nil
+}
+
+// Verify reads the chain journal in the specified directory, checking that the links are in sequence,
+// that each covers the previous link, and that each is signed with the private key of the public key.
+// The links are returned, up to the first bad link if there is an error.
+func Verify(dir string, public ed25519.PublicKey) ([]*Link, error) {
+	file, err := os.Open(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, fmt.Errorf("open chain journal: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	var links []*Link
+	var prev *Link
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		link := &Link{}
+		if err := json.Unmarshal(scanner.Bytes(), link); err != nil {
+			return links, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch {
+		case prev == nil && (link.Seq != 1 || link.Prev != ""):
+			return links, fmt.Errorf("line %d: chain doesn't start with link 1", line)
+		case prev != nil && link.Seq != prev.Seq+1:
+			return links, fmt.Errorf("line %d: link %d follows link %d", line, link.Seq, prev.Seq)
+		case prev != nil && link.Prev != prev.Digest:
+			return links, fmt.Errorf("line %d: link %d doesn't cover link %d", line, link.Seq, prev.Seq)
+		case link.Digest != link.digest():
+			return links, fmt.Errorf("line %d: link %d altered", line, link.Seq)
+		}
+		digest, _ := hex.DecodeString(link.Digest)
+		if signature, err := base64.StdEncoding.DecodeString(link.Signature); err != nil || !ed25519.Verify(public, digest, signature) {
+			return links, fmt.Errorf("line %d: link %d not signed by key", line, link.Seq)
+		}
+		links = append(links, link)
+		prev = link
+	}
+	if err := scanner.Err(); err != nil {
+		return links, fmt.Errorf("read chain journal: %w", err)
+	}
+	return links, nil
+}
@@ -0,0 +1,125 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testChain appends links for the number of files to a new chain, returning its directory
+// and the public key of the chain.
+func testChain(t *testing.T, files int) (string, ed25519.PublicKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %s", err)
+	}
+	dir := t.TempDir()
+	// Reopened part way to check that appending continues from the head.
+	for _, count := range []int{files / 2, files - files/2} {
+		chain, err := Open(dir, private)
+		if err != nil {
+			t.Fatalf("open chain: %s", err)
+		}
+		for i := 0; i < count; i++ {
+			if _, err := chain.Append("2024/file"+strconv.Itoa(i)+".jpg", "sha256:00"); err != nil {
+				t.Fatalf("append: %s", err)
+			}
+		}
+		if err := chain.Close(); err != nil {
+			t.Fatalf("close chain: %s", err)
+		}
+	}
+	return dir, public
+}
+
+// editLines rewrites the chain journal in the directory with the edited lines.
+func editLines(t *testing.T, dir string, edit func(lines [][]byte) [][]byte) {
+	t.Helper()
+	path := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read chain: %s", err)
+	}
+	lines := edit(bytes.Split(bytes.TrimSuffix(data, []byte{'\n'}), []byte{'\n'}))
+	if err := os.WriteFile(path, append(bytes.Join(lines, []byte{'\n'}), '\n'), 0666); err != nil {
+		t.Fatalf("write chain: %s", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %s", err)
+	}
+	tests := []struct {
+		name  string
+		edit  func(lines [][]byte) [][]byte
+		other bool
+		links int
+		err   string
+	}{
+		{name: "intact", links: 5},
+		{name: "altered", edit: func(lines [][]byte) [][]byte {
+			lines[2] = bytes.Replace(lines[2], []byte("2024/"), []byte("2025/"), 1)
+			return lines
+		}, links: 2, err: "link 3 altered"},
+		{name: "removed", edit: func(lines [][]byte) [][]byte {
+			return append(lines[:2], lines[3:]...)
+		}, links: 2, err: "link 4 follows link 2"},
+		{name: "reordered", edit: func(lines [][]byte) [][]byte {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, links: 1, err: "link 3 follows link 1"},
+		{name: "removed first", edit: func(lines [][]byte) [][]byte {
+			return lines[1:]
+		}, err: "doesn't start with link 1"},
+		// Undetectable from the chain alone (see the package documentation).
+		{name: "truncated", edit: func(lines [][]byte) [][]byte {
+			return lines[:3]
+		}, links: 3},
+		{name: "other key", other: true, err: "link 1 not signed by key"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, public := testChain(t, 5)
+			if test.edit != nil {
+				editLines(t, dir, test.edit)
+			}
+			if test.other {
+				public = other
+			}
+			links, err := Verify(dir, public)
+			if test.err == "" && err != nil {
+				t.Errorf("verify: %s", err)
+			} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("error %v, want %q", err, test.err)
+			}
+			if len(links) != test.links {
+				t.Errorf("%d links, want %d", len(links), test.links)
+			}
+		})
+	}
+}
+
+func TestHead(t *testing.T) {
+	dir, public := testChain(t, 3)
+	links, err := Verify(dir, public)
+	if err != nil {
+		t.Fatalf("verify: %s", err)
+	}
+	head, err := Head(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("head: %s", err)
+	} else if head == nil || head.Seq != 3 || head.Digest != links[2].Digest {
+		t.Errorf("head %+v, want link 3", head)
+	}
+	if head, err := Head(filepath.Join(t.TempDir(), FileName)); err != nil || head != nil {
+		t.Errorf("head of no chain %+v, %v", head, err)
+	}
+}
@@ -0,0 +1,104 @@
+package chain
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// PublicSuffix is appended to the private key path for the public key file.
+const PublicSuffix = ".pub"
+
+// GenerateKey writes a new Ed25519 private key to the specified path (readable only by the owner)
+// and its public key to the path plus PublicSuffix, both PEM encoded.
+// Existing files are not overwritten.
+func GenerateKey(path string) error {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return fmt.Errorf("marshal private key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return fmt.Errorf("marshal public key: %w", err)
+	}
+	if err := writePEM(path, "PRIVATE KEY", privateDER, 0600); err != nil {
+		return err
+	}
+	return writePEM(path+PublicSuffix, "PUBLIC KEY", publicDER, 0644)
+}
+
+func writePEM(path, kind string, der []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return fmt.Errorf("create key file: %w", err)
+	}
+	if err := pem.Encode(file, &pem.Block{Type: kind, Bytes: der}); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("write key file: %w", err)
+	}
+	return file.Close()
+}
+
+// ReadPrivateKey reads a PEM encoded Ed25519 private key.
+func ReadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+	}
+	return private, nil
+}
+
+// ReadPublicKey reads a PEM encoded Ed25519 public key, or the public key of a private key.
+func ReadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path, "")
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "PRIVATE KEY" {
+		private, err := ReadPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return private.Public().(ed25519.PublicKey), nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+	}
+	return public, nil
+}
+
+// readPEM reads the first PEM block of a file, which must be of the specified type if not empty.
+func readPEM(path, kind string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data in " + path)
+	} else if kind != "" && block.Type != kind {
+		return nil, fmt.Errorf("%s is not a %s", path, kind)
+	}
+	return block, nil
+}
@@ -0,0 +1,161 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// Timeout is how long a time stamp request may take.
+const Timeout = time.Minute
+
+// RFC 3161 time stamp request and response, only as far as needed.
+type (
+	algorithmIdentifier struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters asn1.RawValue `asn1:"optional"`
+	}
+	messageImprint struct {
+		HashAlgorithm algorithmIdentifier
+		HashedMessage []byte
+	}
+	timeStampReq struct {
+		Version        int
+		MessageImprint messageImprint
+		Nonce          *big.Int `asn1:"optional"`
+		CertReq        bool     `asn1:"optional,default:false"`
+	}
+	pkiStatusInfo struct {
+		Status       int
+		StatusString asn1.RawValue  `asn1:"optional"`
+		FailInfo     asn1.BitString `asn1:"optional"`
+	}
+	timeStampResp struct {
+		Status         pkiStatusInfo
+		TimeStampToken asn1.RawValue `asn1:"optional"`
+	}
+	// The time stamp token is a CMS ContentInfo holding SignedData which encapsulates the TSTInfo.
+	// The certificates and signatures that follow the encapsulated content are skipped.
+	contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     signedData `asn1:"explicit,tag:0"`
+	}
+	signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		EncapContentInfo encapsulatedContentInfo
+	}
+	encapsulatedContentInfo struct {
+		EContentType asn1.ObjectIdentifier
+		EContent     []byte `asn1:"explicit,tag:0"`
+	}
+	accuracy struct {
+		Seconds int `asn1:"optional"`
+		Millis  int `asn1:"optional,tag:0"`
+		Micros  int `asn1:"optional,tag:1"`
+	}
+	tstInfo struct {
+		Version        int
+		Policy         asn1.ObjectIdentifier
+		MessageImprint messageImprint
+		SerialNumber   *big.Int
+		GenTime        time.Time `asn1:"generalized"`
+		Accuracy       accuracy  `asn1:"optional"`
+		Ordering       bool      `asn1:"optional"`
+		Nonce          *big.Int  `asn1:"optional"`
+	}
+)
+
+var (
+	oidSHA256     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+)
+
+// Timestamp asks the RFC 3161 time stamp authority at the URL to timestamp the digest of a link
+// and returns the DER encoded time stamp response, e.g. to be saved as a .tsr file.
+// The response is checked to be granted and to timestamp the digest in answer to this request (its nonce),
+// but its signature is not: verify the token with the certificate of the authority,
+// e.g. openssl ts -verify -digest DIGEST -in FILE.tsr -CAfile CERTS.pem
+func Timestamp(url string, link *Link) ([]byte, error) {
+	digest, err := hex.DecodeString(link.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("link digest: %w", err)
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, fmt.Errorf("nonce: %w", err)
+	}
+	request, err := asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: algorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+			HashedMessage: digest,
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal time stamp request: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	post, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(request))
+	if err != nil {
+		return nil, fmt.Errorf("time stamp request: %w", err)
+	}
+	post.Header.Set("Content-Type", "application/timestamp-query")
+	response, err := http.DefaultClient.Do(post)
+	if err != nil {
+		return nil, fmt.Errorf("time stamp request: %w", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read time stamp response: %w", err)
+	} else if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("time stamp request: %s", response.Status)
+	}
+	var resp timeStampResp
+	if _, err := asn1.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal time stamp response: %w", err)
+	}
+	// 0 is granted, 1 is granted with modifications.
+	if resp.Status.Status > 1 || len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, fmt.Errorf("time stamp request rejected with status %d", resp.Status.Status)
+	}
+	info, err := parseToken(resp.TimeStampToken.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) || !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, fmt.Errorf("time stamp is not of link %d", link.Seq)
+	} else if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, fmt.Errorf("time stamp nonce doesn't match request")
+	}
+	return data, nil
+}
+
+// parseToken returns the TSTInfo of a time stamp token.
+func parseToken(token []byte) (*tstInfo, error) {
+	var content contentInfo
+	if _, err := asn1.Unmarshal(token, &content); err != nil {
+		return nil, fmt.Errorf("unmarshal time stamp token: %w", err)
+	} else if !content.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("time stamp token is not signed data")
+	} else if !content.Content.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, fmt.Errorf("time stamp token does not hold TSTInfo")
+	}
+	info := &tstInfo{}
+	if _, err := asn1.Unmarshal(content.Content.EncapContentInfo.EContent, info); err != nil {
+		return nil, fmt.Errorf("unmarshal time stamp info: %w", err)
+	}
+	return info, nil
+}
@@ -0,0 +1,150 @@
+package chain
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The trailing fields of a time stamp token which Timestamp skips.
+type (
+	testSignedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		EncapContentInfo encapsulatedContentInfo
+		Certificates     asn1.RawValue
+		SignerInfos      asn1.RawValue
+	}
+	testContentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     testSignedData `asn1:"explicit,tag:0"`
+	}
+	testTSTInfo struct {
+		Version        int
+		Policy         asn1.ObjectIdentifier
+		MessageImprint messageImprint
+		SerialNumber   *big.Int
+		GenTime        time.Time `asn1:"generalized"`
+		Accuracy       accuracy  `asn1:"optional"`
+		Ordering       bool      `asn1:"optional"`
+		Nonce          *big.Int  `asn1:"optional"`
+		Extensions     asn1.RawValue
+	}
+)
+
+// testTSA returns the URL of a time stamp authority which answers each request
+// with the response made by the respond function from the request and the TSTInfo it would grant.
+func testTSA(t *testing.T, respond func(request *timeStampReq, info *testTSTInfo) *timeStampResp) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var request timeStampReq
+		if _, err := asn1.Unmarshal(data, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		info := &testTSTInfo{
+			Version:        1,
+			Policy:         asn1.ObjectIdentifier{1, 2, 3, 4},
+			MessageImprint: request.MessageImprint,
+			SerialNumber:   big.NewInt(42),
+			GenTime:        time.Now().UTC().Truncate(time.Second),
+			Accuracy:       accuracy{Seconds: 1},
+			Nonce:          request.Nonce,
+			Extensions:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: []byte{}},
+		}
+		response := respond(&request, info)
+		if response == nil {
+			response = &timeStampResp{Status: pkiStatusInfo{Status: 0}, TimeStampToken: testToken(t, oidTSTInfo, info)}
+		}
+		data, err := asn1.Marshal(*response)
+		if err != nil {
+			t.Errorf("marshal response: %s", err)
+		}
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// testToken returns an (unsigned) time stamp token encapsulating the info as the content type.
+func testToken(t *testing.T, contentType asn1.ObjectIdentifier, info *testTSTInfo) asn1.RawValue {
+	t.Helper()
+	eContent, err := asn1.Marshal(*info)
+	if err != nil {
+		t.Fatalf("marshal info: %s", err)
+	}
+	set := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: []byte{}}
+	token, err := asn1.Marshal(testContentInfo{
+		ContentType: oidSignedData,
+		Content: testSignedData{
+			Version:          3,
+			DigestAlgorithms: set,
+			EncapContentInfo: encapsulatedContentInfo{EContentType: contentType, EContent: eContent},
+			Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: []byte{}},
+			SignerInfos:      set,
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal token: %s", err)
+	}
+	return asn1.RawValue{FullBytes: token}
+}
+
+func TestTimestamp(t *testing.T) {
+	sum := sha256.Sum256([]byte("link"))
+	link := &Link{Seq: 7, Digest: hex.EncodeToString(sum[:])}
+	tests := []struct {
+		name    string
+		respond func(request *timeStampReq, info *testTSTInfo) *timeStampResp
+		err     string
+	}{
+		{"granted", func(*timeStampReq, *testTSTInfo) *timeStampResp { return nil }, ""},
+		{"rejected", func(*timeStampReq, *testTSTInfo) *timeStampResp {
+			return &timeStampResp{Status: pkiStatusInfo{Status: 2}}
+		}, "rejected with status 2"},
+		{"other digest", func(_ *timeStampReq, info *testTSTInfo) *timeStampResp {
+			info.MessageImprint.HashedMessage = make([]byte, sha256.Size)
+			return nil
+		}, "not of link 7"},
+		{"other algorithm", func(_ *timeStampReq, info *testTSTInfo) *timeStampResp {
+			info.MessageImprint.HashAlgorithm.Algorithm = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+			return nil
+		}, "not of link 7"},
+		{"other nonce", func(_ *timeStampReq, info *testTSTInfo) *timeStampResp {
+			info.Nonce = new(big.Int).Add(info.Nonce, big.NewInt(1))
+			return nil
+		}, "nonce doesn't match"},
+		{"no nonce", func(_ *timeStampReq, info *testTSTInfo) *timeStampResp {
+			info.Nonce = nil
+			return nil
+		}, "nonce doesn't match"},
+		{"not TSTInfo", func(_ *timeStampReq, info *testTSTInfo) *timeStampResp {
+			return &timeStampResp{TimeStampToken: testToken(t, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}, info)}
+		}, "does not hold TSTInfo"},
+		{"garbage", func(*timeStampReq, *testTSTInfo) *timeStampResp {
+			return &timeStampResp{TimeStampToken: asn1.RawValue{FullBytes: []byte{0x30, 0x03, 0x02, 0x01, 0x00}}}
+		}, "unmarshal time stamp token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := Timestamp(testTSA(t, test.respond), link)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("timestamp: %s", err)
+			case test.err == "" && len(response) == 0:
+				t.Error("empty response")
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+func activityCommand(args []string) {
+	var sites bool
+	var by, format, from, output, tag, target, until string
+
+	flags := flag.NewFlagSet("activity", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&by, "by", "hour", "Count captures by hour of day or week of year (hour or week)")
+	flags.StringVar(&format, "format", "csv", "Output format (csv or json)")
+	flags.StringVar(&from, "from", "", "First capture date (YYYY-MM-DD)")
+	flags.StringVar(&until, "until", "", "Last capture date (YYYY-MM-DD)")
+	flags.StringVar(&tag, "tag", "", "Only count captures with this tag or label")
+	flags.StringVar(&output, "o", "", "Output file [standard output]")
+	flags.BoolVar(&sites, "sites", false, "Include the cameras of merged site catalogs")
+	_ = flags.Parse(args)
+	if target == "" || by != "hour" && by != "week" || format != "csv" && format != "json" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	var start, end time.Time
+	var err error
+	if from != "" {
+		if start, err = time.Parse(dateFmt, from); err != nil {
+			log.Fatal().Err(err).Msg("Parse -from")
+		}
+	}
+	if until != "" {
+		if end, err = time.Parse(dateFmt, until); err != nil {
+			log.Fatal().Err(err).Msg("Parse -until")
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	defer func() { _ = cat.Close() }()
+	activity := importer.Activity(cat, start, end, tag)
+	if sites {
+		withSites(target, func(site string, cat *catalog.Catalog) {
+			for _, camera := range importer.Activity(cat, start, end, tag) {
+				camera.Camera = site + ":" + camera.Camera
+				activity = append(activity, camera)
+			}
+		})
+	}
+
+	var writer io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Create output")
+		}
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Fatal().Err(err).Msg("Close output")
+			}
+		}()
+		writer = file
+	}
+	if format == "json" {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(activity)
+	} else {
+		err = writeActivityCSV(writer, activity, by)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Write activity")
+	}
+}
+
+// writeActivityCSV writes a row of capture counts by hour or week for each camera.
+func writeActivityCSV(writer io.Writer, activity []*importer.CameraActivity, by string) error {
+	csvWriter := csv.NewWriter(writer)
+	header := []string{"camera", "files"}
+	if by == "week" {
+		for week := 1; week <= 53; week++ {
+			header = append(header, "week "+strconv.Itoa(week))
+		}
+	} else {
+		for hour := 0; hour < 24; hour++ {
+			header = append(header, "hour "+strconv.Itoa(hour))
+		}
+	}
+	_ = csvWriter.Write(header)
+	for _, camera := range activity {
+		counts := camera.Hours[:]
+		if by == "week" {
+			counts = camera.Weeks[:]
+		}
+		row := []string{camera.Camera, strconv.Itoa(camera.Files)}
+		for _, count := range counts {
+			row = append(row, strconv.Itoa(count))
+		}
+		_ = csvWriter.Write(row)
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func adoptCommand(args []string) {
+	var checkExposure, sidecarGPS bool
+	var hashAlgorithm, pluginDir, pool string
+
+	flags := flag.NewFlagSet("adopt", flag.ExitOnError)
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.BoolVar(&checkExposure, "exposure", false, "Flag badly exposed JPG files in the catalog")
+	flags.StringVar(&hashAlgorithm, "hash", importer.HashSHA256, "Hash algorithm (sha256, xxh3, or blake3)")
+	flags.StringVar(&pluginDir, "plugins", "", "Plugin directory [user config dir/gardepro/plugins]")
+	flags.BoolVar(&sidecarGPS, "sidecar-gps", false, "Record GPS positions from drone SRT sidecars in the catalog")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	target := flags.Arg(0)
+
+	consoleLog()
+	options := importer.Options{
+		CheckExposure: checkExposure,
+		Hash:          hashAlgorithm,
+		Pool:          poolRoots(pool),
+		SidecarGPS:    sidecarGPS,
+	}
+	if err := loadPlugins(pluginDir, &options); err != nil {
+		log.Fatal().Err(err).Msg("Load plugins")
+	}
+	options.Notify = nil
+	cat := commandCatalog(target, "adopt", "")
+	defer func() { _ = cat.Close() }()
+	options.Catalog = cat
+	result, err := importer.New(target, options).Adopt()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Adopt")
+	}
+	log.Info().Int("adopted", result.Adopted).Int("cataloged", result.Cataloged).
+		Int("unnamed", result.Unnamed).Int("mismatched", result.Mismatched).Msg("Adopt finished")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func auditCardCommand(args []string) {
+	var verify bool
+	var hashAlgorithm, target string
+
+	flags := flag.NewFlagSet("audit-card", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&hashAlgorithm, "hash", importer.HashSHA256, "Hash algorithm of the target catalog (sha256, xxh3, or blake3)")
+	flags.BoolVar(&verify, "verify", false, "Hash archived files again instead of trusting the catalog")
+	_ = flags.Parse(args)
+	if target == "" || flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	card := flags.Arg(0)
+
+	consoleLog()
+	stragglers, err := importer.Audit(card, target, hashAlgorithm, verify)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Audit card")
+	}
+	if len(stragglers) == 0 {
+		fmt.Printf("Safe to format: every media file on %s is archived in %s\n", card, target)
+		return
+	}
+	fmt.Printf("NOT safe to format: %d media files on %s are not archived in %s:\n", len(stragglers), card, target)
+	for _, path := range stragglers {
+		fmt.Println(path)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func benchCommand(args []string) {
+	var size int64
+	var source, target string
+
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	flags.StringVar(&source, "source", "", "Source directory of media files (e.g. a card)")
+	flags.StringVar(&target, "target", "", "Target directory on the archive device")
+	flags.Int64Var(&size, "size", 256, "Maximum MiB of source files to use")
+	_ = flags.Parse(args)
+	if source == "" || target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	sources, err := importer.SourceFiles(source)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Find source files")
+	}
+	results, err := importer.Bench(sources, target, &importer.BenchOptions{
+		BlockSizes: []int{32 << 10, 128 << 10, 512 << 10, 1 << 20, 4 << 20},
+		Jobs:       []int{1, 2, 4, 8},
+		Limit:      size << 20,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Benchmark")
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(writer, "Test\tHash\tBlock KiB\tJobs\tMiB\tMiB/s\t")
+	for _, result := range results {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%d\t%.1f\t\n", result.Test, result.Hash,
+			result.BlockSize>>10, result.Jobs, result.Bytes>>20, result.Throughput())
+	}
+	_ = writer.Flush()
+	if best := importer.BenchRecommend(results); best != nil {
+		fmt.Printf("\nRecommended: -jobs %d -blocksize %d\n", best.Jobs, best.BlockSize)
+	}
+}
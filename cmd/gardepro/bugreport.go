@@ -0,0 +1,347 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+// bugReportLogSize is how much of the end of the log is included in a bug report.
+const bugReportLogSize = 1 << 20
+
+// bugReportEnv are environment variables other than GARDEPRO_ ones included in a bug report.
+var bugReportEnv = []string{"LANG", "LANGUAGE", "LC_ALL", "LC_MESSAGES", "NO_COLOR", "TZ"}
+
+// bugReportEnvPrefixes are the prefixes of environment variables included in a bug report,
+// those setting flags (see envFlags) and those configuring S3 (e.g. AWS_ENDPOINT_URL).
+var bugReportEnvPrefixes = []string{"GARDEPRO_", "AWS_"}
+
+var (
+	// redactUserInfo matches the password of a URL, e.g. in an -mqtt or -smtp flag.
+	redactUserInfo = regexp.MustCompile(`(\w+://[^:/@\s]*:)[^@\s]+@`)
+	// redactQuery matches credentials passed as URL query parameters, e.g. in a -push flag.
+	redactQuery = regexp.MustCompile(`(?i)([?&](?:api_?key|key|password|secret|sig|signature|token)=)[^&\s]+`)
+	// redactPushover matches the tokens of a Pushover -push service.
+	redactPushover = regexp.MustCompile(`(pushover:)[^,\s]+`)
+	// redactNtfy matches the topic of an ntfy -push service, which anyone knowing it can read and publish to.
+	redactNtfy = regexp.MustCompile(`(ntfy:(?:https?://[^/,\s]+/)?)[^,\s]+`)
+	// redactName matches setting and environment variable names whose values are secret,
+	// e.g. API keys and AWS_SECRET_ACCESS_KEY.
+	redactName = regexp.MustCompile(`(?i)password|secret|token|key|credential`)
+)
+
+func bugReportCommand(args []string) {
+	var logFile, output, session, target string
+
+	flags := flag.NewFlagSet("bugreport", flag.ExitOnError)
+	flags.StringVar(&logFile, "log", "/tmp/gardepro.log", "Path to log file")
+	flags.StringVar(&output, "o", "", "Report file [gardepro-bugreport-DATE-TIME.tar.gz]")
+	flags.StringVar(&session, "session", "", "Session whose journal is included [latest]")
+	flags.StringVar(&target, "target", "", "Target directory whose catalog and session journal are included")
+	_ = flags.Parse(args)
+
+	consoleLog()
+	now := time.Now()
+	if output == "" {
+		output = "gardepro-bugreport-" + now.Format("20060102-150405") + ".tar.gz"
+	}
+
+	files := map[string][]byte{
+		"environment.txt": bugReportEnvironment(now, target),
+		"config.txt":      bugReportConfig(),
+	}
+	if data, err := bugReportLog(logFile); err != nil {
+		log.Warn().Err(err).Str("log", logFile).Msg("Log not included")
+	} else {
+		files["gardepro.log"] = data
+	}
+	if target != "" {
+		files["catalog.txt"] = bugReportCatalog(target)
+		if name, data, err := bugReportJournal(target, session); err != nil {
+			log.Warn().Err(err).Msg("Session journal not included")
+		} else {
+			files[name] = data
+		}
+	}
+
+	if err := writeBugReport(output, files, now); err != nil {
+		log.Fatal().Err(err).Msg("Write bug report")
+	}
+	fmt.Printf("Wrote %s, please check it for anything private before attaching it to an issue\n", output)
+}
+
+// bugReportEnvironment describes the build and the system.
+func bugReportEnvironment(now time.Time, target string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Version:  %s\n", version)
+	fmt.Fprintf(&buf, "Go:       %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&buf, "CPUs:     %d\n", runtime.NumCPU())
+	zone, offset := now.Zone()
+	fmt.Fprintf(&buf, "Time:     %s (%s %+d)\n", now.Format(time.RFC3339), zone, offset/3600)
+	if executable, err := os.Executable(); err == nil {
+		fmt.Fprintf(&buf, "Command:  %s\n", executable)
+	}
+	if target != "" {
+		if stat, err := os.Stat(target); err != nil {
+			fmt.Fprintf(&buf, "Target:   %s\n", err)
+		} else {
+			fmt.Fprintf(&buf, "Target:   %s (%s)\n", target, stat.Mode())
+		}
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(&buf, "\nModule:   %s %s\n", info.Main.Path, info.Main.Version)
+		for _, setting := range info.Settings {
+			fmt.Fprintf(&buf, "Setting:  %s=%s\n", setting.Key, setting.Value)
+		}
+		for _, dep := range info.Deps {
+			fmt.Fprintf(&buf, "Depends:  %s %s\n", dep.Path, dep.Version)
+		}
+	}
+	return buf.Bytes()
+}
+
+// bugReportConfig lists the config file (see configFlags), the GARDEPRO_ environment variables
+// setting flags (see envFlags), other environment variables affecting the application (e.g. AWS_),
+// and the installed plugins.
+// Secrets are redacted.
+func bugReportConfig() []byte {
+	var lines []string
+	for _, variable := range os.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		if !hasPrefix(name, bugReportEnvPrefixes) && !contains(bugReportEnv, name) {
+			continue
+		}
+		if redactName.MatchString(name) {
+			value = "REDACTED"
+		}
+		lines = append(lines, name+"="+redact(value))
+	}
+	sort.Strings(lines)
+	var buf bytes.Buffer
+	path := configPath()
+	fmt.Fprintf(&buf, "# Config file %s\n", path)
+	if settings, err := readConfig(path); err != nil {
+		fmt.Fprintln(&buf, err)
+	} else {
+		for _, setting := range settings {
+			if isSection(setting) {
+				fmt.Fprintln(&buf, setting[0])
+				continue
+			}
+			value := setting[1]
+			if redactName.MatchString(setting[0]) {
+				value = "REDACTED"
+			}
+			fmt.Fprintf(&buf, "%s=%s\n", setting[0], redact(value))
+		}
+	}
+	fmt.Fprintln(&buf, "\n# Environment")
+	for _, line := range lines {
+		fmt.Fprintln(&buf, line)
+	}
+	dir := defaultPluginDir()
+	fmt.Fprintf(&buf, "\n# Plugins in %s\n", dir)
+	if entries, err := os.ReadDir(dir); err != nil {
+		fmt.Fprintln(&buf, err)
+	} else {
+		for _, entry := range entries {
+			fmt.Fprintln(&buf, entry.Name())
+		}
+	}
+	return buf.Bytes()
+}
+
+// bugReportLog returns the end of the log file, starting at a line, with secrets redacted.
+func bugReportLog(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() > bugReportLogSize {
+		if _, err := file.Seek(stat.Size()-bugReportLogSize, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() > bugReportLogSize {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return []byte(redact(string(data))), nil
+}
+
+// bugReportCatalog describes the catalog of the target.
+// The catalog has no schema version, so the kinds of records in it are counted instead,
+// which shows the features that wrote to it.
+func bugReportCatalog(target string) []byte {
+	var buf bytes.Buffer
+	path := filepath.Join(target, importer.StateDir, catalog.FileName)
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(&buf, err)
+		return buf.Bytes()
+	}
+	defer func() { _ = file.Close() }()
+	counts := map[string]int{}
+	var first, last time.Time
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		record := map[string]json.RawMessage{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			counts["unparsable"]++
+			continue
+		}
+		for kind := range record {
+			if kind == "time" {
+				var when time.Time
+				if json.Unmarshal(record[kind], &when) == nil {
+					if first.IsZero() {
+						first = when
+					}
+					last = when
+				}
+			} else {
+				counts[kind]++
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(&buf, "Error:    %s\n", err)
+	}
+	fmt.Fprintf(&buf, "Catalog:  %s\n", path)
+	if !first.IsZero() {
+		fmt.Fprintf(&buf, "First:    %s\n", first.Local().Format(timeFmt))
+		fmt.Fprintf(&buf, "Last:     %s\n", last.Local().Format(timeFmt))
+	}
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Fprintf(&buf, "Records:  %d %s\n", counts[kind], kind)
+	}
+	return buf.Bytes()
+}
+
+// bugReportJournal returns the name and contents of the journal of the session in the target,
+// or of the latest session if none is specified.
+func bugReportJournal(target, session string) (string, []byte, error) {
+	dir := filepath.Join(target, importer.JournalDir)
+	name := session + ".json"
+	if session == "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", nil, err
+		}
+		var latest time.Time
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && !entry.IsDir() && info.ModTime().After(latest) {
+				name, latest = entry.Name(), info.ModTime()
+			}
+		}
+		if latest.IsZero() {
+			return "", nil, fmt.Errorf("no journals in %s", dir)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", nil, err
+	}
+	return "sessions/" + name, data, nil
+}
+
+// writeBugReport writes the files into a gzipped tar file.
+func writeBugReport(path string, files map[string][]byte, now time.Time) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	zipper := gzip.NewWriter(out)
+	archive := tar.NewWriter(zipper)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// Files are put in a directory named after the report.
+	dir, _, _ := strings.Cut(filepath.Base(path), ".")
+	for _, name := range names {
+		header := &tar.Header{Name: dir + "/" + name, Mode: 0644, Size: int64(len(files[name])), ModTime: now}
+		if err = archive.WriteHeader(header); err != nil {
+			break
+		}
+		if _, err = archive.Write(files[name]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err == nil {
+		err = zipper.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
+
+// redact replaces URL passwords, credential query parameters, Pushover tokens, and ntfy topics in the text.
+func redact(text string) string {
+	text = redactUserInfo.ReplaceAllString(text, "${1}REDACTED@")
+	text = redactPushover.ReplaceAllString(text, "${1}REDACTED")
+	text = redactNtfy.ReplaceAllString(text, "${1}REDACTED")
+	return redactQuery.ReplaceAllString(text, "${1}REDACTED")
+}
+
+// hasPrefix returns whether the string has any of the prefixes.
+func hasPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns whether the list contains the string.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
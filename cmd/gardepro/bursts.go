@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func burstsCommand(args []string) {
+	var options importer.BurstOptions
+	var pool, target string
+
+	flags := flag.NewFlagSet("bursts", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.DurationVar(&options.Gap, "gap", importer.DefaultBurstGap, "Longest time between frames of a burst")
+	flags.StringVar(&options.LinkDir, "link", "", "Directory for symbolic links to the best frame of each burst")
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	cat := commandCatalog(target, "bursts", "")
+	defer func() { _ = cat.Close() }()
+	if count, err := importer.New(target, importer.Options{Catalog: cat, Pool: poolRoots(pool)}).MarkBursts(&options); err != nil {
+		log.Fatal().Err(err).Int("bursts", count).Msg("Mark bursts")
+	} else {
+		log.Info().Int("bursts", count).Msg("Mark bursts finished")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/chain"
+	"github.com/madkins23/gardepro/importer"
+)
+
+func chainCommand(args []string) {
+	var keyFile, keygen, target, tsa string
+
+	flags := flag.NewFlagSet("chain", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&keyFile, "key", "", "Public (or private) key file of the chain")
+	flags.StringVar(&keygen, "keygen", "", "Write a new private key to this file and its public key to FILE.pub")
+	flags.StringVar(&tsa, "tsa", "", "RFC 3161 time stamp authority URL for timestamping the end of the chain")
+	_ = flags.Parse(args)
+	if keygen != "" {
+		if err := chain.GenerateKey(keygen); err != nil {
+			fatalf("Generate key: %s", err)
+		}
+		fmt.Printf("Private key: %s (keep it safe, use with -chain-key)\n", keygen)
+		fmt.Printf("Public key:  %s (give it to anyone verifying the chain)\n", keygen+chain.PublicSuffix)
+		return
+	}
+	if target == "" || keyFile == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	public, err := chain.ReadPublicKey(keyFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Read key")
+	}
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	defer func() { _ = cat.Close() }()
+	result, err := importer.New(target, importer.Options{Catalog: cat}).CheckChain(public)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Chain broken")
+	}
+	log.Info().Int("verified", result.Verified).Int("damaged", len(result.Damaged)).
+		Int("missing", len(result.Missing)).Msg("Chain verified")
+	if tsa != "" {
+		timestampChain(target, tsa)
+	}
+	if len(result.Damaged) > 0 || len(result.Missing) > 0 {
+		os.Exit(1)
+	}
+}
+
+// timestampChain has the last link of the hash chain of the target timestamped by the time stamp authority,
+// saving the response as chain-SEQ.tsr in the state directory. Failures are logged.
+func timestampChain(target, tsa string) {
+	dir := filepath.Join(target, importer.StateDir)
+	head, err := chain.Head(filepath.Join(dir, chain.FileName))
+	if err != nil {
+		log.Warn().Err(err).Msg("Timestamp chain")
+		return
+	} else if head == nil {
+		return
+	}
+	response, err := chain.Timestamp(tsa, head)
+	if err != nil {
+		log.Warn().Err(err).Str("tsa", tsa).Msg("Timestamp chain")
+		return
+	}
+	path := filepath.Join(dir, "chain-"+strconv.Itoa(head.Seq)+".tsr")
+	if err := os.WriteFile(path, response, 0666); err != nil {
+		log.Warn().Err(err).Msg("Timestamp chain")
+		return
+	}
+	log.Info().Int("link", head.Seq).Str("digest", head.Digest).Str("response", path).Msg("Timestamped chain")
+}
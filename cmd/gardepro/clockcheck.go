@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+// clockTolerance is how far capture times may go backwards in sequence order
+// (e.g. between a photo and the video started before it finished) before a clock change is suspected.
+const clockTolerance = time.Minute
+
+func clockCheckCommand(args []string) {
+	var chainKey, fix, pool, target string
+	var tolerance time.Duration
+
+	flags := flag.NewFlagSet("clock-check", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.DurationVar(&tolerance, "tolerance", clockTolerance, "How far capture times may go backwards in sequence order")
+	flags.StringVar(&fix, "fix", "", "Archived path of the first file after a clock change to fix (as listed)")
+	flags.StringVar(&chainKey, "chain-key", "", "Private key file for chaining the fixed files in the hash chain")
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	cat := commandCatalog(target, "clock-check", "")
+	defer func() { _ = cat.Close() }()
+	jumps := importer.ClockJumps(cat, "", tolerance)
+	if fix == "" {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(writer, "Camera\tBefore\tCaptured\tAfter\tCaptured\tOffset\tFiles\t")
+		for _, jump := range jumps {
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t\n", jump.Camera,
+				jump.Before.Path, jump.Before.Captured.Format(timeFmt),
+				jump.After.Path, jump.After.Captured.Format(timeFmt), jump.Offset, len(jump.Files))
+		}
+		_ = writer.Flush()
+		return
+	}
+	for _, jump := range jumps {
+		if jump.After.Path != fix {
+			continue
+		}
+		options := importer.FixTimeOptions{Offset: jump.Offset, Files: jump.Files}
+		ch := fixTimeChain(target, chainKey)
+		if ch != nil {
+			defer func() { _ = ch.Close() }()
+		}
+		if fixed, err := importer.New(target, importer.Options{Catalog: cat, Chain: ch, Pool: poolRoots(pool)}).FixTime(&options); err != nil {
+			log.Fatal().Err(err).Int("fixed", fixed).Msg("Fix time")
+		} else {
+			log.Info().Int("fixed", fixed).Str("offset", jump.Offset.String()).Msg("Fixed clock change")
+		}
+		return
+	}
+	fatalf("No clock change before %s", fix)
+}
+
+// warnClockJumps logs the probable clock changes among the files imported from the source,
+// which can be fixed with clock-check.
+func warnClockJumps(cat *catalog.Catalog, source, target string) {
+	for _, jump := range importer.ClockJumps(cat, source, clockTolerance) {
+		log.Warn().Str("camera", jump.Camera).Str("before", jump.Before.Path).Str("after", jump.After.Path).
+			Str("offset", jump.Offset.String()).Int("files", len(jump.Files)).
+			Str("fix", fmt.Sprintf("gardepro clock-check -target %s -fix %s", shellQuote(target), shellQuote(jump.After.Path))).
+			Msg("Capture times go backwards, probable clock change")
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+// configEnv is the environment variable naming the config file instead of configPath.
+const configEnv = "GARDEPRO_CONFIG"
+
+// configPath returns the path of the config file, by default in the user configuration directory.
+func configPath() string {
+	if path := os.Getenv(configEnv); path != "" {
+		return path
+	}
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(config, "gardepro", "config")
+}
+
+// configFlags sets flags from the config file, if there is one.
+// Each line of the file is a flag name and value (e.g. target=/data) and lines starting with # are comments.
+// The file is shared by the import and kiosk modes, so names of flags not in the set are ignored.
+// Flags are set until the first section (see configCameras).
+// The environment (see envFlags) and command line take precedence.
+func configFlags(flags *flag.FlagSet) error {
+	settings, err := readConfig(configPath())
+	if err != nil {
+		return err
+	}
+	for _, setting := range globalSettings(settings) {
+		if flags.Lookup(setting[0]) == nil {
+			continue
+		}
+		if err := flags.Set(setting[0], setting[1]); err != nil {
+			return fmt.Errorf("%s: %w", setting[0], err)
+		}
+	}
+	return nil
+}
+
+// readConfig returns the flag names and values in the config file, none if it doesn't exist.
+// Section headers (e.g. [camera north]) are returned as names with empty values.
+func readConfig(path string) ([][2]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("open config: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	var settings [][2]string
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			settings = append(settings, [2]string{line, ""})
+			continue
+		}
+		name, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected name=value", path, number)
+		}
+		settings = append(settings, [2]string{strings.TrimSpace(name), strings.TrimSpace(value)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	return settings, nil
+}
+
+// isSection returns whether the setting is a section header.
+func isSection(setting [2]string) bool {
+	return strings.HasPrefix(setting[0], "[")
+}
+
+// globalSettings returns the settings before the first section.
+func globalSettings(settings [][2]string) [][2]string {
+	for i, setting := range settings {
+		if isSection(setting) {
+			return settings[:i]
+		}
+	}
+	return settings
+}
+
+// setConfig returns the settings with the named flag set to the value,
+// replacing any earlier value, or removed if the value is empty.
+// Sections are kept after the flags.
+func setConfig(settings [][2]string, name, value string) [][2]string {
+	global := len(globalSettings(settings))
+	for i, setting := range settings[:global] {
+		if setting[0] == name {
+			if value == "" {
+				return append(settings[:i], settings[i+1:]...)
+			}
+			settings[i][1] = value
+			return settings
+		}
+	}
+	if value == "" {
+		return settings
+	}
+	updated := append(settings[:global:global], [2]string{name, value})
+	return append(updated, settings[global:]...)
+}
+
+// writeConfig writes the settings to the config file on behalf of the command.
+// The file may hold passwords so only the user may read it.
+func writeConfig(path, command string, settings [][2]string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("make config dir: %w", err)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Written by gardepro %s on %s.\n", command, time.Now().Format(dateFmt))
+	fmt.Fprintln(&b, "# Each line sets a flag of the import and kiosk modes (see gardepro -help).")
+	for _, setting := range settings {
+		if isSection(setting) {
+			fmt.Fprintf(&b, "\n%s\n", setting[0])
+		} else {
+			fmt.Fprintf(&b, "%s=%s\n", setting[0], setting[1])
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0600)
+}
+
+// configCameras returns the camera overrides in the config file (see importer.CameraOverride),
+// each in a section such as
+//
+//	[camera north]
+//	card=1A2B-3C4D
+//	clock-offset=-1h2m
+//
+// Overrides are matched by card (volume ID or label) and/or EXIF model and may set the timezone,
+// clock-offset, prefix, and min-confidence of classifier tags for the camera's files.
+func configCameras() ([]*importer.CameraOverride, error) {
+	settings, err := readConfig(configPath())
+	if err != nil {
+		return nil, err
+	}
+	var cameras []*importer.CameraOverride
+	var camera *importer.CameraOverride
+	for _, setting := range settings[len(globalSettings(settings)):] {
+		if isSection(setting) {
+			camera = nil
+			if kind, name, _ := strings.Cut(strings.Trim(setting[0], "[]"), " "); kind == "camera" {
+				camera = &importer.CameraOverride{Name: strings.TrimSpace(name)}
+				cameras = append(cameras, camera)
+			}
+			continue
+		} else if camera == nil {
+			continue
+		}
+		name, value := setting[0], setting[1]
+		switch name {
+		case "card":
+			camera.Card = value
+		case "model":
+			camera.Model = value
+		case "timezone":
+			camera.Zone, err = time.LoadLocation(value)
+		case "clock-offset":
+			camera.ClockOffset, err = time.ParseDuration(value)
+		case "prefix":
+			camera.Prefix = value
+		case "min-confidence":
+			camera.MinConfidence, err = strconv.ParseFloat(value, 64)
+		default:
+			err = errors.New("unknown setting")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("camera %s: %s: %w", camera.Name, name, err)
+		}
+	}
+	for _, camera := range cameras {
+		if camera.Card == "" && camera.Model == "" {
+			return nil, fmt.Errorf("camera %s: no card or model", camera.Name)
+		}
+	}
+	return cameras, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/release"
+)
+
+// contextMenuLabel is the label of the file manager context menu item.
+const contextMenuLabel = "Import with GardePro"
+
+func contextMenuCommand(args []string) {
+	var remove bool
+	var target string
+
+	flags := flag.NewFlagSet("context-menu", flag.ExitOnError)
+	flags.BoolVar(&remove, "remove", false, "Remove the context menu item")
+	flags.StringVar(&target, "target", "", "Target directory for imported files [from the config file]")
+	_ = flags.Parse(args)
+
+	consoleLog()
+	if remove {
+		if err := removeContextMenu(); err != nil {
+			log.Fatal().Err(err).Msg("Remove context menu item")
+		}
+		fmt.Printf("Removed %q from the file manager context menu\n", contextMenuLabel)
+		return
+	}
+	executable, err := release.Executable()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Find executable")
+	}
+	var importArgs []string
+	if target != "" {
+		if target, err = filepath.Abs(target); err != nil {
+			log.Fatal().Err(err).Msg("Target path")
+		}
+		importArgs = append(importArgs, "-target", target)
+	}
+	if err := installContextMenu(executable, importArgs); err != nil {
+		log.Fatal().Err(err).Msg("Install context menu item")
+	}
+	fmt.Printf("Added %q to the file manager context menu\n", contextMenuLabel)
+}
+
+// shellQuote quotes a string for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellImport returns the shell command importing each argument ("$@") with the executable.
+func shellImport(executable string, importArgs []string) string {
+	command := shellQuote(executable)
+	for _, arg := range importArgs {
+		command += " " + shellQuote(arg)
+	}
+	return "for path in \"$@\"; do\n\t" + command + " -source \"$path\"\ndone\n"
+}
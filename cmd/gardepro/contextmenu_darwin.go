@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// contextMenuInfo is the Info.plist of the Quick Action, offering it in the Finder for files and folders.
+const contextMenuInfo = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>NSServices</key>
+	<array>
+		<dict>
+			<key>NSMenuItem</key>
+			<dict>
+				<key>default</key>
+				<string>%s</string>
+			</dict>
+			<key>NSMessage</key>
+			<string>runWorkflowAsService</string>
+			<key>NSRequiredContext</key>
+			<dict>
+				<key>NSApplicationIdentifier</key>
+				<string>com.apple.finder</string>
+			</dict>
+			<key>NSSendFileTypes</key>
+			<array>
+				<string>public.item</string>
+			</array>
+		</dict>
+	</array>
+</dict>
+</plist>
+`
+
+// contextMenuWorkflow is the document.wflow of the Quick Action:
+// a single Run Shell Script action passed the selected files as arguments.
+const contextMenuWorkflow = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>AMApplicationBuild</key>
+	<string>521</string>
+	<key>AMApplicationVersion</key>
+	<string>2.10</string>
+	<key>AMDocumentVersion</key>
+	<string>2</string>
+	<key>actions</key>
+	<array>
+		<dict>
+			<key>action</key>
+			<dict>
+				<key>AMActionVersion</key>
+				<string>2.0.3</string>
+				<key>AMApplication</key>
+				<array>
+					<string>Automator</string>
+				</array>
+				<key>AMParameterProperties</key>
+				<dict>
+					<key>COMMAND_STRING</key>
+					<dict/>
+					<key>CheckedForUserDefaultShell</key>
+					<dict/>
+					<key>inputMethod</key>
+					<dict/>
+					<key>shell</key>
+					<dict/>
+					<key>source</key>
+					<dict/>
+				</dict>
+				<key>AMProvides</key>
+				<dict>
+					<key>Container</key>
+					<string>List</string>
+					<key>Types</key>
+					<array>
+						<string>com.apple.cocoa.string</string>
+					</array>
+				</dict>
+				<key>ActionBundlePath</key>
+				<string>/System/Library/Automator/Run Shell Script.action</string>
+				<key>ActionName</key>
+				<string>Run Shell Script</string>
+				<key>ActionParameters</key>
+				<dict>
+					<key>COMMAND_STRING</key>
+					<string>%s</string>
+					<key>CheckedForUserDefaultShell</key>
+					<true/>
+					<key>inputMethod</key>
+					<integer>1</integer>
+					<key>shell</key>
+					<string>/bin/sh</string>
+					<key>source</key>
+					<string></string>
+				</dict>
+				<key>BundleIdentifier</key>
+				<string>com.apple.RunShellScript</string>
+				<key>CFBundleVersion</key>
+				<string>2.0.3</string>
+				<key>CanShowSelectedItemsWhenRun</key>
+				<false/>
+				<key>CanShowWhenRun</key>
+				<true/>
+				<key>Class Name</key>
+				<string>RunShellScriptAction</string>
+				<key>InputUUID</key>
+				<string>5C3C8A2E-6F0B-4E4B-9A5E-1D7C2B3A4F01</string>
+				<key>OutputUUID</key>
+				<string>5C3C8A2E-6F0B-4E4B-9A5E-1D7C2B3A4F02</string>
+				<key>UUID</key>
+				<string>5C3C8A2E-6F0B-4E4B-9A5E-1D7C2B3A4F03</string>
+			</dict>
+		</dict>
+	</array>
+	<key>workflowMetaData</key>
+	<dict>
+		<key>serviceInputTypeIdentifier</key>
+		<string>com.apple.Automator.fileSystemObject</string>
+		<key>serviceOutputTypeIdentifier</key>
+		<string>com.apple.Automator.nothing</string>
+		<key>serviceProcessesInput</key>
+		<integer>0</integer>
+		<key>workflowTypeIdentifier</key>
+		<string>com.apple.Automator.servicesMenu</string>
+	</dict>
+</dict>
+</plist>
+`
+
+// contextMenuService returns the path of the Quick Action workflow bundle.
+func contextMenuService() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "Services", contextMenuLabel+".workflow"), nil
+}
+
+// installContextMenu writes a Finder Quick Action importing the selected files with the arguments.
+func installContextMenu(executable string, importArgs []string) error {
+	bundle, err := contextMenuService()
+	if err != nil {
+		return err
+	}
+	contents := filepath.Join(bundle, "Contents")
+	if err := os.MkdirAll(contents, 0755); err != nil {
+		return fmt.Errorf("make workflow dir: %w", err)
+	}
+	info := fmt.Sprintf(contextMenuInfo, html.EscapeString(contextMenuLabel))
+	if err := os.WriteFile(filepath.Join(contents, "Info.plist"), []byte(info), 0644); err != nil {
+		return fmt.Errorf("write Info.plist: %w", err)
+	}
+	workflow := fmt.Sprintf(contextMenuWorkflow, html.EscapeString(shellImport(executable, importArgs)))
+	if err := os.WriteFile(filepath.Join(contents, "document.wflow"), []byte(workflow), 0644); err != nil {
+		return fmt.Errorf("write document.wflow: %w", err)
+	}
+	refreshServices()
+	return nil
+}
+
+// removeContextMenu removes the Quick Action.
+func removeContextMenu() error {
+	bundle, err := contextMenuService()
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(bundle); err != nil {
+		return fmt.Errorf("remove workflow: %w", err)
+	}
+	refreshServices()
+	return nil
+}
+
+// refreshServices asks macOS to rescan the services so the Finder menu changes right away.
+func refreshServices() {
+	_ = exec.Command("/System/Library/CoreServices/pbs", "-update").Run()
+}
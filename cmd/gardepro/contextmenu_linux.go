@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// contextMenuScripts returns the paths of the file manager scripts (Nautilus and Nemo),
+// which are listed in the Scripts submenu of the context menu.
+func contextMenuScripts() ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	share := filepath.Join(home, ".local", "share")
+	if data := os.Getenv("XDG_DATA_HOME"); data != "" {
+		share = data
+	}
+	return []string{
+		filepath.Join(share, "nautilus", "scripts", contextMenuLabel),
+		filepath.Join(share, "nemo", "scripts", contextMenuLabel),
+	}, nil
+}
+
+// installContextMenu writes a script importing the selected files with the arguments
+// for each file manager.
+func installContextMenu(executable string, importArgs []string) error {
+	scripts, err := contextMenuScripts()
+	if err != nil {
+		return err
+	}
+	script := "#!/bin/sh\n# Written by gardepro context-menu.\n" + shellImport(executable, importArgs)
+	for _, path := range scripts {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("make scripts dir: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			return fmt.Errorf("write script: %w", err)
+		}
+	}
+	return nil
+}
+
+// removeContextMenu removes the file manager scripts.
+func removeContextMenu() error {
+	scripts, err := contextMenuScripts()
+	if err != nil {
+		return err
+	}
+	for _, path := range scripts {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove script: %w", err)
+		}
+	}
+	return nil
+}
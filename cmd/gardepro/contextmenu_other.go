@@ -0,0 +1,15 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+import "errors"
+
+// installContextMenu is only supported for the file managers of Linux, macOS, and Windows.
+func installContextMenu(string, []string) error {
+	return errors.New("no supported file manager on this platform")
+}
+
+// removeContextMenu is only supported for the file managers of Linux, macOS, and Windows.
+func removeContextMenu() error {
+	return errors.New("no supported file manager on this platform")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// contextMenuKeys are the Explorer registry keys (beneath HKEY_CURRENT_USER) of the context menu item
+// for files, folders, and drives (e.g. a camera card), with the -source argument of each.
+// The path of a drive ends with a backslash (E:\), which would escape a closing quote ("E:\" is read as E:"),
+// so it isn't quoted; drive paths have no spaces.
+var contextMenuKeys = []struct {
+	path, source string
+}{
+	{`Software\Classes\*\shell\GardePro`, `"%1"`},
+	{`Software\Classes\Directory\shell\GardePro`, `"%1"`},
+	{`Software\Classes\Drive\shell\GardePro`, `%V`},
+}
+
+// installContextMenu adds an Explorer context menu item importing the file or folder with the arguments.
+func installContextMenu(executable string, importArgs []string) error {
+	command := `"` + executable + `"`
+	for _, arg := range importArgs {
+		command += ` "` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+	}
+	for _, menu := range contextMenuKeys {
+		path := menu.path
+		key, _, err := registry.CreateKey(registry.CURRENT_USER, path, registry.SET_VALUE)
+		if err != nil {
+			return fmt.Errorf("create key %s: %w", path, err)
+		}
+		err = key.SetStringValue("", contextMenuLabel)
+		if err == nil {
+			err = key.SetStringValue("Icon", executable)
+		}
+		_ = key.Close()
+		if err != nil {
+			return fmt.Errorf("set %s: %w", path, err)
+		}
+		if key, _, err = registry.CreateKey(registry.CURRENT_USER, path+`\command`, registry.SET_VALUE); err != nil {
+			return fmt.Errorf("create key %s: %w", path+`\command`, err)
+		}
+		err = key.SetStringValue("", command+" -source "+menu.source)
+		_ = key.Close()
+		if err != nil {
+			return fmt.Errorf("set %s: %w", path+`\command`, err)
+		}
+	}
+	return nil
+}
+
+// removeContextMenu removes the Explorer context menu item.
+func removeContextMenu() error {
+	for _, menu := range contextMenuKeys {
+		for _, key := range []string{menu.path + `\command`, menu.path} {
+			if err := registry.DeleteKey(registry.CURRENT_USER, key); err != nil && err != registry.ErrNotExist {
+				return fmt.Errorf("delete key %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/madkins23/gardepro/chain"
+	"github.com/madkins23/gardepro/i18n"
+	"github.com/madkins23/gardepro/importer"
+)
+
+func custodyCommand(args []string) {
+	var keyFile, output string
+
+	flags := flag.NewFlagSet("custody", flag.ExitOnError)
+	flags.StringVar(&keyFile, "key", "", "Public (or private) key file of the hash chain")
+	flags.StringVar(&output, "o", "", "Output file [standard output]")
+	_ = flags.Parse(args)
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var public ed25519.PublicKey
+	if keyFile != "" {
+		var err error
+		if public, err = chain.ReadPublicKey(keyFile); err != nil {
+			fatalf("Read key: %s", err)
+		}
+	}
+	out := io.Writer(os.Stdout)
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fatalf("Create report: %s", err)
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+
+	host, _ := os.Hostname()
+	_, _ = fmt.Fprintf(out, "%s\n\n%s\n", i18n.T("CHAIN OF CUSTODY REPORT"),
+		i18n.T("Generated %s on %s", time.Now().Format(timeFmt+" MST"), host))
+	if keyFile != "" {
+		_, _ = fmt.Fprintln(out, i18n.T("Hash chain verified with public key %s", keyFile))
+	}
+	intact := true
+	for _, path := range flags.Args() {
+		cat, file := catalogFile(path)
+		target, _, _ := importer.FindTarget(path)
+		custody, err := importer.New(target, importer.Options{Catalog: cat}).Custody(file.Path, public)
+		_ = cat.Close()
+		if err != nil {
+			fatalf("Custody of %s: %s", path, err)
+		}
+		writeCustody(out, custody, public != nil)
+		intact = intact && custody.Intact
+	}
+	if !intact {
+		os.Exit(1)
+	}
+}
+
+// writeCustody writes the custody report of a file.
+func writeCustody(out io.Writer, custody *importer.Custody, chained bool) {
+	file := custody.File
+	_, _ = fmt.Fprintf(out, "\n%s\n\n", file.Path)
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	row := func(label, format string, args ...interface{}) {
+		_, _ = fmt.Fprintf(table, "%s:\t%s\n", i18n.T(label), i18n.T(format, args...))
+	}
+	if file.Root != "" {
+		row("Archive root", "%s", file.Root)
+	}
+	row("Source", "%s", file.Source)
+	row("Captured", "%s (camera clock)", file.Captured.Format(timeFmt))
+	row("Imported on", "%s", file.Imported.Local().Format(timeFmt+" MST"))
+	if session := custody.Session; session != nil {
+		row("Import session", "%s (%s on %s)", session.ID, session.Command, session.Host)
+		if session.Operator != "" {
+			row("Operator", "%s", session.Operator)
+		}
+		if session.Card != nil {
+			row("Source card", "%s", session.Card)
+		}
+	}
+	row("Cataloged hash", "%s", file.Hash)
+	switch {
+	case file.Offloaded != "":
+		row("Current hash", "not checked, offloaded to %s", file.Offloaded)
+	case custody.Err != nil:
+		row("Current hash", "UNREADABLE (%s)", custody.Err)
+	case custody.Hash == file.Hash:
+		row("Current hash", "%s (matches)", custody.Hash)
+	default:
+		row("Current hash", "%s (DIFFERS)", custody.Hash)
+	}
+	if chained {
+		if custody.ChainErr != nil {
+			row("Hash chain", "BROKEN (%s)", custody.ChainErr)
+		}
+		if link := custody.Link; link == nil {
+			row("Hash chain link", "NONE")
+		} else {
+			row("Hash chain link", "%d, signed %s, digest %s", link.Seq, link.Time.Local().Format(timeFmt+" MST"), link.Digest)
+			if link.Hash != file.Hash {
+				row("Chained hash", "%s", link.Hash)
+			}
+		}
+		if custody.Timestamp != "" {
+			row("Time stamp", "%s", filepath.Base(custody.Timestamp))
+		}
+	}
+	if custody.Intact {
+		row("Status", "intact")
+	} else {
+		row("Status", "NOT VERIFIED")
+	}
+	_ = table.Flush()
+	_, _ = fmt.Fprintf(out, "%s:\n", i18n.T("History"))
+	for _, event := range custody.Events {
+		_, _ = fmt.Fprintf(out, "  %s  %s\n", event.Time.Local().Format(timeFmt), i18n.T(event.What, event.Args...))
+	}
+}
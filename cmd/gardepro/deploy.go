@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+func deployCommand(args []string) {
+	var camera, id, installed, location, pulled, references, settings, target string
+	var latitude, longitude float64
+
+	flags := flag.NewFlagSet("deploy", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&camera, "camera", "", "Source directory of the camera's files, as shown by stats (add)")
+	flags.StringVar(&id, "id", "", "Deployment ID [installation date] (add)")
+	flags.StringVar(&installed, "installed", "", "Installation time by the camera clock, YYYY-MM-DD [hh:mm] (add)")
+	flags.StringVar(&pulled, "pulled", "", "Time the camera was pulled by the camera clock, YYYY-MM-DD [hh:mm] [now] (add, pull)")
+	flags.StringVar(&location, "location", "", "Name of the location (add)")
+	flags.Float64Var(&latitude, "lat", 0, "Latitude of the location (add)")
+	flags.Float64Var(&longitude, "lon", 0, "Longitude of the location (add)")
+	flags.StringVar(&settings, "settings", "", "Camera settings, e.g. \"3 photos, 10s delay\" (add)")
+	flags.StringVar(&references, "reference", "", "JPEG frames of the camera's scene by which its files are recognized (add, comma separated)")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(),
+			"Usage: gardepro deploy add -target DIR -camera DIR -installed TIME [...] | pull -target DIR [-pulled TIME] ID |"+
+				" reference -target DIR ID FILE... | remove -target DIR ID | list -target DIR")
+		flags.PrintDefaults()
+	}
+	var action string
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	switch {
+	case action == "add" && camera != "" && installed != "" && flags.NArg() == 0:
+		consoleLog()
+		deployment := &catalog.Deployment{ID: id, Location: location, Settings: settings}
+		var err error
+		if deployment.Camera, err = filepath.Abs(camera); err != nil {
+			fatalf("Camera directory: %s", err)
+		}
+		if deployment.Installed, err = parseShareTime(installed, false); err != nil {
+			fatalf("Bad installation time: %s", err)
+		}
+		if pulled != "" {
+			if deployment.Pulled, err = parseShareTime(pulled, false); err != nil {
+				fatalf("Bad pulled time: %s", err)
+			}
+		}
+		if latitude != 0 || longitude != 0 {
+			deployment.Position = &catalog.Position{Latitude: latitude, Longitude: longitude}
+		}
+		if references != "" {
+			deployment.Fingerprints = fingerprints(strings.Split(references, ","))
+		}
+		cat := commandCatalog(target, "deploy", "")
+		defer func() { _ = cat.Close() }()
+		if deployment.ID == "" {
+			deployment.ID = deploymentID(cat, deployment.Installed)
+		} else if cat.Deployment(deployment.ID) != nil {
+			fatalf("Deployment %s already exists", deployment.ID)
+		}
+		if err := cat.AddDeployment(deployment); err != nil {
+			log.Fatal().Err(err).Msg("Record deployment")
+		}
+		log.Info().Str("id", deployment.ID).Str("camera", deployment.Camera).Msg("Added deployment")
+	case action == "pull" && flags.NArg() == 1:
+		consoleLog()
+		when := clockNow()
+		if pulled != "" && pulled != "now" {
+			var err error
+			if when, err = parseShareTime(pulled, false); err != nil {
+				fatalf("Bad pulled time: %s", err)
+			}
+		}
+		updateDeployment(target, flags.Arg(0), func(deployment *catalog.Deployment) {
+			deployment.Pulled = when
+		})
+		log.Info().Str("id", flags.Arg(0)).Str("pulled", when.Format(timeFmt)).Msg("Pulled deployment")
+	case action == "reference" && flags.NArg() >= 2:
+		consoleLog()
+		added := fingerprints(flags.Args()[1:])
+		updateDeployment(target, flags.Arg(0), func(deployment *catalog.Deployment) {
+			deployment.Fingerprints = append(append([]string(nil), deployment.Fingerprints...), added...)
+		})
+		log.Info().Str("id", flags.Arg(0)).Strs("fingerprints", added).Msg("Added reference frames")
+	case action == "remove" && flags.NArg() == 1:
+		consoleLog()
+		updateDeployment(target, flags.Arg(0), func(deployment *catalog.Deployment) {
+			deployment.Removed = true
+		})
+		log.Info().Str("id", flags.Arg(0)).Msg("Removed deployment")
+	case action == "list" && flags.NArg() == 0:
+		listDeployments(target)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// clockNow returns the current local time as a camera clock time.
+func clockNow() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 0, 0, time.UTC)
+}
+
+// fingerprints returns the fingerprints of the reference frames (see importer.Fingerprint).
+func fingerprints(paths []string) []string {
+	var fingerprints []string
+	for _, path := range paths {
+		fingerprint, err := importer.Fingerprint(path)
+		if err != nil {
+			fatalf("Fingerprint %s: %s", path, err)
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	return fingerprints
+}
+
+// deploymentID returns an unused ID for a deployment installed at the time,
+// the installation date with a sequence number if there are several on the same day.
+func deploymentID(cat *catalog.Catalog, installed time.Time) string {
+	id := installed.Format("20060102")
+	for n := 2; cat.Deployment(id) != nil; n++ {
+		id = fmt.Sprintf("%s-%d", installed.Format("20060102"), n)
+	}
+	return id
+}
+
+// updateDeployment records a change to the deployment with the ID in the target catalog.
+func updateDeployment(target, id string, update func(deployment *catalog.Deployment)) {
+	cat := commandCatalog(target, "deploy", "")
+	defer func() { _ = cat.Close() }()
+	existing := cat.Deployment(id)
+	if existing == nil {
+		fatalf("No deployment %s", id)
+	}
+	updated := *existing
+	update(&updated)
+	if err := cat.AddDeployment(&updated); err != nil {
+		log.Fatal().Err(err).Msg("Record deployment")
+	}
+}
+
+// listDeployments prints the deployments in the target catalog.
+func listDeployments(target string) {
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		fatalf("Open catalog: %s", err)
+	}
+	defer func() { _ = cat.Close() }()
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "ID\tCamera\tLocation\tPosition\tInstalled\tPulled\tReferences\tSettings\t")
+	for _, deployment := range cat.Deployments() {
+		var position, pulled string
+		if deployment.Position != nil {
+			position = fmt.Sprintf("%.5f,%.5f", deployment.Position.Latitude, deployment.Position.Longitude)
+		}
+		if !deployment.Pulled.IsZero() {
+			pulled = deployment.Pulled.Format(timeFmt)
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t\n", deployment.ID, deployment.Camera, deployment.Location,
+			position, deployment.Installed.Format(timeFmt), pulled, len(deployment.Fingerprints), deployment.Settings)
+	}
+	_ = writer.Flush()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+func deriveCommand(args []string) {
+	var kind, target string
+
+	flags := flag.NewFlagSet("derive", flag.ExitOnError)
+	flags.StringVar(&kind, "kind", "", "Kind of derivative ("+strings.Join(catalog.Derivations, ", ")+")")
+	flags.StringVar(&target, "target", "", "Target directory of archived files (list all derivatives)")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: gardepro derive add -kind KIND ORIGINAL FILE... | remove FILE... | list -target DIR")
+		flags.PrintDefaults()
+	}
+	var action string
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	_ = flags.Parse(args)
+	switch {
+	case action == "add" && kind != "" && flags.NArg() >= 2:
+		if !validDerivation(kind) {
+			fatalf("Unknown -kind %s (%s)", kind, strings.Join(catalog.Derivations, ", "))
+		}
+		consoleLog()
+		if err := deriveFiles(flags.Args()[1:], flags.Arg(0), kind); err != nil {
+			log.Fatal().Err(err).Msg("Link derivatives")
+		}
+	case action == "remove" && flags.NArg() > 0:
+		consoleLog()
+		if err := deriveFiles(flags.Args(), "", ""); err != nil {
+			log.Fatal().Err(err).Msg("Unlink derivatives")
+		}
+	case action == "list" && target != "":
+		listDerivatives(target)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// validDerivation returns true if the kind is one of catalog.Derivations.
+func validDerivation(kind string) bool {
+	for _, derivation := range catalog.Derivations {
+		if kind == derivation {
+			return true
+		}
+	}
+	return false
+}
+
+// deriveFiles links the catalog entries of the files to the original as derivatives of the kind,
+// or unlinks them if the original is empty. The original must be archived in the same target.
+func deriveFiles(paths []string, original, kind string) error {
+	var originalTarget, originalPath string
+	if original != "" {
+		cat, file := catalogFile(original)
+		_ = cat.Close()
+		if file.Derivative() {
+			return fmt.Errorf("original %s is itself derived from %s", original, file.Original)
+		}
+		originalTarget, _, _ = importer.FindTarget(original)
+		originalPath = file.Path
+	}
+	return updateFiles(paths, "derive", func(path string, file *catalog.File) (bool, error) {
+		if original == "" {
+			if !file.Derivative() {
+				return false, nil
+			}
+			file.Original, file.Derivation = "", ""
+			log.Info().Str("path", file.Path).Msg("Unlinked derivative")
+			return true, nil
+		}
+		if target, _, err := importer.FindTarget(path); err != nil || target != originalTarget {
+			return false, fmt.Errorf("%s is not archived in the target of %s", path, original)
+		} else if file.Path == originalPath {
+			return false, fmt.Errorf("%s can't be derived from itself", path)
+		}
+		file.Original, file.Derivation = originalPath, kind
+		log.Info().Str("path", file.Path).Str("original", originalPath).Str("kind", kind).Msg("Linked derivative")
+		return true, nil
+	})
+}
+
+// listDerivatives prints the derivatives in the target catalog with their originals.
+func listDerivatives(target string) {
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		fatalf("Open catalog: %s", err)
+	}
+	defer func() { _ = cat.Close() }()
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "Path\tKind\tOriginal\t")
+	for _, file := range cat.Files() {
+		if !file.Derivative() {
+			continue
+		}
+		original := file.Original
+		if cat.File(original) == nil {
+			original += " (missing)"
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t\n", file.Path, file.Derivation, original)
+	}
+	_ = writer.Flush()
+}
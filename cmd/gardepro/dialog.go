@@ -0,0 +1,36 @@
+//go:build !nogui
+
+package main
+
+import (
+	"github.com/sqweek/dialog"
+
+	"github.com/madkins23/gardepro/i18n"
+)
+
+// errorDialog displays an error message to the user, translating the title.
+func errorDialog(title, message string) {
+	dialog.Message("%s", message).Title(i18n.T(title)).Error()
+}
+
+// infoDialog displays a message to the user, translating the title.
+func infoDialog(title, message string) {
+	dialog.Message("%s", message).Title(i18n.T(title)).Info()
+}
+
+// yesNoDialog asks the user a question, translating the title.
+// Dialogs have no default answer so def is only used by headless builds.
+func yesNoDialog(title, question string, def bool) bool {
+	return dialog.Message("%s", question).Title(i18n.T(title)).YesNo()
+}
+
+// directoryDialog asks the user to choose a directory starting at start (if not empty),
+// translating the title. Returns false if the user cancels.
+func directoryDialog(title, start string) (string, bool) {
+	builder := dialog.Directory().Title(i18n.T(title))
+	if start != "" {
+		builder = builder.SetStartDir(start)
+	}
+	dir, err := builder.Browse()
+	return dir, err == nil
+}
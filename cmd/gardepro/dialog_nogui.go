@@ -0,0 +1,38 @@
+//go:build nogui
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/madkins23/gardepro/i18n"
+)
+
+// terminal asks questions instead of dialogs in headless builds.
+var terminal = &wizard{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+
+// errorDialog prints an error message since there are no dialogs in headless builds,
+// translating the title.
+func errorDialog(title, message string) {
+	_, _ = fmt.Fprintf(os.Stderr, "%s: %s\n", i18n.T(title), message)
+}
+
+// infoDialog prints a message, translating the title.
+func infoDialog(title, message string) {
+	fmt.Printf("%s: %s\n", i18n.T(title), message)
+}
+
+// yesNoDialog asks a question on the terminal with the answer def by default, translating the title.
+func yesNoDialog(title, question string, def bool) bool {
+	fmt.Printf("\n%s\n", i18n.T(title))
+	return terminal.confirm(question, def)
+}
+
+// directoryDialog asks for a directory on the terminal, start (if not empty) by default,
+// translating the title. Returns false if there is no answer.
+func directoryDialog(title, start string) (string, bool) {
+	dir, err := terminal.ask(i18n.T(title), start, nil)
+	return dir, err == nil && dir != ""
+}
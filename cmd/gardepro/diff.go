@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func diffCommand(args []string) {
+	var hashAlgorithm string
+
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.StringVar(&hashAlgorithm, "hash", importer.HashSHA256, "Hash algorithm (sha256, xxh3, or blake3)")
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	dirA, dirB := flags.Arg(0), flags.Arg(1)
+
+	consoleLog()
+	a, err := importer.Inventory(dirA, hashAlgorithm)
+	if err != nil {
+		log.Fatal().Err(err).Str("dir", dirA).Msg("Inventory")
+	}
+	b, err := importer.Inventory(dirB, hashAlgorithm)
+	if err != nil {
+		log.Fatal().Err(err).Str("dir", dirB).Msg("Inventory")
+	}
+	onlyA, onlyB := importer.Diff(a, b)
+	for _, file := range onlyA {
+		fmt.Printf("< %s\t%s\n", file.Captured.Format(timeFmt), file.Path)
+	}
+	for _, file := range onlyB {
+		fmt.Printf("> %s\t%s\n", file.Captured.Format(timeFmt), file.Path)
+	}
+	log.Info().Int("files-a", len(a)).Int("files-b", len(b)).
+		Int("only-a", len(onlyA)).Int("only-b", len(onlyB)).Msg("Diff finished")
+	if len(onlyA) > 0 || len(onlyB) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+// dropTemplate formats the window onto which files are dropped.
+// Dropped files (and the files in dropped folders) are sent one at a time to the import URL
+// with their path and modification time, and the result of importing each is shown beneath.
+var dropTemplate = template.Must(template.New("drop").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GardePro</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+#drop { border: 3px dashed #888; border-radius: 1em; padding: 3em; text-align: center; color: #555; }
+#drop.over { border-color: #2a7; color: #2a7; }
+table { border-collapse: collapse; margin-top: 1em; width: 100%; }
+td { padding: 0.2em 0.5em; border-bottom: 1px solid #ddd; font-size: small; }
+.imported { color: #2a7; } .identical, .presumed, .duplicate, .excluded { color: #888; }
+.failed, .quarantined, .aborted, .error { color: #c22; }
+</style>
+</head>
+<body>
+<h1>GardePro</h1>
+<p>Files are archived in {{.Target}}.</p>
+<div id="drop">Drop camera files or folders here<br><br><input type="file" id="pick" multiple></div>
+<table><tbody id="results"></tbody></table>
+<script>
+const drop = document.getElementById("drop"), results = document.getElementById("results");
+let queue = Promise.resolve();
+
+function add(file, path) {
+  const row = results.insertRow(0);
+  row.insertCell().textContent = path;
+  const status = row.insertCell(), detail = row.insertCell();
+  status.textContent = "waiting";
+  queue = queue.then(async () => {
+    status.textContent = "importing";
+    try {
+      const response = await fetch("{{.Import}}", {method: "POST", body: file, headers: {
+        "X-Path": encodeURIComponent(path), "X-Modified": String(file.lastModified)}});
+      const result = await response.json();
+      status.textContent = result.action;
+      status.className = result.action;
+      detail.textContent = result.target || result.reason || "";
+    } catch (err) {
+      status.textContent = "error";
+      status.className = "error";
+      detail.textContent = err;
+    }
+  });
+}
+
+function walk(entry, path) {
+  if (entry.isFile) {
+    entry.file(file => add(file, path + entry.name));
+  } else if (entry.isDirectory) {
+    const reader = entry.createReader();
+    const more = () => reader.readEntries(entries => {
+      entries.forEach(child => walk(child, path + entry.name + "/"));
+      if (entries.length) more();
+    });
+    more();
+  }
+}
+
+drop.addEventListener("dragover", event => { event.preventDefault(); drop.classList.add("over"); });
+drop.addEventListener("dragleave", () => drop.classList.remove("over"));
+drop.addEventListener("drop", event => {
+  event.preventDefault();
+  drop.classList.remove("over");
+  for (const item of event.dataTransfer.items) {
+    const entry = item.webkitGetAsEntry && item.webkitGetAsEntry();
+    if (entry) walk(entry, ""); else if (item.kind === "file") add(item.getAsFile(), item.getAsFile().name);
+  }
+});
+document.getElementById("pick").addEventListener("change", event => {
+  for (const file of event.target.files) add(file, file.name);
+  event.target.value = "";
+});
+</script>
+</body>
+</html>
+`))
+
+// dropResult is the result of importing a dropped file.
+type dropResult struct {
+	Action string `json:"action"`
+	Target string `json:"target,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// dropper imports the files dropped onto the window.
+type dropper struct {
+	imp     *importer.Importer
+	journal *importer.Journal
+	staging string
+	// Files are imported one at a time so that each decision can be read from the journal.
+	mutex sync.Mutex
+}
+
+func dropCommand(args []string) {
+	var fixOrientation, modTime, noBrowser, quick, verify bool
+	var addr, hashAlgorithm, only, operator, pluginDir, target, timeZone string
+
+	flags := flag.NewFlagSet("drop", flag.ExitOnError)
+	flags.StringVar(&addr, "addr", "127.0.0.1:0", "Address of the window's web server")
+	flags.BoolVar(&noBrowser, "no-browser", false, "Print the window URL instead of opening it in the browser")
+	flags.StringVar(&target, "target", "", "Target directory for image files")
+	flags.BoolVar(&fixOrientation, "fix-orientation", false, "Set non-standard EXIF Orientation values to upright")
+	flags.BoolVar(&modTime, "mtime", false, "Use the modification time of files without capture times")
+	flags.StringVar(&hashAlgorithm, "hash", importer.HashSHA256, "Hash algorithm (sha256, xxh3, or blake3)")
+	flags.StringVar(&only, "only", "", "Import only files with these extensions (comma separated)")
+	flags.StringVar(&operator, "operator", "", operatorUsage)
+	flags.StringVar(&pluginDir, "plugins", "", "Plugin directory [user config dir/gardepro/plugins]")
+	flags.BoolVar(&quick, "quick", false, "Presume pre-existing target files of the same size are identical")
+	flags.StringVar(&timeZone, "timezone", "", "Time zone of camera clocks (e.g. America/Chicago)")
+	flags.BoolVar(&verify, "verify", false, "Verify media data, quarantining damaged files")
+	if err := configFlags(flags); err != nil {
+		fatalf("Read config: %s", err)
+	}
+	if err := envFlags(flags); err != nil {
+		fatalf("Parse environment: %s", err)
+	}
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	options := importer.Options{
+		FixOrientation: fixOrientation,
+		Hash:           hashAlgorithm,
+		Lenient:        true,
+		QuickSkip:      quick,
+		Timeout:        time.Minute,
+		Verify:         verify,
+	}
+	if only != "" {
+		options.Only = strings.Split(only, ",")
+	}
+	if timeZone != "" {
+		location, err := time.LoadLocation(timeZone)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Parse -timezone")
+		}
+		options.CameraZone = location
+	}
+	cameras, err := configCameras()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Read camera overrides")
+	}
+	options.Cameras = cameras
+	importer.UseModTime(modTime)
+	catalog.Operator = operator
+	if err := loadPlugins(pluginDir, &options); err != nil {
+		log.Fatal().Err(err).Msg("Load plugins")
+	}
+	staging, err := os.MkdirTemp("", "gardepro-drop-")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Make staging dir")
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	defer func() { _ = cat.Close() }()
+	session, err := cat.StartSession("drop", staging, nil)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Start catalog session")
+	}
+	options.Catalog = cat
+	options.Journal = importer.NewJournal(session.ID, staging, target, &options)
+	d := &dropper{imp: importer.New(target, options), journal: options.Journal, staging: staging}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		log.Fatal().Err(err).Msg("Make token")
+	}
+	// The random path keeps other web pages from importing files through the server.
+	base := "/" + hex.EncodeToString(token) + "/"
+	mux := http.NewServeMux()
+	mux.HandleFunc(base, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dropTemplate.Execute(w, map[string]string{"Target": target, "Import": base + "import"}); err != nil {
+			log.Warn().Err(err).Msg("Write drop window")
+		}
+	})
+	mux.HandleFunc(base+"import", d.serveImport)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Listen")
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	address := "http://" + listener.Addr().String() + base
+	log.Info().Str("target", target).Str("url", address).Msg("Drop window ready (Ctrl-C to quit)")
+	if !noBrowser {
+		if err := openBrowser(address); err != nil {
+			log.Warn().Err(err).Msg("Open the URL in a browser")
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error().Err(err).Msg("Serve drop window")
+	}
+	if _, err := options.Journal.Write(); err != nil {
+		log.Warn().Err(err).Msg("Write session journal")
+	}
+}
+
+// serveImport imports a dropped file, writing the result as JSON.
+// The file is staged with its dropped path, so that its name and folders are available
+// to the importer, and removed once imported.
+func (d *dropper) serveImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	result := d.importBody(r)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// importBody stages and imports the file in the body of the request.
+func (d *dropper) importBody(r *http.Request) *dropResult {
+	path, err := dropPath(r.Header.Get("X-Path"))
+	if err != nil {
+		return &dropResult{Action: importer.ActionFailed, Reason: err.Error()}
+	}
+	source := filepath.Join(d.staging, path)
+	if err := os.MkdirAll(filepath.Dir(source), 0755); err != nil {
+		return &dropResult{Action: importer.ActionFailed, Reason: err.Error()}
+	}
+	defer func() { _ = os.Remove(source) }()
+	file, err := os.Create(source)
+	if err != nil {
+		return &dropResult{Action: importer.ActionFailed, Reason: err.Error()}
+	}
+	_, err = io.Copy(file, r.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return &dropResult{Action: importer.ActionFailed, Reason: fmt.Sprintf("receive file: %s", err)}
+	}
+	// The modification time is used for files without capture times if -mtime is specified.
+	if ms, err := strconv.ParseInt(r.Header.Get("X-Modified"), 10, 64); err == nil {
+		modified := time.UnixMilli(ms)
+		_ = os.Chtimes(source, modified, modified)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	decided := len(d.journal.Decisions)
+	targetPath, err := d.imp.Import(source)
+	result := &dropResult{Action: importer.ActionImported}
+	if len(d.journal.Decisions) > decided {
+		decision := d.journal.Decisions[len(d.journal.Decisions)-1]
+		result.Action, result.Reason = decision.Action, decision.Reason
+	} else if err != nil {
+		result.Action, result.Reason = importer.ActionFailed, err.Error()
+	}
+	if targetPath != "" {
+		if rel, err := filepath.Rel(d.imp.Target(), targetPath); err == nil {
+			targetPath = rel
+		}
+		result.Target = filepath.ToSlash(targetPath)
+	}
+	log.Info().Str("file", path).Str("action", result.Action).Str("target", result.Target).Msg("Dropped file")
+	return result
+}
+
+// dropPath returns the relative path of a dropped file from the URL encoded header value,
+// failing if it is absolute or leads out of the staging directory.
+func dropPath(header string) (string, error) {
+	path, err := url.PathUnescape(header)
+	if err != nil || path == "" {
+		return "", fmt.Errorf("bad path %q", header)
+	}
+	path = filepath.Clean(filepath.FromSlash(path))
+	if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) ||
+		filepath.VolumeName(path) != "" {
+		return "", fmt.Errorf("bad path %q", path)
+	}
+	return path, nil
+}
+
+// openBrowser opens the address in the default browser.
+func openBrowser(address string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", address).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", address).Start()
+	default:
+		return exec.Command("xdg-open", address).Start()
+	}
+}
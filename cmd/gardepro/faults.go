@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/i18n"
+	"github.com/madkins23/gardepro/importer"
+	"github.com/madkins23/gardepro/plugin"
+)
+
+func faultsCommand(args []string) {
+	var options importer.FaultOptions
+	var pluginDir, pool, pushSpecs, target string
+
+	flags := flag.NewFlagSet("faults", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.IntVar(&options.Days, "days", 2, "Number of recent days with captures checked per camera")
+	flags.DurationVar(&options.Silent, "silent", 0, "How long a camera may capture nothing (e.g. 168h), 0 for no limit")
+	flags.StringVar(&pluginDir, "plugins", "", "Plugin directory [user config dir/gardepro/plugins]")
+	flags.StringVar(&pushSpecs, "push", "", pushUsage)
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	pushers := pushServices(pushSpecs)
+
+	consoleLog()
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	defer func() { _ = cat.Close() }()
+	faults, err := importer.New(target, importer.Options{Catalog: cat, Pool: poolRoots(pool)}).Faults(&options)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Check cameras")
+	}
+	notifiers, err := notifierPlugins(pluginDir)
+	if err != nil {
+		log.Error().Err(err).Msg("Load plugins")
+	}
+	for _, fault := range faults {
+		log.Warn().Str("camera", fault.Camera).Str("reason", fault.Reason).
+			Str("since", fault.Since.Format(timeFmt)).Int("frames", fault.Frames).Msg("Camera fault")
+		for _, notifier := range notifiers {
+			if err := notifier.Notify(&plugin.Request{
+				Event: plugin.EventCameraFault, Source: fault.Camera, Error: fault.Reason}); err != nil {
+				log.Warn().Err(err).Str("camera", fault.Camera).Msg("Notify plugin")
+			}
+		}
+		sendPush(pushers, i18n.T("GardePro camera fault"),
+			i18n.T("%s: %s since %s", fault.Camera, fault.Reason, fault.Since.Format(timeFmt)), true)
+	}
+	log.Info().Int("faults", len(faults)).Msg("Check cameras finished")
+	if len(faults) > 0 {
+		os.Exit(1)
+	}
+}
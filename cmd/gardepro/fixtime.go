@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/chain"
+	"github.com/madkins23/gardepro/importer"
+)
+
+func fixTimeCommand(args []string) {
+	var options importer.FixTimeOptions
+	var chainKey, pool, target string
+
+	flags := flag.NewFlagSet("fix-time", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.DurationVar(&options.Offset, "offset", 0, "Offset added to capture times (e.g. -1h30m)")
+	flags.StringVar(&options.From, "from", "", "First capture date to fix (YYYY-MM-DD)")
+	flags.StringVar(&options.Until, "until", "", "Last capture date to fix (YYYY-MM-DD)")
+	flags.StringVar(&options.Camera, "camera", "", "Camera model (EXIF Model) to fix")
+	flags.StringVar(&chainKey, "chain-key", "", "Private key file for chaining the fixed files in the hash chain")
+	_ = flags.Parse(args)
+	if target == "" || options.Offset == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	cat := commandCatalog(target, "fix-time", "")
+	defer func() { _ = cat.Close() }()
+	ch := fixTimeChain(target, chainKey)
+	if ch != nil {
+		defer func() { _ = ch.Close() }()
+	}
+	if fixed, err := importer.New(target, importer.Options{Catalog: cat, Chain: ch, Pool: poolRoots(pool)}).FixTime(&options); err != nil {
+		log.Fatal().Err(err).Int("fixed", fixed).Msg("Fix time")
+	} else {
+		log.Info().Int("fixed", fixed).Msg("Fix time finished")
+	}
+}
+
+// fixTimeChain opens the hash chain of the target for chaining fixed files (which are rewritten)
+// with the private key file, nil if none is specified.
+func fixTimeChain(target, chainKey string) *chain.Chain {
+	if chainKey == "" {
+		if _, err := os.Stat(filepath.Join(target, importer.StateDir, chain.FileName)); err == nil {
+			log.Warn().Msg("Fixed files won't match the hash chain without -chain-key")
+		}
+		return nil
+	}
+	key, err := chain.ReadPrivateKey(chainKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Read chain key")
+	}
+	ch, err := importer.OpenChain(target, key)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open hash chain")
+	}
+	return ch
+}
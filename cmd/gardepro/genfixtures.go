@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/fixture"
+)
+
+func genFixturesCommand(args []string) {
+	var date string
+
+	flags := flag.NewFlagSet("gen-fixtures", flag.ExitOnError)
+	flags.StringVar(&date, "date", "2024-01-02", "Capture date (YYYY-MM-DD) of the fixtures")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	base, err := time.ParseInLocation(dateFmt, date, time.Local)
+	if err != nil {
+		log.Fatal().Err(err).Str("date", date).Msg("Parse -date")
+	}
+	fixtures, err := fixture.Standard(base)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Generate fixtures")
+	}
+	dir := flags.Arg(0)
+	if err := fixture.Write(dir, fixtures, base); err != nil {
+		log.Fatal().Err(err).Str("dir", dir).Msg("Write fixtures")
+	}
+	for _, fx := range fixtures {
+		fmt.Printf("%-32s %-16s %s\n", fx.Path, fx.Expect, fx.Note)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func highlightsCommand(args []string) {
+	var options importer.HighlightOptions
+	var from, pool, target, until string
+
+	flags := flag.NewFlagSet("highlights", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.StringVar(&from, "from", "", "First capture date (YYYY-MM-DD)")
+	flags.StringVar(&until, "until", "", "Last capture date (YYYY-MM-DD)")
+	flags.IntVar(&options.Count, "count", importer.DefaultHighlights, "Number of events in the highlights")
+	flags.StringVar(&options.Tag, "tag", "highlight", "Tag of events preferred to all others")
+	flags.DurationVar(&options.StillLength, "still", importer.DefaultStillLength, "How long each image is shown")
+	flags.DurationVar(&options.ClipLength, "clip", importer.DefaultClipLength, "Longest part of each video shown")
+	flags.StringVar(&options.FFmpeg, "ffmpeg", "ffmpeg", "Path of the ffmpeg executable")
+	flags.StringVar(&options.Output, "o", "highlights.mp4", "Highlights video file")
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	var err error
+	if from != "" {
+		if options.From, err = time.Parse(dateFmt, from); err != nil {
+			log.Fatal().Err(err).Msg("Parse -from")
+		}
+	}
+	if until != "" {
+		if options.Until, err = time.Parse(dateFmt, until); err != nil {
+			log.Fatal().Err(err).Msg("Parse -until")
+		}
+		options.Until = options.Until.AddDate(0, 0, 1)
+	}
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	defer func() { _ = cat.Close() }()
+	if count, err := importer.New(target, importer.Options{Catalog: cat, Pool: poolRoots(pool)}).Highlights(&options); err != nil {
+		log.Fatal().Err(err).Msg("Highlights")
+	} else {
+		log.Info().Int("events", count).Str("output", options.Output).Msg("Highlights finished")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+	"github.com/madkins23/gardepro/mqtt"
+)
+
+const (
+	// haDiscovery is the Home Assistant MQTT discovery topic prefix.
+	haDiscovery = "homeassistant"
+	// haState is the prefix of the topics to which states are published.
+	haState = "gardepro"
+)
+
+// haSensor is a Home Assistant MQTT discovery sensor configuration.
+type haSensor struct {
+	Name              string    `json:"name"`
+	UniqueID          string    `json:"unique_id"`
+	StateTopic        string    `json:"state_topic"`
+	ValueTemplate     string    `json:"value_template"`
+	DeviceClass       string    `json:"device_class,omitempty"`
+	StateClass        string    `json:"state_class,omitempty"`
+	UnitOfMeasurement string    `json:"unit_of_measurement,omitempty"`
+	Icon              string    `json:"icon,omitempty"`
+	Device            *haDevice `json:"device"`
+}
+
+type haDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+}
+
+// publishHomeAssistant publishes Home Assistant MQTT discovery configurations and states
+// (retained, so Home Assistant picks them up whenever it starts) to the broker:
+// a device for each camera in the catalog (if not nil) with its number of captures,
+// last capture time, and battery level, and a device for the importer with the number
+// of files imported and failed by the last import. Failures are logged.
+func publishHomeAssistant(broker string, cat *catalog.Catalog, imported, failed int) {
+	host, _ := os.Hostname()
+	client, err := mqtt.Dial(broker, "gardepro-"+haSlug(host))
+	if err != nil {
+		log.Warn().Err(err).Msg("Connect to MQTT broker")
+		return
+	}
+	defer func() { _ = client.Close() }()
+	publish := func(topic string, value interface{}) error {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return client.Publish(topic, data, true)
+	}
+
+	importerID := "gardepro_" + haSlug(host)
+	device := &haDevice{Identifiers: []string{importerID}, Name: "GardePro " + host}
+	state := haState + "/" + importerID
+	messages := map[string]interface{}{
+		state: map[string]interface{}{"imported": imported, "failed": failed, "finished": time.Now().Format(time.RFC3339)},
+	}
+	for _, sensor := range []*haSensor{
+		{Name: "Files imported", UniqueID: importerID + "_imported", ValueTemplate: "{{ value_json.imported }}", Icon: "mdi:file-import"},
+		{Name: "Files failed", UniqueID: importerID + "_failed", ValueTemplate: "{{ value_json.failed }}", Icon: "mdi:file-alert"},
+		{Name: "Last import", UniqueID: importerID + "_finished", ValueTemplate: "{{ value_json.finished }}", DeviceClass: "timestamp"},
+	} {
+		sensor.StateTopic, sensor.Device = state, device
+		messages[haDiscovery+"/sensor/"+importerID+"/"+strings.TrimPrefix(sensor.UniqueID, importerID+"_")+"/config"] = sensor
+	}
+
+	if cat != nil {
+		for _, stat := range importer.Stats(cat, 30*24*time.Hour) {
+			cameraID := "gardepro_" + haSlug(stat.Camera)
+			device := &haDevice{Identifiers: []string{cameraID}, Name: "Trail camera " + stat.Camera, Manufacturer: "GardePro"}
+			state := haState + "/" + cameraID
+			// Capture times are camera clock times, taken to be local time.
+			last := time.Date(stat.Last.Year(), stat.Last.Month(), stat.Last.Day(),
+				stat.Last.Hour(), stat.Last.Minute(), stat.Last.Second(), 0, time.Local)
+			values := map[string]interface{}{"captures": stat.Files, "last_capture": last.Format(time.RFC3339)}
+			sensors := []*haSensor{
+				{Name: "Captures", UniqueID: cameraID + "_captures", ValueTemplate: "{{ value_json.captures }}",
+					StateClass: "total_increasing", Icon: "mdi:camera"},
+				{Name: "Last capture", UniqueID: cameraID + "_last_capture", ValueTemplate: "{{ value_json.last_capture }}",
+					DeviceClass: "timestamp"},
+			}
+			if stat.Battery != nil && stat.Battery.Percent > 0 {
+				values["battery"] = stat.Battery.Percent
+				sensors = append(sensors, &haSensor{Name: "Battery", UniqueID: cameraID + "_battery",
+					ValueTemplate: "{{ value_json.battery }}", DeviceClass: "battery", UnitOfMeasurement: "%"})
+			}
+			messages[state] = values
+			for _, sensor := range sensors {
+				sensor.StateTopic, sensor.Device = state, device
+				messages[haDiscovery+"/sensor/"+cameraID+"/"+strings.TrimPrefix(sensor.UniqueID, cameraID+"_")+"/config"] = sensor
+			}
+		}
+	}
+
+	// Publish configurations before states so that entities exist when their states arrive.
+	for _, configs := range []bool{true, false} {
+		for topic, message := range messages {
+			if strings.HasPrefix(topic, haDiscovery+"/") == configs {
+				if err := publish(topic, message); err != nil {
+					log.Warn().Err(err).Msg("Publish to MQTT broker")
+					return
+				}
+			}
+		}
+	}
+	log.Debug().Str("broker", broker).Int("messages", len(messages)).Msg("Published Home Assistant entities")
+}
+
+// haSlug returns a Home Assistant object ID made from a name, e.g. a camera source directory.
+func haSlug(name string) string {
+	slug := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		} else if r >= 'A' && r <= 'Z' {
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, name)
+	return strings.Trim(slug, "_")
+}
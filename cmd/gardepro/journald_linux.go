@@ -0,0 +1,112 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// journalSocket is the native protocol socket of the systemd journal.
+const journalSocket = "/run/systemd/journal/socket"
+
+// journald writes zerolog events to the systemd journal using its native protocol,
+// so that event fields can be matched with journalctl (e.g. journalctl CAMERA=...).
+type journald struct {
+	conn net.Conn
+}
+
+// journaldWriter returns a writer to the systemd journal.
+func journaldWriter() (zerolog.LevelWriter, error) {
+	conn, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return nil, fmt.Errorf("connect to journald: %w", err)
+	}
+	return &journald{conn: conn}, nil
+}
+
+func (j *journald) Write(p []byte) (int, error) {
+	return j.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel sends a JSON event as a journal entry with the message, syslog priority, and event fields.
+func (j *journald) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var event map[string]interface{}
+	if err := json.Unmarshal(p, &event); err != nil {
+		return 0, fmt.Errorf("parse log event: %w", err)
+	}
+	var entry bytes.Buffer
+	journalField(&entry, "SYSLOG_IDENTIFIER", "gardepro")
+	journalField(&entry, "PRIORITY", journalPriority(level))
+	for key, value := range event {
+		switch key {
+		case zerolog.TimestampFieldName, zerolog.LevelFieldName:
+			continue
+		case zerolog.MessageFieldName:
+			key = "MESSAGE"
+		}
+		text, ok := value.(string)
+		if !ok {
+			data, _ := json.Marshal(value)
+			text = string(data)
+		}
+		journalField(&entry, journalName(key), text)
+	}
+	if _, err := j.conn.Write(entry.Bytes()); err != nil {
+		return 0, fmt.Errorf("write journal entry: %w", err)
+	}
+	return len(p), nil
+}
+
+// journalField appends a field to a journal entry,
+// with an explicit length if the value contains a newline.
+func journalField(entry *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		entry.WriteString(name + "=" + value + "\n")
+		return
+	}
+	entry.WriteString(name + "\n")
+	_ = binary.Write(entry, binary.LittleEndian, uint64(len(value)))
+	entry.WriteString(value + "\n")
+}
+
+// journalName converts an event field name to a journal field name:
+// upper case letters, digits, and underscores, not starting with an underscore or digit.
+func journalName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "" || name[0] == '_' || name[0] >= '0' && name[0] <= '9' {
+		name = "F" + name
+	}
+	return name
+}
+
+// journalPriority returns the syslog priority of a level.
+func journalPriority(level zerolog.Level) string {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return "7"
+	case zerolog.InfoLevel, zerolog.NoLevel:
+		return "6"
+	case zerolog.WarnLevel:
+		return "4"
+	case zerolog.ErrorLevel:
+		return "3"
+	case zerolog.FatalLevel:
+		return "2"
+	}
+	return "0"
+}
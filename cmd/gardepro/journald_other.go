@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog"
+)
+
+// journaldWriter is not supported, the systemd journal only exists on Linux.
+func journaldWriter() (zerolog.LevelWriter, error) {
+	return nil, errors.New("journald is only supported on Linux")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Log targets other than a log file or the console.
+const (
+	logSyslog   = "syslog"
+	logJournald = "journald"
+)
+
+// logTargetUsage is the usage of the -log-target flag.
+const logTargetUsage = "Send the log to syslog or journald (Linux) instead"
+
+// setLogTarget directs the log to syslog or journald, returning false if the target is empty.
+// Events are sent as JSON to syslog (for log management that parses it)
+// and with their fields as journal fields (e.g. SOURCE and TARGET) to journald.
+func setLogTarget(target string) (bool, error) {
+	var writer zerolog.LevelWriter
+	var err error
+	switch target {
+	case "":
+		return false, nil
+	case logSyslog:
+		writer, err = syslogWriter()
+	case logJournald:
+		writer, err = journaldWriter()
+	default:
+		return false, fmt.Errorf("unknown log target %q (syslog or journald)", target)
+	}
+	if err != nil {
+		return false, err
+	}
+	zerolog.TimestampFunc = localTime
+	log.Logger = zerolog.New(writer).With().Timestamp().Logger()
+	return true, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"html/template"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/i18n"
+	"github.com/madkins23/gardepro/importer"
+)
+
+// mapTemplate formats a map of the cameras as an HTML page with a gallery of the latest captures
+// of each camera (thumbnails are embedded) below the map.
+// The map itself is drawn by Leaflet with OpenStreetMap tiles, so viewing it requires internet access.
+// Text is translated with t.
+var mapTemplate = template.Must(template.New("map").Funcs(template.FuncMap{
+	"lang":      func() string { return i18n.Lang().String() },
+	"t":         i18n.T,
+	"thumbnail": thumbnail,
+	"time":      func(t time.Time) string { return t.Format(timeFmt) },
+}).Parse(`<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="https://unpkg.com/leaflet@1.9.4/dist/leaflet.css">
+<script src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"></script>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+#map { height: 70vh; margin-bottom: 2em; }
+.gallery { display: flex; flex-wrap: wrap; gap: 1em; }
+figure { margin: 0; }
+figcaption { font-size: small; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div id="map"></div>
+{{- range $index, $camera := .Cameras}}
+<h2 id="camera-{{$index}}">{{.Camera}}{{if .Location}} ({{.Location}}){{end}}</h2>
+<p>{{t "%d captures" .Files}}{{if .Last}}, {{t "last %s" (time .Last.Captured)}}{{end}}{{if not .Position}}, {{t "position unknown"}}{{end}}</p>
+<div class="gallery">
+{{- range .Recent}}
+<figure>
+{{- if .Thumbnail}}<img src="{{thumbnail .Thumbnail}}" alt="{{.File.Path}}">{{end}}
+<figcaption>{{time .File.Captured}}<br>{{.File.Path}}</figcaption>
+</figure>
+{{- end}}
+</div>
+{{- end}}
+<script>
+var markers = {{.Markers}};
+var map = L.map("map");
+L.tileLayer("https://tile.openstreetmap.org/{z}/{x}/{y}.png", {
+	maxZoom: 19,
+	attribution: '&copy; <a href="https://www.openstreetmap.org/copyright">OpenStreetMap</a>'
+}).addTo(map);
+var bounds = [];
+markers.forEach(function (m) {
+	var popup = document.createElement("div");
+	var link = document.createElement("a");
+	link.href = "#camera-" + m.index;
+	link.textContent = m.camera;
+	popup.appendChild(link);
+	[m.location, m.last].forEach(function (line) {
+		if (line) {
+			popup.appendChild(document.createElement("br"));
+			popup.appendChild(document.createTextNode(line));
+		}
+	});
+	L.marker([m.lat, m.lon]).addTo(map).bindPopup(popup);
+	bounds.push([m.lat, m.lon]);
+});
+if (bounds.length > 0) {
+	map.fitBounds(bounds, {maxZoom: 15, padding: [40, 40]});
+} else {
+	map.setView([0, 0], 2);
+}
+</script>
+</body>
+</html>
+`))
+
+// mapMarker is the data of a camera marker for the map script.
+type mapMarker struct {
+	Index     int     `json:"index"`
+	Camera    string  `json:"camera"`
+	Location  string  `json:"location,omitempty"`
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+	Last      string  `json:"last,omitempty"`
+}
+
+func mapCommand(args []string) {
+	var recent int
+	var output, target, title string
+
+	flags := flag.NewFlagSet("map", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.IntVar(&recent, "recent", 8, "Number of latest captures shown for each camera")
+	flags.StringVar(&output, "o", "map.html", "Map file")
+	flags.StringVar(&title, "title", i18n.T("Trail Camera Map"), "Map title")
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	defer func() { _ = cat.Close() }()
+	cameras, err := importer.New(target, importer.Options{Catalog: cat}).CameraMap(recent)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Map cameras")
+	}
+	markers := make([]mapMarker, 0, len(cameras))
+	for index, camera := range cameras {
+		if camera.Position == nil {
+			continue
+		}
+		marker := mapMarker{Index: index, Camera: camera.Camera, Location: camera.Location,
+			Latitude: camera.Position.Latitude, Longitude: camera.Position.Longitude}
+		if camera.Last != nil {
+			marker.Last = i18n.T("last %s", camera.Last.Captured.Format(timeFmt))
+		}
+		markers = append(markers, marker)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Create map")
+	}
+	data := map[string]interface{}{"Title": title, "Cameras": cameras, "Markers": markers}
+	if err := mapTemplate.Execute(file, data); err != nil {
+		_ = file.Close()
+		log.Fatal().Err(err).Msg("Write map")
+	} else if err := file.Close(); err != nil {
+		log.Fatal().Err(err).Msg("Close map")
+	}
+	log.Info().Str("map", output).Int("cameras", len(cameras)).Int("placed", len(markers)).Msg("Map finished")
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+func mergeCommand(args []string) {
+	var site, target string
+
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&site, "site", "", "Name of the other installation (e.g. cabin)")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: gardepro merge -target DIR -site NAME SOURCE")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if target == "" || site == "" || flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	files, err := importer.MergeSite(target, site, flags.Arg(0))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Merge site catalog")
+	}
+	log.Info().Str("site", site).Int("files", files).Msg("Merged site catalog")
+}
+
+// withSites calls the function with the name and catalog of each site merged into the target.
+func withSites(target string, fn func(site string, cat *catalog.Catalog)) {
+	sites, err := importer.Sites(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("List sites")
+	}
+	for _, site := range sites {
+		cat, err := importer.OpenSite(target, site)
+		if err != nil {
+			log.Fatal().Err(err).Str("site", site).Msg("Open site catalog")
+		}
+		fn(site, cat)
+		_ = cat.Close()
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+	"github.com/madkins23/gardepro/remote"
+)
+
+func offloadCommand(args []string) {
+	var before, pool, storageClass, target, to string
+
+	flags := flag.NewFlagSet("offload", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.StringVar(&before, "before", "", "Offload files captured before this date (YYYY-MM-DD)")
+	flags.StringVar(&to, "to", "", "Remote storage URL (s3://bucket/prefix)")
+	flags.StringVar(&storageClass, "storage-class", "DEEP_ARCHIVE", "S3 storage class")
+	_ = flags.Parse(args)
+	if target == "" || before == "" || to == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	ctx := context.Background()
+	store, err := remote.NewS3(ctx, to)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Remote storage")
+	} else if err := store.Probe(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Remote storage unavailable")
+	}
+	cat := commandCatalog(target, "offload", to)
+	defer func() { _ = cat.Close() }()
+	imp := importer.New(target, importer.Options{Catalog: cat, Pool: poolRoots(pool)})
+	if offloaded, err := imp.Offload(ctx, store, before, storageClass); err != nil {
+		log.Fatal().Err(err).Int("offloaded", offloaded).Msg("Offload")
+	} else {
+		log.Info().Int("offloaded", offloaded).Msg("Offload finished")
+	}
+}
+
+func restoreCommand(args []string) {
+	var days int
+	var pool, tier string
+
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.IntVar(&days, "days", 7, "Days to keep cold storage restores available")
+	flags.StringVar(&tier, "tier", "Bulk", "Cold storage restore tier (Expedited, Standard, or Bulk)")
+	_ = flags.Parse(args)
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	ctx := context.Background()
+	var pending, failed int
+	for _, path := range flags.Args() {
+		target, root, err := importer.FindTarget(path)
+		if err != nil {
+			log.Error().Err(err).Str("path", path).Msg("Find target root")
+			failed++
+			continue
+		}
+		roots := poolRoots(pool)
+		if root != target {
+			// The file is in a pool root, which may not have been specified.
+			roots = append(roots, root)
+		}
+		cat := commandCatalog(target, "restore", path)
+		err = importer.New(target, importer.Options{Catalog: cat, Pool: roots}).Restore(ctx, path, days, tier)
+		_ = cat.Close()
+		if errors.Is(err, importer.ErrRestorePending) {
+			log.Info().Str("path", path).Msg("Restore pending, try again later")
+			pending++
+		} else if err != nil {
+			log.Error().Err(err).Str("path", path).Msg("Restore")
+			failed++
+		}
+	}
+	log.Info().Int("pending", pending).Int("failed", failed).Msg("Restore finished")
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
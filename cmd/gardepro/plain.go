@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// plainUsage is the usage of the -plain flag.
+const plainUsage = "Plain console output without colors, with progress lines (e.g. for screen readers)"
+
+// plain is set by the -plain flag.
+var plain bool
+
+// plainOutput returns whether console output is to be plain: set by -plain,
+// or for commands by the GARDEPRO_PLAIN or NO_COLOR (https://no-color.org) environment variables.
+func plainOutput() bool {
+	if plain || os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	set, _ := strconv.ParseBool(os.Getenv("GARDEPRO_PLAIN"))
+	return set
+}
+
+// plainWriter returns a console log writer without colors, with spelled out levels
+// in a column of fixed width.
+func plainWriter() zerolog.ConsoleWriter {
+	return zerolog.ConsoleWriter{
+		Out:        os.Stderr,
+		TimeFormat: "15:04:05",
+		NoColor:    true,
+		FormatLevel: func(level interface{}) string {
+			return fmt.Sprintf("%-5s", level)
+		},
+	}
+}
+
+// progressLines returns an import progress function (see importer.Options.Progress)
+// that logs a line at every tenth of the files rather than redrawing a progress bar,
+// since screen readers read each line.
+func progressLines() func(done, total int) {
+	var reported int
+	return func(done, total int) {
+		if percent := 100 * done / total; percent/10 > reported/10 || done == total {
+			reported = percent
+			log.Info().Int("done", done).Int("files", total).Msgf("Import progress %d%%", percent)
+		}
+	}
+}
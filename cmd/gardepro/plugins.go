@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+	"github.com/madkins23/gardepro/plugin"
+)
+
+// defaultPluginDir returns the default plugin directory in the user configuration directory.
+func defaultPluginDir() string {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(config, "gardepro", "plugins")
+}
+
+// notifierPlugins returns the notifier plugins in the directory (the default plugin directory if empty).
+func notifierPlugins(dir string) ([]*plugin.Plugin, error) {
+	if dir == "" {
+		if dir = defaultPluginDir(); dir == "" {
+			return nil, nil
+		}
+	}
+	plugins, err := plugin.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	var notifiers []*plugin.Plugin
+	for _, p := range plugins {
+		if p.Kind == plugin.KindNotifier {
+			notifiers = append(notifiers, p)
+		}
+	}
+	return notifiers, nil
+}
+
+// loadPlugins loads the plugins in the directory (the default plugin directory if empty),
+// registering extractors and setting the classify and notify functions of the options (if not nil).
+func loadPlugins(dir string, options *importer.Options) error {
+	if dir == "" {
+		if dir = defaultPluginDir(); dir == "" {
+			return nil
+		}
+	}
+	plugins, err := plugin.Load(dir)
+	if err != nil {
+		return err
+	}
+	var classifiers, notifiers []*plugin.Plugin
+	for _, p := range plugins {
+		switch p.Kind {
+		case plugin.KindExtractor:
+			for _, ext := range p.Extensions {
+				importer.RegisterExtractor(ext, p.CaptureTime)
+			}
+		case plugin.KindClassifier:
+			classifiers = append(classifiers, p)
+		case plugin.KindNotifier:
+			notifiers = append(notifiers, p)
+		default:
+			log.Warn().Str("plugin", p.Path).Str("kind", p.Kind).Msg("Unknown plugin kind")
+		}
+	}
+	if options == nil {
+		return nil
+	}
+	if len(classifiers) > 0 {
+		options.Classify = func(path string, captured time.Time) ([]string, float64, error) {
+			var tags []string
+			var confidence float64
+			for _, classifier := range classifiers {
+				more, certainty, err := classifier.Classify(path, captured)
+				if err != nil {
+					return tags, confidence, err
+				}
+				tags = append(tags, more...)
+				if certainty > confidence {
+					confidence = certainty
+				}
+			}
+			return tags, confidence, nil
+		}
+	}
+	if len(notifiers) > 0 {
+		options.Notify = func(source, targetPath string, repeat bool, err error) {
+			if repeat && err == nil {
+				log.Debug().Str("source", source).Msg("Not notifying copy of capture already imported")
+				return
+			}
+			request := &plugin.Request{Event: plugin.EventFileImported, Source: source, Target: targetPath}
+			if err != nil {
+				request.Event, request.Error = plugin.EventFileFailed, err.Error()
+			}
+			for _, notifier := range notifiers {
+				if err := notifier.Notify(request); err != nil {
+					log.Warn().Err(err).Str("source", source).Msg("Notify plugin")
+				}
+			}
+		}
+	}
+	return nil
+}
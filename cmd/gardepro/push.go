@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/i18n"
+	"github.com/madkins23/gardepro/push"
+)
+
+// pushUsage is the usage of the -push flag.
+const pushUsage = "Push notification services (ntfy:TOPIC, ntfy:URL, or pushover:APP_TOKEN:USER_KEY, comma separated)"
+
+// pushServices parses the -push flag, exiting on errors.
+func pushServices(specs string) []*push.Service {
+	services, err := push.Parse(specs)
+	if err != nil {
+		fatalf("Parse -push: %s", err)
+	}
+	return services
+}
+
+// sendPush sends a notification to the services, logging failures.
+func sendPush(services []*push.Service, title, message string, urgent bool) {
+	for _, service := range services {
+		if err := service.Send(title, message, urgent); err != nil {
+			log.Warn().Err(err).Str("service", service.String()).Msg("Push notification")
+		}
+	}
+}
+
+// importPush returns the notification sent when importing from a source finishes
+// with a session status (ok or failed), urgent if the import failed or any files failed.
+func importPush(source, status string, imported, failed int) (string, string, bool) {
+	message := i18n.T("%d files imported from %s", imported, source)
+	if failed > 0 {
+		message += i18n.T(", %d failed", failed)
+	}
+	if status != "ok" {
+		return i18n.T("GardePro import failed"), message, true
+	}
+	return i18n.T("GardePro import finished"), message, failed > 0
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+func rateCommand(args []string) {
+	var noXMP bool
+
+	flags := flag.NewFlagSet("rate", flag.ExitOnError)
+	flags.BoolVar(&noXMP, "no-xmp", false, "Don't write the rating to XMP sidecars")
+	_ = flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	rating, err := strconv.Atoi(flags.Arg(0))
+	if err != nil || rating < 0 || rating > 5 {
+		fatalf("Rating must be 0 (none) to 5 stars: %s", flags.Arg(0))
+	}
+
+	consoleLog()
+	if err := updateFiles(flags.Args()[1:], "rate", func(path string, file *catalog.File) (bool, error) {
+		changed := file.Rating != rating
+		file.Rating = rating
+		if !noXMP && file.Offloaded == "" {
+			if written, err := importer.XMPwrite(path, file); err != nil {
+				return false, err
+			} else if !written && rating == 0 {
+				// Clear the rating in another application's sidecar.
+				if err := importer.XMPsetRating(path, rating); err != nil {
+					return false, err
+				}
+			}
+		}
+		if changed {
+			log.Info().Str("path", file.Path).Int("rating", rating).Msg("Rated file")
+		}
+		return changed, nil
+	}); err != nil {
+		log.Fatal().Err(err).Msg("Rate files")
+	}
+}
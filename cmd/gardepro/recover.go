@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func recoverCommand(args []string) {
+	var target, work string
+
+	flags := flag.NewFlagSet("recover", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory for recovered files")
+	flags.StringVar(&work, "work", "", "Directory for carved files [-target/.gardepro/recovered/<time>]")
+	_ = flags.Parse(args)
+	if target == "" || flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if work == "" {
+		work = filepath.Join(target, importer.StateDir, "recovered", time.Now().Format("20060102-150405"))
+	}
+
+	consoleLog()
+	carved, err := importer.Carve(flags.Arg(0), work)
+	if err != nil {
+		log.Error().Err(err).Msg("Carve device")
+	}
+	cat := commandCatalog(target, "recover", flags.Arg(0))
+	defer func() { _ = cat.Close() }()
+	imp := importer.New(target, importer.Options{Catalog: cat, Verify: true})
+	var imported int
+	for _, result := range imp.ImportBatch(work, carved) {
+		if result.Duplicate == "" && result.Err == nil {
+			imported++
+		}
+	}
+	log.Info().Int("carved", len(carved)).Int("imported", imported).Str("work", work).Msg("Recover finished")
+}
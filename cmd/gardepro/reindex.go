@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func reindexCommand(args []string) {
+	var checkExposure, sidecarGPS bool
+	var options importer.ReindexOptions
+	var hashAlgorithm, pluginDir, pool, target string
+
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.IntVar(&options.Year, "year", 0, "Only reindex this year")
+	flags.BoolVar(&checkExposure, "exposure", false, "Flag badly exposed JPG files in the catalog")
+	flags.StringVar(&hashAlgorithm, "hash", importer.HashSHA256, "Hash algorithm (sha256, xxh3, or blake3)")
+	flags.StringVar(&pluginDir, "plugins", "", "Plugin directory [user config dir/gardepro/plugins]")
+	flags.BoolVar(&sidecarGPS, "sidecar-gps", false, "Record GPS positions from drone SRT sidecars in the catalog")
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	impOptions := importer.Options{
+		CheckExposure: checkExposure,
+		Hash:          hashAlgorithm,
+		Pool:          poolRoots(pool),
+		SidecarGPS:    sidecarGPS,
+	}
+	if err := loadPlugins(pluginDir, &impOptions); err != nil {
+		log.Fatal().Err(err).Msg("Load plugins")
+	}
+	impOptions.Notify = nil
+	cat := commandCatalog(target, "reindex", "")
+	defer func() { _ = cat.Close() }()
+	impOptions.Catalog = cat
+	if count, err := importer.New(target, impOptions).Reindex(&options); err != nil {
+		log.Fatal().Err(err).Int("files", count).Msg("Reindex")
+	} else {
+		log.Info().Int("files", count).Msg("Reindex finished")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func renameCommand(args []string) {
+	flags := flag.NewFlagSet("rename", flag.ExitOnError)
+	_ = flags.Parse(args)
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	if err := loadPlugins("", nil); err != nil {
+		log.Fatal().Err(err).Msg("Load plugins")
+	}
+	var renamed, failed int
+	for _, arg := range flags.Args() {
+		sources, err := importer.SourceFiles(arg)
+		if err != nil {
+			log.Error().Err(err).Str("source", arg).Msg("Find source files")
+			failed++
+			continue
+		}
+		for _, source := range sources {
+			if path, err := importer.RenameInPlace(source); err != nil {
+				log.Error().Err(err).Msg("Rename file")
+				failed++
+			} else if path != source {
+				log.Info().Str("source", source).Str("path", path).Msg("Renamed file")
+				renamed++
+			}
+		}
+	}
+	log.Info().Int("renamed", renamed).Int("failed", failed).Msg("Rename finished")
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
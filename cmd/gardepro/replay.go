@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func replayCommand(args []string) {
+	var dryRun, all bool
+	var target string
+
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.BoolVar(&dryRun, "dry-run", false, "Only report the decisions, don't import")
+	flags.BoolVar(&all, "all", false, "Report all files, not only those that would be treated differently")
+	flags.StringVar(&target, "target", "", "Target directory [that of the session]")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	journal, err := importer.ReadJournal(flags.Arg(0))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Read session journal")
+	}
+	if target == "" {
+		target = journal.Target
+	}
+	if err := loadPlugins("", nil); err != nil {
+		log.Fatal().Err(err).Msg("Load plugins")
+	}
+	var options importer.Options
+	if err := journal.Settings.Apply(&options); err != nil {
+		log.Fatal().Err(err).Msg("Apply session settings")
+	}
+	if options.Catalog, err = importer.OpenCatalog(target); err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	replayed := importer.New(target, options).Replay(journal)
+	_ = options.Catalog.Close()
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(writer, "Session %s of %s started %s\n\n", journal.Session, journal.Source, journal.Started.Local().Format(timeFmt))
+	_, _ = fmt.Fprintln(writer, "Source\tThen\tNow\t")
+	var changed int
+	var imports []string
+	for _, r := range replayed {
+		if r.Now.Action == importer.ActionImported {
+			imports = append(imports, r.Source)
+		}
+		if r.Changed() {
+			changed++
+		} else if !all {
+			continue
+		}
+		then := "(none)"
+		if r.Then != nil {
+			then = describeDecision(r.Then)
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t\n", r.Source, then, describeDecision(r.Now))
+	}
+	_ = writer.Flush()
+	fmt.Printf("\n%d files, %d would be treated differently, %d would be imported\n", len(replayed), changed, len(imports))
+	if dryRun || len(imports) == 0 {
+		return
+	}
+
+	options.Catalog = commandCatalog(target, "replay", journal.Source)
+	defer func() { _ = options.Catalog.Close() }()
+	var failed int
+	for _, result := range importer.New(target, options).ImportBatch(journal.Source, imports) {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	log.Info().Int("files", len(imports)).Int("failed", failed).Msg("Imported files")
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// describeDecision returns a short description of a decision: its action and target or reason.
+func describeDecision(decision *importer.Decision) string {
+	switch {
+	case decision.Reason != "":
+		return decision.Action + ": " + decision.Reason
+	case decision.Target != "":
+		return decision.Action + " as " + decision.Target
+	default:
+		return decision.Action
+	}
+}
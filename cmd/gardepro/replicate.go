@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func replicateCommand(args []string) {
+	var ssh, target string
+
+	flags := flag.NewFlagSet("replicate", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&ssh, "ssh", "ssh", "Path of the ssh executable")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: gardepro replicate -target DIR [-ssh PATH] HOST:DIR")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if target == "" || flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	host, dir, found := strings.Cut(flags.Arg(0), ":")
+	if !found || host == "" || dir == "" {
+		fatalf("Peer %s is not HOST:DIR", flags.Arg(0))
+	}
+
+	consoleLog()
+	cat := commandCatalog(target, "replicate", flags.Arg(0))
+	defer func() { _ = cat.Close() }()
+	imp := importer.New(target, importer.Options{Catalog: cat})
+	result, err := imp.Replicate(&importer.Peer{SSH: ssh, Host: host, Target: dir})
+	if result != nil {
+		log.Info().Int("pulled", result.Pulled).Int("pushed", result.Pushed).Msg("Replicated")
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Replicate")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/email"
+	"github.com/madkins23/gardepro/i18n"
+	"github.com/madkins23/gardepro/importer"
+)
+
+// reportTemplate formats a monthly report as a self-contained HTML page
+// (thumbnails are embedded) which can be emailed or printed to PDF from a browser.
+// Text is translated with t.
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"heat":      heat,
+	"lang":      func() string { return i18n.Lang().String() },
+	"month":     i18n.Month,
+	"t":         i18n.T,
+	"thumbnail": thumbnail,
+	"time":      func(t time.Time) string { return t.Format(timeFmt) },
+}).Parse(`<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{month .Report.Month}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.heat td { min-width: 1.5em; text-align: center; }
+.events { display: flex; flex-wrap: wrap; gap: 1em; }
+figure { margin: 0; break-inside: avoid; }
+figcaption { font-size: small; }
+</style>
+</head>
+<body>
+<h1>{{.Title}} {{month .Report.Month}}</h1>
+<p>{{t "%d captures by %d cameras." .Report.Files (len .Report.Cameras)}}</p>
+{{- if .Report.Cameras}}
+<h2>{{t "Captures by camera and hour of day"}}</h2>
+<table class="heat">
+<tr><th>{{t "Camera"}}</th><th>{{t "Total"}}</th>{{range $hour, $_ := (index .Report.Cameras 0).Hours}}<th>{{$hour}}</th>{{end}}</tr>
+{{- range .Report.Cameras}}
+<tr><td>{{.Camera}}</td><td>{{.Files}}</td>{{range .Hours}}<td style="{{heat . $.Max}}">{{if .}}{{.}}{{end}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Report.Tags}}
+<h2>{{t "Species and tags"}}</h2>
+<table>
+<tr><th>{{t "Tag"}}</th><th>{{t "Captures"}}</th></tr>
+{{- range .Report.Tags}}
+<tr><td>{{.Tag}}</td><td>{{.Count}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Report.Notable}}
+<h2>{{t "Notable events"}}</h2>
+<div class="events">
+{{- range .Report.Notable}}
+<figure>
+{{- if .Thumbnail}}<img src="{{thumbnail .Thumbnail}}" alt="{{.File.Path}}">{{end}}
+<figcaption>{{time .File.Captured}}<br>{{.File.Path}}{{range .File.AllTags}} [{{.}}]{{end}}</figcaption>
+</figure>
+{{- end}}
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+func reportCommand(args []string) {
+	var notable int
+	var emailTo, month, output, pool, smtpURL, target, title string
+
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.StringVar(&month, "month", time.Now().AddDate(0, -1, 0).Format("2006-01"), "Month of the report (YYYY-MM)")
+	flags.IntVar(&notable, "notable", 12, "Maximum number of notable events")
+	flags.StringVar(&output, "o", "", "Report file [report-YYYY-MM.html]")
+	flags.StringVar(&title, "title", i18n.T("Trail Camera Activity"), "Report title")
+	flags.StringVar(&emailTo, "email", "", "Email addresses to which the report is sent (comma separated)")
+	flags.StringVar(&smtpURL, "smtp", "", smtpUsage)
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	when, err := time.Parse("2006-01", month)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Parse -month")
+	}
+	mail, err := newMailer(emailTo, smtpURL)
+	if err != nil {
+		fatalf("Parse flags: %s", err)
+	}
+	if output == "" && mail == nil {
+		output = "report-" + month + ".html"
+	}
+
+	consoleLog()
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	defer func() { _ = cat.Close() }()
+	report, err := importer.New(target, importer.Options{Catalog: cat, Pool: poolRoots(pool)}).Report(when, notable)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Report")
+	}
+	var max int
+	for _, camera := range report.Cameras {
+		for _, count := range camera.Hours {
+			if count > max {
+				max = count
+			}
+		}
+	}
+	data := map[string]interface{}{"Title": title, "Report": report, "Max": max}
+
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Create report")
+		}
+		if err := reportTemplate.Execute(file, data); err != nil {
+			_ = file.Close()
+			log.Fatal().Err(err).Msg("Write report")
+		} else if err := file.Close(); err != nil {
+			log.Fatal().Err(err).Msg("Close report")
+		}
+	}
+	if mail != nil {
+		msg, err := reportEmail(data)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Format report email")
+		}
+		msg.Subject = title + " " + i18n.Month(report.Month)
+		mail.send(msg)
+	}
+	log.Info().Str("report", output).Int("files", report.Files).Int("notable", len(report.Notable)).Msg("Report finished")
+}
+
+// reportEmail formats a report as an HTML email with the thumbnails attached inline,
+// since many mail clients don't show data URLs.
+func reportEmail(data interface{}) (*email.Message, error) {
+	msg := &email.Message{}
+	tmpl, err := reportTemplate.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("clone report template: %w", err)
+	}
+	tmpl.Funcs(template.FuncMap{"thumbnail": func(jpeg []byte) template.URL {
+		id := fmt.Sprintf("thumbnail-%d@gardepro", len(msg.Inline)+1)
+		msg.Inline = append(msg.Inline, &email.Inline{ID: id, ContentType: "image/jpeg", Data: jpeg})
+		return template.URL("cid:" + id)
+	}})
+	var html strings.Builder
+	if err := tmpl.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("format report: %w", err)
+	}
+	msg.HTML = html.String()
+	return msg, nil
+}
+
+// heat returns the style of a heatmap cell, shaded by its count relative to the maximum.
+func heat(count, max int) template.CSS {
+	if count == 0 || max == 0 {
+		return ""
+	}
+	return template.CSS(fmt.Sprintf("background-color: rgba(200, 60, 0, %.2f)", 0.1+0.9*float64(count)/float64(max)))
+}
+
+// thumbnail returns a data URL for a JPEG thumbnail.
+func thumbnail(jpeg []byte) template.URL {
+	return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(jpeg))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func scrubCommand(args []string) {
+	var options importer.ScrubOptions
+	var pool, target string
+
+	flags := flag.NewFlagSet("scrub", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.DurationVar(&options.Budget, "budget", time.Hour, "How long the scrub may take, 0 for no limit")
+	flags.DurationVar(&options.Period, "period", 90*24*time.Hour, "How often each file is verified, 0 to verify all files")
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	cat := commandCatalog(target, "scrub", "")
+	defer func() { _ = cat.Close() }()
+	result, err := importer.New(target, importer.Options{Catalog: cat, Pool: poolRoots(pool)}).Scrub(&options)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Scrub")
+	}
+	log.Info().Int("verified", result.Verified).Int64("bytes", result.Bytes).
+		Int("damaged", len(result.Damaged)).Int("missing", len(result.Missing)).
+		Int("remaining", result.Remaining).Int("orphans", len(result.Orphans)).Msg("Scrub finished")
+	if len(result.Damaged) > 0 || len(result.Missing) > 0 {
+		os.Exit(1)
+	}
+}
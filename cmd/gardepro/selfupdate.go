@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/chain"
+	"github.com/madkins23/gardepro/release"
+)
+
+// version is set by release builds with -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+func selfUpdateCommand(args []string) {
+	var check, force bool
+	var keyFile, repository, sign, tag string
+
+	flags := flag.NewFlagSet("self-update", flag.ExitOnError)
+	flags.BoolVar(&check, "check", false, "Only report whether an update is available")
+	flags.BoolVar(&force, "force", false, "Install the latest release even if it isn't newer than the running version")
+	flags.StringVar(&keyFile, "key", "", "Public key file of the release signer instead of the built-in key (or private key file for -sign)")
+	flags.StringVar(&repository, "repo", release.DefaultRepository, "GitHub repository of releases")
+	flags.StringVar(&sign, "sign", "", "Sign a "+release.SumsName+" file for publishing with a release")
+	flags.StringVar(&tag, "tag", "", "Release tag (version) signed with -sign")
+	_ = flags.Parse(args)
+
+	consoleLog()
+	if sign != "" {
+		private, err := chain.ReadPrivateKey(keyFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Read key")
+		}
+		if err := release.Sign(sign, tag, private); err != nil {
+			log.Fatal().Err(err).Msg("Sign checksums")
+		}
+		log.Info().Str("signature", sign+release.SignatureSuffix).
+			Str("public-key", release.EncodePublicKey(private.Public().(ed25519.PublicKey))).Msg("Signed checksums")
+		return
+	}
+
+	var public ed25519.PublicKey
+	var err error
+	if keyFile != "" {
+		public, err = chain.ReadPublicKey(keyFile)
+	} else {
+		public, err = release.EmbeddedKey()
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Read key")
+	} else if public == nil && !check {
+		fatalf("No release key built in (see -ldflags in the usage), give the public -key of the release signer")
+	}
+	latest, err := release.Latest(repository)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Find latest release")
+	}
+	if newer, err := release.CompareVersions(latest.Tag, version); err != nil && !force {
+		// e.g. a dev build, which can't be compared with releases.
+		fmt.Printf("gardepro %s is the latest release (running %s, use -force to install it): %s\n", latest.Tag, version, err)
+		return
+	} else if err == nil && newer == 0 && !force {
+		fmt.Printf("gardepro %s is the latest release\n", version)
+		return
+	} else if err == nil && newer < 0 && !force {
+		fmt.Printf("gardepro %s is the latest release, older than running %s (use -force to install it)\n", latest.Tag, version)
+		return
+	}
+	fmt.Printf("gardepro %s is available (running %s)\n", latest.Tag, version)
+	if check {
+		return
+	}
+	data, signed, err := latest.Download(public)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Download release")
+	}
+	// The tag isn't signed, so the version is checked again against the signed version.
+	if newer, err := release.CompareVersions(signed, version); !force && (err != nil || newer <= 0) {
+		fatalf("Signed version %s of release %s is not newer than running %s (use -force to install it)", signed, latest.Tag, version)
+	}
+	executable, err := release.Executable()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Find executable")
+	}
+	if err := release.Install(executable, data); err != nil {
+		log.Fatal().Err(err).Msg("Install release")
+	}
+	// A running kiosk keeps running the old version until restarted.
+	log.Info().Str("executable", executable).Str("version", signed).Msg("Updated, restart running services")
+}
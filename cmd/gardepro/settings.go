@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strconv"
+
+	"github.com/madkins23/gardepro/i18n"
+)
+
+// settingsTitle is the title of the settings dialogs.
+const settingsTitle = "GardePro Settings"
+
+// switchSettings are the on or off settings edited by the settings command.
+var switchSettings = []struct {
+	name, question string
+}{
+	{"preserve-structure", "Keep the folders of the card beneath the year folders?"},
+	{"verify", "Check that each file is undamaged before archiving it?"},
+	{"fix-orientation", "Turn pictures with an unusual orientation upright?"},
+	{"quick", "Presume files already archived with the same size are identical?"},
+}
+
+func settingsCommand(args []string) {
+	var lang, path string
+	flags := flag.NewFlagSet("settings", flag.ExitOnError)
+	flags.StringVar(&lang, "lang", "", langUsage)
+	flags.StringVar(&path, "o", configPath(), "Config file")
+	_ = flags.Parse(args)
+
+	settings, err := readConfig(path)
+	if err != nil {
+		errorDialog("Error reading config file", err.Error())
+		os.Exit(1)
+	}
+	if lang == "" {
+		lang = configValue(settings, "lang")
+	}
+	if err := i18n.Set(lang); err != nil {
+		errorDialog("Error parsing command line flags", err.Error())
+		os.Exit(2)
+	}
+	if path == "" {
+		errorDialog("Error reading config file", i18n.T("No user config directory"))
+		os.Exit(1)
+	}
+
+	if target, ok := directoryDialog("Directory into which camera files are archived",
+		configValue(settings, "target")); ok {
+		settings = setConfig(settings, "target", target)
+	}
+	for _, setting := range switchSettings {
+		value, on := "false", configSwitch(settings, setting.name)
+		if yesNoDialog(settingsTitle, i18n.T(setting.question)+"\n\n"+currently(on), on) {
+			value = "true"
+		}
+		settings = setConfig(settings, setting.name, value)
+	}
+	// Files that fail to import either stop the import (strict) or are quarantined (lenient).
+	lenient := configSwitch(settings, "lenient") || !configSwitch(settings, "strict")
+	question := i18n.T("When a file can't be imported, set it aside in quarantine and continue with the others?")
+	if yesNoDialog(settingsTitle, question+"\n\n"+currently(lenient), lenient) {
+		settings = setConfig(setConfig(settings, "strict", ""), "lenient", "true")
+	} else {
+		settings = setConfig(setConfig(settings, "lenient", ""), "strict", "true")
+	}
+
+	if err := writeConfig(path, "settings", settings); err != nil {
+		errorDialog("Error writing config file", err.Error())
+		os.Exit(1)
+	}
+	infoDialog(settingsTitle, i18n.T("Settings saved to %s", path))
+}
+
+// configValue returns the value of the named flag in the settings, empty if none.
+func configValue(settings [][2]string, name string) string {
+	for _, setting := range globalSettings(settings) {
+		if setting[0] == name {
+			return setting[1]
+		}
+	}
+	return ""
+}
+
+// configSwitch returns whether the named switch flag is on in the settings.
+func configSwitch(settings [][2]string, name string) bool {
+	on, _ := strconv.ParseBool(configValue(settings, name))
+	return on
+}
+
+// currently describes the current state of a switch setting.
+func currently(on bool) string {
+	if on {
+		return i18n.T("Currently: yes")
+	}
+	return i18n.T("Currently: no")
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+	"github.com/madkins23/gardepro/remote"
+)
+
+// shareTemplate formats a read-only gallery of shared files.
+// The files are linked by presigned URLs which expire with the link to the gallery.
+var shareTemplate = template.Must(template.New("share").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.Format(timeFmt) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+figure { margin: 0 0 2em 0; }
+img, video { max-width: 100%; max-height: 90vh; }
+figcaption { font-size: small; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>This page and its files are available until {{time .Expires}}.</p>
+{{- range .Files}}
+<figure>
+{{- if .Video}}
+<video src="{{.URL}}" controls preload="metadata"></video>
+{{- else}}
+<img src="{{.URL}}" alt="{{.File.Path}}" loading="lazy">
+{{- end}}
+<figcaption>{{time .File.Captured}}{{range .File.AllTags}} [{{.}}]{{end}}</figcaption>
+</figure>
+{{- end}}
+</body>
+</html>
+`))
+
+func shareCommand(args []string) {
+	var expires time.Duration
+	var from, hide, pool, tag, target, title, to, until string
+
+	flags := flag.NewFlagSet("share", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.StringVar(&to, "to", "", "Remote storage URL (s3://bucket/prefix)")
+	flags.StringVar(&from, "from", "", "First capture time (YYYY-MM-DD or YYYY-MM-DD hh:mm)")
+	flags.StringVar(&until, "until", "", "Last capture time (YYYY-MM-DD or YYYY-MM-DD hh:mm)")
+	flags.StringVar(&tag, "tag", "", "Share only files with this tag or label")
+	flags.StringVar(&hide, "hide", "human,person,vehicle", "Never share files with these tags or labels (comma separated)")
+	flags.DurationVar(&expires, "expires", 3*24*time.Hour, "How long the link is valid (at most 168h)")
+	flags.StringVar(&title, "title", "Trail camera captures", "Gallery title")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: gardepro share [flags] [FILE...]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if target == "" || to == "" || flags.NArg() == 0 && from == "" && until == "" && tag == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if expires <= 0 || expires > remote.MaxExpires {
+		fatalf("-expires must be between 0 and %s", remote.MaxExpires)
+	}
+	options := &importer.ShareOptions{Tag: tag, Paths: flags.Args(), Expires: expires}
+	if hide != "" {
+		options.Hide = strings.Split(hide, ",")
+	}
+	var err error
+	if from != "" {
+		if options.From, err = parseShareTime(from, false); err != nil {
+			fatalf("Parse -from: %s", err)
+		}
+	}
+	if until != "" {
+		if options.Until, err = parseShareTime(until, true); err != nil {
+			fatalf("Parse -until: %s", err)
+		}
+	}
+
+	consoleLog()
+	ctx := context.Background()
+	store, err := remote.NewS3(ctx, to)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Remote storage")
+	} else if err := store.Probe(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Remote storage unavailable")
+	}
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	defer func() { _ = cat.Close() }()
+	imp := importer.New(target, importer.Options{Catalog: cat, Pool: poolRoots(pool)})
+	prefix, shared, err := imp.Share(ctx, store, options)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Share files")
+	}
+
+	var page bytes.Buffer
+	if err := shareTemplate.Execute(&page, map[string]interface{}{
+		"Title":   title,
+		"Expires": time.Now().Add(expires),
+		"Files":   shared,
+	}); err != nil {
+		log.Fatal().Err(err).Msg("Format gallery")
+	}
+	key := prefix + "index.html"
+	if err := store.PutContent(ctx, key, bytes.NewReader(page.Bytes()), int64(page.Len()), "text/html; charset=utf-8"); err != nil {
+		log.Fatal().Err(err).Msg("Upload gallery")
+	}
+	link, err := store.Presign(ctx, key, expires)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Link gallery")
+	}
+	log.Info().Int("files", len(shared)).Time("expires", time.Now().Add(expires)).Msg("Shared gallery")
+	fmt.Println(link)
+}
+
+// parseShareTime parses a capture time (YYYY-MM-DD or YYYY-MM-DD hh:mm).
+// The end of a date is the start of the following day.
+func parseShareTime(value string, end bool) (time.Time, error) {
+	if when, err := time.Parse("2006-01-02 15:04", value); err == nil {
+		return when, nil
+	}
+	when, err := time.Parse(dateFmt, value)
+	if err == nil && end {
+		when = when.AddDate(0, 0, 1)
+	}
+	return when, err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+// spooler imports files into a local spool directory when the target is unreachable.
+// The spool directory is a target tree with its own catalog,
+// so the spooled files retain their source metadata when they are flushed to the target.
+type spooler struct {
+	dir     string
+	source  string
+	options importer.Options
+	imp     *importer.Importer
+	cat     *catalog.Catalog
+}
+
+// importer returns the importer for the spool directory, opening its catalog on first use.
+func (s *spooler) importer() *importer.Importer {
+	if s.imp == nil {
+		if err := os.MkdirAll(s.dir, 0766); err != nil {
+			errorFatal("Make spool dir", err, nil)
+		}
+		cat, err := importer.OpenCatalog(s.dir)
+		if err != nil {
+			errorFatal("Open spool catalog", err, nil)
+		} else if _, err := cat.StartSession("import", s.source, nil); err != nil {
+			errorFatal("Start spool catalog session", err, nil)
+		}
+		s.cat = cat
+		options := s.options
+		options.Catalog = cat
+		// The spool is a single local directory.
+		options.Pool = nil
+		s.imp = importer.New(s.dir, options)
+	}
+	return s.imp
+}
+
+// close the spool catalog if it was opened.
+func (s *spooler) close() {
+	if s.cat != nil {
+		_ = s.cat.Close()
+	}
+}
+
+// flush moves any spooled files into the target, verifying each one.
+func (s *spooler) flush(imp *importer.Importer) {
+	if _, err := os.Stat(filepath.Join(s.dir, importer.StateDir, catalog.FileName)); err != nil {
+		return
+	}
+	cat, err := importer.OpenCatalog(s.dir)
+	if err != nil {
+		errorFatal("Open spool catalog", err, nil)
+	}
+	defer func() { _ = cat.Close() }()
+	if len(cat.Files()) == 0 {
+		return
+	}
+	if flushed, err := importer.New(s.dir, importer.Options{Catalog: cat}).Forward(imp); err != nil {
+		log.Error().Err(err).Int("flushed", flushed).Str("spool", s.dir).Msg("Flush spool")
+	} else {
+		log.Info().Int("flushed", flushed).Str("spool", s.dir).Msg("Flushed spool")
+	}
+}
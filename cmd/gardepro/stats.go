@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/i18n"
+	"github.com/madkins23/gardepro/importer"
+	"github.com/madkins23/gardepro/plugin"
+)
+
+const dateFmt = "2006-01-02"
+
+func statsCommand(args []string) {
+	var sites bool
+	var gapDays int
+	var lowPercent float64
+	var pluginDir, pushSpecs, target string
+	var trend, warn time.Duration
+
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.DurationVar(&trend, "trend", 30*24*time.Hour, "Period over which battery trends are computed")
+	flags.DurationVar(&warn, "warn", 14*24*time.Hour, "Warn of batteries projected to be empty within this period")
+	flags.Float64Var(&lowPercent, "low", 20, "Warn of batteries at or below this percentage")
+	flags.IntVar(&gapDays, "gap", 3, "Report runs of this many days without captures, 0 for none")
+	flags.StringVar(&pluginDir, "plugins", "", "Plugin directory [user config dir/gardepro/plugins]")
+	flags.StringVar(&pushSpecs, "push", "", pushUsage)
+	flags.BoolVar(&sites, "sites", false, "Include the cameras of merged site catalogs")
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	pushers := pushServices(pushSpecs)
+
+	consoleLog()
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	defer func() { _ = cat.Close() }()
+	now := time.Now()
+	stats := importer.Stats(cat, trend)
+	var gaps []*importer.Gap
+	if gapDays > 0 {
+		gaps = importer.Gaps(cat, gapDays, now)
+	}
+	if sites {
+		withSites(target, func(site string, cat *catalog.Catalog) {
+			for _, stat := range importer.Stats(cat, trend) {
+				stat.Camera = site + ":" + stat.Camera
+				stats = append(stats, stat)
+			}
+			if gapDays > 0 {
+				for _, gap := range importer.Gaps(cat, gapDays, now) {
+					gap.Camera = site + ":" + gap.Camera
+					gaps = append(gaps, gap)
+				}
+			}
+		})
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "Camera\tFiles\tFirst\tLast\tBattery\tTrend/day\tEmpty\t")
+	var low []*importer.CameraStats
+	for _, stat := range stats {
+		var empty string
+		if !stat.BatteryEmpty.IsZero() {
+			empty = stat.BatteryEmpty.Format(dateFmt)
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t\n", stat.Camera, stat.Files,
+			stat.First.Format(dateFmt), stat.Last.Format(dateFmt), batteryString(stat.Battery),
+			trendString(stat), empty)
+		if stat.Battery != nil && (stat.Battery.Percent > 0 && stat.Battery.Percent <= lowPercent ||
+			!stat.BatteryEmpty.IsZero() && stat.BatteryEmpty.Sub(stat.Last) <= warn) {
+			low = append(low, stat)
+		}
+	}
+	_ = writer.Flush()
+
+	var open []*importer.Gap
+	if len(gaps) > 0 {
+		fmt.Println()
+		_, _ = fmt.Fprintln(writer, "Camera\tLocation\tNo captures from\tUntil\tDays\t")
+		for _, gap := range gaps {
+			var location string
+			if gap.Deployment != nil {
+				location = gap.Deployment.Location
+			}
+			until := gap.Until.Format(dateFmt)
+			if gap.Open {
+				until = "now"
+				open = append(open, gap)
+			}
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t\n", gap.Camera, location, gap.From.Format(dateFmt), until, gap.Days)
+		}
+		_ = writer.Flush()
+	}
+
+	if len(low) == 0 && len(open) == 0 {
+		return
+	}
+	notifiers, err := notifierPlugins(pluginDir)
+	if err != nil {
+		log.Error().Err(err).Msg("Load plugins")
+	}
+	// Only open gaps are notified, earlier gaps are already over.
+	for _, gap := range open {
+		since := gap.From.Format(dateFmt)
+		log.Warn().Str("camera", gap.Camera).Str("since", since).Int("days", gap.Days).Msg("No captures")
+		for _, notifier := range notifiers {
+			if err := notifier.Notify(&plugin.Request{
+				Event: plugin.EventCameraGap, Source: gap.Camera, Error: "no captures since " + since}); err != nil {
+				log.Warn().Err(err).Str("camera", gap.Camera).Msg("Notify plugin")
+			}
+		}
+		sendPush(pushers, i18n.T("GardePro camera silent"),
+			i18n.T("%s: no captures for %d days since %s", gap.Camera, gap.Days, since), true)
+	}
+	for _, stat := range low {
+		log.Warn().Str("camera", stat.Camera).Str("battery", batteryString(stat.Battery)).
+			Str("empty", stat.BatteryEmpty.Format(dateFmt)).Msg("Battery low")
+		for _, notifier := range notifiers {
+			if err := notifier.Notify(&plugin.Request{
+				Event: plugin.EventBatteryLow, Source: stat.Camera, Error: batteryString(stat.Battery)}); err != nil {
+				log.Warn().Err(err).Str("camera", stat.Camera).Msg("Notify plugin")
+			}
+		}
+		message := i18n.T("%s: battery %s", stat.Camera, batteryString(stat.Battery))
+		if !stat.BatteryEmpty.IsZero() {
+			message += i18n.T(", empty by %s", stat.BatteryEmpty.Format(dateFmt))
+		}
+		sendPush(pushers, i18n.T("GardePro battery low"), message, false)
+	}
+}
+
+// batteryString formats a battery level.
+func batteryString(battery *catalog.Battery) string {
+	switch {
+	case battery == nil:
+		return ""
+	case battery.Percent > 0:
+		return fmt.Sprintf("%.0f%%", battery.Percent)
+	}
+	return fmt.Sprintf("%.2fV", battery.Volts)
+}
+
+// trendString formats a battery trend in the units of the battery level.
+func trendString(stat *importer.CameraStats) string {
+	switch {
+	case stat.BatteryTrend == 0:
+		return ""
+	case stat.Battery.Percent > 0:
+		return fmt.Sprintf("%+.1f%%", stat.BatteryTrend)
+	}
+	return fmt.Sprintf("%+.3fV", stat.BatteryTrend)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func stitchCommand(args []string) {
+	var options importer.StitchOptions
+	var pool, target string
+
+	flags := flag.NewFlagSet("stitch", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	flags.DurationVar(&options.Gap, "gap", importer.DefaultStitchGap, "Longest time between the end of a clip and the start of the next")
+	flags.StringVar(&options.FFmpeg, "ffmpeg", "ffmpeg", "Path of the ffmpeg executable")
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	cat := commandCatalog(target, "stitch", "")
+	defer func() { _ = cat.Close() }()
+	if count, err := importer.New(target, importer.Options{Catalog: cat, Pool: poolRoots(pool)}).Stitch(&options); err != nil {
+		log.Fatal().Err(err).Int("events", count).Msg("Stitch clips")
+	} else {
+		log.Info().Int("events", count).Msg("Stitch clips finished")
+	}
+}
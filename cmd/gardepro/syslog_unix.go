@@ -0,0 +1,19 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"log/syslog"
+
+	"github.com/rs/zerolog"
+)
+
+// syslogWriter returns a writer to the local syslog daemon.
+func syslogWriter() (zerolog.LevelWriter, error) {
+	writer, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, "gardepro")
+	if err != nil {
+		return nil, fmt.Errorf("connect to syslog: %w", err)
+	}
+	return zerolog.SyslogLevelWriter(writer), nil
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog"
+)
+
+// syslogWriter is not supported, Windows has no syslog daemon.
+func syslogWriter() (zerolog.LevelWriter, error) {
+	return nil, errors.New("syslog is not supported on Windows")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+func tagCommand(args []string) {
+	var target string
+
+	flags := flag.NewFlagSet("tag", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files (list all labels and tags)")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: gardepro tag add LABEL FILE... | remove LABEL FILE... | list [-target DIR] [FILE...]")
+		flags.PrintDefaults()
+	}
+	var action string
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	_ = flags.Parse(args)
+	switch {
+	case (action == "add" || action == "remove") && flags.NArg() >= 2:
+		consoleLog()
+		label, files := flags.Arg(0), flags.Args()[1:]
+		if err := labelFiles(files, label, action == "add"); err != nil {
+			log.Fatal().Err(err).Msg("Label files")
+		}
+	case action == "list" && (target != "" || flags.NArg() > 0):
+		if target != "" {
+			listTags(target)
+		} else {
+			for _, path := range flags.Args() {
+				cat, file := catalogFile(path)
+				_ = cat.Close()
+				fmt.Printf("%s\tlabels: %s\ttags: %s\n", path, strings.Join(file.Labels, ", "), strings.Join(file.Tags, ", "))
+			}
+		}
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// labelFiles adds the label to (or removes it from) the catalog entries of the files.
+func labelFiles(paths []string, label string, add bool) error {
+	return updateFiles(paths, "tag", func(path string, file *catalog.File) (bool, error) {
+		labels := file.Labels
+		file.Labels = nil
+		for _, existing := range labels {
+			if !strings.EqualFold(existing, label) {
+				file.Labels = append(file.Labels, existing)
+			}
+		}
+		if add {
+			file.Labels = append(file.Labels, label)
+		} else if len(file.Labels) == len(labels) {
+			return false, nil
+		}
+		log.Info().Str("path", file.Path).Strs("labels", file.Labels).Msg("Labeled file")
+		return true, nil
+	})
+}
+
+// updateFiles updates the catalog entries of the files, which may be in different targets.
+// The update function is called with the path and a copy of its entry to change,
+// and returns whether the entry was changed and should be recorded.
+func updateFiles(paths []string, command string, update func(path string, file *catalog.File) (bool, error)) error {
+	catalogs := make(map[string]*catalog.Catalog)
+	defer func() {
+		for _, cat := range catalogs {
+			_ = cat.Close()
+		}
+	}()
+	for _, path := range paths {
+		target, root, err := importer.FindTarget(path)
+		if err != nil {
+			return fmt.Errorf("find target root of %s: %w", path, err)
+		}
+		cat, found := catalogs[target]
+		if !found {
+			cat = commandCatalog(target, command, "")
+			catalogs[target] = cat
+		}
+		file, err := rootFile(cat, root, path)
+		if err != nil {
+			return err
+		}
+		updated := *file
+		if changed, err := update(path, &updated); err != nil {
+			return err
+		} else if !changed {
+			continue
+		} else if err := cat.AddFile(&updated); err != nil {
+			return fmt.Errorf("catalog %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// listTags prints the number of files with each label and tag in the target catalog.
+func listTags(target string) {
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		fatalf("Open catalog: %s", err)
+	}
+	defer func() { _ = cat.Close() }()
+	labels, tags := make(map[string]int), make(map[string]int)
+	for _, file := range cat.Files() {
+		for _, label := range file.Labels {
+			labels[label]++
+		}
+		for _, tag := range file.Tags {
+			tags[tag]++
+		}
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "Kind\tName\tFiles\t")
+	for _, kind := range []struct {
+		name   string
+		counts map[string]int
+	}{{"label", labels}, {"tag", tags}} {
+		names := make([]string, 0, len(kind.counts))
+		for name := range kind.counts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%d\t\n", kind.name, name, kind.counts[name])
+		}
+	}
+	_ = writer.Flush()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/trace"
+)
+
+// fileStages are the stages of importing a file in pipeline order.
+var fileStages = []string{"verify", "extract", "copy", "hash", "catalog"}
+
+// logThroughput logs the time spent in each stage of importing a file by file class,
+// with the throughput and the stage taking the most time, and the time spent
+// finding source files and detecting duplicates.
+func logThroughput(tracer *trace.Tracer) {
+	byClass := make(map[string]map[string]*trace.Stat)
+	var classes []string
+	for _, stat := range tracer.Stats() {
+		if byClass[stat.Class] == nil {
+			byClass[stat.Class] = make(map[string]*trace.Stat)
+			classes = append(classes, stat.Class)
+		}
+		byClass[stat.Class][stat.Stage] = stat
+	}
+	for _, class := range classes {
+		stages := byClass[class]
+		if class == "" {
+			event := log.Info()
+			for _, stage := range []string{"scan", "duplicates"} {
+				if stat := stages[stage]; stat != nil {
+					event = event.Str(stage, stat.Total.Round(time.Millisecond).String())
+				}
+			}
+			event.Msg("Import time finding files")
+			continue
+		}
+		file := stages["file"]
+		if file == nil || file.Total <= 0 {
+			continue
+		}
+		event := log.Info().Str("class", class).Int("files", file.Count).
+			Str("elapsed", file.Total.Round(time.Millisecond).String())
+		if file.Bytes > 0 {
+			event = event.Str("throughput", fmt.Sprintf("%.1f MB/s", float64(file.Bytes)/1e6/file.Total.Seconds()))
+		}
+		var bottleneck string
+		var most time.Duration
+		for _, stage := range fileStages {
+			if stat := stages[stage]; stat != nil {
+				event = event.Str(stage, fmt.Sprintf("%.0f%%", 100*stat.Total.Seconds()/file.Total.Seconds()))
+				if stat.Total > most {
+					bottleneck, most = stage, stat.Total
+				}
+			}
+		}
+		event.Str("bottleneck", bottleneck).Msg("Import time by stage")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/remote"
+)
+
+func uploadCommand(args []string) {
+	var dir, rate, storageClass string
+
+	flags := flag.NewFlagSet("upload", flag.ExitOnError)
+	flags.StringVar(&dir, "dir", defaultUploadDir(), "Directory recording upload progress")
+	flags.StringVar(&rate, "rate", "", "Maximum upload rate in bytes per second (e.g. 200K or 1M) [no limit]")
+	flags.StringVar(&storageClass, "storage-class", "STANDARD", "S3 storage class")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: gardepro upload [flags] FILE s3://bucket/prefix | status [-dir DIR]")
+		flags.PrintDefaults()
+	}
+	if len(args) > 0 && args[0] == "status" {
+		_ = flags.Parse(args[1:])
+		uploadStatus(dir)
+		return
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 || dir == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	limit, err := parseRate(rate)
+	if err != nil {
+		fatalf("Parse -rate: %s", err)
+	}
+
+	consoleLog()
+	ctx := context.Background()
+	store, err := remote.NewS3(ctx, flags.Arg(1))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Remote storage")
+	} else if err := store.Probe(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Remote storage unavailable")
+	}
+	path := flags.Arg(0)
+	key := store.Key(filepath.Base(path))
+	log.Info().Str("path", path).Str("url", store.URL(key)).Int64("rate", limit).Msg("Uploading")
+	if err := store.Trickle(ctx, key, path, storageClass, limit, dir); err != nil {
+		log.Fatal().Err(err).Msg("Upload interrupted, run the same command again to resume")
+	}
+	log.Info().Str("url", store.URL(key)).Msg("Upload finished")
+}
+
+// uploadStatus prints the progress of the uploads recorded in the directory.
+func uploadStatus(dir string) {
+	uploads, err := remote.Uploads(dir)
+	if err != nil {
+		fatalf("Read uploads: %s", err)
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "File\tURL\tMiB\tDone\tStatus\tUpdated\t")
+	for _, upload := range uploads {
+		status := "in progress or interrupted"
+		if !upload.Finished.IsZero() {
+			status = "finished"
+		} else if upload.Error != "" {
+			status = "failed: " + upload.Error
+		}
+		var done float64
+		if upload.Size > 0 {
+			done = 100 * float64(upload.Uploaded) / float64(upload.Size)
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%.1f\t%.0f%%\t%s\t%s\t\n", upload.Path, upload.URL,
+			float64(upload.Size)/(1<<20), done, status, upload.Updated.Local().Format(timeFmt))
+	}
+	_ = writer.Flush()
+}
+
+// defaultUploadDir returns the default directory recording uploads in the user configuration directory.
+func defaultUploadDir() string {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(config, "gardepro", "uploads")
+}
+
+// parseRate parses a rate in bytes per second with an optional K, M, or G (binary) suffix,
+// the empty string for no limit.
+func parseRate(rate string) (int64, error) {
+	if rate == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	switch strings.ToUpper(rate[len(rate)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		rate = rate[:len(rate)-1]
+	}
+	value, err := strconv.ParseInt(rate, 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("bad rate %q", rate)
+	}
+	return value * multiplier, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+// watcher imports the media files appearing in a sync folder.
+type watcher struct {
+	imp    *importer.Importer
+	folder string
+	retry  time.Duration
+	// imported are the files imported (or quarantined, duplicates, or excluded), which aren't imported again.
+	imported map[string]bool
+	// seen are the files found by the last scan, which are only imported once unchanged since then.
+	seen map[string]watchedFile
+	// failed are the files which failed to import and when to retry them.
+	failed map[string]time.Time
+	// waiting are the files not yet downloaded by the sync client.
+	waiting map[string]bool
+}
+
+// watchedFile is the size and modification time of a file found in a sync folder.
+type watchedFile struct {
+	size     int64
+	modified time.Time
+}
+
+func watchCommand(args []string) {
+	var fixOrientation, modTime, quick, verify bool
+	var folder, hashAlgorithm, only, operator, pluginDir, preset, target, timeZone string
+	var interval, retry time.Duration
+
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory for image files")
+	flags.StringVar(&folder, "folder", "", "Sync folder to watch [the GardePro folder of -preset]")
+	flags.StringVar(&preset, "preset", "", "Sync client (google-drive or icloud)")
+	flags.DurationVar(&interval, "interval", time.Minute, "Interval between scans of the folder")
+	flags.DurationVar(&retry, "retry", 10*time.Minute, "How long to wait before retrying a file which failed to import")
+	flags.BoolVar(&fixOrientation, "fix-orientation", false, "Set non-standard EXIF Orientation values to upright")
+	flags.BoolVar(&modTime, "mtime", false, "Use the modification time of files without capture times")
+	flags.StringVar(&hashAlgorithm, "hash", importer.HashSHA256, "Hash algorithm (sha256, xxh3, or blake3)")
+	flags.StringVar(&only, "only", "", "Import only files with these extensions (comma separated)")
+	flags.StringVar(&operator, "operator", "", operatorUsage)
+	flags.StringVar(&pluginDir, "plugins", "", "Plugin directory [user config dir/gardepro/plugins]")
+	flags.BoolVar(&quick, "quick", false, "Presume pre-existing target files of the same size are identical")
+	flags.StringVar(&timeZone, "timezone", "", "Time zone of camera clocks (e.g. America/Chicago)")
+	flags.BoolVar(&verify, "verify", false, "Verify media data, quarantining damaged files")
+	if err := configFlags(flags); err != nil {
+		fatalf("Read config: %s", err)
+	}
+	if err := envFlags(flags); err != nil {
+		fatalf("Parse environment: %s", err)
+	}
+	_ = flags.Parse(args)
+	if target == "" || folder == "" && preset == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	if folder == "" {
+		var err error
+		if folder, err = importer.SyncFolder(preset); err != nil {
+			log.Fatal().Err(err).Msg("Find sync folder")
+		}
+	}
+	if abs, err := filepath.Abs(folder); err == nil {
+		folder = abs
+	}
+	options := importer.Options{
+		FixOrientation: fixOrientation,
+		Hash:           hashAlgorithm,
+		Lenient:        true,
+		QuickSkip:      quick,
+		Timeout:        time.Minute,
+		Verify:         verify,
+	}
+	if only != "" {
+		options.Only = strings.Split(only, ",")
+	}
+	if timeZone != "" {
+		location, err := time.LoadLocation(timeZone)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Parse -timezone")
+		}
+		options.CameraZone = location
+	}
+	cameras, err := configCameras()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Read camera overrides")
+	}
+	options.Cameras = cameras
+	importer.UseModTime(modTime)
+	catalog.Operator = operator
+	if err := loadPlugins(pluginDir, &options); err != nil {
+		log.Fatal().Err(err).Msg("Load plugins")
+	}
+	cat := commandCatalog(target, "watch", folder)
+	defer func() { _ = cat.Close() }()
+	options.Catalog = cat
+
+	w := &watcher{
+		imp:      importer.New(target, options),
+		folder:   folder,
+		retry:    retry,
+		imported: make(map[string]bool),
+		failed:   make(map[string]time.Time),
+		waiting:  make(map[string]bool),
+	}
+	// Files imported by earlier runs aren't read again, which for streamed
+	// (not yet downloaded) files would make the sync client download them.
+	for _, file := range cat.Files() {
+		if strings.HasPrefix(file.Source, folder+string(filepath.Separator)) {
+			w.imported[file.Source] = true
+		}
+	}
+	log.Info().Str("folder", folder).Str("target", target).Msg("Watching sync folder (Ctrl-C to quit)")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		w.scan()
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Watch finished")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan imports the files in the sync folder which haven't changed since the last scan.
+// Files not yet downloaded are waited for and files which fail are retried later.
+func (w *watcher) scan() {
+	ready, pending, err := importer.SyncFiles(w.folder)
+	if err != nil {
+		log.Error().Err(err).Str("folder", w.folder).Msg("Scan sync folder")
+		return
+	}
+	for _, path := range pending {
+		if !w.waiting[path] && !w.imported[path] {
+			log.Info().Str("source", path).Msg("Waiting for sync client to download file")
+			if err := importer.RequestDownload(path); err != nil {
+				log.Warn().Err(err).Str("source", path).Msg("Request download")
+			}
+			w.waiting[path] = true
+		}
+	}
+	now := time.Now()
+	seen := make(map[string]watchedFile)
+	var sources []string
+	for _, path := range ready {
+		delete(w.waiting, path)
+		if w.imported[path] {
+			continue
+		} else if retry, found := w.failed[path]; found && now.Before(retry) {
+			continue
+		}
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		current := watchedFile{size: stat.Size(), modified: stat.ModTime()}
+		// Files still being written by the sync client are imported once they stop changing.
+		if previous, found := w.seen[path]; found && previous.size == current.size &&
+			previous.modified.Equal(current.modified) && current.size > 0 {
+			sources = append(sources, path)
+		} else {
+			seen[path] = current
+		}
+	}
+	w.seen = seen
+	if len(sources) == 0 {
+		return
+	}
+	var imported, failed int
+	for _, result := range w.imp.ImportBatch(w.folder, sources) {
+		if result.Err != nil && !importer.Quarantined(result.Err) {
+			log.Warn().Err(result.Err).Str("source", result.Source).Dur("retry", w.retry).Msg("Import failed, will retry")
+			w.failed[result.Source] = now.Add(w.retry)
+			failed++
+			continue
+		}
+		delete(w.failed, result.Source)
+		w.imported[result.Source] = true
+		if result.Err == nil && result.Duplicate == "" && !result.Excluded {
+			imported++
+		}
+	}
+	log.Info().Int("files", len(sources)).Int("imported", imported).Int("failed", failed).Msg("Imported sync folder files")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/weather"
+)
+
+func weatherCommand(args []string) {
+	var all bool
+	var latitude, longitude float64
+	var api, target string
+
+	flags := flag.NewFlagSet("weather", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&api, "api", weather.DefaultURL, "URL of an Open-Meteo compatible historical weather API")
+	flags.Float64Var(&latitude, "lat", 0, "Latitude of files without a recorded position")
+	flags.Float64Var(&longitude, "lon", 0, "Longitude of files without a recorded position")
+	flags.BoolVar(&all, "all", false, "Look up weather for files that already have it")
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	defaultPosition := latitude != 0 || longitude != 0
+
+	consoleLog()
+	cat := commandCatalog(target, "weather", "")
+	defer func() { _ = cat.Close() }()
+	client := weather.NewClient(api)
+	var recorded, skipped, failed int
+	for _, file := range cat.Files() {
+		if file.Weather != nil && !all {
+			continue
+		}
+		lat, lon := latitude, longitude
+		if file.Position != nil {
+			lat, lon = file.Position.Latitude, file.Position.Longitude
+		} else if !defaultPosition {
+			skipped++
+			continue
+		}
+		conditions, err := client.Lookup(context.Background(), lat, lon, file.Captured)
+		if err != nil {
+			log.Error().Err(err).Str("path", file.Path).Msg("Look up weather")
+			failed++
+			continue
+		}
+		updated := *file
+		updated.Weather = conditions
+		if err := cat.AddFile(&updated); err != nil {
+			log.Fatal().Err(err).Str("path", file.Path).Msg("Catalog file")
+		}
+		recorded++
+	}
+	log.Info().Int("recorded", recorded).Int("no-position", skipped).Int("failed", failed).Msg("Weather finished")
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/importer"
+)
+
+func whenceCommand(args []string) {
+	flags := flag.NewFlagSet("whence", flag.ExitOnError)
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	path := flags.Arg(0)
+
+	cat, file := catalogFile(path)
+	defer func() { _ = cat.Close() }()
+
+	fmt.Printf("File:     %s\n", file.Path)
+	if file.Root != "" {
+		fmt.Printf("Root:     %s\n", file.Root)
+	}
+	fmt.Printf("Source:   %s\n", file.Source)
+	fmt.Printf("Captured: %s\n", file.Captured.Format(timeFmt))
+	fmt.Printf("Imported: %s\n", file.Imported.Local().Format(timeFmt))
+	if len(file.Tags) > 0 {
+		fmt.Printf("Tags:     %s\n", strings.Join(file.Tags, ", "))
+	}
+	if len(file.Labels) > 0 {
+		fmt.Printf("Labels:   %s\n", strings.Join(file.Labels, ", "))
+	}
+	if session := cat.Session(file.Session); session != nil {
+		fmt.Printf("Session:  %s (%s %s)\n", session.ID, session.Command, session.Source)
+		if session.Operator != "" {
+			fmt.Printf("Operator: %s\n", session.Operator)
+		}
+		if session.Card != nil {
+			fmt.Printf("Card:     %s\n", session.Card)
+		}
+	} else {
+		fmt.Printf("Session:  %s\n", file.Session)
+	}
+}
+
+// catalogFile opens the catalog of the target containing the path and returns the path's entry.
+// Errors are fatal.
+func catalogFile(path string) (*catalog.Catalog, *catalog.File) {
+	target, root, err := importer.FindTarget(path)
+	if err != nil {
+		fatalf("Find target root: %s", err)
+	}
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		fatalf("Open catalog: %s", err)
+	}
+	file, err := rootFile(cat, root, path)
+	if err != nil {
+		_ = cat.Close()
+		fatalf("%s", err)
+	}
+	return cat, file
+}
+
+// rootFile returns the catalog entry of a path in the root.
+func rootFile(cat *catalog.Catalog, root, path string) (*catalog.File, error) {
+	abs, _ := filepath.Abs(path)
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return nil, fmt.Errorf("relative path: %w", err)
+	}
+	file := cat.File(filepath.ToSlash(rel))
+	if file == nil {
+		return nil, fmt.Errorf("not in catalog: %s", rel)
+	}
+	return file, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/importer"
+)
+
+func xmpCommand(args []string) {
+	var pool, target string
+
+	flags := flag.NewFlagSet("xmp", flag.ExitOnError)
+	flags.StringVar(&target, "target", "", "Target directory of archived files")
+	flags.StringVar(&pool, "pool", "", "Additional target root directories (comma separated)")
+	_ = flags.Parse(args)
+	if target == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	consoleLog()
+	cat, err := importer.OpenCatalog(target)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Open catalog")
+	}
+	defer func() { _ = cat.Close() }()
+	written, others, err := importer.New(target, importer.Options{Catalog: cat, Pool: poolRoots(pool)}).ExportXMP()
+	if err != nil {
+		log.Fatal().Err(err).Int("written", written).Msg("Export XMP")
+	}
+	log.Info().Int("written", written).Int("other-applications", others).Msg("Export XMP finished")
+}
@@ -0,0 +1,239 @@
+// Package email sends messages (e.g. session summaries and monthly reports) through an SMTP server,
+// for users without a chat or home automation platform to notify.
+//
+// Servers are specified as smtp://[user:password@]host[:port] (STARTTLS if the server offers it,
+// port 587 by default) or smtps://[user:password@]host[:port] (implicit TLS, port 465 by default).
+// HTML messages may include inline images referenced as cid:ID.
+package email
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Default ports.
+const (
+	DefaultPort    = "587"
+	DefaultTLSPort = "465"
+)
+
+// dialTimeout bounds connecting to the server.
+const dialTimeout = 30 * time.Second
+
+// Server is an SMTP server.
+type Server struct {
+	// Address is host:port.
+	Address  string
+	Username string
+	Password string
+	// TLS is set for implicit TLS (smtps), otherwise STARTTLS is used if offered.
+	TLS bool
+}
+
+// Parse parses an smtp:// or smtps:// server URL.
+func Parse(serverURL string) (*Server, error) {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse SMTP URL: %w", err)
+	}
+	server := &Server{}
+	port := DefaultPort
+	switch parsed.Scheme {
+	case "smtp":
+	case "smtps":
+		server.TLS, port = true, DefaultTLSPort
+	default:
+		return nil, fmt.Errorf("SMTP URL scheme must be smtp or smtps: %s", serverURL)
+	}
+	if parsed.Hostname() == "" {
+		return nil, fmt.Errorf("no host in SMTP URL %s", serverURL)
+	}
+	if parsed.Port() != "" {
+		port = parsed.Port()
+	}
+	server.Address = net.JoinHostPort(parsed.Hostname(), port)
+	if parsed.User != nil {
+		server.Username = parsed.User.Username()
+		server.Password, _ = parsed.User.Password()
+	}
+	return server, nil
+}
+
+// From returns the default sender address: the user name if it is an address,
+// otherwise gardepro at the local host name.
+func (s *Server) From() string {
+	if strings.Contains(s.Username, "@") {
+		return s.Username
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return "gardepro@" + host
+}
+
+// Message is an email message with a plain text or HTML body.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	// Text is the plain text body, used if HTML is empty.
+	Text string
+	HTML string
+	// Inline images referenced from the HTML body.
+	Inline []*Inline
+}
+
+// Inline is an image referenced from an HTML body as cid:ID.
+type Inline struct {
+	ID          string
+	ContentType string
+	Data        []byte
+}
+
+// Send the message through the server.
+func (s *Server) Send(msg *Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("no email recipients")
+	}
+	if msg.From == "" {
+		msg.From = s.From()
+	}
+	data, err := msg.bytes()
+	if err != nil {
+		return err
+	}
+	host, _, _ := net.SplitHostPort(s.Address)
+	var conn net.Conn
+	if s.TLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", s.Address, &tls.Config{ServerName: host})
+	} else {
+		conn, err = net.DialTimeout("tcp", s.Address, dialTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to SMTP server: %w", err)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("start SMTP session: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+	if ok, _ := client.Extension("STARTTLS"); ok && !s.TLS {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("start TLS: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return fmt.Errorf("SMTP authentication: %w", err)
+		}
+	}
+	if err := client.Mail(msg.From); err != nil {
+		return fmt.Errorf("SMTP sender: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP recipient %s: %w", to, err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP data: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	} else if err := writer.Close(); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+	return client.Quit()
+}
+
+// bytes formats the message in MIME.
+func (m *Message) bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	header("From", m.From)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	if m.HTML == "" {
+		return single(&buf, header, "text/plain; charset=utf-8", m.Text)
+	} else if len(m.Inline) == 0 {
+		return single(&buf, header, "text/html; charset=utf-8", m.HTML)
+	}
+	related := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/related; type="text/html"; boundary=`+related.Boundary())
+	buf.WriteString("\r\n")
+	part, err := related.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create HTML part: %w", err)
+	}
+	if err := quotedPrintable(part, m.HTML); err != nil {
+		return nil, err
+	}
+	for _, inline := range m.Inline {
+		part, err := related.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {inline.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-ID":                {"<" + inline.ID + ">"},
+			"Content-Disposition":       {"inline"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create inline part: %w", err)
+		}
+		encoded := base64.StdEncoding.EncodeToString(inline.Data)
+		for len(encoded) > 76 {
+			_, _ = part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		_, _ = part.Write([]byte(encoded + "\r\n"))
+	}
+	if err := related.Close(); err != nil {
+		return nil, fmt.Errorf("close message: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// single finishes a single part message with the content type and body.
+func single(buf *bytes.Buffer, header func(key, value string), contentType, body string) ([]byte, error) {
+	header("Content-Type", contentType)
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+	if err := quotedPrintable(buf, body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// quotedPrintable writes the body with quoted-printable encoding.
+func quotedPrintable(w io.Writer, body string) error {
+	writer := quotedprintable.NewWriter(w)
+	if _, err := writer.Write([]byte(body)); err != nil {
+		return fmt.Errorf("encode body: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("encode body: %w", err)
+	}
+	return nil
+}
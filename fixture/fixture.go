@@ -0,0 +1,210 @@
+// Package fixture synthesizes minimal media files for testing:
+// JPEG files with arbitrary EXIF capture times, MP4 (or MOV) files with arbitrary mvhd times,
+// and AVI files with arbitrary IDIT times,
+// each only a few hundred bytes, so that tests don't need to ship large camera files.
+package fixture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"time"
+
+	"github.com/dsoprea/go-exif/v3"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+)
+
+// exifTimeFmt is the format of EXIF date and time tags.
+const exifTimeFmt = "2006:01:02 15:04:05"
+
+// JPEG describes a synthesized JPEG file.
+type JPEG struct {
+	// Captured is the camera clock time written to the DateTime and DateTimeOriginal tags,
+	// zero for no EXIF data at all.
+	Captured time.Time
+	// Model is the camera model, empty for none.
+	Model string
+	// Description is the ImageDescription (e.g. a GardePro battery and temperature stamp), empty for none.
+	Description string
+	// Orientation is the EXIF Orientation, zero for none.
+	Orientation uint16
+	// Shade is the gray level of the 8x8 pixel image, so that files can be made to differ
+	// in contents but not metadata.
+	Shade uint8
+}
+
+// Bytes returns the contents of the JPEG file.
+func (j *JPEG) Bytes() ([]byte, error) {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = color.Gray{Y: j.Shade}.Y
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, fmt.Errorf("encode image: %w", err)
+	}
+	if j.Captured.IsZero() {
+		return buf.Bytes(), nil
+	}
+	raw, err := j.exif()
+	if err != nil {
+		return nil, err
+	}
+	// The APP1 segment goes right after the SOI marker.
+	data := buf.Bytes()
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(2+6+len(raw)))
+	segment = append(append(segment, "Exif\x00\x00"...), raw...)
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...), nil
+}
+
+// exif returns the encoded EXIF data (from the byte order mark) of the JPEG file.
+func (j *JPEG) exif() ([]byte, error) {
+	mapping, err := exifcommon.NewIfdMappingWithStandard()
+	if err != nil {
+		return nil, fmt.Errorf("IFD mapping: %w", err)
+	}
+	root := exif.NewIfdBuilder(mapping, exif.NewTagIndex(), exifcommon.IfdStandardIfdIdentity, exifcommon.EncodeDefaultByteOrder)
+	when := j.Captured.Format(exifTimeFmt)
+	if err := root.AddStandardWithName("DateTime", when); err != nil {
+		return nil, fmt.Errorf("add DateTime: %w", err)
+	}
+	if j.Model != "" {
+		if err := root.AddStandardWithName("Model", j.Model); err != nil {
+			return nil, fmt.Errorf("add Model: %w", err)
+		}
+	}
+	if j.Description != "" {
+		if err := root.AddStandardWithName("ImageDescription", j.Description); err != nil {
+			return nil, fmt.Errorf("add ImageDescription: %w", err)
+		}
+	}
+	if j.Orientation != 0 {
+		if err := root.AddStandardWithName("Orientation", []uint16{j.Orientation}); err != nil {
+			return nil, fmt.Errorf("add Orientation: %w", err)
+		}
+	}
+	exifIfd, err := exif.GetOrCreateIbFromRootIb(root, "IFD/Exif")
+	if err != nil {
+		return nil, fmt.Errorf("EXIF IFD: %w", err)
+	}
+	if err := exifIfd.AddStandardWithName("DateTimeOriginal", when); err != nil {
+		return nil, fmt.Errorf("add DateTimeOriginal: %w", err)
+	}
+	raw, err := exif.NewIfdByteEncoder().EncodeToExif(root)
+	if err != nil {
+		return nil, fmt.Errorf("encode EXIF: %w", err)
+	}
+	return raw, nil
+}
+
+// MP4 describes a synthesized MP4 (or MOV) file.
+type MP4 struct {
+	// Created is the creation (and modification) time written to the mvhd box.
+	// Cameras write it in UTC, so it is read as a time in the local time zone.
+	Created time.Time
+	// Duration of the movie.
+	Duration time.Duration
+	// Version1 writes a version 1 mvhd box with 64-bit times.
+	Version1 bool
+	// QuickTime marks the file as a QuickTime (MOV) file.
+	QuickTime bool
+	// NoMovie leaves out the moov box, as in a file truncated while recording.
+	NoMovie bool
+}
+
+// mp4Epoch is the zero time of MP4 times.
+var mp4Epoch = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Bytes returns the contents of the MP4 file: an ftyp box, a moov box containing only an mvhd box,
+// and a tiny mdat box.
+func (m *MP4) Bytes() []byte {
+	brand := "isom"
+	if m.QuickTime {
+		brand = "qt  "
+	}
+	ftyp := box("ftyp", []byte(brand+"\x00\x00\x02\x00"+brand))
+	mdat := box("mdat", []byte{1, 2, 3, 4})
+	if m.NoMovie {
+		return append(ftyp, mdat...)
+	}
+	seconds := uint64(m.Created.Sub(mp4Epoch) / time.Second)
+	const timescale = 1000
+	duration := uint64(m.Duration / time.Millisecond)
+	var mvhd []byte
+	if m.Version1 {
+		// Version and flags, creation and modification times, timescale, duration.
+		mvhd = make([]byte, 4+8+8+4+8, 4+8+8+4+8+80)
+		mvhd[0] = 1
+		binary.BigEndian.PutUint64(mvhd[4:], seconds)
+		binary.BigEndian.PutUint64(mvhd[12:], seconds)
+		binary.BigEndian.PutUint32(mvhd[20:], timescale)
+		binary.BigEndian.PutUint64(mvhd[24:], duration)
+	} else {
+		mvhd = make([]byte, 4+4+4+4+4, 4+4+4+4+4+80)
+		binary.BigEndian.PutUint32(mvhd[4:], uint32(seconds))
+		binary.BigEndian.PutUint32(mvhd[8:], uint32(seconds))
+		binary.BigEndian.PutUint32(mvhd[12:], timescale)
+		binary.BigEndian.PutUint32(mvhd[16:], uint32(duration))
+	}
+	// Rate, volume, reserved, matrix, pre-defined, and next track ID.
+	rest := make([]byte, 80)
+	binary.BigEndian.PutUint32(rest[0:], 0x00010000)
+	binary.BigEndian.PutUint16(rest[4:], 0x0100)
+	for i, value := range []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000} {
+		binary.BigEndian.PutUint32(rest[16+4*i:], value)
+	}
+	binary.BigEndian.PutUint32(rest[76:], 2)
+	mvhd = append(mvhd, rest...)
+	return append(append(ftyp, box("moov", box("mvhd", mvhd))...), mdat...)
+}
+
+// box returns an MP4 box of the type with the payload.
+func box(kind string, payload []byte) []byte {
+	header := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(header, uint32(8+len(payload)))
+	copy(header[4:], kind)
+	return append(header, payload...)
+}
+
+// AVI describes a synthesized AVI file.
+type AVI struct {
+	// Created is the camera clock time written to the IDIT chunk.
+	Created time.Time
+}
+
+// Bytes returns the contents of the AVI file: an hdrl list with an empty avih chunk and an IDIT chunk,
+// a movi list with one tiny frame, and an idx1 index.
+func (a *AVI) Bytes() []byte {
+	idit := append([]byte(a.Created.Format(time.ANSIC)), '\n', 0)
+	hdrl := append(riffChunk("avih", make([]byte, 56)), riffChunk("IDIT", idit)...)
+	movi := riffChunk("00dc", []byte{1, 2, 3, 4})
+	index := make([]byte, 16)
+	copy(index, "00dc")
+	binary.LittleEndian.PutUint32(index[8:], 4)
+	binary.LittleEndian.PutUint32(index[12:], 4)
+	body := append([]byte("AVI "), riffList("hdrl", hdrl)...)
+	body = append(body, riffList("movi", movi)...)
+	body = append(body, riffChunk("idx1", index)...)
+	return riffChunk("RIFF", body)
+}
+
+// riffChunk returns a RIFF chunk of the type with the payload, padded to an even size.
+func riffChunk(kind string, payload []byte) []byte {
+	header := make([]byte, 8, 8+len(payload)+1)
+	copy(header, kind)
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(payload)))
+	chunk := append(header, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// riffList returns a RIFF list of the type containing the chunks.
+func riffList(kind string, chunks []byte) []byte {
+	return riffChunk("LIST", append([]byte(kind), chunks...))
+}
@@ -0,0 +1,53 @@
+package fixture
+
+import (
+	"encoding/binary"
+	"math/rand"
+)
+
+// Mutate returns a copy of the data damaged in the ways failing cards and interrupted writes damage files:
+// flipped bits, overwritten bytes, truncation, zeroed blocks, dropped or duplicated spans,
+// and corrupted 32-bit size fields (as in MP4 boxes, RIFF chunks, and EXIF offsets).
+// One to four mutations are applied.
+func Mutate(data []byte, rng *rand.Rand) []byte {
+	out := append([]byte{}, data...)
+	for n := 1 + rng.Intn(4); n > 0 && len(out) > 0; n-- {
+		at := rng.Intn(len(out))
+		switch rng.Intn(7) {
+		case 0:
+			out[at] ^= 1 << uint(rng.Intn(8))
+		case 1:
+			out[at] = byte(rng.Intn(256))
+		case 2:
+			out = out[:at]
+		case 3:
+			for i := at; i < len(out) && i < at+1+rng.Intn(64); i++ {
+				out[i] = 0
+			}
+		case 4:
+			end := at + 1 + rng.Intn(16)
+			if end > len(out) {
+				end = len(out)
+			}
+			out = append(out[:at], out[end:]...)
+		case 5:
+			end := at + 1 + rng.Intn(16)
+			if end > len(out) {
+				end = len(out)
+			}
+			span := append([]byte{}, out[at:end]...)
+			out = append(out[:end], append(span, out[end:]...)...)
+		case 6:
+			if at+4 <= len(out) {
+				sizes := []uint32{0, 1, 7, 8, 0x7FFFFFFF, 0xFFFFFFFF, uint32(len(out)), rng.Uint32()}
+				size := sizes[rng.Intn(len(sizes))]
+				if rng.Intn(2) == 0 {
+					binary.BigEndian.PutUint32(out[at:], size)
+				} else {
+					binary.LittleEndian.PutUint32(out[at:], size)
+				}
+			}
+		}
+	}
+	return out
+}
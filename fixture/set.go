@@ -0,0 +1,150 @@
+package fixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestName is the name of the manifest written with a set of fixtures.
+const ManifestName = "fixtures.json"
+
+// Expected outcomes of importing a fixture.
+const (
+	Imported      = "imported"        // Copied to the target.
+	Duplicate     = "duplicate"       // Skipped as identical to a file already imported.
+	Conflict      = "conflict"        // Failed, a different file has the same target path.
+	NoCaptureTime = "no-capture-time" // Failed, no capture time in the metadata or file name.
+)
+
+// Fixture is a synthesized media file.
+type Fixture struct {
+	// Path relative to the fixture directory.
+	Path string `json:"path"`
+	// Captured is the capture time the importer should find, zero for none.
+	Captured time.Time `json:"captured"`
+	// Expect is the outcome of importing the fixtures in order.
+	Expect string `json:"expect"`
+	// What the fixture exercises.
+	Note string `json:"note"`
+
+	data []byte
+}
+
+// Data returns the contents of the fixture file.
+func (fx *Fixture) Data() []byte {
+	return fx.data
+}
+
+// Standard returns a set of fixtures exercising each capture time extractor,
+// file name dates, target file naming, and duplicate and conflicting files.
+// Capture times are on the day of base, which should be in the local time zone.
+func Standard(base time.Time) ([]*Fixture, error) {
+	day := time.Date(base.Year(), base.Month(), base.Day(), 0, 0, 0, 0, base.Location())
+	at := func(hour, minute, second int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second)
+	}
+	jpegs := []struct {
+		path string
+		spec JPEG
+		fx   Fixture
+	}{
+		{"IMG_0001.JPG", JPEG{Captured: at(6, 30, 0), Model: "GardePro E6"},
+			Fixture{Expect: Imported, Note: "EXIF DateTimeOriginal"}},
+		{"IMG_0002.JPG", JPEG{Captured: at(6, 30, 0), Model: "GardePro E6", Shade: 64,
+			Description: "GardePro 050% 021C"},
+			Fixture{Expect: Imported, Note: "same capture time as IMG_0001.JPG, different name"}},
+		{"IMG_0003.JPG", JPEG{Captured: at(18, 45, 10), Model: "GardePro E6", Orientation: 6},
+			Fixture{Expect: Imported, Note: "EXIF Orientation"}},
+		{"copy/IMG_0001.JPG", JPEG{Captured: at(6, 30, 0), Model: "GardePro E6"},
+			Fixture{Expect: Duplicate, Note: "identical to IMG_0001.JPG"}},
+		{"other/IMG_0001.JPG", JPEG{Captured: at(6, 30, 0), Model: "GardePro E6", Shade: 192},
+			Fixture{Expect: Conflict, Note: "same name and capture time as IMG_0001.JPG, different contents"}},
+		{"IMG_" + at(10, 11, 12).Format("20060102_150405") + ".jpg", JPEG{Shade: 32},
+			Fixture{Expect: Imported, Captured: at(10, 11, 12), Note: "no EXIF, date in file name"}},
+		{"NOEXIF.JPG", JPEG{Shade: 96},
+			Fixture{Expect: NoCaptureTime, Note: "no EXIF, no date in file name"}},
+	}
+	movies := []struct {
+		path string
+		spec MP4
+		fx   Fixture
+	}{
+		{"VID_0001.MP4", MP4{Created: at(7, 0, 0), Duration: 10 * time.Second},
+			Fixture{Expect: Imported, Note: "version 0 mvhd"}},
+		{"VID_0002.MP4", MP4{Created: at(7, 5, 30), Duration: 20 * time.Second, Version1: true},
+			Fixture{Expect: Imported, Note: "version 1 mvhd"}},
+		{"VID_0003.MOV", MP4{Created: at(7, 10, 0), Duration: 5 * time.Second, QuickTime: true},
+			Fixture{Expect: Imported, Note: "QuickTime mvhd"}},
+		{"VID_0004.MP4", MP4{NoMovie: true},
+			Fixture{Expect: NoCaptureTime, Note: "no moov box"}},
+	}
+	avis := []struct {
+		path string
+		spec AVI
+		fx   Fixture
+	}{
+		{"VID_0005.AVI", AVI{Created: at(20, 15, 45)},
+			Fixture{Expect: Imported, Note: "AVI IDIT chunk"}},
+	}
+
+	var fixtures []*Fixture
+	for _, j := range jpegs {
+		data, err := j.spec.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", j.path, err)
+		}
+		fx := j.fx
+		fx.Path, fx.data = j.path, data
+		if fx.Captured.IsZero() {
+			fx.Captured = j.spec.Captured
+		}
+		fixtures = append(fixtures, &fx)
+	}
+	for _, m := range movies {
+		fx := m.fx
+		fx.Path, fx.Captured, fx.data = m.path, m.spec.Created, m.spec.Bytes()
+		fixtures = append(fixtures, &fx)
+	}
+	for _, a := range avis {
+		fx := a.fx
+		fx.Path, fx.Captured, fx.data = a.path, a.spec.Created, a.spec.Bytes()
+		fixtures = append(fixtures, &fx)
+	}
+	fixtures = append(fixtures, &Fixture{
+		Path: "BROKEN.JPG", Expect: NoCaptureTime, Note: "not a JPEG file",
+		data: []byte("not a JPEG file\n"),
+	})
+	return fixtures, nil
+}
+
+// Write writes the fixtures and their manifest into dir, creating subdirectories as needed.
+// Files are given their capture time (or base if none) as modification time.
+func Write(dir string, fixtures []*Fixture, base time.Time) error {
+	for _, fx := range fixtures {
+		path := filepath.Join(dir, filepath.FromSlash(fx.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("make fixture dir: %w", err)
+		}
+		if err := os.WriteFile(path, fx.data, 0644); err != nil {
+			return fmt.Errorf("write fixture: %w", err)
+		}
+		modified := fx.Captured
+		if modified.IsZero() {
+			modified = base
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			return fmt.Errorf("set fixture time: %w", err)
+		}
+	}
+	manifest, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), append(manifest, '\n'), 0644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
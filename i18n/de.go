@@ -0,0 +1,135 @@
+package i18n
+
+// german translations.
+var german = map[string]string{
+	// Dialogs.
+	"Error reading config file":                         "Fehler beim Lesen der Konfigurationsdatei",
+	"Error parsing environment variables":               "Fehler in den Umgebungsvariablen",
+	"Error parsing command line flags":                  "Fehler in den Befehlszeilenoptionen",
+	"Error reading chain key":                           "Fehler beim Lesen des Kettenschlüssels",
+	"Error loading GPX file":                            "Fehler beim Laden der GPX-Datei",
+	"Error loading plugins":                             "Fehler beim Laden der Plugins",
+	"Log File Creation":                                 "Erstellen der Protokolldatei",
+	"Fatal Error":                                       "Schwerer Fehler",
+	"Missing command line flag -source or -target":      "Die Option -source oder -target fehlt",
+	"Unknown -nas index %s":                             "Unbekannter -nas-Index: %s",
+	"Unknown -hash algorithm %s":                        "Unbekannter -hash-Algorithmus: %s",
+	"Only one of -strict and -lenient may be specified": "Nur eine der Optionen -strict und -lenient ist erlaubt",
+	"%d of %d files failed to import, see log":          "%d von %d Dateien konnten nicht importiert werden, siehe Protokoll",
+	"Pre-import hook":                                   "Befehl vor dem Import",
+	"Target unavailable":                                "Ziel nicht verfügbar",
+	"Check target dir":                                  "Zielverzeichnis prüfen",
+	"Open catalog":                                      "Katalog öffnen",
+	"Start catalog session":                             "Katalogsitzung starten",
+	"Open hash chain":                                   "Hash-Kette öffnen",
+	"Unrecognized file format":                          "Unbekanntes Dateiformat",
+	"Damaged file quarantined":                          "Beschädigte Datei in Quarantäne verschoben",
+	"Get capture time":                                  "Aufnahmezeit ermitteln",
+	"Import hung":                                       "Import hängt",
+	"Pre-existing target file not identical":            "Vorhandene Zieldatei ist nicht identisch",
+	"Copy source file to target directory":              "Datei ins Zielverzeichnis kopieren",
+	"Find source files":                                 "Quelldateien suchen",
+	"Make spool dir":                                    "Zwischenspeicherverzeichnis anlegen",
+	"Open spool catalog":                                "Zwischenspeicherkatalog öffnen",
+	"Start spool catalog session":                       "Zwischenspeicher-Katalogsitzung starten",
+
+	// Settings.
+	"Error writing config file":                                                               "Fehler beim Schreiben der Konfigurationsdatei",
+	"No user config directory":                                                                "Kein Konfigurationsverzeichnis des Benutzers",
+	"GardePro Settings":                                                                       "GardePro-Einstellungen",
+	"Directory into which camera files are archived":                                          "Verzeichnis, in dem die Kameradateien archiviert werden",
+	"Keep the folders of the card beneath the year folders?":                                  "Die Ordner der Karte unter den Jahresordnern beibehalten?",
+	"Check that each file is undamaged before archiving it?":                                  "Vor dem Archivieren prüfen, ob jede Datei unbeschädigt ist?",
+	"Turn pictures with an unusual orientation upright?":                                      "Bilder mit ungewöhnlicher Ausrichtung aufrecht drehen?",
+	"Presume files already archived with the same size are identical?":                        "Bereits archivierte Dateien gleicher Größe als identisch annehmen?",
+	"When a file can't be imported, set it aside in quarantine and continue with the others?": "Wenn eine Datei nicht importiert werden kann, sie in Quarantäne verschieben und mit den anderen fortfahren?",
+	"Currently: yes":       "Derzeit: ja",
+	"Currently: no":        "Derzeit: nein",
+	"Settings saved to %s": "Einstellungen gespeichert in %s",
+
+	// Notifications and email.
+	"GardePro import finished":             "GardePro-Import abgeschlossen",
+	"GardePro import failed":               "GardePro-Import fehlgeschlagen",
+	"%d files imported from %s":            "%d Dateien aus %s importiert",
+	", %d failed":                          ", %d fehlgeschlagen",
+	"GardePro camera fault":                "GardePro-Kamerastörung",
+	"%s: %s since %s":                      "%s: %s seit %s",
+	"GardePro battery low":                 "GardePro-Akku schwach",
+	"%s: battery %s":                       "%s: Akku %s",
+	", empty by %s":                        ", leer bis %s",
+	"GardePro camera silent":               "GardePro-Kamera ohne Aufnahmen",
+	"%s: no captures for %d days since %s": "%s: seit %[3]s %[2]d Tage ohne Aufnahmen",
+	"Host":                                 "Rechner",
+	"Source":                               "Quelle",
+	"Target":                               "Ziel",
+	"Started":                              "Beginn",
+	"Duration":                             "Dauer",
+	"Imported":                             "Importiert",
+	"Failed":                               "Fehlgeschlagen",
+
+	// Monthly report.
+	"Trail Camera Activity":              "Wildkamera-Aktivität",
+	"%d captures by %d cameras.":         "%d Aufnahmen von %d Kameras.",
+	"Captures by camera and hour of day": "Aufnahmen nach Kamera und Tageszeit",
+	"Camera":                             "Kamera",
+	"Total":                              "Gesamt",
+	"Species and tags":                   "Arten und Schlagwörter",
+	"Tag":                                "Schlagwort",
+	"Captures":                           "Aufnahmen",
+	"Notable events":                     "Bemerkenswerte Ereignisse",
+	"January":                            "Januar",
+	"February":                           "Februar",
+	"March":                              "März",
+	"April":                              "April",
+	"May":                                "Mai",
+	"June":                               "Juni",
+	"July":                               "Juli",
+	"August":                             "August",
+	"September":                          "September",
+	"October":                            "Oktober",
+	"November":                           "November",
+	"December":                           "Dezember",
+
+	// Camera map.
+	"Trail Camera Map": "Wildkamera-Karte",
+	"%d captures":      "%d Aufnahmen",
+	"last %s":          "letzte %s",
+	"position unknown": "Position unbekannt",
+
+	// Custody report.
+	"CHAIN OF CUSTODY REPORT":                "NACHWEIS DER BEWEISMITTELKETTE",
+	"Generated %s on %s":                     "Erstellt am %s auf %s",
+	"Hash chain verified with public key %s": "Hash-Kette mit dem öffentlichen Schlüssel %s geprüft",
+	"Archive root":                           "Archivwurzel",
+	"Captured":                               "Aufgenommen",
+	"Imported on":                            "Importiert am",
+	"%s (camera clock)":                      "%s (Kamerauhr)",
+	"Import session":                         "Importsitzung",
+	"%s (%s on %s)":                          "%s (%s auf %s)",
+	"Operator":                               "Bearbeiter",
+	"Source card":                            "Quellkarte",
+	"Cataloged hash":                         "Katalogisierter Hash",
+	"Current hash":                           "Aktueller Hash",
+	"not checked, offloaded to %s":           "nicht geprüft, ausgelagert nach %s",
+	"UNREADABLE (%s)":                        "UNLESBAR (%s)",
+	"%s (matches)":                           "%s (stimmt überein)",
+	"%s (DIFFERS)":                           "%s (WEICHT AB)",
+	"Hash chain":                             "Hash-Kette",
+	"BROKEN (%s)":                            "UNTERBROCHEN (%s)",
+	"Hash chain link":                        "Kettenglied",
+	"NONE":                                   "KEINES",
+	"%d, signed %s, digest %s":               "%d, signiert am %s, Prüfsumme %s",
+	"Chained hash":                           "Verketteter Hash",
+	"Time stamp":                             "Zeitstempel",
+	"Status":                                 "Status",
+	"intact":                                 "unverändert",
+	"NOT VERIFIED":                           "NICHT BESTÄTIGT",
+	"History":                                "Verlauf",
+	"imported from %s with hash %s":          "importiert aus %s mit Hash %s",
+	"renamed from %s":                        "umbenannt von %s",
+	"contents changed to hash %s":            "Inhalt geändert auf Hash %s",
+	"offloaded to %s":                        "ausgelagert nach %s",
+	"restored from %s":                       "wiederhergestellt aus %s",
+	"verified against its hash":              "anhand des Hashs geprüft",
+	"description updated (e.g. tags or rating)": "Beschreibung geändert (z. B. Schlagwörter oder Bewertung)",
+}
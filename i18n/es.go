@@ -0,0 +1,135 @@
+package i18n
+
+// spanish translations.
+var spanish = map[string]string{
+	// Dialogs.
+	"Error reading config file":                         "Error al leer el archivo de configuración",
+	"Error parsing environment variables":               "Error en las variables de entorno",
+	"Error parsing command line flags":                  "Error en las opciones de la línea de comandos",
+	"Error reading chain key":                           "Error al leer la clave de la cadena",
+	"Error loading GPX file":                            "Error al cargar el archivo GPX",
+	"Error loading plugins":                             "Error al cargar los complementos",
+	"Log File Creation":                                 "Creación del archivo de registro",
+	"Fatal Error":                                       "Error fatal",
+	"Missing command line flag -source or -target":      "Falta la opción -source o -target",
+	"Unknown -nas index %s":                             "Índice -nas desconocido: %s",
+	"Unknown -hash algorithm %s":                        "Algoritmo -hash desconocido: %s",
+	"Only one of -strict and -lenient may be specified": "Solo se puede indicar -strict o -lenient",
+	"%d of %d files failed to import, see log":          "No se pudieron importar %d de %d archivos, consulte el registro",
+	"Pre-import hook":                                   "Comando previo a la importación",
+	"Target unavailable":                                "Destino no disponible",
+	"Check target dir":                                  "Revise el directorio de destino",
+	"Open catalog":                                      "Abrir el catálogo",
+	"Start catalog session":                             "Iniciar la sesión del catálogo",
+	"Open hash chain":                                   "Abrir la cadena de hashes",
+	"Unrecognized file format":                          "Formato de archivo no reconocido",
+	"Damaged file quarantined":                          "Archivo dañado puesto en cuarentena",
+	"Get capture time":                                  "Obtener la hora de captura",
+	"Import hung":                                       "La importación se detuvo",
+	"Pre-existing target file not identical":            "El archivo de destino existente no es idéntico",
+	"Copy source file to target directory":              "Copiar el archivo al directorio de destino",
+	"Find source files":                                 "Buscar los archivos de origen",
+	"Make spool dir":                                    "Crear el directorio de cola",
+	"Open spool catalog":                                "Abrir el catálogo de la cola",
+	"Start spool catalog session":                       "Iniciar la sesión del catálogo de la cola",
+
+	// Settings.
+	"Error writing config file":                                                               "Error al escribir el archivo de configuración",
+	"No user config directory":                                                                "No hay directorio de configuración del usuario",
+	"GardePro Settings":                                                                       "Configuración de GardePro",
+	"Directory into which camera files are archived":                                          "Directorio donde se archivan los archivos de las cámaras",
+	"Keep the folders of the card beneath the year folders?":                                  "¿Conservar las carpetas de la tarjeta dentro de las carpetas de año?",
+	"Check that each file is undamaged before archiving it?":                                  "¿Comprobar que cada archivo no está dañado antes de archivarlo?",
+	"Turn pictures with an unusual orientation upright?":                                      "¿Enderezar las fotos con una orientación inusual?",
+	"Presume files already archived with the same size are identical?":                        "¿Suponer que los archivos ya archivados del mismo tamaño son idénticos?",
+	"When a file can't be imported, set it aside in quarantine and continue with the others?": "Si un archivo no se puede importar, ¿ponerlo en cuarentena y seguir con los demás?",
+	"Currently: yes":       "Actualmente: sí",
+	"Currently: no":        "Actualmente: no",
+	"Settings saved to %s": "Configuración guardada en %s",
+
+	// Notifications and email.
+	"GardePro import finished":             "Importación de GardePro terminada",
+	"GardePro import failed":               "Importación de GardePro fallida",
+	"%d files imported from %s":            "%d archivos importados de %s",
+	", %d failed":                          ", %d fallidos",
+	"GardePro camera fault":                "Falla de cámara GardePro",
+	"%s: %s since %s":                      "%s: %s desde %s",
+	"GardePro battery low":                 "Batería baja en GardePro",
+	"%s: battery %s":                       "%s: batería %s",
+	", empty by %s":                        ", agotada hacia el %s",
+	"GardePro camera silent":               "Cámara GardePro sin capturas",
+	"%s: no captures for %d days since %s": "%s: sin capturas durante %d días desde el %s",
+	"Host":                                 "Equipo",
+	"Source":                               "Origen",
+	"Target":                               "Destino",
+	"Started":                              "Inicio",
+	"Duration":                             "Duración",
+	"Imported":                             "Importados",
+	"Failed":                               "Fallidos",
+
+	// Monthly report.
+	"Trail Camera Activity":              "Actividad de las cámaras trampa",
+	"%d captures by %d cameras.":         "%d capturas de %d cámaras.",
+	"Captures by camera and hour of day": "Capturas por cámara y hora del día",
+	"Camera":                             "Cámara",
+	"Total":                              "Total",
+	"Species and tags":                   "Especies y etiquetas",
+	"Tag":                                "Etiqueta",
+	"Captures":                           "Capturas",
+	"Notable events":                     "Eventos destacados",
+	"January":                            "enero",
+	"February":                           "febrero",
+	"March":                              "marzo",
+	"April":                              "abril",
+	"May":                                "mayo",
+	"June":                               "junio",
+	"July":                               "julio",
+	"August":                             "agosto",
+	"September":                          "septiembre",
+	"October":                            "octubre",
+	"November":                           "noviembre",
+	"December":                           "diciembre",
+
+	// Camera map.
+	"Trail Camera Map": "Mapa de las cámaras trampa",
+	"%d captures":      "%d capturas",
+	"last %s":          "última %s",
+	"position unknown": "posición desconocida",
+
+	// Custody report.
+	"CHAIN OF CUSTODY REPORT":                "INFORME DE CADENA DE CUSTODIA",
+	"Generated %s on %s":                     "Generado el %s en %s",
+	"Hash chain verified with public key %s": "Cadena de hashes verificada con la clave pública %s",
+	"Archive root":                           "Raíz del archivo",
+	"Captured":                               "Capturado",
+	"Imported on":                            "Importado el",
+	"%s (camera clock)":                      "%s (reloj de la cámara)",
+	"Import session":                         "Sesión de importación",
+	"%s (%s on %s)":                          "%s (%s en %s)",
+	"Operator":                               "Operador",
+	"Source card":                            "Tarjeta de origen",
+	"Cataloged hash":                         "Hash catalogado",
+	"Current hash":                           "Hash actual",
+	"not checked, offloaded to %s":           "no comprobado, trasladado a %s",
+	"UNREADABLE (%s)":                        "ILEGIBLE (%s)",
+	"%s (matches)":                           "%s (coincide)",
+	"%s (DIFFERS)":                           "%s (DIFIERE)",
+	"Hash chain":                             "Cadena de hashes",
+	"BROKEN (%s)":                            "ROTA (%s)",
+	"Hash chain link":                        "Eslabón de la cadena",
+	"NONE":                                   "NINGUNO",
+	"%d, signed %s, digest %s":               "%d, firmado el %s, resumen %s",
+	"Chained hash":                           "Hash encadenado",
+	"Time stamp":                             "Sello de tiempo",
+	"Status":                                 "Estado",
+	"intact":                                 "intacto",
+	"NOT VERIFIED":                           "NO VERIFICADO",
+	"History":                                "Historial",
+	"imported from %s with hash %s":          "importado de %s con hash %s",
+	"renamed from %s":                        "renombrado desde %s",
+	"contents changed to hash %s":            "contenido cambiado al hash %s",
+	"offloaded to %s":                        "trasladado a %s",
+	"restored from %s":                       "restaurado desde %s",
+	"verified against its hash":              "verificado con su hash",
+	"description updated (e.g. tags or rating)": "descripción actualizada (p. ej. etiquetas o valoración)",
+}
@@ -0,0 +1,100 @@
+// Package i18n translates the text shown to people (dialogs, notifications, and reports)
+// into the language of their locale: English, Spanish, or German.
+//
+// Messages are keyed by their English text, a fmt format, and translated with golang.org/x/text/message.
+// Text without a translation is shown in English. Log messages are not translated.
+//
+// The language is taken from the LC_ALL, LC_MESSAGES, or LANG environment variable
+// (e.g. es_MX.UTF-8) unless set with Set.
+package i18n
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Supported languages, the first is the default.
+var Supported = []language.Tag{language.English, language.Spanish, language.German}
+
+var (
+	mutex   sync.Mutex
+	tag     = language.English
+	printer = message.NewPrinter(language.English)
+	matcher = language.NewMatcher(Supported)
+)
+
+func init() {
+	for lang, messages := range map[language.Tag]map[string]string{
+		language.Spanish: spanish,
+		language.German:  german,
+	} {
+		for key, msg := range messages {
+			_ = message.SetString(lang, key, msg)
+		}
+	}
+	_ = Set("")
+}
+
+// Set selects the supported language closest to lang (e.g. de-AT is German),
+// or to the locale environment variables if lang is empty. Unsupported languages select English.
+// An error is returned if lang isn't a language tag.
+func Set(lang string) error {
+	if lang == "" {
+		lang = envLocale()
+	}
+	requested := language.English
+	if lang != "" {
+		var err error
+		if requested, err = language.Parse(lang); err != nil {
+			return err
+		}
+	}
+	_, index, _ := matcher.Match(requested)
+	mutex.Lock()
+	defer mutex.Unlock()
+	tag = Supported[index]
+	printer = message.NewPrinter(tag)
+	return nil
+}
+
+// envLocale returns the language of the locale environment variables (e.g. es-MX for es_MX.UTF-8),
+// empty if none or the C locale.
+func envLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			if i := strings.IndexAny(locale, ".@"); i >= 0 {
+				locale = locale[:i]
+			}
+			if locale == "C" || locale == "POSIX" {
+				return ""
+			}
+			return strings.ReplaceAll(locale, "_", "-")
+		}
+	}
+	return ""
+}
+
+// Lang returns the selected language.
+func Lang() language.Tag {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return tag
+}
+
+// T translates a message and formats it with the arguments as by fmt.Sprintf.
+func T(key string, args ...interface{}) string {
+	mutex.Lock()
+	p := printer
+	mutex.Unlock()
+	return p.Sprintf(key, args...)
+}
+
+// Month formats the month and year of a time (e.g. January 2006) in the selected language.
+func Month(t time.Time) string {
+	return T(t.Month().String()) + " " + t.Format("2006")
+}
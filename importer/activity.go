@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// CameraActivity counts the captures of a camera.
+type CameraActivity struct {
+	Camera string `json:"camera"`
+	Files  int    `json:"files"`
+	// Hours counts the captures in each hour of the day (camera clock time).
+	Hours [24]int `json:"hours"`
+	// Weeks counts the captures in each ISO week of the year (week 1 first), over all years.
+	Weeks [53]int `json:"weeks"`
+}
+
+// Activity counts the captures of each camera in the catalog from the start time until
+// (but not including) the end time, either of which may be zero for no limit,
+// with the tag or label, empty for all captures.
+// Cameras are identified by the source directory of their files (see cameraOf)
+// and are returned in camera order.
+func Activity(cat *catalog.Catalog, start, end time.Time, tag string) []*CameraActivity {
+	cameras := make(map[string]*CameraActivity)
+	for _, file := range cat.Originals() {
+		if !start.IsZero() && file.Captured.Before(start) || !end.IsZero() && !file.Captured.Before(end) ||
+			tag != "" && !file.HasTag(tag) {
+			continue
+		}
+		camera := cameras[cameraOf(file)]
+		if camera == nil {
+			camera = &CameraActivity{Camera: cameraOf(file)}
+			cameras[camera.Camera] = camera
+		}
+		camera.Files++
+		camera.Hours[file.Captured.Hour()]++
+		_, week := file.Captured.ISOWeek()
+		camera.Weeks[week-1]++
+	}
+	activity := make([]*CameraActivity, 0, len(cameras))
+	for _, camera := range cameras {
+		activity = append(activity, camera)
+	}
+	sort.Slice(activity, func(i, j int) bool {
+		return activity[i].Camera < activity[j].Camera
+	})
+	return activity
+}
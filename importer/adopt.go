@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// AdoptResult counts the files found when adopting an archive.
+type AdoptResult struct {
+	// Adopted files were added to the catalog.
+	Adopted int
+	// Cataloged files were already in the catalog.
+	Cataloged int
+	// Unnamed files don't have an archive name and were skipped.
+	Unnamed int
+	// Mismatched files have names that don't match their embedded capture times.
+	// They were adopted with the time in their name, fix them with FixTime.
+	Mismatched int
+}
+
+// Adopt adds the media files already in the target tree (e.g. organized by hand before
+// this application was used) to the catalog without copying or renaming them.
+// Files must be named like imported files (Year/Mon-Day-Hour:Minute:Second-BaseName.Ext).
+// The capture time in each file name is checked against the file's embedded capture time
+// and mismatches are logged. Adopted files have no source.
+func (imp *Importer) Adopt() (*AdoptResult, error) {
+	if imp.options.Catalog == nil {
+		return nil, errors.New("adopting requires a catalog")
+	}
+	result := &AdoptResult{}
+	err := imp.walkArchive(0, func(path string) error {
+		rel, err := imp.relative(path)
+		if err != nil {
+			return err
+		}
+		if imp.options.Catalog.File(rel) != nil {
+			result.Cataloged++
+			return nil
+		}
+		metadata, metadataErr := metadataCaptureTime(path)
+		location := time.UTC
+		if metadataErr == nil {
+			location = metadata.Location()
+		}
+		when, err := archiveNameTime(rel, location)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Adopt: not an archive name")
+			result.Unnamed++
+			return nil
+		}
+		if metadataErr != nil {
+			log.Debug().Err(metadataErr).Str("path", path).Msg("Adopt: no embedded capture time")
+		} else if !metadata.Equal(when) {
+			log.Warn().Str("path", path).Str("embedded", metadata.Format(dateFmt+" 15:04:05")).Msg("Adopt: capture time mismatch")
+			result.Mismatched++
+		}
+		if err := imp.indexFile(path, rel, when, nil); err != nil {
+			return err
+		}
+		result.Adopted++
+		return nil
+	})
+	return result, err
+}
+
+// archiveNameTime returns the capture time in the path (relative to its root) of an archived file,
+// from its year directory and the Mon-Day-Hour:Minute:Second- prefix of its name,
+// as a wall clock time in the location.
+func archiveNameTime(rel string, location *time.Location) (time.Time, error) {
+	year, _, _ := strings.Cut(rel, "/")
+	base := filepath.Base(filepath.FromSlash(rel))
+	if !yearDir.MatchString(year) || len(base) <= len(fileNameStubFmt) {
+		return time.Time{}, fmt.Errorf("no capture time in %s", rel)
+	}
+	when, err := time.ParseInLocation("2006/"+fileNameStubFmt, year+"/"+base[:len(fileNameStubFmt)], location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse capture time in %s: %w", rel, err)
+	}
+	return when, nil
+}
@@ -0,0 +1,204 @@
+package importer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Audio recordings are filed by the start time of the recording.
+//   - AudioMoth acoustic monitors write WAV files named for the start time in UTC
+//     (e.g. 20240117_063012.WAV) with an INFO ICMT comment such as
+//     "Recorded at 06:30:12 17/01/2024 (UTC-5) by AudioMoth ...".
+//   - Other recorders write WAV files with a Broadcast Wave bext chunk
+//     holding the local date and time the recording started.
+//   - MP3 files may have an ID3v2 recording time (TDRC, or TYER, TDAT, and TIME).
+// Times in UTC are converted to the local time zone like MP4 creation times.
+
+var (
+	// audioMothComment matches the start time in an AudioMoth ICMT comment.
+	audioMothComment = regexp.MustCompile(`Recorded at (\d\d:\d\d:\d\d) (\d\d/\d\d/\d{4}) \(UTC(?:([+-]\d{1,2})(?::(\d\d))?)?\)`)
+	// audioMothFileName matches AudioMoth file names.
+	audioMothFileName = regexp.MustCompile(`^(\d{8}_\d{6})\.[Ww][Aa][Vv]$`)
+)
+
+// WAVcaptureTime returns the start time of a WAV recording from its AudioMoth comment,
+// bext chunk, or AudioMoth file name.
+func WAVcaptureTime(path string) (time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: open file: %s", ErrNoCaptureTime, err)
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: stat file: %s", ErrNoCaptureTime, err)
+	}
+	var comment, bext []byte
+	walkErr := riffWalk(file, stat.Size(), "WAVE", func(id string, offset, size int64) error {
+		if (id == "ICMT" || id == "bext") && size <= 4096 {
+			data := make([]byte, size)
+			if _, err := file.ReadAt(data, offset); err != nil {
+				return err
+			}
+			if id == "ICMT" {
+				comment = data
+			} else {
+				bext = data
+			}
+		}
+		return nil
+	})
+	if match := audioMothComment.FindSubmatch(comment); match != nil {
+		when, err := time.Parse("15:04:05 02/01/2006", string(match[1])+" "+string(match[2]))
+		if err == nil {
+			hours, _ := strconv.Atoi(string(match[3]))
+			minutes, _ := strconv.Atoi(string(match[4]))
+			offset := hours*3600 + minutes*60
+			if hours < 0 {
+				offset = hours*3600 - minutes*60
+			}
+			return when.Add(-time.Duration(offset) * time.Second).In(localTimeZone), nil
+		}
+	}
+	// The bext chunk starts with the description (256), originator (32), and originator reference (32),
+	// followed by the origination date (10, yyyy-mm-dd) and time (8, hh:mm:ss).
+	if len(bext) >= 338 {
+		if when, err := time.Parse("2006-01-02 15:04:05", string(bext[320:330])+" "+string(bext[330:338])); err == nil {
+			return when, nil
+		}
+	}
+	if match := audioMothFileName.FindStringSubmatch(filepath.Base(path)); match != nil {
+		if when, err := time.Parse("20060102_150405", match[1]); err == nil {
+			return when.In(localTimeZone), nil
+		}
+	}
+	if walkErr != nil {
+		return time.Time{}, fmt.Errorf("%w: read WAV chunks: %s", ErrNoCaptureTime, walkErr)
+	}
+	return time.Time{}, fmt.Errorf("%w: no AudioMoth comment, bext chunk, or AudioMoth file name", ErrNoCaptureTime)
+}
+
+// WAVverify checks the chunk structure of a WAV file.
+// Recordings interrupted by a flat battery may end partway through the data chunk.
+func WAVverify(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	header := make([]byte, 12)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("%w: truncated RIFF header", ErrCorrupt)
+	} else if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return fmt.Errorf("%w: not a RIFF WAVE file", ErrCorrupt)
+	}
+	var hasFormat, hasData bool
+	for offset := int64(12); offset+8 <= stat.Size(); {
+		if _, err := file.ReadAt(header[:8], offset); err != nil {
+			return fmt.Errorf("%w: read chunk header at offset %d: %s", ErrCorrupt, offset, err)
+		}
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+		if offset+8+size > stat.Size() {
+			return fmt.Errorf("%w: %s chunk at offset %d truncated by %d bytes",
+				ErrCorrupt, id, offset, offset+8+size-stat.Size())
+		}
+		switch id {
+		case "fmt ":
+			hasFormat = true
+		case "data":
+			hasData = true
+		}
+		offset += 8 + size + size%2
+	}
+	if !hasFormat {
+		return fmt.Errorf("%w: no fmt chunk", ErrCorrupt)
+	} else if !hasData {
+		return fmt.Errorf("%w: no data chunk", ErrCorrupt)
+	}
+	return nil
+}
+
+// MP3captureTime returns the recording time of an MP3 file from its ID3v2 tag.
+func MP3captureTime(path string) (time.Time, error) {
+	frames, err := id3Frames(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: read ID3 tag: %s", ErrNoCaptureTime, err)
+	}
+	if recorded := frames["TDRC"]; recorded != "" {
+		for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+			if when, err := time.Parse(layout, recorded); err == nil {
+				return when, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%w: unrecognized TDRC time '%s'", ErrNoCaptureTime, recorded)
+	}
+	// ID3v2.3 splits the time into year (YYYY), date (DDMM), and time (HHMM) frames.
+	if year, date := frames["TYER"], frames["TDAT"]; year != "" && date != "" {
+		clock := frames["TIME"]
+		if clock == "" {
+			clock = "0000"
+		}
+		if when, err := time.Parse("2006 0201 1504", year+" "+date+" "+clock); err == nil {
+			return when, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: no ID3 recording time", ErrNoCaptureTime)
+}
+
+// id3Frames returns the text frames of the ID3v2.3 or ID3v2.4 tag at the start of an MP3 file.
+func id3Frames(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	} else if string(header[0:3]) != "ID3" {
+		return nil, fmt.Errorf("no ID3v2 tag")
+	}
+	version := header[3]
+	if version != 3 && version != 4 {
+		return nil, fmt.Errorf("unsupported ID3v2.%d tag", version)
+	}
+	tag := make([]byte, id3SyncSafe(header[6:10]))
+	if _, err := io.ReadFull(file, tag); err != nil {
+		return nil, fmt.Errorf("read tag: %w", err)
+	}
+	frames := make(map[string]string)
+	for len(tag) >= 10 && tag[0] != 0 {
+		id := string(tag[0:4])
+		size := int(binary.BigEndian.Uint32(tag[4:8]))
+		if version == 4 {
+			size = id3SyncSafe(tag[4:8])
+		}
+		if size > len(tag)-10 {
+			break
+		}
+		// Text frames start with an encoding byte; only the ISO-8859-1 and UTF-8 encodings
+		// are decoded, which is enough for the dates.
+		if data := tag[10 : 10+size]; strings.HasPrefix(id, "T") && len(data) > 1 && (data[0] == 0 || data[0] == 3) {
+			frames[id] = strings.TrimRight(string(data[1:]), "\x00")
+		}
+		tag = tag[10+size:]
+	}
+	return frames, nil
+}
+
+// id3SyncSafe decodes an ID3v2 synchsafe integer (seven bits per byte).
+func id3SyncSafe(data []byte) int {
+	return int(data[0]&0x7f)<<21 | int(data[1]&0x7f)<<14 | int(data[2]&0x7f)<<7 | int(data[3]&0x7f)
+}
@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// Audit checks that every media file beneath a source directory (e.g. a card)
+// is in the catalog of the target with identical contents, whatever its name or root,
+// so that the source can safely be erased. Archived files must exist (or be offloaded).
+// Files whose archived copies were modified on import are found by their cataloged source hashes.
+// If verify is true the contents of each archived file are hashed again.
+// Returns the source files that are not archived.
+func Audit(source, target, algorithm string, verify bool) ([]string, error) {
+	if algorithm == "" {
+		algorithm = HashSHA256
+	}
+	cat, err := OpenCatalog(target)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cat.Close() }()
+	imp := New(target, Options{})
+	archived := make(map[string][]*catalog.File)
+	for _, file := range cat.Files() {
+		archived[file.Hash] = append(archived[file.Hash], file)
+		if file.SourceHash != "" {
+			archived[file.SourceHash] = append(archived[file.SourceHash], file)
+		}
+	}
+	sources, err := SourceFiles(source)
+	if err != nil {
+		return nil, err
+	}
+	var stragglers []string
+	for _, path := range sources {
+		sum, err := hashFile(path, algorithm)
+		if err != nil {
+			return stragglers, fmt.Errorf("hash %s: %w", path, err)
+		}
+		if !imp.archived(archived[algorithm+":"+sum], verify) {
+			stragglers = append(stragglers, path)
+		}
+	}
+	return stragglers, nil
+}
+
+// archived returns whether any of the cataloged files exists (or is offloaded),
+// rehashing the file if verify is true.
+func (imp *Importer) archived(files []*catalog.File, verify bool) bool {
+	for _, file := range files {
+		algorithm, _ := splitHash(file.Hash)
+		if file.Offloaded != "" {
+			return true
+		}
+		path := imp.catalogPath(file)
+		if !verify {
+			if _, err := os.Stat(path); err == nil {
+				return true
+			}
+		} else if archivedSum, err := hashFile(path, algorithm); err == nil && algorithm+":"+archivedSum == file.Hash {
+			return true
+		} else if err == nil {
+			log.Warn().Str("path", path).Msg("Audit: archived file differs from catalog")
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/madkins23/gardepro/fixture"
+)
+
+// TestAuditRewrittenJPEG checks that a card whose JPEG files were rewritten on import
+// (here with the EXIF OffsetTime of the camera time zone) is found to be archived, by audit-card and diff.
+func TestAuditRewrittenJPEG(t *testing.T) {
+	zone, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("time zone database: %s", err)
+	}
+	imp := testImporter(t, Options{CameraZone: zone})
+	card := t.TempDir()
+	source := writeJPEG(t, card, "DCIM/IMG_0001.JPG", fixture.JPEG{Captured: testTime, Model: "GardePro E6"})
+	writeFile(t, card, "DCIM/VID_0002.MP4", (&fixture.MP4{Created: testTime.Add(time.Minute), Duration: time.Second}).Bytes())
+	archived := importFiles(t, imp, source, filepath.Join(card, "DCIM", "VID_0002.MP4"))
+
+	sourceData, _ := os.ReadFile(source)
+	archivedData, _ := os.ReadFile(archived[0])
+	if bytes.Equal(sourceData, archivedData) {
+		t.Fatal("JPEG file not rewritten on import")
+	} else if file := catalogEntry(t, imp, archived[0]); file.SourceHash == "" || file.SourceHash == file.Hash {
+		t.Errorf("rewritten file has source hash %q, hash %q", file.SourceHash, file.Hash)
+	} else if file := catalogEntry(t, imp, archived[1]); file.SourceHash != "" {
+		t.Errorf("unchanged file has source hash %q", file.SourceHash)
+	}
+
+	for _, verify := range []bool{false, true} {
+		if stragglers, err := Audit(card, imp.Target(), "", verify); err != nil {
+			t.Fatalf("audit: %s", err)
+		} else if len(stragglers) > 0 {
+			t.Errorf("audit (verify %t) found stragglers %v", verify, stragglers)
+		}
+	}
+	cardFiles, err := Inventory(card, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetFiles, err := Inventory(imp.Target(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onlyCard, onlyTarget := Diff(cardFiles, targetFiles); len(onlyCard) > 0 || len(onlyTarget) > 0 {
+		t.Errorf("diff found %d files only on the card, %d only in the target", len(onlyCard), len(onlyTarget))
+	}
+
+	// A file not yet imported is still a straggler.
+	straggler := writeJPEG(t, card, "DCIM/IMG_0003.JPG", fixture.JPEG{Captured: testTime.Add(time.Hour), Model: "GardePro E6"})
+	if stragglers, err := Audit(card, imp.Target(), "", false); err != nil {
+		t.Fatalf("audit: %s", err)
+	} else if len(stragglers) != 1 || stragglers[0] != straggler {
+		t.Errorf("audit found stragglers %v, want %s", stragglers, straggler)
+	}
+}
@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// riffSearchLimit is how far into a file lists are searched for date chunks.
+// The AVI hdrl and INFO lists precede the media data.
+const riffSearchLimit = 1 << 20
+
+// AVIcaptureTime returns the capture time of an AVI file (e.g. from Browning and Bushnell cameras)
+// from its IDIT (digitization time) or INFO ICRD (creation date) chunk.
+func AVIcaptureTime(path string) (time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: open file: %s", ErrNoCaptureTime, err)
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: stat file: %s", ErrNoCaptureTime, err)
+	}
+	var found []string
+	err = riffWalk(file, stat.Size(), "AVI ", func(id string, offset, size int64) error {
+		if (id == "IDIT" || id == "ICRD") && size <= 64 {
+			data := make([]byte, size)
+			if _, err := file.ReadAt(data, offset); err != nil {
+				return err
+			}
+			found = append(found, string(data))
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: read AVI chunks: %s", ErrNoCaptureTime, err)
+	}
+	for _, value := range found {
+		if when, err := parseCameraTime(value); err == nil {
+			return when, nil
+		}
+	}
+	if len(found) > 0 {
+		return time.Time{}, fmt.Errorf("%w: unrecognized AVI date '%s'", ErrNoCaptureTime, strings.TrimRight(found[0], "\x00\n "))
+	}
+	return time.Time{}, fmt.Errorf("%w: no IDIT or ICRD chunk", ErrNoCaptureTime)
+}
+
+// AVIverify checks the chunk structure of an AVI file.
+// Clips truncated by power failure end partway through the movi list
+// and lack the idx1 index which the cameras write last.
+func AVIverify(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	header := make([]byte, 12)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("%w: truncated RIFF header", ErrCorrupt)
+	} else if string(header[0:4]) != "RIFF" || string(header[8:12]) != "AVI " {
+		return fmt.Errorf("%w: not a RIFF AVI file", ErrCorrupt)
+	} else if size := int64(binary.LittleEndian.Uint32(header[4:8])) + 8; size > stat.Size() {
+		return fmt.Errorf("%w: RIFF chunk truncated by %d bytes (recording probably interrupted by power failure)",
+			ErrCorrupt, size-stat.Size())
+	}
+	var hasHeader, hasMovie bool
+	for offset := int64(12); offset+8 <= stat.Size(); {
+		if _, err := file.ReadAt(header[:8], offset); err != nil {
+			return fmt.Errorf("%w: read chunk header at offset %d: %s", ErrCorrupt, offset, err)
+		}
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+		if offset+8+size > stat.Size() {
+			return fmt.Errorf("%w: %s chunk at offset %d truncated by %d bytes",
+				ErrCorrupt, id, offset, offset+8+size-stat.Size())
+		}
+		if id == "LIST" && size >= 4 {
+			if _, err := file.ReadAt(header[8:12], offset+8); err != nil {
+				return fmt.Errorf("%w: read list type at offset %d: %s", ErrCorrupt, offset, err)
+			}
+			switch string(header[8:12]) {
+			case "hdrl":
+				hasHeader = true
+			case "movi":
+				hasMovie = true
+			}
+		}
+		offset += 8 + size + size%2
+	}
+	if !hasHeader {
+		return fmt.Errorf("%w: no hdrl list", ErrCorrupt)
+	} else if !hasMovie {
+		return fmt.Errorf("%w: no movi list", ErrCorrupt)
+	}
+	return nil
+}
+
+// riffWalk calls the function with the ID, data offset, and data size of each chunk
+// in a RIFF file of the specified form (e.g. "AVI "), descending into lists
+// other than movi (which holds the media data).
+func riffWalk(file *os.File, fileSize int64, form string, fn func(id string, offset, size int64) error) error {
+	header := make([]byte, 12)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("read RIFF header: %w", err)
+	} else if string(header[0:4]) != "RIFF" || string(header[8:12]) != form {
+		return fmt.Errorf("not a RIFF %s file", strings.TrimSpace(form))
+	}
+	var walk func(offset, end int64) error
+	walk = func(offset, end int64) error {
+		for offset+8 <= end {
+			if _, err := file.ReadAt(header[:8], offset); err != nil {
+				return fmt.Errorf("read chunk header at offset %d: %w", offset, err)
+			}
+			id := string(header[0:4])
+			size := int64(binary.LittleEndian.Uint32(header[4:8]))
+			if offset+8+size > end {
+				size = end - offset - 8
+			}
+			if id == "LIST" && size >= 4 {
+				if _, err := file.ReadAt(header[8:12], offset+8); err != nil {
+					return fmt.Errorf("read list type at offset %d: %w", offset, err)
+				}
+				if listType := string(header[8:12]); listType != "movi" && offset < riffSearchLimit {
+					if err := walk(offset+12, offset+8+size); err != nil {
+						return err
+					}
+				}
+			} else if err := fn(id, offset+8, size); err != nil {
+				return err
+			}
+			offset += 8 + size + size%2
+		}
+		return nil
+	}
+	return walk(12, fileSize)
+}
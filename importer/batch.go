@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Result records the outcome of importing a single file in a batch.
+type Result struct {
+	Source string
+	Target string
+	// Duplicate is the earlier source file in the batch with identical contents, if any.
+	// Duplicate source files are not imported.
+	Duplicate string
+	// Excluded source files (see Importer.Excluded) are not imported.
+	Excluded bool
+	Err      error
+}
+
+// SourceFiles returns the supported media files beneath the source directory,
+// skipping state and NAS thumbnail directories (see skipDir).
+// If the source is a file it is returned by itself.
+func SourceFiles(source string) ([]string, error) {
+	if stat, err := os.Stat(source); err != nil {
+		return nil, fmt.Errorf("stat source: %w", err)
+	} else if !stat.IsDir() {
+		return []string{source}, nil
+	}
+	var sources []string
+	err := filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if entry.IsDir() && path != source && skipDir(entry.Name()) {
+			return filepath.SkipDir
+		} else if !entry.IsDir() && Supported(path) {
+			sources = append(sources, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk source: %w", err)
+	}
+	return sources, nil
+}
+
+// Supported returns true if the file extension is that of a supported media format
+// or has a registered Extractor.
+func Supported(path string) bool {
+	if extractor(path) != nil {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".heic", ".mp4", ".mov", ".avi", ".wav", ".mp3":
+		return true
+	}
+	return false
+}
+
+// ImportBatch imports a batch of source files, continuing after errors.
+// Source files with identical contents are only imported once.
+// Source files excluded by the import filters (see Excluded) are skipped.
+// The root is the source directory containing the files, used when preserving structure.
+// Files are imported by Options.Jobs concurrent workers,
+// the results are in the same order as the sources.
+// With Options.Strict no more files are started once one fails.
+func (imp *Importer) ImportBatch(root string, sources []string) []Result {
+	imp.forgetCards()
+	excluded := imp.excludedFiles(sources)
+	included := make([]string, 0, len(sources)-len(excluded))
+	for _, source := range sources {
+		if !excluded[source] {
+			included = append(included, source)
+		}
+	}
+	span := imp.options.Tracer.Start(nil, "duplicates", "files", strconv.Itoa(len(included)))
+	duplicates := findDuplicates(included, imp.options.Hash)
+	span.End(nil)
+	results := make([]Result, len(sources))
+	jobs := imp.options.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	var progress sync.Mutex
+	var done int
+	var aborted int32
+	for j := 0; j < jobs; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if excluded[sources[i]] {
+					results[i] = Result{Source: sources[i], Excluded: true}
+					imp.decide(sources[i], ActionExcluded, "", time.Time{}, "")
+				} else if atomic.LoadInt32(&aborted) != 0 {
+					results[i] = Result{Source: sources[i], Err: &Error{Source: sources[i], Err: ErrAborted}}
+					imp.decideError(sources[i], "", results[i].Err)
+				} else {
+					results[i] = imp.importBatchFile(root, sources[i], duplicates[sources[i]])
+					if results[i].Err != nil && imp.options.Strict && atomic.CompareAndSwapInt32(&aborted, 0, 1) {
+						log.Warn().Str("source", sources[i]).Msg("Strict import stopped after failure")
+					}
+				}
+				if imp.options.Progress != nil {
+					progress.Lock()
+					done++
+					imp.options.Progress(done, len(sources))
+					progress.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range sources {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return results
+}
+
+// importBatchFile imports a single file from a batch unless it is a duplicate.
+func (imp *Importer) importBatchFile(root, source, duplicate string) Result {
+	result := Result{Source: source, Duplicate: duplicate}
+	if result.Duplicate != "" {
+		log.Info().Str("source", source).Str("duplicate-of", result.Duplicate).
+			Msg("Skipping duplicate source file")
+		imp.decide(source, ActionDuplicate, "", time.Time{}, result.Duplicate)
+	} else {
+		result.Target, result.Err = imp.importFile(source, imp.sourceSubDir(root, source))
+		if result.Err != nil {
+			log.Error().Err(result.Err).Str("source", source).Msg("Import file")
+		}
+	}
+	return result
+}
+
+// sourceSubDir returns the directory of the source file relative to the root (slash separated)
+// if the source directory structure is to be preserved, otherwise the empty string.
+func (imp *Importer) sourceSubDir(root, source string) string {
+	if !imp.options.PreserveStructure || root == "" {
+		return ""
+	}
+	if rel, err := filepath.Rel(root, filepath.Dir(source)); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return ""
+}
+
+// findDuplicates returns a map from the path of each duplicate file
+// to the path of the first file with the same contents.
+// Only files with the same size are hashed.
+func findDuplicates(paths []string, algorithm string) map[string]string {
+	bySize := make(map[int64][]string)
+	for _, path := range paths {
+		if stat, err := os.Stat(path); err == nil {
+			bySize[stat.Size()] = append(bySize[stat.Size()], path)
+		}
+	}
+	duplicates := make(map[string]string)
+	for _, sameSize := range bySize {
+		if len(sameSize) < 2 {
+			continue
+		}
+		byHash := make(map[string]string)
+		for _, path := range sameSize {
+			hash, err := hashFile(path, algorithm)
+			if err != nil {
+				log.Warn().Err(err).Str("source", path).Msg("Hash source file")
+				continue
+			}
+			if first, found := byHash[hash]; found {
+				duplicates[path] = first
+			} else {
+				byHash[hash] = path
+			}
+		}
+	}
+	return duplicates
+}
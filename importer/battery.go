@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	exifundefined "github.com/dsoprea/go-exif/v3/undefined"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// Some cameras report their battery level in text in the EXIF ImageDescription or UserComment
+// or in the maker note (e.g. "BATT:80%"), and AudioMoth recorders report the battery voltage
+// in their WAV comment (e.g. "battery state was 4.5V").
+
+const (
+	tagIDImageDescription = 0x10e
+	tagIDUserComment      = 0x9286
+)
+
+var (
+	batteryPercent = regexp.MustCompile(`(?i)batt(?:ery)?(?: level)?\s*[:=]?\s*(\d{1,3})\s*%`)
+	batteryVolts   = regexp.MustCompile(`(?i)batt(?:ery)?[a-z ]*?\s*[:=]?\s*(\d{1,2}\.\d{1,2})\s*V\b`)
+)
+
+// BatteryLevel returns the battery level reported by the camera in a media file, nil if none.
+func BatteryLevel(path string) *catalog.Battery {
+	switch {
+	case isJPEG(path):
+		metadata, err := exifRead(path)
+		if err != nil {
+			return nil
+		}
+		for _, tagID := range []uint16{tagIDImageDescription, tagIDUserComment, tagIDMakerNote} {
+			value, err := metadata.value(tagID)
+			if err != nil {
+				continue
+			}
+			var text []byte
+			switch value := value.(type) {
+			case string:
+				text = []byte(value)
+			case exifundefined.Tag9286UserComment:
+				text = value.EncodingBytes
+			case exifundefined.Tag927CMakerNote:
+				text = value.MakerNoteBytes
+			}
+			if battery := parseBattery(text); battery != nil {
+				return battery
+			}
+		}
+	case strings.EqualFold(filepath.Ext(path), ".wav"):
+		// The INFO list with the comment precedes the audio data.
+		file, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer func() { _ = file.Close() }()
+		header := make([]byte, 4096)
+		n, _ := file.Read(header)
+		return parseBattery(header[:n])
+	}
+	return nil
+}
+
+// parseBattery returns the battery level in text, nil if none.
+func parseBattery(text []byte) *catalog.Battery {
+	text = bytes.ReplaceAll(text, []byte{0}, []byte{' '})
+	if match := batteryPercent.FindSubmatch(text); match != nil {
+		if percent, err := strconv.ParseFloat(string(match[1]), 64); err == nil && percent <= 100 {
+			return &catalog.Battery{Percent: percent}
+		}
+	}
+	if match := batteryVolts.FindSubmatch(text); match != nil {
+		if volts, err := strconv.ParseFloat(string(match[1]), 64); err == nil {
+			return &catalog.Battery{Volts: volts}
+		}
+	}
+	return nil
+}
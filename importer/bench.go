@@ -0,0 +1,250 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Benchmark tests.
+const (
+	BenchRead  = "read"
+	BenchHash  = "hash"
+	BenchWrite = "write"
+	BenchCopy  = "copy"
+)
+
+// BenchResult is the throughput of a single benchmark test.
+type BenchResult struct {
+	Test      string
+	Hash      string
+	BlockSize int
+	Jobs      int
+	Bytes     int64
+	Elapsed   time.Duration
+}
+
+// Throughput returns the benchmark throughput in MiB per second.
+func (r BenchResult) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / (1 << 20) / r.Elapsed.Seconds()
+}
+
+// BenchOptions configures a benchmark.
+type BenchOptions struct {
+	// BlockSizes are the copy buffer sizes to test.
+	BlockSizes []int
+	// Jobs are the numbers of concurrent copies to test.
+	Jobs []int
+	// Limit is the maximum number of bytes of source files to use.
+	Limit int64
+}
+
+// Bench measures the throughput of reading the source files, hashing, writing into the directory,
+// and copying the source files into the directory, which should be on the target device.
+// Reading and writing are tested with each block size, copying with each number of jobs
+// using the block size with the best combined read and write throughput.
+// Files written into the directory are removed.
+func Bench(sources []string, dir string, options *BenchOptions) ([]BenchResult, error) {
+	var sample []string
+	var size int64
+	for _, source := range sources {
+		if stat, err := os.Stat(source); err == nil && stat.Size() > 0 {
+			sample = append(sample, source)
+			if size += stat.Size(); size >= options.Limit {
+				break
+			}
+		}
+	}
+	if len(sample) == 0 {
+		return nil, fmt.Errorf("no source files")
+	}
+	benchDir, err := os.MkdirTemp(dir, "bench-")
+	if err != nil {
+		return nil, fmt.Errorf("make bench dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(benchDir) }()
+
+	var results []BenchResult
+	var bestBlockSize int
+	var bestRate float64
+	for _, blockSize := range options.BlockSizes {
+		read, err := benchRead(sample, blockSize)
+		if err != nil {
+			return results, err
+		}
+		write, err := benchWrite(filepath.Join(benchDir, "write"), size, blockSize)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, read, write)
+		// Copying is limited by the slower of reading and writing.
+		if rate := math.Min(read.Throughput(), write.Throughput()); rate > bestRate {
+			bestBlockSize, bestRate = blockSize, rate
+		}
+	}
+	for _, algorithm := range []string{HashSHA256, HashXXH3, HashBLAKE3} {
+		result, err := benchHash(algorithm, size, bestBlockSize)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	for _, jobs := range options.Jobs {
+		result, err := benchCopy(sample, benchDir, bestBlockSize, jobs)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// BenchRecommend returns the copy result with the best throughput,
+// preferring fewer jobs unless more jobs are at least ten percent faster.
+func BenchRecommend(results []BenchResult) *BenchResult {
+	var best *BenchResult
+	for i := range results {
+		result := &results[i]
+		if result.Test != BenchCopy {
+			continue
+		}
+		if best == nil || result.Throughput() > best.Throughput()*1.1 {
+			best = result
+		}
+	}
+	return best
+}
+
+func benchRead(sources []string, blockSize int) (BenchResult, error) {
+	result := BenchResult{Test: BenchRead, BlockSize: blockSize, Jobs: 1}
+	buffer := make([]byte, blockSize)
+	start := time.Now()
+	for _, source := range sources {
+		n, err := benchReadFile(source, buffer)
+		if err != nil {
+			return result, err
+		}
+		result.Bytes += n
+	}
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
+
+func benchReadFile(source string, buffer []byte) (int64, error) {
+	file, err := os.Open(source)
+	if err != nil {
+		return 0, fmt.Errorf("open source file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	dropCache(file)
+	n, err := io.CopyBuffer(io.Discard, struct{ io.Reader }{file}, buffer)
+	if err != nil {
+		return n, fmt.Errorf("read source file: %w", err)
+	}
+	return n, nil
+}
+
+func benchWrite(path string, size int64, blockSize int) (BenchResult, error) {
+	result := BenchResult{Test: BenchWrite, BlockSize: blockSize, Jobs: 1}
+	buffer := make([]byte, blockSize)
+	start := time.Now()
+	file, err := os.Create(path)
+	if err != nil {
+		return result, fmt.Errorf("create bench file: %w", err)
+	}
+	defer func() { _ = os.Remove(path) }()
+	for result.Bytes < size {
+		n, err := file.Write(buffer)
+		result.Bytes += int64(n)
+		if err != nil {
+			_ = file.Close()
+			return result, fmt.Errorf("write bench file: %w", err)
+		}
+	}
+	// Include the time to get the data onto the device.
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return result, fmt.Errorf("sync bench file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return result, fmt.Errorf("close bench file: %w", err)
+	}
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
+
+func benchHash(algorithm string, size int64, blockSize int) (BenchResult, error) {
+	result := BenchResult{Test: BenchHash, Hash: algorithm, BlockSize: blockSize, Jobs: 1}
+	h, err := newHash(algorithm)
+	if err != nil {
+		return result, err
+	}
+	buffer := make([]byte, blockSize)
+	start := time.Now()
+	for result.Bytes < size {
+		n, _ := h.Write(buffer)
+		result.Bytes += int64(n)
+	}
+	h.Sum(nil)
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
+
+func benchCopy(sources []string, dir string, blockSize, jobs int) (BenchResult, error) {
+	result := BenchResult{Test: BenchCopy, BlockSize: blockSize, Jobs: jobs}
+	indices := make(chan int)
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	start := time.Now()
+	for j := 0; j < jobs; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				errs[i] = benchCopyFile(sources[i], filepath.Join(dir, fmt.Sprintf("copy-%d", i)), blockSize)
+			}
+		}()
+	}
+	for i, source := range sources {
+		if stat, err := os.Stat(source); err == nil {
+			result.Bytes += stat.Size()
+		}
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+	for i := range sources {
+		if errs[i] != nil {
+			return result, errs[i]
+		}
+		_ = os.Remove(filepath.Join(dir, fmt.Sprintf("copy-%d", i)))
+	}
+	return result, nil
+}
+
+func benchCopyFile(source, target string, blockSize int) error {
+	if file, err := os.Open(source); err == nil {
+		dropCache(file)
+		_ = file.Close()
+	}
+	if err := copyFileHash(source, target, nil, blockSize); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("open copied file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync copied file: %w", err)
+	}
+	return nil
+}
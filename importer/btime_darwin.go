@@ -0,0 +1,36 @@
+//go:build darwin
+
+package importer
+
+import (
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// attrList is the attribute list argument of setattrlist(2).
+type attrList struct {
+	bitmapCount uint16
+	reserved    uint16
+	commonAttr  uint32
+	volAttr     uint32
+	dirAttr     uint32
+	fileAttr    uint32
+	forkAttr    uint32
+}
+
+// setBirthTime sets the creation time of a file.
+func setBirthTime(path string, when time.Time) error {
+	pathPtr, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	list := attrList{bitmapCount: unix.ATTR_BIT_MAP_COUNT, commonAttr: unix.ATTR_CMN_CRTIME}
+	crtime := unix.NsecToTimespec(when.UnixNano())
+	if _, _, errno := unix.Syscall6(unix.SYS_SETATTRLIST, uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&list)), uintptr(unsafe.Pointer(&crtime)), unsafe.Sizeof(crtime), 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !windows
+
+package importer
+
+import "time"
+
+// setBirthTime does nothing since file creation times can't be set
+// on Linux and other systems (even where they can be read).
+func setBirthTime(string, time.Time) error {
+	return nil
+}
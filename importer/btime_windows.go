@@ -0,0 +1,35 @@
+//go:build windows
+
+package importer
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// setBirthTime sets the creation time of a file.
+func setBirthTime(path string, when time.Time) error {
+	// Unlike the os package the Windows API needs the extended-length prefix for long paths.
+	if len(path) >= windows.MAX_PATH-12 {
+		path = strings.ReplaceAll(path, "/", `\`)
+		if strings.HasPrefix(path, `\\`) {
+			path = longPathUNCPrefix + path[2:]
+		} else {
+			path = longPathPrefix + path
+		}
+	}
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	handle, err := windows.CreateFile(pathPtr, windows.FILE_WRITE_ATTRIBUTES,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = windows.CloseHandle(handle) }()
+	creation := windows.NsecToFiletime(when.UnixNano())
+	return windows.SetFileTime(handle, &creation, nil, nil)
+}
@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// DefaultBurstGap is the longest time between consecutive frames of a burst unless specified.
+const DefaultBurstGap = 2 * time.Second
+
+// BurstOptions specifies how bursts are detected.
+type BurstOptions struct {
+	// Gap is the longest time between consecutive frames of a burst, DefaultBurstGap if zero.
+	Gap time.Duration
+	// LinkDir is a directory into which a symbolic link to the best frame of each burst is put,
+	// empty for none.
+	LinkDir string
+}
+
+// MarkBursts finds bursts of JPEG files (captured less than the gap apart from the same source directory)
+// in the catalog, scores each frame for sharpness and exposure (see Sharpness),
+// and marks the best frame of each burst in the catalog.
+// Returns the number of bursts.
+func (imp *Importer) MarkBursts(options *BurstOptions) (int, error) {
+	if imp.options.Catalog == nil {
+		return 0, errors.New("burst detection requires a catalog")
+	}
+	gap := options.Gap
+	if gap <= 0 {
+		gap = DefaultBurstGap
+	}
+	if options.LinkDir != "" {
+		if err := os.MkdirAll(options.LinkDir, 0755); err != nil {
+			return 0, fmt.Errorf("make link dir: %w", err)
+		}
+	}
+	var count int
+	for _, burst := range bursts(imp.options.Catalog.Originals(), gap) {
+		best, err := imp.markBurst(burst)
+		if err != nil {
+			return count, err
+		}
+		count++
+		if options.LinkDir != "" && best != "" {
+			link := filepath.Join(options.LinkDir, filepath.Base(best))
+			_ = os.Remove(link)
+			if err := os.Symlink(best, link); err != nil {
+				return count, fmt.Errorf("link best frame: %w", err)
+			}
+		}
+	}
+	return count, nil
+}
+
+// bursts groups the JPEG files in the catalog by source directory (i.e. camera card)
+// and returns the runs of two or more files captured within the gap of each other.
+func bursts(files []*catalog.File, gap time.Duration) [][]*catalog.File {
+	cameras := make(map[string][]*catalog.File)
+	for _, file := range files {
+		if isJPEG(file.Path) && file.Offloaded == "" {
+			camera := cameraOf(file)
+			cameras[camera] = append(cameras[camera], file)
+		}
+	}
+	var result [][]*catalog.File
+	for _, frames := range cameras {
+		sort.Slice(frames, func(i, j int) bool {
+			return captureBefore(frames[i], frames[j])
+		})
+		start := 0
+		for i := 1; i <= len(frames); i++ {
+			if i == len(frames) || frames[i].Captured.Sub(frames[i-1].Captured) > gap {
+				if i-start > 1 {
+					result = append(result, frames[start:i])
+				}
+				start = i
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i][0].Captured.Before(result[j][0].Captured)
+	})
+	return result
+}
+
+// markBurst scores the frames of a burst and records them in the catalog.
+// The burst is identified by the catalog path of its first frame.
+// Returns the path of the best frame, empty if no frame could be scored.
+func (imp *Importer) markBurst(frames []*catalog.File) (string, error) {
+	scores := make([]float64, len(frames))
+	best := -1
+	for i, frame := range frames {
+		score, err := Sharpness(imp.catalogPath(frame))
+		if err != nil {
+			log.Warn().Err(err).Str("path", frame.Path).Msg("Score burst frame")
+			continue
+		}
+		scores[i] = score
+		if best < 0 || score > scores[best] {
+			best = i
+		}
+	}
+	for i, frame := range frames {
+		marked := *frame
+		marked.Burst, marked.Score, marked.Best = frames[0].Path, scores[i], i == best
+		if marked.Burst == frame.Burst && marked.Score == frame.Score && marked.Best == frame.Best {
+			continue
+		}
+		if err := imp.options.Catalog.AddFile(&marked); err != nil {
+			return "", fmt.Errorf("catalog burst frame: %w", err)
+		}
+	}
+	if best < 0 {
+		return "", nil
+	}
+	log.Debug().Str("burst", frames[0].Path).Int("frames", len(frames)).Str("best", frames[best].Path).Msg("Marked burst")
+	return imp.catalogPath(frames[best]), nil
+}
+
+// catalogPath returns the absolute path of a file in the catalog.
+func (imp *Importer) catalogPath(file *catalog.File) string {
+	root := file.Root
+	if root == "" {
+		root = imp.target
+	}
+	path := filepath.Join(root, filepath.FromSlash(file.Path))
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,15 @@
+//go:build linux
+
+package importer
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// dropCache advises the kernel to drop cached pages of the file
+// so that it is read from the device again.
+func dropCache(file *os.File) {
+	_ = unix.Fadvise(int(file.Fd()), 0, 0, unix.FADV_DONTNEED)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package importer
+
+import "os"
+
+// dropCache is not supported, cached file pages may inflate read throughput.
+func dropCache(*os.File) {}
@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// MapCamera is a camera placed on a map with its latest captures.
+type MapCamera struct {
+	// Camera is the source directory of the camera's files (i.e. its card).
+	Camera string
+	// Location is the name of the location of the camera's current (or latest) deployment, if any.
+	Location string
+	// Position is where the camera is, nil if unknown.
+	Position *catalog.Position
+	Files    int
+	// Last is the latest capture, nil if the camera has captured nothing.
+	Last *catalog.File
+	// Recent are the latest captures with thumbnails, newest first.
+	Recent []*Event
+}
+
+// CameraMap returns each camera in the catalog in camera order with thumbnails of up to
+// the specified number of its latest JPEG captures.
+// A camera is placed at the position of its current (or latest) deployment with one (see deploy),
+// otherwise at the position of its latest capture with one (e.g. from a GPX track).
+// Cameras with deployments but no captures are included.
+func (imp *Importer) CameraMap(recent int) ([]*MapCamera, error) {
+	if imp.options.Catalog == nil {
+		return nil, errors.New("maps require a catalog")
+	}
+	cameras := make(map[string]*MapCamera)
+	camera := func(name string) *MapCamera {
+		if cameras[name] == nil {
+			cameras[name] = &MapCamera{Camera: name}
+		}
+		return cameras[name]
+	}
+	jpegs := make(map[string][]*catalog.File)
+	positioned := make(map[string]*catalog.File)
+	for _, file := range imp.options.Catalog.Originals() {
+		name := cameraOf(file)
+		c := camera(name)
+		c.Files++
+		if c.Last == nil || file.Captured.After(c.Last.Captured) {
+			c.Last = file
+		}
+		if file.Position != nil && (positioned[name] == nil || file.Captured.After(positioned[name].Captured)) {
+			positioned[name] = file
+		}
+		if isJPEG(file.Path) && file.Offloaded == "" {
+			jpegs[name] = append(jpegs[name], file)
+		}
+	}
+	for name, file := range positioned {
+		cameras[name].Position = file.Position
+	}
+	// Deployments are ordered by installation, so the latest of each camera wins.
+	for _, deployment := range imp.options.Catalog.Deployments() {
+		c := camera(deployment.Camera)
+		c.Location = deployment.Location
+		if deployment.Position != nil {
+			c.Position = deployment.Position
+		}
+	}
+
+	result := make([]*MapCamera, 0, len(cameras))
+	for name, c := range cameras {
+		files := jpegs[name]
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Captured.After(files[j].Captured)
+		})
+		if len(files) > recent {
+			files = files[:recent]
+		}
+		for _, file := range files {
+			thumbnail, err := Thumbnail(imp.catalogPath(file), thumbnailWidth)
+			if err != nil {
+				log.Warn().Err(err).Str("path", file.Path).Msg("Thumbnail")
+			}
+			c.Recent = append(c.Recent, &Event{File: file, Thumbnail: thumbnail})
+		}
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Camera < result[j].Camera
+	})
+	return result, nil
+}
@@ -0,0 +1,18 @@
+package importer
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// CardIdentity returns the identity of the device (e.g. an SD card) containing the path
+// for recording with an import session, nil if it can't be determined. Failures are logged.
+func CardIdentity(path string) *catalog.Card {
+	card, err := cardIdentity(plainPath(path))
+	if err != nil {
+		log.Debug().Err(err).Str("path", path).Msg("Card identity")
+		return nil
+	}
+	return card
+}
@@ -0,0 +1,97 @@
+//go:build linux
+
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// cardIdentity finds the block device of the file system containing the path through sysfs
+// and its volume UUID, label, and reader through the udev /dev/disk links.
+// The hardware serial number is only available for cards in MMC (e.g. built-in) readers.
+func cardIdentity(path string) (*catalog.Card, error) {
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+	sys, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", unix.Major(stat.Dev), unix.Minor(stat.Dev)))
+	if err != nil {
+		return nil, fmt.Errorf("not on a block device: %w", err)
+	}
+	name := filepath.Base(sys)
+	card := &catalog.Card{Device: "/dev/" + name}
+	card.Volume = diskLink("uuid", name)
+	card.Label = diskLink("label", name)
+	// The by-id link names the disk (the reader, or the card in an MMC reader) rather than the partition.
+	if id := diskLink("id", name); id != "" {
+		if i := strings.LastIndex(id, "-part"); i > 0 {
+			id = id[:i]
+		}
+		card.Reader = id
+	}
+	// Partitions are beneath their disk, e.g. .../block/mmcblk0/mmcblk0p1.
+	disk := sys
+	if _, err := os.Stat(filepath.Join(sys, "partition")); err == nil {
+		disk = filepath.Dir(sys)
+	}
+	if serial := sysAttr(filepath.Join(disk, "device", "serial")); serial != "" {
+		card.Serial = serial
+		if name := sysAttr(filepath.Join(disk, "device", "name")); name != "" {
+			card.Serial = name + " " + serial
+		}
+	}
+	return card, nil
+}
+
+// diskLink returns the name of the first /dev/disk/by-KIND link to the device,
+// with udev escapes (e.g. \x20 for spaces) decoded, empty if none.
+func diskLink(kind, device string) string {
+	dir := filepath.Join("/dev/disk", "by-"+kind)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var found string
+	for _, entry := range entries {
+		if target, err := os.Readlink(filepath.Join(dir, entry.Name())); err == nil && filepath.Base(target) == device {
+			// Prefer USB ids (e.g. usb-Generic_STORAGE_DEVICE_000000000819-0:0) to wwn- and others.
+			if found == "" || strings.HasPrefix(entry.Name(), "usb-") || strings.HasPrefix(entry.Name(), "mmc-") {
+				found = entry.Name()
+			}
+		}
+	}
+	return udevUnescape(found)
+}
+
+// udevUnescape decodes \xHH escapes in udev link names.
+func udevUnescape(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) && name[i+1] == 'x' {
+			if c, err := strconv.ParseUint(name[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// sysAttr returns the trimmed contents of a sysfs attribute file, empty if none.
+func sysAttr(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
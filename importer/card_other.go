@@ -0,0 +1,14 @@
+//go:build !linux && !windows
+
+package importer
+
+import (
+	"errors"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// cardIdentity is not supported.
+func cardIdentity(string) (*catalog.Card, error) {
+	return nil, errors.New("not supported")
+}
@@ -0,0 +1,34 @@
+//go:build windows
+
+package importer
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// cardIdentity returns the volume (e.g. E:\), label, and volume serial number of the path.
+// The card reader isn't identified.
+func cardIdentity(path string) (*catalog.Card, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	root := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(pathPtr, &root[0], uint32(len(root))); err != nil {
+		return nil, fmt.Errorf("volume path: %w", err)
+	}
+	label := make([]uint16, windows.MAX_PATH+1)
+	var serial uint32
+	if err := windows.GetVolumeInformation(&root[0], &label[0], uint32(len(label)), &serial, nil, nil, nil, 0); err != nil {
+		return nil, fmt.Errorf("volume information: %w", err)
+	}
+	return &catalog.Card{
+		Device: windows.UTF16ToString(root),
+		Volume: fmt.Sprintf("%04X-%04X", serial>>16, serial&0xFFFF),
+		Label:  windows.UTF16ToString(label),
+	}, nil
+}
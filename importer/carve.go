@@ -0,0 +1,202 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	carveSectorSize = 512
+	carveChunkSize  = 2048 * carveSectorSize
+	carveMaxJPEG    = 64 << 20
+)
+
+var (
+	errCarveEnd = errors.New("end of carved file not found")
+
+	// mp4TopLevelBoxes are the box types expected at the top level of an MP4 file.
+	mp4TopLevelBoxes = map[string]bool{
+		"ftyp": true, "free": true, "mdat": true, "meta": true, "mfra": true, "moof": true,
+		"moov": true, "pdin": true, "sidx": true, "skip": true, "styp": true, "udta": true,
+		"uuid": true, "wide": true,
+	}
+)
+
+// Carve scans a raw device or disk image for JPEG and MP4 files
+// and writes any that are found into the specified directory.
+// Files on camera cards are written in whole clusters so only sector boundaries are checked.
+// Files are assumed to be contiguous, which is usual for cards that have only been
+// written by a camera since formatting. Returns the paths of the recovered files.
+func Carve(device, dir string) ([]string, error) {
+	file, err := os.Open(device)
+	if err != nil {
+		return nil, fmt.Errorf("open device: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	// Block devices report zero size from Stat() so seek to the end instead.
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("get device size: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0766); err != nil {
+		return nil, fmt.Errorf("make recovery dir: %w", err)
+	}
+
+	var recovered []string
+	chunk := make([]byte, carveChunkSize)
+	for chunkOffset := int64(0); chunkOffset < size; chunkOffset += carveChunkSize {
+		n, err := file.ReadAt(chunk, chunkOffset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return recovered, fmt.Errorf("read device at %d: %w", chunkOffset, err)
+		}
+		for sector := 0; sector+8 <= n; sector += carveSectorSize {
+			offset := chunkOffset + int64(sector)
+			var ext string
+			var length int64
+			switch header := chunk[sector:]; {
+			case header[0] == 0xFF && header[1] == jpegMarkerSOI && header[2] == 0xFF:
+				ext = ".jpg"
+				length, err = jpegCarveLength(io.NewSectionReader(file, offset, carveMaxJPEG))
+			case string(header[4:8]) == "ftyp":
+				ext = ".mp4"
+				length, err = mp4CarveLength(file, offset, size)
+			default:
+				continue
+			}
+			if err != nil {
+				log.Debug().Err(err).Int64("offset", offset).Str("ext", ext).Msg("Carve failed")
+				continue
+			}
+			path := filepath.Join(dir, "REC_"+strconv.FormatInt(offset, 16)+ext)
+			if err := carveFile(file, offset, length, path); err != nil {
+				return recovered, err
+			}
+			log.Info().Int64("offset", offset).Int64("length", length).Str("path", path).Msg("Carved file")
+			recovered = append(recovered, path)
+			// Continue with the first sector after the carved file.
+			next := offset + (length+carveSectorSize-1)/carveSectorSize*carveSectorSize
+			if next >= chunkOffset+carveChunkSize {
+				chunkOffset = next - carveChunkSize
+				break
+			}
+			sector = int(next-chunkOffset) - carveSectorSize
+		}
+	}
+	return recovered, nil
+}
+
+func carveFile(file *os.File, offset, length int64, path string) error {
+	target, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create carved file: %w", err)
+	}
+	defer func() { _ = target.Close() }()
+	if _, err := io.Copy(target, io.NewSectionReader(file, offset, length)); err != nil {
+		return fmt.Errorf("copy carved file: %w", err)
+	}
+	return nil
+}
+
+// jpegCarveLength returns the length of the JPEG file at the start of the reader
+// by following the segment structure to the end of image marker.
+// Embedded thumbnails are skipped within their APP1 segment.
+func jpegCarveLength(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+	var length int64
+	readByte := func() (byte, error) {
+		b, err := reader.ReadByte()
+		if err == nil {
+			length++
+		}
+		return b, err
+	}
+	if _, err := reader.Discard(2); err != nil {
+		return 0, err
+	}
+	length = 2
+	inScan := false
+	for {
+		b, err := readByte()
+		if err != nil {
+			return 0, errCarveEnd
+		}
+		if b != 0xFF {
+			if inScan {
+				continue
+			}
+			return 0, fmt.Errorf("bad JPEG marker at %d", length-1)
+		}
+		marker, err := readByte()
+		for err == nil && marker == 0xFF {
+			marker, err = readByte()
+		}
+		if err != nil {
+			return 0, errCarveEnd
+		}
+		switch {
+		case marker == jpegMarkerEOI:
+			return length, nil
+		case inScan && (marker == 0x00 || marker >= 0xD0 && marker <= 0xD7):
+			// Stuffed byte or restart marker within entropy coded data.
+			continue
+		case marker == 0x01 || marker >= 0xD0 && marker <= 0xD7:
+			// Standalone markers without length.
+			continue
+		}
+		var segmentLength [2]byte
+		if _, err := io.ReadFull(reader, segmentLength[:]); err != nil {
+			return 0, errCarveEnd
+		}
+		skip := int(binary.BigEndian.Uint16(segmentLength[:])) - 2
+		if skip < 0 {
+			return 0, fmt.Errorf("bad JPEG segment length at %d", length)
+		} else if _, err := reader.Discard(skip); err != nil {
+			return 0, errCarveEnd
+		}
+		length += int64(2 + skip)
+		inScan = marker == jpegMarkerSOS
+	}
+}
+
+// mp4CarveLength returns the length of the MP4 file starting at the offset
+// by following the top level boxes until something that is not a box
+// (or the start of the next file) is found.
+func mp4CarveLength(file io.ReaderAt, start, size int64) (int64, error) {
+	header := make([]byte, 16)
+	offset := start
+	for offset+8 <= size {
+		if _, err := file.ReadAt(header[:8], offset); err != nil {
+			return 0, err
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header))
+		boxType := string(header[4:8])
+		if !mp4TopLevelBoxes[boxType] || (boxType == "ftyp") != (offset == start) {
+			break
+		}
+		if boxSize == 1 {
+			if offset+16 > size {
+				break
+			} else if _, err := file.ReadAt(header[8:16], offset+8); err != nil {
+				return 0, err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		// Compared with the remaining size, as a 64-bit size can overflow the offset.
+		if boxSize < 8 || boxSize > size-offset {
+			break
+		}
+		offset += boxSize
+	}
+	if offset == start {
+		return 0, fmt.Errorf("no MP4 boxes")
+	}
+	return offset - start, nil
+}
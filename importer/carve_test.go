@@ -0,0 +1,158 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/madkins23/gardepro/fixture"
+)
+
+// carveJPEG returns a synthesized JPEG file for carving.
+func carveJPEG(t *testing.T) []byte {
+	t.Helper()
+	spec := fixture.JPEG{Captured: testTime}
+	data, err := spec.Bytes()
+	if err != nil {
+		t.Fatalf("synthesize JPEG: %s", err)
+	}
+	return data
+}
+
+// join returns the concatenation of the slices.
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// largeBox returns the header of an MP4 box of the type with a 64-bit size.
+func largeBox(kind string, size uint64) []byte {
+	header := make([]byte, 16)
+	binary.BigEndian.PutUint32(header, 1)
+	copy(header[4:], kind)
+	binary.BigEndian.PutUint64(header[8:], size)
+	return header
+}
+
+func TestJPEGCarveLength(t *testing.T) {
+	jpeg := carveJPEG(t)
+	tests := []struct {
+		name   string
+		data   []byte
+		length int
+		err    string
+	}{
+		{name: "whole", data: jpeg, length: len(jpeg)},
+		{name: "trailing data", data: join(jpeg, []byte{0xFF, 0xD8, 0xFF, 0xE0}, make([]byte, 100)), length: len(jpeg)},
+		{name: "fill bytes", data: []byte{0xFF, 0xD8, 0xFF, 0xFF, 0xFF, 0xD9}, length: 6},
+		{name: "truncated", data: jpeg[:len(jpeg)/2], err: errCarveEnd.Error()},
+		{name: "no end", data: jpeg[:len(jpeg)-2], err: errCarveEnd.Error()},
+		{name: "only start", data: []byte{0xFF, 0xD8}, err: errCarveEnd.Error()},
+		{name: "empty", data: nil, err: "EOF"},
+		{name: "truncated marker", data: []byte{0xFF, 0xD8, 0xFF}, err: errCarveEnd.Error()},
+		{name: "truncated segment length", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00}, err: errCarveEnd.Error()},
+		{name: "truncated segment", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x10, 1, 2, 3}, err: errCarveEnd.Error()},
+		{name: "bad segment length", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 0xFF, 0xD9}, err: "bad JPEG segment length"},
+		{name: "garbage", data: []byte{0xFF, 0xD8, 0x12, 0x34, 0xFF, 0xD9}, err: "bad JPEG marker at 2"},
+		{name: "garbage after segment", data: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x02, 0x00, 0xFF, 0xD9}, err: "bad JPEG marker at 6"},
+		{name: "scan", data: []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02, 1, 0xFF, 0x00, 2, 0xFF, 0xD0, 3, 0xFF, 0xD9}, length: 15},
+		{name: "scan without end", data: []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02, 1, 2, 3}, err: errCarveEnd.Error()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			length, err := jpegCarveLength(bytes.NewReader(test.data))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error %v, want %q", err, test.err)
+				}
+			} else if err != nil {
+				t.Errorf("carve: %s", err)
+			} else if length != int64(test.length) {
+				t.Errorf("length %d, want %d", length, test.length)
+			}
+		})
+	}
+}
+
+func TestMP4CarveLength(t *testing.T) {
+	mp4 := (&fixture.MP4{Created: testTime}).Bytes()
+	ftyp := mp4[:binary.BigEndian.Uint32(mp4)]
+	mdat := largeBox("mdat", 16+4)
+	tests := []struct {
+		name   string
+		data   []byte
+		length int
+		err    string
+	}{
+		{name: "whole", data: mp4, length: len(mp4)},
+		{name: "zero padding", data: join(mp4, make([]byte, 512)), length: len(mp4)},
+		{name: "next file", data: join(mp4, mp4), length: len(mp4)},
+		{name: "garbage", data: join(mp4, []byte("\x00\x00\x00\x10junkjunkjunk")), length: len(mp4)},
+		{name: "truncated box", data: mp4[:len(mp4)-1], length: len(mp4) - 12},
+		{name: "only ftyp", data: ftyp, length: len(ftyp)},
+		{name: "short box", data: join(ftyp, []byte("\x00\x00\x00\x04moov")), length: len(ftyp)},
+		{name: "large box", data: join(ftyp, mdat, []byte{1, 2, 3, 4}), length: len(ftyp) + 20},
+		{name: "truncated large box", data: join(ftyp, mdat[:12]), length: len(ftyp)},
+		{name: "large box past end", data: join(ftyp, largeBox("mdat", 1<<40)), length: len(ftyp)},
+		{name: "large box overflow", data: join(ftyp, largeBox("mdat", math.MaxInt64-8)), length: len(ftyp)},
+		{name: "negative large box", data: join(ftyp, largeBox("mdat", math.MaxUint64)), length: len(ftyp)},
+		{name: "no ftyp", data: mp4[len(ftyp):], err: "no MP4 boxes"},
+		{name: "bad ftyp size", data: join([]byte("\x00\x00\x00\x00ftyp"), mp4[8:]), err: "no MP4 boxes"},
+		{name: "too short", data: mp4[:7], err: "no MP4 boxes"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			length, err := mp4CarveLength(bytes.NewReader(test.data), 0, int64(len(test.data)))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error %v, want %q", err, test.err)
+				}
+			} else if err != nil {
+				t.Errorf("carve: %s", err)
+			} else if length != int64(test.length) {
+				t.Errorf("length %d, want %d", length, test.length)
+			}
+		})
+	}
+}
+
+func TestCarve(t *testing.T) {
+	jpeg := carveJPEG(t)
+	mp4 := (&fixture.MP4{Created: testTime}).Bytes()
+	// A JPEG and an MP4 file in whole sectors after a sector of garbage, and a damaged JPEG file.
+	sectors := func(data []byte) []byte {
+		return join(data, make([]byte, (carveSectorSize-len(data)%carveSectorSize)%carveSectorSize))
+	}
+	image := join(sectors(bytes.Repeat([]byte{0xA5}, 100)), sectors(jpeg), sectors(mp4), sectors(jpeg[:len(jpeg)/2]))
+	device := writeFile(t, t.TempDir(), "card.img", image)
+	dir := filepath.Join(t.TempDir(), "recovered")
+	paths, err := Carve(device, dir)
+	if err != nil {
+		t.Fatalf("carve: %s", err)
+	}
+	want := map[string][]byte{
+		"REC_200.jpg": jpeg,
+		"REC_" + strconv.FormatInt(int64(carveSectorSize+len(sectors(jpeg))), 16) + ".mp4": mp4,
+	}
+	if len(paths) != len(want) {
+		t.Errorf("recovered %v, want %d files", paths, len(want))
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("read recovered file: %s", err)
+		} else if expected, ok := want[filepath.Base(path)]; !ok {
+			t.Errorf("unexpected file %s", filepath.Base(path))
+		} else if !bytes.Equal(data, expected) {
+			t.Errorf("%s: recovered %d bytes, want %d", filepath.Base(path), len(data), len(expected))
+		}
+	}
+	if _, err := Carve(filepath.Join(dir, "missing.img"), dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("carve missing device: %v", err)
+	}
+}
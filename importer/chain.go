@@ -0,0 +1,87 @@
+package importer
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/chain"
+)
+
+// ChainResult summarizes the check of archived files against a hash chain.
+type ChainResult struct {
+	// Verified is the number of files whose contents match their chained hashes.
+	Verified int
+	// Damaged files don't match their chained hashes, Missing files aren't in the catalog or don't exist.
+	// Both are chained paths.
+	Damaged []string
+	Missing []string
+}
+
+// CheckChain verifies the hash chain of the target root directory with the public key
+// and checks that the archived files match the hashes in the chain.
+// Files are found in the catalog by their chained hashes as well as their paths,
+// so files renamed since they were chained are still checked.
+// Links of files rewritten since (e.g. by fix-time) are superseded by the links of their new hashes,
+// and the files are damaged if there are none.
+// Failed checks are logged. An error is returned if the chain itself doesn't verify.
+func (imp *Importer) CheckChain(public ed25519.PublicKey) (*ChainResult, error) {
+	if imp.options.Catalog == nil {
+		return nil, errors.New("checking the chain requires a catalog")
+	}
+	links, err := chain.Verify(filepath.Join(imp.target, StateDir), public)
+	if err != nil {
+		return nil, fmt.Errorf("verify chain: %w", err)
+	}
+	byHash := make(map[string]*catalog.File)
+	for _, file := range imp.options.Catalog.Files() {
+		byHash[file.Hash] = file
+	}
+	rehashed, err := imp.options.Catalog.Rehashed()
+	if err != nil {
+		return nil, err
+	}
+	chained := make(map[string]bool)
+	for _, link := range links {
+		chained[link.Hash] = true
+	}
+	result := &ChainResult{}
+	for _, link := range links {
+		file := imp.options.Catalog.File(link.Path)
+		if file == nil || file.Hash != link.Hash {
+			file = byHash[link.Hash]
+		}
+		if file == nil && rehashed[link.Hash] != "" {
+			if current := imp.options.Catalog.File(rehashed[link.Hash]); current != nil && chained[current.Hash] {
+				continue
+			}
+			log.Error().Str("path", rehashed[link.Hash]).Int("link", link.Seq).Msg("Chained file rewritten and not chained again")
+			result.Damaged = append(result.Damaged, link.Path)
+			continue
+		} else if file == nil {
+			log.Error().Str("path", link.Path).Int("link", link.Seq).Msg("Chained file not in catalog")
+			result.Missing = append(result.Missing, link.Path)
+			continue
+		} else if file.Offloaded != "" {
+			continue
+		}
+		path := imp.catalogPath(file)
+		algorithm, expected := splitHash(link.Hash)
+		sum, err := hashFile(path, algorithm)
+		if errors.Is(err, os.ErrNotExist) {
+			log.Error().Str("path", file.Path).Int("link", link.Seq).Msg("Chained file missing")
+			result.Missing = append(result.Missing, link.Path)
+		} else if err != nil || sum != expected {
+			log.Error().Err(err).Str("path", file.Path).Int("link", link.Seq).Msg("Chained file differs from chain")
+			result.Damaged = append(result.Damaged, link.Path)
+		} else {
+			result.Verified++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// ClockJump is a probable change of a camera clock (e.g. by a battery change or daylight saving time)
+// found where the capture times of a camera's files go backwards while their sequence numbers increase.
+type ClockJump struct {
+	// Camera is the source directory of the camera's files (i.e. its card).
+	Camera string
+	// Before is the last file before the jump and After the first file after it, in sequence order.
+	Before, After *catalog.File
+	// Offset is the correction of the files after the jump which would make After
+	// as late as Before, the least the clock must have gone back.
+	Offset time.Duration
+	// Files are the files after the jump (until the next one), in sequence order.
+	Files []*catalog.File
+}
+
+// sequencePattern matches the sequence number of a camera file name, e.g. 0042 in IMG_0042.JPG
+// or PICT0042.AVI. Longer runs of digits are dates or times rather than sequence numbers.
+var sequencePattern = regexp.MustCompile(`(?:^|\D)(\d{3,5})$`)
+
+// sequenceNumber returns the sequence number in the name of a source file, false if none.
+func sequenceNumber(source string) (int, bool) {
+	base := filepath.Base(source)
+	match := sequencePattern.FindStringSubmatch(strings.TrimSuffix(base, filepath.Ext(base)))
+	if match == nil {
+		return 0, false
+	}
+	number, err := strconv.Atoi(match[1])
+	return number, err == nil
+}
+
+// captureBefore returns whether file a was captured before file b.
+// Capture times are whole seconds, so files captured in the same second (e.g. burst frames)
+// are ordered by the sequence numbers in their source names, which the camera counts up,
+// and otherwise by path.
+func captureBefore(a, b *catalog.File) bool {
+	if !a.Captured.Equal(b.Captured) {
+		return a.Captured.Before(b.Captured)
+	}
+	if aNumber, ok := sequenceNumber(a.Source); ok {
+		if bNumber, ok := sequenceNumber(b.Source); ok && aNumber != bNumber {
+			return aNumber < bNumber
+		}
+	}
+	return a.Path < b.Path
+}
+
+// ClockJumps returns the probable clock changes of each camera whose files are in the catalog
+// and (if within is not empty) were imported from beneath the source directory within.
+// Files are ordered by the sequence numbers in their source names and a jump is found
+// where a file was captured more than the tolerance before the file preceding it.
+// Files without sequence numbers are ignored.
+func ClockJumps(cat *catalog.Catalog, within string, tolerance time.Duration) []*ClockJump {
+	if within != "" && !IsMTP(within) {
+		if abs, err := filepath.Abs(within); err == nil {
+			within = abs
+		}
+	}
+	type numbered struct {
+		file   *catalog.File
+		number int
+	}
+	cameras := make(map[string][]numbered)
+	for _, file := range cat.Originals() {
+		if within != "" && file.Source != within && !strings.HasPrefix(file.Source, within+string(filepath.Separator)) {
+			continue
+		}
+		// The sequence is that of the card, so files are grouped by source directory even if recognized
+		// as another camera's.
+		if number, ok := sequenceNumber(file.Source); ok && !file.Captured.IsZero() {
+			camera := filepath.Dir(file.Source)
+			cameras[camera] = append(cameras[camera], numbered{file: file, number: number})
+		}
+	}
+	var jumps []*ClockJump
+	for camera, files := range cameras {
+		sort.Slice(files, func(i, j int) bool {
+			if files[i].number != files[j].number {
+				return files[i].number < files[j].number
+			}
+			return files[i].file.Source < files[j].file.Source
+		})
+		var jump *ClockJump
+		for i, current := range files {
+			if i > 0 {
+				previous := files[i-1].file
+				if current.file.Captured.Before(previous.Captured.Add(-tolerance)) {
+					jump = &ClockJump{Camera: camera, Before: previous, After: current.file,
+						Offset: previous.Captured.Sub(current.file.Captured)}
+					jumps = append(jumps, jump)
+				}
+			}
+			if jump != nil {
+				jump.Files = append(jump.Files, current.file)
+			}
+		}
+	}
+	sort.Slice(jumps, func(i, j int) bool {
+		if jumps[i].Camera != jumps[j].Camera {
+			return jumps[i].Camera < jumps[j].Camera
+		}
+		return jumps[i].After.Source < jumps[j].After.Source
+	})
+	return jumps
+}
@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// copyDistance is the largest number of differing bits between the fingerprints (see Fingerprint)
+// of copies of the same capture at different resolutions or compression.
+// Frames of the same burst have the same dimensions and aren't compared.
+const copyDistance = 4
+
+// linkCopies looks for cataloged copies of the capture in the archived JPEG file at the path
+// at other resolutions, e.g. the low resolution copy emailed by a cellular camera or uploaded
+// by the phone app and the full resolution file later imported from the card.
+// Copies are captured at the same time (after any clock offset) with the same scene (see Fingerprint)
+// by the same camera (as far as known from recognition or EXIF Model) and have different dimensions.
+// A smaller file is linked to the largest copy as its Original, and smaller copies not yet linked
+// to a file as large are linked to a larger file, updating their catalog entries.
+// Returns whether any were linked, in which case the capture has already been notified.
+func (imp *Importer) linkCopies(file *catalog.File, path string) bool {
+	if imp.options.Catalog == nil || !isJPEG(path) {
+		return false
+	}
+	width, height, err := jpegSize(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Read image size")
+		return false
+	}
+	var fingerprint string
+	largest, largestPixels := file, width*height
+	var copies []*catalog.File
+	for _, other := range imp.options.Catalog.Files() {
+		if other.Path == file.Path || !other.Captured.Equal(file.Captured) || !isJPEG(other.Path) {
+			continue
+		} else if other.Derivative() && other.Derivation != catalog.DerivationLowRes {
+			// Other derivatives (e.g. crops) were linked on purpose.
+			continue
+		}
+		otherPath := imp.catalogPath(other)
+		otherWidth, otherHeight, err := jpegSize(otherPath)
+		if err != nil || otherWidth == width && otherHeight == height || !sameCamera(file, path, other, otherPath) {
+			continue
+		}
+		if fingerprint == "" {
+			if fingerprint, err = Fingerprint(path); err != nil {
+				log.Warn().Err(err).Str("path", path).Msg("Fingerprint file")
+				return false
+			}
+		}
+		if otherFingerprint, err := Fingerprint(otherPath); err != nil || fingerprintDifference(fingerprint, otherFingerprint) > copyDistance {
+			continue
+		}
+		copies = append(copies, other)
+		if pixels := otherWidth * otherHeight; pixels > largestPixels {
+			largest, largestPixels = other, pixels
+		}
+	}
+	if largest != file {
+		log.Info().Str("path", file.Path).Str("original", largest.Path).Msg("Linked copy of capture")
+		file.Original, file.Derivation = largest.Path, catalog.DerivationLowRes
+		return true
+	}
+	var linked bool
+	for _, copied := range copies {
+		// Copies already linked to another file as large (e.g. an earlier frame of a burst) are left alone.
+		if original := imp.options.Catalog.File(copied.Original); original != nil {
+			if originalWidth, originalHeight, err := jpegSize(imp.catalogPath(original)); err == nil && originalWidth*originalHeight >= largestPixels {
+				continue
+			}
+		}
+		log.Info().Str("path", copied.Path).Str("original", file.Path).Msg("Linked copy of capture")
+		updated := *copied
+		updated.Original, updated.Derivation = file.Path, catalog.DerivationLowRes
+		if err := imp.options.Catalog.AddFile(&updated); err != nil {
+			log.Warn().Err(err).Str("path", copied.Path).Msg("Link copy of capture")
+		}
+		linked = true
+	}
+	return linked
+}
+
+// sameCamera returns whether two files may have been captured by the same camera:
+// they aren't recognized as different cameras and don't have different EXIF Models.
+// The source directories of copies differ (e.g. a card and a sync folder) so aren't compared.
+func sameCamera(a *catalog.File, aPath string, b *catalog.File, bPath string) bool {
+	if a.Camera != "" && b.Camera != "" {
+		return a.Camera == b.Camera
+	}
+	aModel, bModel := exifModel(aPath), exifModel(bPath)
+	return aModel == "" || bModel == "" || aModel == bModel
+}
+
+// exifModel returns the EXIF Model of a JPEG file, empty if none.
+func exifModel(path string) string {
+	metadata, err := exifRead(path)
+	if err != nil {
+		return ""
+	}
+	model, err := metadata.value(tagIDModel)
+	if err != nil {
+		return ""
+	}
+	modelStr, _ := model.(string)
+	return strings.TrimSpace(modelStr)
+}
+
+// jpegSize returns the dimensions of a JPEG image, read from its header.
+func jpegSize(path string) (int, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	config, err := jpeg.DecodeConfig(bufio.NewReaderSize(file, streamBufferSize))
+	if err != nil {
+		return 0, 0, fmt.Errorf("decode JPEG header: %w", err)
+	}
+	return config.Width, config.Height, nil
+}
+
+// repeat returns whether the file at the target path is a copy of a capture already imported
+// (see linkCopies), forgetting it.
+func (imp *Importer) repeat(targetPath string) bool {
+	imp.repeatsMutex.Lock()
+	defer imp.repeatsMutex.Unlock()
+	repeat := imp.repeats[targetPath]
+	delete(imp.repeats, targetPath)
+	return repeat
+}
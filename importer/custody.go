@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/chain"
+)
+
+// Custody is the chain of custody of an archived file.
+type Custody struct {
+	File *catalog.File
+	// Session in which the file was imported, nil if unknown.
+	Session *catalog.Session
+	// Hash is the current hash of the archived file (of the cataloged algorithm),
+	// empty if it couldn't be read (see Err) or is offloaded.
+	Hash string
+	// Intact is true if the current hash matches the catalog,
+	// or the hash chain link if the chain was checked.
+	Intact bool
+	// Err is why the archived file couldn't be hashed, nil if it was.
+	Err error
+	// Link is the hash chain link of the file, nil if none or the chain wasn't checked.
+	Link *chain.Link
+	// ChainErr is why the hash chain didn't verify, nil if it did or wasn't checked.
+	ChainErr error
+	// Timestamp is the path of the first RFC 3161 time stamp response covering the link, empty if none.
+	Timestamp string
+	// Events are the changes to the catalog entry of the file, oldest first.
+	Events []CustodyEvent
+}
+
+// CustodyEvent is a change to the catalog entry of a file.
+type CustodyEvent struct {
+	Time time.Time
+	// What describes the change as an English fmt format (e.g. for translation) of the Args.
+	What string
+	Args []interface{}
+}
+
+// Custody returns the chain of custody of an archived file at a path relative to its root:
+// its provenance, its hashes now and when imported, its hash chain link and time stamp
+// (if public is not nil), and the history of its catalog entry, e.g. verifications by scrub.
+func (imp *Importer) Custody(path string, public ed25519.PublicKey) (*Custody, error) {
+	if imp.options.Catalog == nil {
+		return nil, errors.New("custody requires a catalog")
+	}
+	file := imp.options.Catalog.File(path)
+	if file == nil {
+		return nil, fmt.Errorf("not in catalog: %s", path)
+	}
+	custody := &Custody{File: file, Session: imp.options.Catalog.Session(file.Session)}
+	if file.Offloaded == "" {
+		algorithm, _ := splitHash(file.Hash)
+		if sum, err := hashFile(imp.catalogPath(file), algorithm); err != nil {
+			custody.Err = err
+		} else {
+			custody.Hash = algorithm + ":" + sum
+			custody.Intact = custody.Hash == file.Hash
+		}
+	}
+	history, err := imp.options.Catalog.History(path)
+	if err != nil {
+		return nil, err
+	}
+	custody.Events = custodyEvents(history)
+	if public != nil {
+		// The contents of a file may have been changed since it was chained (e.g. by fix-time),
+		// in which case the link is found by its hash when imported.
+		hashes := []string{file.Hash}
+		if len(history) > 0 && history[0].File != nil {
+			hashes = append(hashes, history[0].File.Hash)
+		}
+		dir := filepath.Join(imp.target, StateDir)
+		links, err := chain.Verify(dir, public)
+		custody.ChainErr = err
+		for _, hash := range hashes {
+			for _, link := range links {
+				if custody.Link == nil && link.Hash == hash {
+					custody.Link = link
+				}
+			}
+		}
+		if custody.Link != nil {
+			custody.Timestamp = chainTimestamp(dir, custody.Link.Seq)
+		}
+		custody.Intact = err == nil && custody.Link != nil && custody.Hash == custody.Link.Hash
+	}
+	return custody, nil
+}
+
+// chainTimestamp returns the path of the first time stamp response (chain-LINK.tsr)
+// covering the link with the sequence number, empty if none.
+func chainTimestamp(dir string, seq int) string {
+	paths, _ := filepath.Glob(filepath.Join(dir, "chain-*.tsr"))
+	first, found := 0, ""
+	for _, path := range paths {
+		link, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "chain-"), ".tsr"))
+		if err == nil && link >= seq && (found == "" || link < first) {
+			first, found = link, path
+		}
+	}
+	return found
+}
+
+// custodyEvents describes the changes between successive catalog records of a file.
+func custodyEvents(history []*catalog.Record) []CustodyEvent {
+	var events []CustodyEvent
+	var prev *catalog.File
+	for _, record := range history {
+		event := CustodyEvent{Time: record.Time}
+		switch file := record.File; {
+		case record.Move != nil:
+			event.What, event.Args = "renamed from %s", []interface{}{record.Move.From}
+		case prev == nil:
+			event.What, event.Args = "imported from %s with hash %s", []interface{}{file.Source, file.Hash}
+		case file.Hash != prev.Hash:
+			event.What, event.Args = "contents changed to hash %s", []interface{}{file.Hash}
+		case file.Offloaded != prev.Offloaded && file.Offloaded != "":
+			event.What, event.Args = "offloaded to %s", []interface{}{file.Offloaded}
+		case file.Offloaded != prev.Offloaded:
+			event.What, event.Args = "restored from %s", []interface{}{prev.Offloaded}
+		case !file.Verified.Equal(prev.Verified):
+			event.What = "verified against its hash"
+		default:
+			event.What = "description updated (e.g. tags or rating)"
+		}
+		if record.File != nil {
+			prev = record.File
+		}
+		events = append(events, event)
+	}
+	return events
+}
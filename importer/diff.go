@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// MediaFile identifies a media file by its capture time and contents rather than its name.
+type MediaFile struct {
+	Path     string
+	Captured time.Time
+	// Hash of the file contents as algorithm:hex.
+	Hash string
+	// SourceHash is the hash of the file from which an archived file was imported
+	// if it was modified on import (see catalog.File.SourceHash), empty otherwise.
+	SourceHash string
+}
+
+// key returns the identity of the media file.
+func (m *MediaFile) key() string {
+	return m.Captured.Format(dateFmt+" 15:04:05") + " " + m.Hash
+}
+
+// keys returns the identities by which the media file is found:
+// its own and, for files modified on import, that of the unmodified source contents.
+// The capture time of the source may differ (e.g. by a camera clock offset),
+// so unmodified contents are identified by the hash alone.
+func (m *MediaFile) keys() []string {
+	if m.SourceHash != "" {
+		return []string{m.key(), "source " + m.SourceHash}
+	}
+	return []string{m.key(), "source " + m.Hash}
+}
+
+// Inventory returns the media files beneath a directory, in path order.
+// If the directory is a target root its catalog is used instead of reading every file,
+// except those whose catalog hash is missing or uses another algorithm.
+// Cataloged files that are missing (and not offloaded) are logged and left out.
+func Inventory(dir, algorithm string) ([]*MediaFile, error) {
+	if algorithm == "" {
+		algorithm = HashSHA256
+	}
+	if stat, err := os.Stat(filepath.Join(dir, StateDir, catalog.FileName)); err == nil && !stat.IsDir() {
+		cat, err := OpenCatalog(dir)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = cat.Close() }()
+		return catalogInventory(New(dir, Options{}), cat, algorithm)
+	}
+	paths, err := SourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []*MediaFile
+	for _, path := range paths {
+		file, err := mediaFile(path, algorithm)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Inventory")
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// catalogInventory returns the media files in the catalog of a target.
+func catalogInventory(imp *Importer, cat *catalog.Catalog, algorithm string) ([]*MediaFile, error) {
+	var files []*MediaFile
+	for _, file := range cat.Files() {
+		path := imp.catalogPath(file)
+		if file.Offloaded == "" {
+			if _, err := os.Stat(path); err != nil {
+				log.Warn().Err(err).Str("path", file.Path).Msg("Inventory: cataloged file missing")
+				continue
+			}
+		}
+		if fileAlgorithm, _ := splitHash(file.Hash); file.Hash != "" && fileAlgorithm == algorithm {
+			files = append(files, &MediaFile{Path: path, Captured: file.Captured, Hash: file.Hash, SourceHash: file.SourceHash})
+		} else if file.Offloaded != "" {
+			log.Warn().Str("path", file.Path).Msg("Inventory: offloaded file has no " + algorithm + " hash")
+		} else if media, err := mediaFile(path, algorithm); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Inventory")
+		} else {
+			files = append(files, media)
+		}
+	}
+	return files, nil
+}
+
+// mediaFile reads the capture time and hash of a media file.
+func mediaFile(path, algorithm string) (*MediaFile, error) {
+	when, err := CaptureTime(path)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := hashFile(path, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &MediaFile{Path: path, Captured: when, Hash: algorithm + ":" + sum}, nil
+}
+
+// Diff returns the media files in a that are not in b and those in b that are not in a,
+// comparing their capture times and contents (see Inventory).
+// Archived files modified on import match the files from which they were imported.
+func Diff(a, b []*MediaFile) ([]*MediaFile, []*MediaFile) {
+	return missingFrom(a, b), missingFrom(b, a)
+}
+
+// missingFrom returns the files in a that are not in b, in path order.
+func missingFrom(a, b []*MediaFile) []*MediaFile {
+	keys := make(map[string]bool, 2*len(b))
+	for _, file := range b {
+		for _, key := range file.keys() {
+			keys[key] = true
+		}
+	}
+	var missing []*MediaFile
+	for _, file := range a {
+		found := false
+		for _, key := range file.keys() {
+			found = found || keys[key]
+		}
+		if !found {
+			missing = append(missing, file)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Path < missing[j].Path
+	})
+	return missing
+}
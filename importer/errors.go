@@ -0,0 +1,51 @@
+package importer
+
+import "errors"
+
+// Errors returned (wrapped) from the import pipeline.
+// Use errors.Is() to branch on these and errors.As() with *Error
+// to recover the source and target paths of the failed import.
+var (
+	// ErrNoCaptureTime means the capture time could not be read from the media file.
+	ErrNoCaptureTime = errors.New("no capture time")
+	// ErrCorrupt means the media data in the source file is damaged.
+	// The source file is copied into the quarantine directory instead of the target tree.
+	// Panics while importing a file (e.g. in a parser) are reported as ErrCorrupt.
+	ErrCorrupt = errors.New("corrupt media")
+	// ErrConflict means a different file already exists at the target path.
+	ErrConflict = errors.New("target conflict")
+	// ErrUnsupportedFormat means the source file is not a recognized media format.
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	// ErrTargetUnavailable means the target directory could not be checked or created.
+	ErrTargetUnavailable = errors.New("target unavailable")
+	// ErrTimeout means the import of a file made no progress for too long, e.g. due to a hung device.
+	// The import may still be blocked in the background.
+	ErrTimeout = errors.New("timed out")
+	// ErrAborted means the file was not imported because a strict batch (see Options.Strict)
+	// stopped after another file failed.
+	ErrAborted = errors.New("aborted")
+)
+
+// Error wraps an import pipeline error with the paths involved.
+type Error struct {
+	Source string
+	Target string
+	Err    error
+	// Quarantined is true if the source file was copied into the quarantine directory,
+	// Target is then the path of the copy.
+	Quarantined bool
+}
+
+func (e *Error) Error() string {
+	return e.Source + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Quarantined returns true if the error is from an import that quarantined the source file.
+func Quarantined(err error) bool {
+	var importErr *Error
+	return errors.As(err, &importErr) && importErr.Quarantined
+}
@@ -0,0 +1,250 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dsoprea/go-exif/v3"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	exifTimeFmt             = "2006:01:02 15:04:05"
+	tagIDDateTime           = 0x132
+	tagIDDateTimeOriginal   = 0x9003
+	tagIDDateTimeDigitized  = 0x9004
+	tagIDModel              = 0x110
+	tagIDOffsetTime         = 0x9010
+	tagIDOffsetTimeOriginal = 0x9011
+	tagNameDateTime         = "DateTime"
+	tagNameDateTimeOriginal = "DateTimeOriginal"
+	tagNameModel            = "Model"
+)
+
+// EXIFcaptureTime returns the capture time of a JPEG file from its EXIF data.
+// DateTime is used if present and valid, otherwise DateTimeOriginal or DateTimeDigitized
+// (see quirks.go for the variations between camera brands).
+func EXIFcaptureTime(path string) (time.Time, error) {
+	metadata, err := exifRead(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: get EXIF index: %s", ErrNoCaptureTime, err)
+	}
+	var firstErr error
+	for _, tagID := range []uint16{tagIDDateTime, tagIDDateTimeOriginal, tagIDDateTimeDigitized} {
+		if whenValue, err := metadata.value(tagID); err != nil {
+			err = fmt.Errorf("get tag 0x%s value: %s", strconv.FormatUint(uint64(tagID), 16), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else if whenStr, ok := whenValue.(string); !ok {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("date/time not string: %v", whenValue)
+			}
+		} else if when, err := parseCameraTime(whenStr); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("parse time: %s", err)
+			}
+		} else {
+			// Parsed as UTC (even though it was local time) since no time zone in string.
+			// Go ahead format it as UTC, it will look like it was local all along.
+			return when, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %s", ErrNoCaptureTime, firstErr)
+}
+
+// EXIFsetCaptureTime sets the DateTime and DateTimeOriginal tags to the capture time (a camera clock time).
+func EXIFsetCaptureTime(rootIb *exif.IfdBuilder, when time.Time) error {
+	whenStr := when.Format(exifTimeFmt)
+	if err := rootIb.SetStandardWithName(tagNameDateTime, whenStr); err != nil {
+		return fmt.Errorf("set %s: %w", tagNameDateTime, err)
+	}
+	if exifIb, err := exif.GetOrCreateIbFromRootIb(rootIb, "IFD/Exif"); err != nil {
+		return fmt.Errorf("get EXIF IFD: %w", err)
+	} else if err := exifIb.SetStandardWithName(tagNameDateTimeOriginal, whenStr); err != nil {
+		return fmt.Errorf("set %s: %w", tagNameDateTimeOriginal, err)
+	}
+	return nil
+}
+
+// EXIFsetOffsetTime sets the OffsetTime and OffsetTimeOriginal tags (e.g. "-05:00").
+// These tags are not in the standard go-exif tag index so they are set by ID.
+func EXIFsetOffsetTime(rootIb *exif.IfdBuilder, offset string) error {
+	exifIb, err := exif.GetOrCreateIbFromRootIb(rootIb, "IFD/Exif")
+	if err != nil {
+		return fmt.Errorf("get EXIF IFD: %w", err)
+	}
+	value := exif.NewIfdBuilderTagValueFromBytes(append([]byte(offset), 0))
+	for _, tagID := range []uint16{tagIDOffsetTime, tagIDOffsetTimeOriginal} {
+		bt := exif.NewBuilderTag("IFD/Exif", tagID, exifcommon.TypeAscii, value, exifcommon.EncodeDefaultByteOrder)
+		if err := exifIb.Set(bt); err != nil {
+			return fmt.Errorf("set tag 0x%s: %w", strconv.FormatUint(uint64(tagID), 16), err)
+		}
+	}
+	return nil
+}
+
+func EXIFenumerateIndex(index exif.IfdIndex) error {
+	err := index.RootIfd.EnumerateTagsRecursively(func(ifd *exif.Ifd, ite *exif.IfdTagEntry) error {
+		log.Debug().Str("path", ite.IfdPath()+"/"+ite.TagName()).
+			Str("ID", "0x"+strconv.FormatUint(uint64(ite.TagId()), 16)).Msg("tag")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func EXIFgetIndex(path string) (exif.IfdIndex, error) {
+	var index exif.IfdIndex
+	if rawExif, err := exifExtract(path); err != nil {
+		return index, fmt.Errorf("getting EXIF from file: %w", err)
+	} else if im, err := exifcommon.NewIfdMappingWithStandard(); err != nil {
+		return index, fmt.Errorf("getting EXIF mapping: %w", err)
+	} else {
+		ti := exif.NewTagIndex()
+		if _, index, err = exif.Collect(im, ti, rawExif); err != nil {
+			return index, fmt.Errorf("getting EXIF index: %w", err)
+		} else {
+			return index, nil
+		}
+	}
+}
+
+func EXIFgetValue(index exif.IfdIndex, tagName string, tagID uint16) (interface{}, error) {
+	value, err := exifFindValue(index, tagID)
+	if err != nil {
+		log.Error().Err(err).Str("tag", tagName).Uint16("ID", tagID).
+			Msg("Find EXIF tag by ID")
+		if err2 := EXIFenumerateIndex(index); err2 != nil {
+			log.Error().Err(err2).Msg("Enumerating EXIF index")
+		}
+		return "", err
+	}
+	return value, nil
+}
+
+// exifCacheSize is the number of files whose decoded EXIF data is kept,
+// enough for the files being imported concurrently.
+const exifCacheSize = 64
+
+// exifMetadata is the decoded EXIF data of a file: the values of the tags in IFD0 and the EXIF IFD.
+// The IFDs of a file are parsed once and the metadata shared by everything reading them
+// (capture time, camera model, orientation, battery level, and Live Photo content identifier).
+type exifMetadata struct {
+	values map[uint16]interface{}
+}
+
+// exifEntry is a cached exifMetadata, keyed by the size and modification time of the file
+// as well as its path so that rewritten files are read again.
+type exifEntry struct {
+	size     int64
+	modified time.Time
+	metadata *exifMetadata
+	err      error
+}
+
+var exifCache = struct {
+	sync.Mutex
+	entries map[string]*exifEntry
+	order   []string
+}{entries: make(map[string]*exifEntry)}
+
+// exifRead returns the decoded EXIF data of a file, from the cache if the file is unchanged.
+// Failures (e.g. files without EXIF data) are cached as well.
+func exifRead(path string) (*exifMetadata, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+	exifCache.Lock()
+	entry, found := exifCache.entries[path]
+	exifCache.Unlock()
+	if found && entry.size == stat.Size() && entry.modified.Equal(stat.ModTime()) {
+		return entry.metadata, entry.err
+	}
+
+	entry = &exifEntry{size: stat.Size(), modified: stat.ModTime()}
+	entry.metadata, entry.err = exifDecode(path)
+	exifCache.Lock()
+	defer exifCache.Unlock()
+	if _, found := exifCache.entries[path]; !found {
+		if len(exifCache.order) >= exifCacheSize {
+			delete(exifCache.entries, exifCache.order[0])
+			exifCache.order = exifCache.order[1:]
+		}
+		exifCache.order = append(exifCache.order, path)
+	}
+	exifCache.entries[path] = entry
+	return entry.metadata, entry.err
+}
+
+// exifDecode parses the EXIF data of a file and decodes the values of the tags in IFD0
+// and the EXIF IFD, with IFD0 taking precedence. Tags that can't be decoded are skipped.
+func exifDecode(path string) (*exifMetadata, error) {
+	index, err := EXIFgetIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &exifMetadata{values: make(map[uint16]interface{})}
+	for _, ifd := range []*exif.Ifd{index.Lookup["IFD/Exif"], index.RootIfd} {
+		if ifd == nil {
+			continue
+		}
+		for _, entry := range ifd.Entries() {
+			if value, err := exifEntryValue(entry); err == nil {
+				metadata.values[entry.TagId()] = value
+			}
+		}
+	}
+	return metadata, nil
+}
+
+// exifMaxValueSize is the largest EXIF value decoded.
+// JPEG EXIF data is limited to a 64 KiB segment, larger sizes come from damaged tag entries
+// for which go-exif would allocate whatever the unit count claims.
+const exifMaxValueSize = 1 << 20
+
+// exifEntryValue returns the decoded value of a tag entry.
+func exifEntryValue(entry *exif.IfdTagEntry) (interface{}, error) {
+	if !entry.TagType().IsValid() {
+		return nil, fmt.Errorf("EXIF tag 0x%s has invalid type %d", strconv.FormatUint(uint64(entry.TagId()), 16), entry.TagType())
+	} else if size := uint64(entry.UnitCount()) * uint64(entry.TagType().Size()); size > exifMaxValueSize {
+		return nil, fmt.Errorf("EXIF tag 0x%s value size %d too large", strconv.FormatUint(uint64(entry.TagId()), 16), size)
+	}
+	return entry.Value()
+}
+
+// value returns the value of a tag.
+func (m *exifMetadata) value(tagID uint16) (interface{}, error) {
+	if value, found := m.values[tagID]; found {
+		return value, nil
+	}
+	return nil, fmt.Errorf("find EXIF tag: no tag 0x%s", strconv.FormatUint(uint64(tagID), 16))
+}
+
+// exifFindValue returns the value of a tag in IFD0 or the EXIF IFD.
+func exifFindValue(index exif.IfdIndex, tagID uint16) (interface{}, error) {
+	tagResults, err := index.RootIfd.FindTagWithId(tagID)
+	if err != nil {
+		if exifIfd := index.Lookup["IFD/Exif"]; exifIfd != nil {
+			tagResults, err = exifIfd.FindTagWithId(tagID)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("find EXIF tag: %w", err)
+	}
+	if len(tagResults) != 1 {
+		return "", fmt.Errorf("wrong number of EXIF tag results: %d", len(tagResults))
+	} else if value, err := exifEntryValue(tagResults[0]); err != nil {
+		return "", fmt.Errorf("getting EXIF tag value: %w", err)
+	} else {
+		return value, nil
+	}
+}
@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"fmt"
+	"image/jpeg"
+	"math"
+	"os"
+)
+
+// Exposure problems flagged in the catalog.
+const (
+	// ExposureDark means the frame is nearly black, e.g. a failed flash or a covered lens.
+	ExposureDark = "dark"
+	// ExposureBright means the frame is washed out, e.g. by an IR flash reflecting off a close object.
+	ExposureBright = "bright"
+	// ExposureFlat means the frame has almost no contrast, e.g. a fogged or snow-covered lens.
+	ExposureFlat = "flat"
+)
+
+const (
+	// exposureDarkLevel and exposureBrightLevel bound the luminance of pixels that are neither black nor white.
+	exposureDarkLevel   = 20
+	exposureBrightLevel = 250
+	// exposureClippedFraction is the fraction of black or white pixels at which a frame is too dark or bright.
+	exposureClippedFraction = 0.9
+	// exposureFlatDeviation is the luminance standard deviation below which a frame is flat.
+	exposureFlatDeviation = 6
+)
+
+// Exposure returns the exposure problem (ExposureDark, ExposureBright, or ExposureFlat)
+// of a JPEG file from its luminance histogram, empty if there is none.
+func Exposure(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	img, err := jpeg.Decode(file)
+	if err != nil {
+		return "", fmt.Errorf("decode JPEG: %w", err)
+	}
+	luma, _, _ := sampleLuminance(img)
+	if len(luma) == 0 {
+		return "", nil
+	}
+	var histogram [256]int
+	for _, value := range luma {
+		histogram[value]++
+	}
+	var dark, bright int
+	var sum, sumSquares float64
+	for value, count := range histogram {
+		if value <= exposureDarkLevel {
+			dark += count
+		} else if value >= exposureBrightLevel {
+			bright += count
+		}
+		sum += float64(value * count)
+		sumSquares += float64(value * value * count)
+	}
+	n := float64(len(luma))
+	switch {
+	case float64(dark)/n >= exposureClippedFraction:
+		return ExposureDark, nil
+	case float64(bright)/n >= exposureClippedFraction:
+		return ExposureBright, nil
+	case math.Sqrt(sumSquares/n-(sum/n)*(sum/n)) < exposureFlatDeviation:
+		return ExposureFlat, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,44 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Extractor returns the capture time of a media file as the camera clock time,
+// e.g. from a plugin for a format or camera brand not built into the application.
+// Only the clock time is used, any time zone is ignored as for EXIF times.
+type Extractor func(path string) (time.Time, error)
+
+var (
+	extractorsMutex sync.RWMutex
+	extractors      = make(map[string]Extractor)
+)
+
+// RegisterExtractor registers the capture time extractor for a file extension (e.g. ".heic").
+// Registered extensions are Supported and override the built-in formats.
+func RegisterExtractor(ext string, extractor Extractor) {
+	extractorsMutex.Lock()
+	defer extractorsMutex.Unlock()
+	extractors[strings.ToLower(ext)] = extractor
+}
+
+// extractor returns the registered extractor for the extension of a file, nil if none.
+func extractor(path string) Extractor {
+	extractorsMutex.RLock()
+	defer extractorsMutex.RUnlock()
+	return extractors[strings.ToLower(filepath.Ext(path))]
+}
+
+// extractCaptureTime returns the capture time from a registered extractor.
+func extractCaptureTime(extractor Extractor, path string) (time.Time, error) {
+	when, err := extractor(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrNoCaptureTime, err)
+	}
+	return time.Date(when.Year(), when.Month(), when.Day(),
+		when.Hour(), when.Minute(), when.Second(), when.Nanosecond(), time.UTC), nil
+}
@@ -0,0 +1,207 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// Camera fault reasons.
+const (
+	// FaultDark means all recent frames are dark, e.g. the lens is covered or the flash has failed.
+	FaultDark = "dark"
+	// FaultStatic means all recent frames are nearly identical, e.g. the camera has fallen over
+	// or is obstructed and keeps being triggered by the same thing.
+	FaultStatic = "static"
+	// FaultSilent means the camera has captured nothing for too long, e.g. its batteries are dead.
+	FaultSilent = "silent"
+)
+
+const (
+	// signatureSize is the width and height of the luminance grid used to compare frames.
+	signatureSize = 8
+	// staticDifference is the mean luminance difference between signatures below which frames are nearly identical.
+	staticDifference = 4
+)
+
+// FaultOptions specifies how camera faults are detected.
+type FaultOptions struct {
+	// Days is the number of most recent days with captures that are checked for each camera.
+	Days int
+	// Silent is how long a camera may capture nothing before it is considered faulty, zero for no limit.
+	Silent time.Duration
+	// Now is the time against which Silent is checked, the current time if zero.
+	Now time.Time
+}
+
+// Fault describes a camera that is likely obstructed, fallen, or dead.
+type Fault struct {
+	// Camera is the source directory of the camera's files (i.e. its card).
+	Camera string
+	// Reason is FaultDark, FaultStatic, or FaultSilent.
+	Reason string
+	// Since is the capture time of the first of the checked frames (or the last frame if silent).
+	Since time.Time
+	// Frames is the number of frames checked.
+	Frames int
+}
+
+// signature is a coarse grid of average luminance used to compare frames.
+type signature [signatureSize * signatureSize]float64
+
+// Faults checks the recent JPEG files of each camera in the catalog for faults.
+// Cameras are identified by the source directory of their files (see cameraOf).
+func (imp *Importer) Faults(options *FaultOptions) ([]*Fault, error) {
+	if imp.options.Catalog == nil {
+		return nil, errors.New("fault detection requires a catalog")
+	}
+	days := options.Days
+	if days < 1 {
+		days = 1
+	}
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	cameras := make(map[string][]*catalog.File)
+	for _, file := range imp.options.Catalog.Originals() {
+		if isJPEG(file.Path) && file.Offloaded == "" {
+			camera := cameraOf(file)
+			cameras[camera] = append(cameras[camera], file)
+		}
+	}
+	var faults []*Fault
+	for camera, frames := range cameras {
+		sort.Slice(frames, func(i, j int) bool {
+			return captureBefore(frames[i], frames[j])
+		})
+		last := frames[len(frames)-1]
+		// Capture times are camera clock times recorded as UTC, compare them as local clock times.
+		lastLocal := time.Date(last.Captured.Year(), last.Captured.Month(), last.Captured.Day(),
+			last.Captured.Hour(), last.Captured.Minute(), last.Captured.Second(), 0, time.Local)
+		if options.Silent > 0 && now.Sub(lastLocal) > options.Silent {
+			faults = append(faults, &Fault{Camera: camera, Reason: FaultSilent, Since: last.Captured, Frames: len(frames)})
+			continue
+		}
+		recent := recentDays(frames, days)
+		if fault, err := imp.checkFrames(recent); err != nil {
+			return faults, fmt.Errorf("check camera %s: %w", camera, err)
+		} else if fault != "" {
+			faults = append(faults, &Fault{Camera: camera, Reason: fault, Since: recent[0].Captured, Frames: len(recent)})
+		}
+	}
+	sort.Slice(faults, func(i, j int) bool {
+		return faults[i].Camera < faults[j].Camera
+	})
+	return faults, nil
+}
+
+// recentDays returns the frames captured on the most recent days with captures.
+func recentDays(frames []*catalog.File, days int) []*catalog.File {
+	start := len(frames)
+	var date string
+	for start > 0 {
+		if frameDate := frames[start-1].Captured.Format(dateFmt); frameDate != date {
+			if days == 0 {
+				break
+			}
+			days--
+			date = frameDate
+		}
+		start--
+	}
+	return frames[start:]
+}
+
+// checkFrames returns FaultDark if all frames are dark, FaultStatic if all frames are nearly identical,
+// or empty if the frames look normal. At least two frames are required to detect a fault.
+// Frames that can't be read are skipped.
+func (imp *Importer) checkFrames(frames []*catalog.File) (string, error) {
+	var signatures []*signature
+	for _, frame := range frames {
+		sig, err := frameSignature(imp.catalogPath(frame))
+		if err != nil {
+			log.Warn().Err(err).Str("path", frame.Path).Msg("Read frame")
+			continue
+		}
+		signatures = append(signatures, sig)
+	}
+	if len(signatures) < 2 {
+		return "", nil
+	}
+	dark, static := true, true
+	for _, sig := range signatures {
+		if sig.mean() > exposureDarkLevel {
+			dark = false
+		}
+		if sig.difference(signatures[0]) >= staticDifference {
+			static = false
+		}
+	}
+	switch {
+	case dark:
+		return FaultDark, nil
+	case static:
+		return FaultStatic, nil
+	}
+	return "", nil
+}
+
+// frameSignature returns the signature of a JPEG file.
+func frameSignature(path string) (*signature, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	img, err := jpeg.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("decode JPEG: %w", err)
+	}
+	luma, width, height := sampleLuminance(img)
+	if width < signatureSize || height < signatureSize {
+		return nil, fmt.Errorf("image too small: %dx%d", width, height)
+	}
+	var sig signature
+	var counts signature
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			cell := (y*signatureSize/height)*signatureSize + x*signatureSize/width
+			sig[cell] += float64(luma[y*width+x])
+			counts[cell]++
+		}
+	}
+	for i := range sig {
+		sig[i] /= counts[i]
+	}
+	return &sig, nil
+}
+
+// mean returns the mean luminance of the signature.
+func (s *signature) mean() float64 {
+	var sum float64
+	for _, value := range s {
+		sum += value
+	}
+	return sum / float64(len(s))
+}
+
+// difference returns the mean absolute luminance difference between two signatures.
+func (s *signature) difference(other *signature) float64 {
+	var sum float64
+	for i, value := range s {
+		if value > other[i] {
+			sum += value - other[i]
+		} else {
+			sum += other[i] - value
+		}
+	}
+	return sum / float64(len(s))
+}
@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Many devices (e.g. phones and dash cameras) write the capture time into the file name.
+// When a media file has no capture time in its metadata the file name is matched against
+// file name date patterns and then, if enabled, the file modification time is used.
+//
+// A pattern is made of the fields YYYY, MM, DD, hh, mm, and ss and literal characters,
+// e.g. YYYYMMDD_hhmmss matches IMG_20240117_063012.jpg and PXL_20240117_063012345.mp4.
+// Patterns match anywhere in the base name but not within a longer number.
+
+// DefaultFileNamePatterns are the file name date patterns used unless set with SetFileNamePatterns.
+var DefaultFileNamePatterns = []string{
+	"YYYYMMDD_hhmmss",
+	"YYYYMMDD-hhmmss",
+	"YYYY-MM-DD_hh-mm-ss",
+	"YYYY-MM-DD-hh-mm-ss",
+	"YYYY-MM-DD hh.mm.ss",
+	"YYYY-MM-DD at hh.mm.ss",
+	"YYYYMMDDhhmmss",
+}
+
+// fileNameFields maps pattern fields to time layout elements.
+var fileNameFields = strings.NewReplacer("YYYY", "2006", "MM", "01", "DD", "02", "hh", "15", "mm", "04", "ss", "05")
+
+// fileNamePattern is a compiled file name date pattern.
+type fileNamePattern struct {
+	regexp *regexp.Regexp
+	layout string
+}
+
+var (
+	fileNameMutex    sync.RWMutex
+	fileNamePatterns = mustFileNamePatterns(DefaultFileNamePatterns)
+	useModTime       bool
+)
+
+// SetFileNamePatterns sets the file name date patterns, none if empty.
+func SetFileNamePatterns(patterns []string) error {
+	compiled, err := compileFileNamePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	fileNameMutex.Lock()
+	defer fileNameMutex.Unlock()
+	fileNamePatterns = compiled
+	return nil
+}
+
+// UseModTime sets whether the file modification time is used as a last resort capture time.
+// Modification times are often changed by copying, so this is disabled by default.
+func UseModTime(use bool) {
+	fileNameMutex.Lock()
+	defer fileNameMutex.Unlock()
+	useModTime = use
+}
+
+func mustFileNamePatterns(patterns []string) []*fileNamePattern {
+	compiled, err := compileFileNamePatterns(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+func compileFileNamePatterns(patterns []string) ([]*fileNamePattern, error) {
+	var compiled []*fileNamePattern
+	for _, pattern := range patterns {
+		layout := fileNameFields.Replace(pattern)
+		for _, field := range []string{"2006", "01", "02", "15", "04"} {
+			if !strings.Contains(layout, field) {
+				return nil, fmt.Errorf("file name date pattern '%s' lacks YYYY, MM, DD, hh, or mm", pattern)
+			}
+		}
+		expression := regexp.QuoteMeta(layout)
+		for _, field := range []string{"2006", "01", "02", "15", "04", "05"} {
+			expression = strings.Replace(expression, field, `\d{`+fmt.Sprint(len(field))+`}`, 1)
+		}
+		re, err := regexp.Compile(`(?:^|\D)(` + expression + `)`)
+		if err != nil {
+			return nil, fmt.Errorf("compile file name date pattern '%s': %w", pattern, err)
+		}
+		compiled = append(compiled, &fileNamePattern{regexp: re, layout: layout})
+	}
+	return compiled, nil
+}
+
+// fileNameTime returns the capture time from the file name of a media file
+// or, if enabled, its modification time.
+func fileNameTime(path string) (time.Time, error) {
+	fileNameMutex.RLock()
+	patterns, modTime := fileNamePatterns, useModTime
+	fileNameMutex.RUnlock()
+	base := filepath.Base(path)
+	for _, pattern := range patterns {
+		for _, match := range pattern.regexp.FindAllStringSubmatch(base, -1) {
+			if when, err := time.Parse(pattern.layout, match[1]); err == nil {
+				return when, nil
+			}
+		}
+	}
+	if modTime {
+		if stat, err := os.Stat(path); err == nil {
+			return stat.ModTime().In(localTimeZone), nil
+		}
+	}
+	return time.Time{}, errors.New("no date in file name")
+}
@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Excluded returns true if the source file is excluded from importing by Options.Only,
+// Options.Since, or Options.Until. Files whose capture time can't be read are not excluded
+// so that importing them reports the error.
+func (imp *Importer) Excluded(source string) bool {
+	if len(imp.options.Only) > 0 {
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(source)), ".")
+		found := false
+		for _, only := range imp.options.Only {
+			if strings.TrimPrefix(strings.ToLower(only), ".") == ext {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return true
+		}
+	}
+	if imp.options.Since.IsZero() && imp.options.Until.IsZero() {
+		return false
+	}
+	when, err := CaptureTime(source)
+	if err != nil {
+		return false
+	}
+	return !imp.options.Since.IsZero() && when.Before(imp.options.Since) ||
+		!imp.options.Until.IsZero() && !when.Before(imp.options.Until)
+}
+
+// excludedFiles returns the set of source files excluded from importing (see Excluded).
+func (imp *Importer) excludedFiles(sources []string) map[string]bool {
+	excluded := make(map[string]bool)
+	for _, source := range sources {
+		if imp.Excluded(source) {
+			log.Debug().Str("source", source).Msg("Excluded by import filters")
+			excluded[source] = true
+		}
+	}
+	return excluded
+}
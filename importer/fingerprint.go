@@ -0,0 +1,94 @@
+package importer
+
+import (
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"math/bits"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// fingerprintDistance is the largest number of differing bits between the fingerprints
+// of frames of the same scene, allowing for lighting, weather, and animals in the frame.
+const fingerprintDistance = 12
+
+// Fingerprint returns the fingerprint of the scene of a JPEG file as 16 hex digits.
+// It is a difference hash: the image is reduced to a grid of 9 by 8 gray cells and each of
+// the 64 bits is whether a cell is brighter than the cell to its right.
+// Since trail cameras are fixed the background dominates the fingerprint,
+// so frames from the same camera have similar fingerprints and those from different places don't.
+// Infrared night frames differ from day frames, so a camera may need a reference frame of each.
+func Fingerprint(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	img, err := jpeg.Decode(file)
+	if err != nil {
+		return "", fmt.Errorf("decode JPEG: %w", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() < 9 || bounds.Dy() < 8 {
+		return "", fmt.Errorf("image too small: %dx%d", bounds.Dx(), bounds.Dy())
+	}
+	grid := resize(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if gray(grid.At(x, y)) > gray(grid.At(x+1, y)) {
+				hash |= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%016x", hash), nil
+}
+
+// gray returns the luminance of a color.
+func gray(c color.Color) uint8 {
+	return color.GrayModel.Convert(c).(color.Gray).Y
+}
+
+// fingerprintDifference returns the number of differing bits between two fingerprints,
+// more than 64 if either is invalid.
+func fingerprintDifference(a, b string) int {
+	x, errA := strconv.ParseUint(a, 16, 64)
+	y, errB := strconv.ParseUint(b, 16, 64)
+	if errA != nil || errB != nil {
+		return 65
+	}
+	return bits.OnesCount64(x ^ y)
+}
+
+// recognize returns the camera of the deployment active at the capture time
+// whose reference frames the scene of the JPEG file matches best (see Fingerprint),
+// empty if there are no such deployments or none match.
+func (imp *Importer) recognize(path string, captured time.Time) string {
+	if imp.options.Catalog == nil || !isJPEG(path) {
+		return ""
+	}
+	var fingerprint, camera string
+	closest := fingerprintDistance + 1
+	for _, deployment := range imp.options.Catalog.Deployments() {
+		if len(deployment.Fingerprints) == 0 || !deployment.Active(captured) {
+			continue
+		}
+		if fingerprint == "" {
+			var err error
+			if fingerprint, err = Fingerprint(path); err != nil {
+				log.Warn().Err(err).Str("path", path).Msg("Fingerprint")
+				return ""
+			}
+		}
+		for _, reference := range deployment.Fingerprints {
+			if difference := fingerprintDifference(fingerprint, reference); difference < closest {
+				camera, closest = deployment.Camera, difference
+			}
+		}
+	}
+	return camera
+}
@@ -0,0 +1,211 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dsoprea/go-exif/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+const (
+	// StateDir is the name of the directory within the target root
+	// in which the application keeps its own data.
+	StateDir = ".gardepro"
+
+	dateFmt = "2006-01-02"
+)
+
+// FixTimeOptions specifies which archived files to fix and by how much.
+type FixTimeOptions struct {
+	// Offset is added to the capture time of each selected file.
+	Offset time.Duration
+	// From and Until are inclusive capture dates (YYYY-MM-DD), empty for no limit.
+	From, Until string
+	// Camera matches the EXIF Model of JPEG files, empty for all cameras.
+	// MP4 files carry no camera model and are skipped when this is set.
+	Camera string
+	// Files limits the fix to these cataloged files (e.g. those after a ClockJump), empty for all files.
+	Files []*catalog.File
+}
+
+// FixTime shifts the capture times of selected archived files by the specified offset.
+// The EXIF DateTime and DateTimeOriginal (JPEG) or mvhd creation time (MP4) are rewritten
+// and the file is renamed to match the new capture time. Files in other formats are skipped.
+// The original bytes of each file are saved in a backup directory beneath the target root.
+// Cataloged files are hashed again and, with Options.Chain, chained again.
+// Returns the number of files fixed.
+func (imp *Importer) FixTime(options *FixTimeOptions) (int, error) {
+	if options.Offset%time.Second != 0 {
+		return 0, fmt.Errorf("offset must be whole seconds: %s", options.Offset)
+	}
+	backupDir := filepath.Join(imp.target, StateDir, "backup", time.Now().Format("20060102-150405"))
+	// Select all files before fixing any so that renamed files are not visited again.
+	var selected []string
+	for _, file := range options.Files {
+		path := imp.catalogPath(file)
+		if ok, err := options.selects(path); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Skipping file")
+		} else if ok {
+			selected = append(selected, path)
+		}
+	}
+	roots := imp.roots()
+	if len(options.Files) > 0 {
+		// Only those files are fixed, so the archive need not be walked.
+		roots = nil
+	}
+	for _, root := range roots {
+		if err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			} else if entry.IsDir() {
+				if skipDir(entry.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ok, err := options.selects(path); err != nil {
+				if !errors.Is(err, ErrUnsupportedFormat) {
+					log.Warn().Err(err).Str("path", path).Msg("Skipping file")
+				}
+			} else if ok {
+				selected = append(selected, path)
+			}
+			return nil
+		}); err != nil {
+			return 0, fmt.Errorf("walk %s: %w", root, err)
+		}
+	}
+
+	var fixed int
+	for _, path := range selected {
+		if newPath, err := imp.fixTime(path, options.Offset, backupDir); err != nil {
+			return fixed, fmt.Errorf("fix time %s: %w", path, err)
+		} else {
+			log.Info().Str("path", path).Str("new-path", newPath).Msg("Fixed time")
+			fixed++
+		}
+	}
+	return fixed, nil
+}
+
+func (options *FixTimeOptions) selects(path string) (bool, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); !isJPEG(path) && ext != ".mp4" {
+		return false, fmt.Errorf("%w: extension %s", ErrUnsupportedFormat, ext)
+	}
+	when, err := CaptureTime(path)
+	if err != nil {
+		return false, err
+	}
+	date := when.Format(dateFmt)
+	if options.From != "" && date < options.From || options.Until != "" && date > options.Until {
+		return false, nil
+	}
+	if options.Camera != "" {
+		if strings.ToLower(filepath.Ext(path)) == ".mp4" {
+			return false, nil
+		}
+		metadata, err := exifRead(path)
+		if err != nil {
+			return false, err
+		}
+		model, err := metadata.value(tagIDModel)
+		if err != nil {
+			return false, err
+		}
+		if modelStr, ok := model.(string); !ok || strings.TrimSpace(modelStr) != options.Camera {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (imp *Importer) fixTime(path string, offset time.Duration, backupDir string) (string, error) {
+	when, err := CaptureTime(path)
+	if err != nil {
+		return "", err
+	}
+	subDir, err := imp.archiveSubDir(path)
+	if err != nil {
+		return "", err
+	}
+	root := imp.rootOf(path)
+	newPath := imp.targetPath(root, when.Add(offset), subDir, archiveBaseName(path))
+	if newPath != path {
+		if _, err := os.Stat(newPath); err == nil {
+			return "", fmt.Errorf("%w: %s", ErrConflict, newPath)
+		}
+	}
+
+	rel, err := imp.relative(path)
+	if err != nil {
+		return "", err
+	}
+	backup := filepath.Join(backupDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(backup), 0766); err != nil {
+		return "", fmt.Errorf("make backup dir: %w", err)
+	} else if err := copyFile(path, backup); err != nil {
+		return "", fmt.Errorf("backup: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp4":
+		err = MP4shiftCreationTime(path, offset)
+	default:
+		err = EXIFupdate(path, func(rootIb *exif.IfdBuilder) error {
+			return EXIFsetCaptureTime(rootIb, when.Add(offset))
+		})
+	}
+	if err != nil {
+		return "", err
+	}
+	setCreated(path, imp.instant(path, when.Add(offset)))
+
+	if newPath != path {
+		if err := imp.checkTargetDir(root, when.Add(offset), filepath.Dir(newPath)); err != nil {
+			return "", err
+		} else if err := os.Rename(path, newPath); err != nil {
+			return "", fmt.Errorf("rename: %w", err)
+		} else if err := moveSidecars(path, newPath); err != nil {
+			return newPath, err
+		}
+	}
+	if imp.options.Catalog != nil {
+		from, _ := imp.relative(path)
+		to, _ := imp.relative(newPath)
+		if from != to {
+			if err := imp.options.Catalog.MoveFile(from, to); err != nil {
+				return newPath, fmt.Errorf("catalog move: %w", err)
+			}
+		}
+		if file := imp.options.Catalog.File(to); file != nil {
+			// The file was rewritten, so it is hashed again (with the algorithm of its entry)
+			// and chained again so that scrub and chain don't report it as damaged.
+			algorithm, _ := splitHash(file.Hash)
+			sum, err := hashFile(newPath, algorithm)
+			if err != nil {
+				return newPath, fmt.Errorf("hash fixed file: %w", err)
+			}
+			updated := *file
+			updated.Captured = when.Add(offset)
+			updated.Hash = algorithm + ":" + sum
+			if err := imp.options.Catalog.AddFile(&updated); err != nil {
+				return newPath, fmt.Errorf("catalog file: %w", err)
+			}
+			if imp.options.Chain != nil {
+				if _, err := imp.options.Chain.Append(updated.Path, updated.Hash); err != nil {
+					return newPath, fmt.Errorf("chain file: %w", err)
+				}
+			}
+		}
+	}
+	return newPath, nil
+}
@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/madkins23/gardepro/fixture"
+)
+
+func TestFixTimeRehashes(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp := testImporter(t, Options{})
+	ch, err := OpenChain(imp.Target(), private)
+	if err != nil {
+		t.Fatalf("open chain: %s", err)
+	}
+	defer func() { _ = ch.Close() }()
+	imp.options.Chain = ch
+	source := t.TempDir()
+	importFiles(t, imp,
+		writeJPEG(t, source, "IMG_0001.JPG", fixture.JPEG{Captured: testTime, Model: "GardePro E6"}),
+		writeFile(t, source, "VID_0001.MP4", (&fixture.MP4{Created: testTime.Add(time.Minute), Duration: time.Second}).Bytes()))
+
+	fixed, err := imp.FixTime(&FixTimeOptions{Offset: time.Hour})
+	if err != nil {
+		t.Fatalf("fix time: %s", err)
+	} else if fixed != 2 {
+		t.Fatalf("fixed %d files, want 2", fixed)
+	}
+	for _, file := range imp.options.Catalog.Files() {
+		if want := testTime.Add(time.Hour); file.Captured.Before(want) {
+			t.Errorf("%s captured %s, want at least %s", file.Path, file.Captured, want)
+		}
+	}
+
+	scrub, err := imp.Scrub(&ScrubOptions{})
+	if err != nil {
+		t.Fatalf("scrub: %s", err)
+	} else if len(scrub.Damaged) > 0 || len(scrub.Missing) > 0 || scrub.Verified != 2 {
+		t.Errorf("scrub verified %d, damaged %d, missing %d, want 2, 0, 0",
+			scrub.Verified, len(scrub.Damaged), len(scrub.Missing))
+	}
+	check, err := imp.CheckChain(public)
+	if err != nil {
+		t.Fatalf("check chain: %s", err)
+	} else if len(check.Damaged) > 0 || len(check.Missing) > 0 || check.Verified != 2 {
+		t.Errorf("chain verified %d, damaged %v, missing %v, want 2, none, none",
+			check.Verified, check.Damaged, check.Missing)
+	}
+}
@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// Forward moves the files archived in this importer's target root into another target tree,
+// for example from a local disk to a NAS that is not always reachable.
+// Files keep their paths relative to the root and their catalog entries,
+// including the original source path, are added to the other catalog.
+// Each file is verified against its copy before it is removed from this target.
+// Both importers must have catalogs.
+// Returns the number of files forwarded.
+func (imp *Importer) Forward(to *Importer) (int, error) {
+	if imp.options.Catalog == nil || to.options.Catalog == nil {
+		return 0, errors.New("forwarding requires catalogs")
+	}
+	var forwarded int
+	for _, file := range imp.options.Catalog.Files() {
+		if file.Root != "" {
+			continue
+		}
+		if err := imp.forward(to, file); err != nil {
+			return forwarded, &Error{Source: filepath.Join(imp.target, filepath.FromSlash(file.Path)), Err: err}
+		}
+		forwarded++
+	}
+	return forwarded, nil
+}
+
+func (imp *Importer) forward(to *Importer, file *catalog.File) error {
+	rel := filepath.FromSlash(file.Path)
+	source := filepath.Join(imp.target, rel)
+	if _, err := os.Stat(source); err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+
+	var root string
+	for _, candidate := range to.roots() {
+		if _, err := os.Stat(existingPath(filepath.Join(candidate, rel))); err == nil {
+			root = candidate
+			break
+		}
+	}
+	if root == "" {
+		var err error
+		if root, err = to.chooseRoot(source, file.Captured, ""); err != nil {
+			return err
+		}
+	}
+	target := existingPath(filepath.Join(root, rel))
+	if err := to.checkTargetDir(root, file.Captured, filepath.Dir(target)); err != nil {
+		return err
+	}
+	algorithm, _ := splitHash(file.Hash)
+	h, err := newHash(algorithm)
+	if err != nil {
+		return err
+	}
+	copied, err := copySourceToTarget(source, target, nil, h, to.options.BlockSize, nil)
+	if err != nil {
+		return err
+	} else if copied {
+		setCreated(target, to.instant(source, file.Captured))
+	}
+	if equal, err := compareFiles(source, target, nil); err != nil {
+		return fmt.Errorf("verify forwarded file: %w", err)
+	} else if !equal {
+		return fmt.Errorf("%w: forwarded file differs", ErrCorrupt)
+	}
+	sidecarSources := sidecars(source)
+	if err := copySidecars(source, target, to.options.BlockSize, nil); err != nil {
+		return err
+	}
+	for _, sidecarSource := range sidecarSources {
+		if equal, err := compareFiles(sidecarSource, sidecarPath(sidecarSource, target), nil); err != nil {
+			return fmt.Errorf("verify forwarded sidecar: %w", err)
+		} else if !equal {
+			return fmt.Errorf("%w: forwarded sidecar differs", ErrCorrupt)
+		}
+	}
+
+	forwarded := *file
+	forwarded.Root = ""
+	if root != to.target {
+		if forwarded.Root, err = filepath.Abs(root); err != nil {
+			forwarded.Root = root
+		}
+	}
+	if copied && forwarded.Hash == "" {
+		forwarded.Hash = algorithm + ":" + hashString(h)
+	}
+	if session := imp.options.Catalog.Session(file.Session); session != nil {
+		if err := to.options.Catalog.AddSession(session); err != nil {
+			return fmt.Errorf("catalog forwarded session: %w", err)
+		}
+	}
+	if err := to.options.Catalog.AddFile(&forwarded); err != nil {
+		return fmt.Errorf("catalog forwarded file: %w", err)
+	}
+	if err := os.Remove(source); err != nil {
+		return fmt.Errorf("remove forwarded file: %w", err)
+	}
+	for _, sidecarSource := range sidecarSources {
+		if err := os.Remove(sidecarSource); err != nil {
+			return fmt.Errorf("remove forwarded sidecar: %w", err)
+		}
+	}
+	if err := imp.options.Catalog.RemoveFile(file.Path); err != nil {
+		return fmt.Errorf("catalog removed file: %w", err)
+	}
+	log.Info().Str("source", source).Str("target-path", target).Msg("Forwarded file")
+	return nil
+}
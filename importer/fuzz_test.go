@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"bytes"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madkins23/gardepro/fixture"
+)
+
+// The metadata parsers must report damaged files as errors (leading to quarantine)
+// rather than panic or hang. Each target is seeded with the standard fixtures of its format
+// and damaged copies of them (see fixture.Mutate), which go test runs as regular tests. Fuzz further with e.g.
+//
+//	go test -run '^$' -fuzz FuzzMP4 ./importer
+//
+// Inputs that panic are saved under testdata/fuzz and run by every go test from then on.
+// A hang only shows as execs that stop increasing, save such inputs there by hand
+// (as for FuzzMP4/moov-zero-large-size), along with any real camera files that are worth keeping.
+
+// fuzzMutations is the number of damaged copies of each fixture added to the seed corpus.
+const fuzzMutations = 16
+
+// addFuzzSeeds adds the standard fixtures with any of the extensions, and damaged copies of them, to the seed corpus.
+func addFuzzSeeds(f *testing.F, extensions ...string) {
+	fixtures, err := fixture.Standard(time.Date(2024, time.January, 2, 12, 0, 0, 0, time.Local))
+	if err != nil {
+		f.Fatalf("synthesize fixtures: %s", err)
+	}
+	// Fixed so that the seed corpus is the same for every run.
+	rng := rand.New(rand.NewSource(1))
+	for _, fx := range fixtures {
+		ext := strings.ToLower(filepath.Ext(fx.Path))
+		for _, extension := range extensions {
+			if ext == extension {
+				data := fx.Data()
+				f.Add(data)
+				for i := 0; i < fuzzMutations; i++ {
+					f.Add(fixture.Mutate(data, rng))
+				}
+			}
+		}
+	}
+}
+
+func FuzzEXIF(f *testing.F) {
+	addFuzzSeeds(f, ".jpg", ".jpeg")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := writeFile(t, t.TempDir(), "input.jpg", data)
+		_, _ = EXIFcaptureTime(path)
+		_, _ = EXIForientation(path)
+		_ = BatteryLevel(path)
+		_ = JPEGverify(path)
+	})
+}
+
+func FuzzMP4(f *testing.F) {
+	addFuzzSeeds(f, ".mp4")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := writeFile(t, t.TempDir(), "input.mp4", data)
+		_, _ = MP4captureTime(path)
+		_, _ = MP4duration(path)
+		_ = MP4verify(path)
+	})
+}
+
+func FuzzMOV(f *testing.F) {
+	addFuzzSeeds(f, ".mov")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := writeFile(t, t.TempDir(), "input.mov", data)
+		_, _ = MOVcaptureTime(path)
+		_, _ = MP4duration(path)
+		_ = MP4verify(path)
+	})
+}
+
+func FuzzAVI(f *testing.F) {
+	addFuzzSeeds(f, ".avi")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := writeFile(t, t.TempDir(), "input.avi", data)
+		_, _ = AVIcaptureTime(path)
+		_ = AVIverify(path)
+	})
+}
+
+// FuzzCaptureTime runs inputs of every format through the parsers chosen by extension, as importing does.
+func FuzzCaptureTime(f *testing.F) {
+	addFuzzSeeds(f, ".jpg", ".mp4", ".mov", ".avi")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dir := t.TempDir()
+		for _, name := range []string{"input.jpg", "input.mp4", "input.mov", "input.avi"} {
+			path := writeFile(t, dir, name, data)
+			_, _ = metadataCaptureTime(path)
+			_ = verify(path)
+		}
+	})
+}
+
+// FuzzCarve runs inputs through the carvers' parsers, as if found at the start of a sector.
+// A length found must be within the input.
+func FuzzCarve(f *testing.F) {
+	addFuzzSeeds(f, ".jpg", ".mp4", ".mov")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if length, err := jpegCarveLength(bytes.NewReader(data)); err == nil && (length < 2 || length > int64(len(data))) {
+			t.Errorf("JPEG length %d of %d bytes", length, len(data))
+		}
+		if length, err := mp4CarveLength(bytes.NewReader(data), 0, int64(len(data))); err == nil && (length < 8 || length > int64(len(data))) {
+			t.Errorf("MP4 length %d of %d bytes", length, len(data))
+		}
+	})
+}
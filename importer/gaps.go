@@ -0,0 +1,126 @@
+package importer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// Gap is a run of days on which a camera captured nothing,
+// usually a sign of dead batteries, a full card, or theft.
+type Gap struct {
+	// Camera is the source directory of the camera's files (i.e. its card).
+	Camera string
+	// Deployment is the deployment of the camera during the gap, nil if the camera has none.
+	Deployment *catalog.Deployment
+	// From and Until are the first and last days (by the camera clock) without captures.
+	From, Until time.Time
+	// Days is the number of days without captures.
+	Days int
+	// Open is true if the gap runs until now, i.e. the camera has captured nothing since.
+	Open bool
+}
+
+// Gaps returns the runs of at least minDays days without captures by each camera in the catalog,
+// in camera and then date order.
+// Cameras with deployments in the catalog are checked while deployed, from the day of installation
+// until the day before they were pulled (or now), so that cameras which never captured anything are found.
+// Other cameras are checked between their first and last capture and, if now is not zero,
+// from their last capture until now. Days before now are checked, since today isn't over.
+// Days are those of the camera clock, which is compared with now as a local clock time.
+func Gaps(cat *catalog.Catalog, minDays int, now time.Time) []*Gap {
+	if minDays < 1 {
+		minDays = 1
+	}
+	captured := make(map[string]map[time.Time]bool)
+	first := make(map[string]time.Time)
+	last := make(map[string]time.Time)
+	for _, file := range cat.Files() {
+		if file.Captured.IsZero() {
+			continue
+		}
+		camera, day := cameraOf(file), clockDay(file.Captured)
+		if captured[camera] == nil {
+			captured[camera] = make(map[time.Time]bool)
+		}
+		captured[camera][day] = true
+		if first[camera].IsZero() || day.Before(first[camera]) {
+			first[camera] = day
+		}
+		if day.After(last[camera]) {
+			last[camera] = day
+		}
+	}
+	var yesterday time.Time
+	if !now.IsZero() {
+		yesterday = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	}
+
+	// The periods during which each camera is expected to capture something.
+	type period struct {
+		from, until time.Time
+		deployment  *catalog.Deployment
+	}
+	periods := make(map[string][]period)
+	for _, deployment := range cat.Deployments() {
+		until := yesterday
+		if !deployment.Pulled.IsZero() {
+			until = clockDay(deployment.Pulled).AddDate(0, 0, -1)
+		} else if until.IsZero() {
+			until = last[deployment.Camera]
+		}
+		periods[deployment.Camera] = append(periods[deployment.Camera],
+			period{from: clockDay(deployment.Installed), until: until, deployment: deployment})
+	}
+	for camera := range captured {
+		if periods[camera] == nil {
+			until := last[camera]
+			if yesterday.After(until) {
+				until = yesterday
+			}
+			periods[camera] = []period{{from: first[camera], until: until}}
+		}
+	}
+
+	var gaps []*Gap
+	for camera, cameraPeriods := range periods {
+		for _, p := range cameraPeriods {
+			var gap *Gap
+			for day := p.from; !day.After(p.until); day = day.AddDate(0, 0, 1) {
+				if captured[camera][day] {
+					gap = nil
+					continue
+				}
+				if gap == nil {
+					gap = &Gap{Camera: camera, Deployment: p.deployment, From: day}
+					gaps = append(gaps, gap)
+				}
+				gap.Until = day
+				gap.Days++
+			}
+			if gap != nil && gap.Until.Equal(yesterday) {
+				gap.Open = p.deployment == nil || p.deployment.Pulled.IsZero()
+			}
+		}
+	}
+	kept := gaps[:0]
+	for _, gap := range gaps {
+		if gap.Days >= minDays {
+			kept = append(kept, gap)
+		}
+	}
+	gaps = kept
+	sort.Slice(gaps, func(i, j int) bool {
+		if gaps[i].Camera != gaps[j].Camera {
+			return gaps[i].Camera < gaps[j].Camera
+		}
+		return gaps[i].From.Before(gaps[j].From)
+	})
+	return gaps
+}
+
+// clockDay returns the day of a camera clock time.
+func clockDay(when time.Time) time.Time {
+	return time.Date(when.Year(), when.Month(), when.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dsoprea/go-exif/v3"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+)
+
+// gpxMaxGap is the maximum time between a capture and the nearest track point.
+const gpxMaxGap = 5 * time.Minute
+
+// TrackPoint is a single GPS position at a point in time.
+type TrackPoint struct {
+	Latitude  float64   `xml:"lat,attr"`
+	Longitude float64   `xml:"lon,attr"`
+	Time      time.Time `xml:"time"`
+}
+
+// Track is a time ordered sequence of GPS positions.
+type Track []TrackPoint
+
+// LoadGPX loads all track points from a GPX file.
+func LoadGPX(path string) (Track, error) {
+	var gpx struct {
+		Tracks []struct {
+			Segments []struct {
+				Points []TrackPoint `xml:"trkpt"`
+			} `xml:"trkseg"`
+		} `xml:"trk"`
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read GPX file: %w", err)
+	} else if err := xml.Unmarshal(data, &gpx); err != nil {
+		return nil, fmt.Errorf("parse GPX file: %w", err)
+	}
+	var track Track
+	for _, trk := range gpx.Tracks {
+		for _, segment := range trk.Segments {
+			for _, point := range segment.Points {
+				if !point.Time.IsZero() {
+					track = append(track, point)
+				}
+			}
+		}
+	}
+	if len(track) == 0 {
+		return nil, fmt.Errorf("no timed track points in GPX file")
+	}
+	sort.Slice(track, func(i, j int) bool {
+		return track[i].Time.Before(track[j].Time)
+	})
+	return track, nil
+}
+
+// Locate returns the position at the specified time,
+// interpolating between the track points on either side.
+// Returns false if there is no track point within gpxMaxGap of the time.
+func (t Track) Locate(when time.Time) (float64, float64, bool) {
+	if len(t) == 0 {
+		return 0, 0, false
+	}
+	i := sort.Search(len(t), func(i int) bool {
+		return !t[i].Time.Before(when)
+	})
+	switch {
+	case i < len(t) && t[i].Time.Equal(when):
+		return t[i].Latitude, t[i].Longitude, true
+	case i == 0:
+		return t[0].Latitude, t[0].Longitude, t[0].Time.Sub(when) <= gpxMaxGap
+	case i == len(t):
+		last := t[len(t)-1]
+		return last.Latitude, last.Longitude, when.Sub(last.Time) <= gpxMaxGap
+	}
+	before, after := t[i-1], t[i]
+	if when.Sub(before.Time) > gpxMaxGap && after.Time.Sub(when) > gpxMaxGap {
+		return 0, 0, false
+	}
+	fraction := float64(when.Sub(before.Time)) / float64(after.Time.Sub(before.Time))
+	return before.Latitude + fraction*(after.Latitude-before.Latitude),
+		before.Longitude + fraction*(after.Longitude-before.Longitude), true
+}
+
+// gpsDegrees converts decimal degrees to EXIF degrees, minutes, and seconds.
+func gpsDegrees(decimal float64) []exifcommon.Rational {
+	decimal = math.Abs(decimal)
+	degrees := math.Floor(decimal)
+	minutes := math.Floor((decimal - degrees) * 60)
+	seconds := ((decimal-degrees)*60 - minutes) * 60
+	return []exifcommon.Rational{
+		{Numerator: uint32(degrees), Denominator: 1},
+		{Numerator: uint32(minutes), Denominator: 1},
+		{Numerator: uint32(math.Round(seconds * 10000)), Denominator: 10000},
+	}
+}
+
+// EXIFsetGPS sets the GPS position tags.
+func EXIFsetGPS(rootIb *exif.IfdBuilder, latitude, longitude float64) error {
+	gpsIb, err := exif.GetOrCreateIbFromRootIb(rootIb, "IFD/GPSInfo")
+	if err != nil {
+		return fmt.Errorf("get GPS IFD: %w", err)
+	}
+	latitudeRef, longitudeRef := "N", "E"
+	if latitude < 0 {
+		latitudeRef = "S"
+	}
+	if longitude < 0 {
+		longitudeRef = "W"
+	}
+	for _, tag := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"GPSVersionID", []byte{2, 3, 0, 0}},
+		{"GPSLatitudeRef", latitudeRef},
+		{"GPSLatitude", gpsDegrees(latitude)},
+		{"GPSLongitudeRef", longitudeRef},
+		{"GPSLongitude", gpsDegrees(longitude)},
+	} {
+		if err := gpsIb.SetStandardWithName(tag.name, tag.value); err != nil {
+			return fmt.Errorf("set %s: %w", tag.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/madkins23/gardepro/fixture"
+)
+
+// testTrack is a track of three points a minute apart from the test time, with a 20 minute gap before the last.
+var testTrack = Track{
+	{Latitude: 35.0, Longitude: -80.0, Time: testTime},
+	{Latitude: 35.1, Longitude: -80.2, Time: testTime.Add(time.Minute)},
+	{Latitude: 36.0, Longitude: -81.0, Time: testTime.Add(21 * time.Minute)},
+}
+
+func TestLocate(t *testing.T) {
+	tests := []struct {
+		name                string
+		track               Track
+		when                time.Time
+		latitude, longitude float64
+		found               bool
+	}{
+		{name: "empty", track: Track{}, when: testTime},
+		{name: "nil", when: testTime},
+		{name: "point", track: testTrack, when: testTime.Add(time.Minute), latitude: 35.1, longitude: -80.2, found: true},
+		{name: "interpolated", track: testTrack, when: testTime.Add(30 * time.Second), latitude: 35.05, longitude: -80.1, found: true},
+		{name: "before", track: testTrack, when: testTime.Add(-5 * time.Minute), latitude: 35.0, longitude: -80.0, found: true},
+		{name: "long before", track: testTrack, when: testTime.Add(-6 * time.Minute)},
+		{name: "after", track: testTrack, when: testTime.Add(26 * time.Minute), latitude: 36.0, longitude: -81.0, found: true},
+		{name: "long after", track: testTrack, when: testTime.Add(27 * time.Minute)},
+		{name: "gap", track: testTrack, when: testTime.Add(11 * time.Minute)},
+		{name: "single point", track: testTrack[:1], when: testTime.Add(time.Minute), latitude: 35.0, longitude: -80.0, found: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			latitude, longitude, found := test.track.Locate(test.when)
+			if found != test.found {
+				t.Fatalf("found %t, want %t", found, test.found)
+			}
+			if found && (math.Abs(latitude-test.latitude) > 1e-9 || math.Abs(longitude-test.longitude) > 1e-9) {
+				t.Errorf("located %g, %g, want %g, %g", latitude, longitude, test.latitude, test.longitude)
+			}
+		})
+	}
+}
+
+func TestImportTrack(t *testing.T) {
+	imp := testImporter(t, Options{Track: testTrack})
+	source := t.TempDir()
+	paths := importFiles(t, imp,
+		writeJPEG(t, source, "IMG_0001.JPG", fixture.JPEG{Captured: testTime, Model: "GardePro E6"}),
+		writeFile(t, source, "IMG_0002.MP4", testMP4(testTime.Add(time.Minute))))
+	tests := []struct {
+		path string
+		want float64
+	}{
+		{path: paths[0], want: 35.0},
+		{path: paths[1], want: 35.1},
+	}
+	for _, test := range tests {
+		// MP4 files are not geotagged, but their position is cataloged.
+		if file := catalogEntry(t, imp, test.path); file.Position == nil || math.Abs(file.Position.Latitude-test.want) > 1e-9 {
+			t.Errorf("%s position %v, want latitude %g", test.path, file.Position, test.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zeebo/blake3"
+	"github.com/zeebo/xxh3"
+)
+
+// Hash algorithms for file contents.
+const (
+	// HashSHA256 is widely supported (e.g. S3 checksums and sidecar files).
+	HashSHA256 = "sha256"
+	// HashXXH3 is very fast but not cryptographic.
+	HashXXH3 = "xxh3"
+	// HashBLAKE3 is both fast and cryptographic.
+	HashBLAKE3 = "blake3"
+)
+
+// newHash returns a new hash for the specified algorithm (HashSHA256 if empty).
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case HashSHA256, "":
+		return sha256.New(), nil
+	case HashXXH3:
+		return xxh3.New(), nil
+	case HashBLAKE3:
+		return blake3.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm '%s'", algorithm)
+	}
+}
+
+// hashString returns the hash sum as a hex string.
+func hashString(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// splitHash splits a catalog hash (algorithm:hex) into the algorithm and the hex string.
+// Hashes without an algorithm are SHA-256.
+func splitHash(sum string) (string, string) {
+	if algorithm, hex, found := strings.Cut(sum, ":"); found {
+		return algorithm, hex
+	}
+	return HashSHA256, sum
+}
+
+// hashFile returns the hash of a file's contents as a hex string.
+func hashFile(path, algorithm string) (string, error) {
+	return hashFileWatched(path, algorithm, nil)
+}
+
+// hashFileWatched returns the hash of a file's contents as hashFile does,
+// notifying the watchdog (if not nil) of progress as the file is read.
+func hashFileWatched(path, algorithm string, dog *watchdog) (string, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	if _, err := io.Copy(h, dog.watchReader(file)); err != nil {
+		return "", fmt.Errorf("hash file: %w", err)
+	}
+	return hashString(h), nil
+}
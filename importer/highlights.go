@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// Highlight reel defaults.
+const (
+	DefaultHighlights  = 10
+	DefaultStillLength = 4 * time.Second
+	DefaultClipLength  = 10 * time.Second
+)
+
+// highlightSize is the frame size of highlight reels, other sizes are scaled and padded to fit.
+const highlightSize = "1280:720"
+
+// HighlightOptions specifies which events are put in a highlights reel and how.
+type HighlightOptions struct {
+	// From and Until limit the capture times of events, zero for no limit. Until is exclusive.
+	From, Until time.Time
+	// Count is the number of events in the reel, DefaultHighlights if zero.
+	Count int
+	// Tag marks events to be preferred to all others (e.g. "highlight"), empty for none.
+	Tag string
+	// StillLength is how long each image is shown, DefaultStillLength if zero.
+	StillLength time.Duration
+	// ClipLength is the longest part of each video shown, DefaultClipLength if zero.
+	ClipLength time.Duration
+	// FFmpeg is the path of the ffmpeg executable, "ffmpeg" (found in the PATH) if empty.
+	FFmpeg string
+	// Output is the path of the highlights reel (MP4).
+	Output string
+}
+
+// Highlights assembles a reel of the top events in the catalog with their capture time
+// and camera captioned (burned in), in capture order. Events are the best frames of bursts,
+// other JPEG files, and clips (or the event videos stitched from them, see Stitch).
+// Events with the tag (or label) are preferred, then the highest rated,
+// then those with the highest classifier confidence,
+// then tagged or labeled events, then the sharpest. Returns the number of events in the reel.
+func (imp *Importer) Highlights(options *HighlightOptions) (int, error) {
+	if imp.options.Catalog == nil {
+		return 0, errors.New("highlights require a catalog")
+	} else if options.Output == "" {
+		return 0, errors.New("no highlights output path")
+	}
+	count, still, clip, ffmpeg := options.Count, options.StillLength, options.ClipLength, options.FFmpeg
+	if count <= 0 {
+		count = DefaultHighlights
+	}
+	if still <= 0 {
+		still = DefaultStillLength
+	}
+	if clip <= 0 {
+		clip = DefaultClipLength
+	}
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	events := highlights(imp.options.Catalog.Originals(), options, count)
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	work, err := os.MkdirTemp("", "gardepro-highlights-")
+	if err != nil {
+		return 0, fmt.Errorf("make work dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(work) }()
+	// Paths in the ffmpeg filter are relative to the work directory to avoid escaping.
+	var list strings.Builder
+	for i, event := range events {
+		path := imp.catalogPath(event)
+		if event.Event != "" {
+			path = imp.catalogPath(&catalog.File{Path: event.Event, Root: event.Root})
+		}
+		caption, segment := "caption-"+strconv.Itoa(i)+".txt", "segment-"+strconv.Itoa(i)+".mp4"
+		if err := os.WriteFile(filepath.Join(work, caption), []byte(highlightCaption(event)), 0644); err != nil {
+			return 0, fmt.Errorf("write caption: %w", err)
+		}
+		var args []string
+		if isJPEG(path) {
+			args = []string{"-loop", "1", "-t", seconds(still), "-i", path}
+		} else {
+			args = []string{"-t", seconds(clip), "-i", path}
+		}
+		args = append(args, "-vf", "scale="+highlightSize+":force_original_aspect_ratio=decrease,"+
+			"pad="+highlightSize+":(ow-iw)/2:(oh-ih)/2,"+
+			"drawtext=textfile="+caption+":x=20:y=h-th-20:fontsize=32:fontcolor=white:box=1:boxcolor=black@0.5:boxborderw=8",
+			"-r", "30", "-pix_fmt", "yuv420p", "-c:v", "libx264", "-an", segment)
+		if err := runFFmpeg(ffmpeg, work, args...); err != nil {
+			return 0, fmt.Errorf("highlight %s: %w", event.Path, err)
+		}
+		list.WriteString("file '" + segment + "'\n")
+	}
+	if err := os.WriteFile(filepath.Join(work, "segments.txt"), []byte(list.String()), 0644); err != nil {
+		return 0, fmt.Errorf("write segment list: %w", err)
+	}
+	output, err := filepath.Abs(options.Output)
+	if err != nil {
+		return 0, fmt.Errorf("output path: %w", err)
+	}
+	if err := runFFmpeg(ffmpeg, work, "-f", "concat", "-safe", "0", "-i", "segments.txt",
+		"-c", "copy", "-movflags", "+faststart", output); err != nil {
+		return 0, fmt.Errorf("join highlights: %w", err)
+	}
+	log.Info().Str("output", options.Output).Int("events", len(events)).Msg("Assembled highlights")
+	return len(events), nil
+}
+
+// highlights returns the top events of the files in capture order.
+func highlights(files []*catalog.File, options *HighlightOptions, count int) []*catalog.File {
+	events := make(map[string]*catalog.File)
+	for _, file := range files {
+		if !options.From.IsZero() && file.Captured.Before(options.From) ||
+			!options.Until.IsZero() && !file.Captured.Before(options.Until) || file.Offloaded != "" {
+			continue
+		}
+		key := file.Path
+		switch ext := strings.ToLower(filepath.Ext(file.Path)); {
+		case isJPEG(file.Path):
+			if file.Burst != "" && !file.Best {
+				continue
+			}
+		case ext == ".mp4" || ext == ".mov" || ext == ".avi":
+			if file.Event != "" {
+				key = file.Event
+			}
+		default:
+			continue
+		}
+		// Keep the best ranked clip of a stitched event.
+		if other, found := events[key]; !found || highlightBefore(file, other, options.Tag) {
+			events[key] = file
+		}
+	}
+	ranked := make([]*catalog.File, 0, len(events))
+	for _, event := range events {
+		ranked = append(ranked, event)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return highlightBefore(ranked[i], ranked[j], options.Tag)
+	})
+	if len(ranked) > count {
+		ranked = ranked[:count]
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return captureBefore(ranked[i], ranked[j])
+	})
+	return ranked
+}
+
+// highlightBefore returns whether file a ranks above file b as a highlight.
+func highlightBefore(a, b *catalog.File, tag string) bool {
+	if tag != "" {
+		if aTagged, bTagged := a.HasTag(tag), b.HasTag(tag); aTagged != bTagged {
+			return aTagged
+		}
+	}
+	if a.Rating != b.Rating {
+		return a.Rating > b.Rating
+	} else if a.Confidence != b.Confidence {
+		return a.Confidence > b.Confidence
+	} else if aTagged, bTagged := len(a.AllTags()) > 0, len(b.AllTags()) > 0; aTagged != bTagged {
+		return aTagged
+	} else if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.Captured.Before(b.Captured)
+}
+
+// highlightCaption returns the caption of a highlight: its capture time, camera, tags, and labels.
+func highlightCaption(file *catalog.File) string {
+	caption := file.Captured.Format("2006-01-02 15:04") + "  " + filepath.Base(cameraOf(file))
+	if tags := file.AllTags(); len(tags) > 0 {
+		caption += "  " + strings.Join(tags, ", ")
+	}
+	return caption
+}
+
+// seconds formats a duration as seconds for ffmpeg.
+func seconds(duration time.Duration) string {
+	return strconv.FormatFloat(duration.Seconds(), 'f', -1, 64)
+}
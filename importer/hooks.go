@@ -0,0 +1,65 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Hooks are shell commands run around an import, e.g. to mount a share,
+// start a backup, or update a dashboard. Empty commands are not run.
+// Hooks are described to the commands by GARDEPRO_* environment variables.
+type Hooks struct {
+	// PreSession is run before anything is imported.
+	// If it fails the import should not proceed.
+	PreSession string
+	// PostSession is run after the import, with GARDEPRO_SESSION_STATUS set to ok or failed.
+	PostSession string
+	// PostFile is run after each file is imported (or fails), with GARDEPRO_FILE_SOURCE,
+	// GARDEPRO_FILE_TARGET, GARDEPRO_FILE_STATUS (imported or failed), and GARDEPRO_FILE_ERROR set.
+	// Failures are logged but don't affect the import.
+	PostFile string
+	// Env is added to the environment of all hooks,
+	// e.g. GARDEPRO_SESSION, GARDEPRO_SESSION_SOURCE, and GARDEPRO_SESSION_TARGET.
+	Env []string
+}
+
+// RunHook runs a hook command (if not empty) using the shell with the specified environment variables
+// added to those of the Hooks and the process. The output of the command is logged.
+func (h *Hooks) RunHook(command string, env ...string) error {
+	if command == "" {
+		return nil
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(append(os.Environ(), h.Env...), env...)
+	output, err := cmd.CombinedOutput()
+	log.Debug().Str("hook", command).Bytes("output", output).Msg("Ran hook")
+	if err != nil {
+		return fmt.Errorf("run hook '%s': %w", command, err)
+	}
+	return nil
+}
+
+// postFile runs the PostFile hook for an imported file.
+func (imp *Importer) postFile(source, targetPath string, importErr error) {
+	if imp.options.Hooks == nil || imp.options.Hooks.PostFile == "" {
+		return
+	}
+	status, message := "imported", ""
+	if importErr != nil {
+		status, message = "failed", importErr.Error()
+	}
+	if err := imp.options.Hooks.RunHook(imp.options.Hooks.PostFile,
+		"GARDEPRO_FILE_SOURCE="+source, "GARDEPRO_FILE_TARGET="+targetPath,
+		"GARDEPRO_FILE_STATUS="+status, "GARDEPRO_FILE_ERROR="+message); err != nil {
+		log.Warn().Err(err).Str("source", source).Msg("Post-file hook")
+	}
+}
@@ -0,0 +1,859 @@
+// Package importer renames and copies media files from GardePro cameras
+// into a target directory tree organized by year.
+package importer
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsoprea/go-exif/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/chain"
+	"github.com/madkins23/gardepro/trace"
+)
+
+const (
+	targetDirFmt    = "/2006"
+	fileNameStubFmt = "01-02-15:04:05-"
+	fileDateStubFmt = targetDirFmt + "/" + fileNameStubFmt
+)
+
+// DefaultBlockSize is the default size of the buffer used to copy files.
+const DefaultBlockSize = 1 << 20
+
+// OpenCatalog opens the catalog of the specified target root directory.
+func OpenCatalog(target string) (*catalog.Catalog, error) {
+	return catalog.Open(filepath.Join(target, StateDir))
+}
+
+// OpenChain opens the hash chain of the specified target root directory for appending links
+// signed with the key.
+func OpenChain(target string, key ed25519.PrivateKey) (*chain.Chain, error) {
+	return chain.Open(filepath.Join(target, StateDir), key)
+}
+
+// FindTarget returns the target root directory and the root directory containing
+// the specified path by looking for the state directory in the path's parent directories.
+// These are the same unless the path is in a pool root,
+// in which case the target root is read from the pool marker file.
+func FindTarget(path string) (string, string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", fmt.Errorf("absolute path: %w", err)
+	}
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		if marker, err := os.ReadFile(filepath.Join(dir, StateDir, poolMarker)); err == nil {
+			return strings.TrimSpace(string(marker)), dir, nil
+		} else if stat, err := os.Stat(filepath.Join(dir, StateDir)); err == nil && stat.IsDir() {
+			return dir, dir, nil
+		} else if parent := filepath.Dir(dir); parent == dir {
+			return "", "", fmt.Errorf("no %s directory above %s", StateDir, path)
+		}
+	}
+}
+
+// Importer copies media files into a target root directory.
+type Importer struct {
+	target  string
+	options Options
+	// cards caches card identities by directory for camera overrides (see cardOf).
+	cards      map[string]*catalog.Card
+	cardsMutex sync.Mutex
+	// aliases are the names recorded for source files extracted from archives (see sourceName).
+	aliases      map[string]string
+	aliasesMutex sync.Mutex
+	// repeats are the target paths of files which are copies of captures already imported (see linkCopies).
+	repeats      map[string]bool
+	repeatsMutex sync.Mutex
+}
+
+// Options configures an Importer.
+// The zero value copies files unchanged.
+type Options struct {
+	// CameraZone is the time zone to which the camera clocks are set, nil if unknown.
+	// When known the EXIF OffsetTime and OffsetTimeOriginal tags
+	// are written into archived JPEG files.
+	CameraZone *time.Location
+	// Cameras are overrides of these options for the files of particular cameras, in order of precedence.
+	Cameras []*CameraOverride
+	// Track is used to write GPS positions into archived JPEG files
+	// captured at times covered by the track, nil for none.
+	Track Track
+	// Catalog records imported files, nil for none.
+	Catalog *catalog.Catalog
+	// PreserveStructure keeps the path of each file relative to a batch source directory
+	// beneath the year directory instead of putting all files directly in the year directory.
+	PreserveStructure bool
+	// Pool is a list of additional root directories across which the archive is spread.
+	// The catalog is kept in the target root and records the root of each file.
+	Pool []string
+	// PoolPolicy chooses the root directory for each file (PoolFillFirst or PoolMonth).
+	PoolPolicy string
+	// Hash is the algorithm (HashSHA256, HashXXH3, or HashBLAKE3) used to hash file contents
+	// for the catalog and for detecting duplicate source files, HashSHA256 if empty.
+	// Copied files are hashed as they are copied.
+	Hash string
+	// Jobs is the number of files imported concurrently by ImportBatch, one if zero.
+	Jobs int
+	// BlockSize is the size of the buffer used to copy files, DefaultBlockSize if zero.
+	BlockSize int
+	// Retry is how long to wait for an unavailable target to return
+	// before failing the import of a file, zero to fail immediately.
+	Retry time.Duration
+	// CheckExposure flags severely under or over exposed and flat JPEG files in the catalog (see Exposure).
+	CheckExposure bool
+	// FixOrientation sets non-standard EXIF Orientation values (other than 1 through 8)
+	// to upright (1) in archived JPEG files. The pixels are not rotated.
+	FixOrientation bool
+	// Hooks are commands run around the import, nil for none.
+	// Only PostFile is run by the Importer.
+	Hooks *Hooks
+	// Classify returns tags describing an archived file (e.g. from a classifier plugin)
+	// and the confidence (0 to 1, zero if unknown) in them, which are recorded in the catalog,
+	// nil for none. Failures are logged but don't affect the import.
+	Classify func(path string, captured time.Time) ([]string, float64, error)
+	// Notify is called after each file is imported (or fails), nil for none.
+	// Repeat is set for a copy of a capture already imported at another resolution (see linkCopies),
+	// which notifiers needn't announce again.
+	Notify func(source, targetPath string, repeat bool, err error)
+	// Progress is called by ImportBatch after each source file is done (imported, skipped, or failed)
+	// with the numbers of files done and in the batch, nil for none. Calls are serialized.
+	Progress func(done, total int)
+	// Timeout is how long an import may go without progress (e.g. on a wedged card reader)
+	// before the file is skipped, zero for no limit.
+	Timeout time.Duration
+	// SidecarGPS records the GPS position from the SRT telemetry sidecar of each video
+	// (written by DJI drones) in the catalog. Sidecars are co-filed with their videos in any case.
+	SidecarGPS bool
+	// Verify the media data of each source file before archiving it.
+	// Damaged files are copied into the quarantine directory instead.
+	Verify bool
+	// Strict stops ImportBatch at the first file that fails to import,
+	// the files not yet started fail with ErrAborted.
+	Strict bool
+	// Lenient quarantines source files without a capture time or of an unrecognized format
+	// (as damaged files are with Verify) so that they are set aside rather than only failed.
+	Lenient bool
+	// Since and Until limit the files imported to those captured at or after Since
+	// and before Until (camera clock times), zero for no limit.
+	Since, Until time.Time
+	// Index is the NAS media index (IndexSynology) to which archived files are added, empty for none.
+	Index string
+	// Only limits the files imported to those with the specified extensions
+	// (e.g. jpg and mp4, ignoring case), empty for all files.
+	Only []string
+	// QuickSkip presumes that a pre-existing target file (named per the capture time of the source)
+	// is identical to the source without reading either file, if it has the same size
+	// or the catalog records it as imported from the same source path (e.g. when the EXIF data
+	// of archived JPEG files is rewritten). Files not yet in the catalog are compared as usual.
+	QuickSkip bool
+	// Chain records the path and hash of each file added to the catalog
+	// in a signed hash chain (see OpenChain), nil for none.
+	Chain *chain.Chain
+	// Journal records the decision made for each source file, nil for none.
+	Journal *Journal
+	// Tracer records spans of the import of each file and its stages
+	// (verify, extract, copy, and catalog), nil for none.
+	Tracer *trace.Tracer
+}
+
+// New returns an Importer for the specified target root directory.
+// The target and pool root paths are made absolute (see plainPath).
+func New(target string, options Options) *Importer {
+	for i, root := range options.Pool {
+		options.Pool[i] = plainPath(root)
+	}
+	return &Importer{target: plainPath(target), options: options}
+}
+
+// Target returns the target root directory.
+func (imp *Importer) Target() string {
+	return imp.target
+}
+
+// Import renames the source file per its capture time and copies it into the target tree.
+// The returned path is the target path of the file, which is also returned on error when known.
+// For ErrCorrupt the returned path is that of the quarantined copy.
+// Errors are returned as *Error wrapping one of the Err* values where applicable.
+// Files excluded by the import filters (see Excluded) are skipped, returning an empty path.
+func (imp *Importer) Import(source string) (string, error) {
+	if imp.Excluded(source) {
+		log.Info().Str("source", source).Msg("Skipping file excluded by import filters")
+		imp.decide(source, ActionExcluded, "", time.Time{}, "")
+		return "", nil
+	}
+	return imp.importFile(source, "")
+}
+
+// importFile imports the source file into the specified subdirectory
+// (slash separated, empty for none) of the year directory.
+// Afterwards the archived file is added to the NAS index
+// and the PostFile hook and Notify function are run (if any).
+func (imp *Importer) importFile(source, subDir string) (string, error) {
+	span := imp.options.Tracer.Start(nil, "file", "source", source, trace.AttrClass, fileClass(source))
+	if imp.options.Tracer != nil {
+		if stat, err := os.Stat(source); err == nil {
+			span.Set(trace.AttrBytes, strconv.FormatInt(stat.Size(), 10))
+		}
+	}
+	targetPath, err := imp.importFileRetry(source, subDir, span)
+	if err == nil {
+		imp.index(targetPath)
+	} else {
+		imp.decideError(source, targetPath, err)
+	}
+	imp.postFile(imp.sourceName(source), targetPath, err)
+	repeat := imp.repeat(targetPath)
+	if imp.options.Notify != nil {
+		imp.options.Notify(imp.sourceName(source), targetPath, repeat, err)
+	}
+	span.Set("target", targetPath)
+	span.End(err)
+	return targetPath, err
+}
+
+// fileClass returns the class of a file for tracing and stats, its lower case extension without the dot.
+func fileClass(path string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+}
+
+// importFileRetry imports the source file, pausing and retrying for up to Options.Retry
+// while the target is unavailable.
+func (imp *Importer) importFileRetry(source, subDir string, span *trace.Span) (string, error) {
+	var deadline time.Time
+	delay := retryMinDelay
+	for {
+		targetPath, err := imp.importFileWatched(source, subDir, span)
+		if err == nil || imp.options.Retry <= 0 || !errors.Is(err, ErrTargetUnavailable) {
+			return targetPath, err
+		}
+		if deadline.IsZero() {
+			deadline = time.Now().Add(imp.options.Retry)
+			log.Warn().Err(err).Dur("retry", imp.options.Retry).Msg("Target unavailable, pausing import")
+		}
+		for {
+			if time.Now().Add(delay).After(deadline) {
+				return targetPath, err
+			}
+			time.Sleep(delay)
+			if delay *= 2; delay > retryMaxDelay {
+				delay = retryMaxDelay
+			}
+			if probeErr := imp.Probe(); probeErr == nil {
+				log.Info().Str("source", source).Msg("Target available, resuming import")
+				break
+			}
+		}
+	}
+}
+
+// importFileOnce imports the source file into the specified subdirectory
+// (slash separated, empty for none) of the year directory.
+// The watchdog (if not nil) is notified of progress.
+// Each stage is traced as a child of the span.
+func (imp *Importer) importFileOnce(source, subDir string, dog *watchdog, span *trace.Span) (string, error) {
+	source = plainPath(source)
+	if imp.options.Verify {
+		stage := imp.options.Tracer.Start(span, "verify")
+		err := verify(source)
+		stage.End(err)
+		if err != nil {
+			if !dog.claim() {
+				return "", abandoned(source)
+			}
+			return imp.quarantineError(source, err)
+		}
+	}
+
+	dog.touch()
+	stage := imp.options.Tracer.Start(span, "extract")
+	when, err := CaptureTime(source)
+	stage.End(err)
+	if err != nil {
+		if imp.options.Lenient && (errors.Is(err, ErrNoCaptureTime) || errors.Is(err, ErrUnsupportedFormat)) {
+			if !dog.claim() {
+				return "", abandoned(source)
+			}
+			return imp.quarantineError(source, err)
+		}
+		return "", &Error{Source: source, Err: err}
+	}
+	baseName := filepath.Base(source)
+	if override := imp.override(source); override != nil {
+		log.Debug().Str("source", source).Str("camera", override.Name).Msg("Camera override")
+		when = when.Add(override.ClockOffset)
+		baseName = override.Prefix + baseName
+	}
+
+	dog.touch()
+	root, err := imp.chooseRoot(source, when, subDir)
+	if err != nil {
+		return "", &Error{Source: source, Err: err}
+	}
+	targetPath := existingPath(imp.targetPath(root, when, subDir, baseName))
+	if err := imp.checkTargetDir(root, when, filepath.Dir(targetPath)); err != nil {
+		return targetPath, &Error{Source: source, Target: targetPath, Err: err}
+	}
+	if imp.options.QuickSkip && imp.presumeImported(source, targetPath) {
+		if !dog.claim() {
+			return targetPath, abandoned(source)
+		}
+		log.Info().Str("target-path", targetPath).Msg("Skipping pre-existing file presumed identical")
+		imp.decide(source, ActionPresumed, targetPath, when, "")
+		return targetPath, nil
+	}
+	dog.touch()
+	stage = imp.options.Tracer.Start(span, "copy", "target", targetPath)
+	data, err := imp.targetData(source, when)
+	if err != nil {
+		stage.End(err)
+		return targetPath, &Error{Source: source, Target: targetPath, Err: err}
+	}
+	hash, err := newHash(imp.options.Hash)
+	if err != nil {
+		stage.End(err)
+		return targetPath, &Error{Source: source, Target: targetPath, Err: err}
+	}
+	timed := &timedHash{Hash: dog.watchHash(hash)}
+	hash = timed
+	copied, err := copySourceToTarget(source, targetPath, data, hash, imp.options.BlockSize, dog)
+	// Files are hashed as they are copied so the time spent hashing is measured within the copy.
+	stage.Measure("hash", timed.elapsed)
+	stage.Set("copied", strconv.FormatBool(copied))
+	stage.End(err)
+	if err != nil {
+		if !errors.Is(err, ErrConflict) {
+			// Copy errors such as I/O errors are due to the target if it can no longer be reached.
+			if probeErr := imp.Probe(); probeErr != nil {
+				err = fmt.Errorf("%w (%s)", probeErr, err)
+			}
+		}
+		return targetPath, &Error{Source: source, Target: targetPath, Err: err}
+	}
+	if copied {
+		setCreated(targetPath, imp.instant(source, when))
+	}
+	if err := copySidecars(source, targetPath, imp.options.BlockSize, dog); err != nil {
+		return targetPath, &Error{Source: source, Target: targetPath, Err: err}
+	}
+	stage = imp.options.Tracer.Start(span, "catalog")
+	err = imp.catalogFile(source, targetPath, when, copied, data != nil, hash, dog)
+	stage.End(err)
+	if err != nil {
+		return targetPath, &Error{Source: source, Target: targetPath, Err: err}
+	}
+	if !dog.claim() {
+		return targetPath, abandoned(source)
+	}
+	if copied {
+		imp.decide(source, ActionImported, targetPath, when, "")
+	} else {
+		imp.decide(source, ActionIdentical, targetPath, when, "")
+	}
+	return targetPath, nil
+}
+
+// presumeImported returns true if the target file exists and is presumed identical
+// to the source (see Options.QuickSkip).
+func (imp *Importer) presumeImported(source, targetPath string) bool {
+	target, err := os.Stat(targetPath)
+	if err != nil {
+		return false
+	}
+	if imp.options.Catalog != nil {
+		path, err := imp.relative(targetPath)
+		if err != nil {
+			return false
+		}
+		file := imp.options.Catalog.File(path)
+		if file == nil {
+			return false
+		} else if abs, err := filepath.Abs(source); err == nil && imp.sourceName(abs) == file.Source {
+			return true
+		}
+	}
+	stat, err := os.Stat(source)
+	return err == nil && stat.Size() == target.Size()
+}
+
+// timedHash is a hash that measures the time spent hashing.
+type timedHash struct {
+	hash.Hash
+	elapsed time.Duration
+}
+
+func (h *timedHash) Write(p []byte) (int, error) {
+	start := time.Now()
+	defer func() { h.elapsed += time.Since(start) }()
+	return h.Hash.Write(p)
+}
+
+// catalogFile records an imported file in the catalog.
+// Pre-existing identical files are only recorded if not already in the catalog
+// so that the original provenance is preserved.
+// The hash has been fed the file contents if the file was copied.
+// If the archived contents were modified the source file is hashed as well (see catalog.File.SourceHash).
+// The watchdog (if not nil) is notified of progress and claimed before anything is recorded.
+func (imp *Importer) catalogFile(source, targetPath string, when time.Time, copied, modified bool, h hash.Hash, dog *watchdog) error {
+	if imp.options.Catalog == nil {
+		return nil
+	}
+	path, err := imp.relative(targetPath)
+	if err != nil {
+		return err
+	}
+	if !copied && imp.options.Catalog.File(path) != nil {
+		return nil
+	}
+	var sourceSum string
+	if modified {
+		// Before the source is renamed (see sourceName), as it may be an extracted copy.
+		if sourceSum, err = hashFileWatched(source, imp.options.Hash, dog); err != nil {
+			return fmt.Errorf("hash source file: %w", err)
+		}
+	}
+	if abs, err := filepath.Abs(source); err == nil {
+		source = abs
+	}
+	source = imp.sourceName(source)
+	var sum string
+	if copied {
+		sum = hashString(h)
+	} else if sum, err = hashFileWatched(targetPath, imp.options.Hash, dog); err != nil {
+		return fmt.Errorf("hash target file: %w", err)
+	}
+	algorithm := imp.options.Hash
+	if algorithm == "" {
+		algorithm = HashSHA256
+	}
+	var root string
+	if root = imp.rootOf(targetPath); root == imp.target {
+		root = ""
+	} else if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	file := &catalog.File{
+		Path:     path,
+		Root:     root,
+		Source:   source,
+		Hash:     algorithm + ":" + sum,
+		Captured: when,
+		Imported: time.Now(),
+	}
+	if sourceSum != "" {
+		file.SourceHash = algorithm + ":" + sourceSum
+	}
+	imp.describeFile(file, targetPath)
+	if !dog.claim() {
+		return abandoned(source)
+	}
+	if imp.linkCopies(file, targetPath) {
+		imp.repeatsMutex.Lock()
+		if imp.repeats == nil {
+			imp.repeats = make(map[string]bool)
+		}
+		imp.repeats[targetPath] = true
+		imp.repeatsMutex.Unlock()
+	}
+	if err := imp.options.Catalog.AddFile(file); err != nil {
+		return fmt.Errorf("catalog file: %w", err)
+	}
+	if imp.options.Chain != nil {
+		if _, err := imp.options.Chain.Append(file.Path, file.Hash); err != nil {
+			return fmt.Errorf("chain file: %w", err)
+		}
+	}
+	return nil
+}
+
+// describeFile sets the catalog entry fields read from an archived file and its sidecars:
+// position, orientation, exposure, classifier tags, battery level, and the camera recognized by its scene.
+// Fields are only changed if they are read, or (for exposure and tags) if they are checked.
+// Failures are logged.
+func (imp *Importer) describeFile(file *catalog.File, path string) {
+	var err error
+	source := file.Source
+	if source == "" {
+		source = path
+	}
+	if imp.options.SidecarGPS {
+		for _, srt := range sidecars(path) {
+			if strings.EqualFold(filepath.Ext(srt), ".srt") {
+				if position, err := SRTposition(srt); err != nil {
+					log.Warn().Err(err).Str("path", srt).Msg("Read sidecar GPS position")
+				} else if position != nil {
+					file.Position = position
+				}
+			}
+		}
+	}
+	if file.Position == nil && imp.options.Track != nil {
+		if latitude, longitude, ok := imp.options.Track.Locate(imp.instant(source, file.Captured)); ok {
+			file.Position = &catalog.Position{Latitude: latitude, Longitude: longitude}
+		}
+	}
+	if isJPEG(path) || strings.EqualFold(filepath.Ext(path), ".heic") {
+		if file.Orientation, err = EXIForientation(path); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Read orientation")
+		}
+	}
+	if imp.options.CheckExposure && isJPEG(path) {
+		if file.Exposure, err = Exposure(path); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Check exposure")
+		}
+	}
+	if imp.options.Classify != nil {
+		if file.Tags, file.Confidence, err = imp.options.Classify(path, file.Captured); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Classify file")
+		}
+		if override := imp.override(source); override != nil && file.Confidence > 0 && file.Confidence < override.MinConfidence {
+			log.Debug().Str("path", path).Strs("tags", file.Tags).Float64("confidence", file.Confidence).Msg("Tags below camera threshold")
+			file.Tags = nil
+		}
+	}
+	if battery := BatteryLevel(path); battery != nil {
+		file.Battery = battery
+	}
+	// Files of cameras identified by card or EXIF model don't need recognizing.
+	if imp.override(source) == nil {
+		if camera := imp.recognize(path, file.Captured); camera != "" && camera != filepath.Dir(file.Source) {
+			log.Debug().Str("path", path).Str("camera", camera).Msg("Recognized camera")
+			file.Camera = camera
+		}
+	}
+}
+
+// relative returns the path relative to its root as used in the catalog.
+func (imp *Importer) relative(path string) (string, error) {
+	if rel, err := filepath.Rel(imp.rootOf(path), path); err != nil {
+		return "", fmt.Errorf("relative path: %w", err)
+	} else {
+		return filepath.ToSlash(rel), nil
+	}
+}
+
+// targetData returns the modified contents for the archived copy of the source file
+// or nil if the source file is to be copied unchanged.
+func (imp *Importer) targetData(source string, when time.Time) ([]byte, error) {
+	if !isJPEG(source) {
+		return nil, nil
+	}
+
+	var updates []func(rootIb *exif.IfdBuilder) error
+	if override := imp.override(source); override != nil && override.ClockOffset != 0 {
+		updates = append(updates, func(rootIb *exif.IfdBuilder) error {
+			return EXIFsetCaptureTime(rootIb, when)
+		})
+	}
+	if imp.zone(source) != nil {
+		offset := imp.instant(source, when).Format("-07:00")
+		updates = append(updates, func(rootIb *exif.IfdBuilder) error {
+			return EXIFsetOffsetTime(rootIb, offset)
+		})
+	}
+	if imp.options.Track != nil {
+		if latitude, longitude, ok := imp.options.Track.Locate(imp.instant(source, when)); ok {
+			updates = append(updates, func(rootIb *exif.IfdBuilder) error {
+				return EXIFsetGPS(rootIb, latitude, longitude)
+			})
+		} else {
+			log.Debug().Str("source", source).Msg("No GPS track position")
+		}
+	}
+	if imp.options.FixOrientation {
+		if orientation, err := EXIForientation(source); err == nil && orientation != 0 && !validOrientation(orientation) {
+			log.Debug().Str("source", source).Int("orientation", orientation).Msg("Fix orientation")
+			updates = append(updates, func(rootIb *exif.IfdBuilder) error {
+				return EXIFsetOrientation(rootIb, orientationUpright)
+			})
+		}
+	}
+	if len(updates) == 0 {
+		return nil, nil
+	}
+
+	data, err := readFileBounded(source)
+	if err != nil {
+		return nil, fmt.Errorf("read source file: %w", err)
+	}
+	if data, err = EXIFupdateData(data, func(rootIb *exif.IfdBuilder) error {
+		for _, update := range updates {
+			if err := update(rootIb); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("update EXIF: %w", err)
+	}
+	return data, nil
+}
+
+// instant returns the capture time as an absolute time.
+// JPEG capture times are camera wall clock times (parsed as UTC)
+// so they are interpreted in the camera time zone (or local time zone if unknown).
+func (imp *Importer) instant(source string, when time.Time) time.Time {
+	if !isJPEG(source) {
+		return when
+	}
+	zone := imp.zone(source)
+	if zone == nil {
+		zone = localTimeZone
+	}
+	return time.Date(when.Year(), when.Month(), when.Day(),
+		when.Hour(), when.Minute(), when.Second(), when.Nanosecond(), zone)
+}
+
+// zone returns the time zone of the camera clock of the source file, nil if unknown.
+func (imp *Importer) zone(source string) *time.Location {
+	if override := imp.override(source); override != nil && override.Zone != nil {
+		return override.Zone
+	}
+	return imp.options.CameraZone
+}
+
+// targetPath returns the path within the root directory for a file with the specified base name
+// within the specified subdirectory (slash separated, empty for none) of the year directory.
+// The subdirectory and base name are normalized (see normalName).
+func (imp *Importer) targetPath(root string, when time.Time, subDir, baseName string) string {
+	subDir, baseName = normalName(subDir), normalName(baseName)
+	if subDir == "" {
+		return root + when.Format(fileDateStubFmt) + baseName
+	}
+	return root + when.Format(targetDirFmt) + "/" + subDir + "/" + when.Format(fileNameStubFmt) + baseName
+}
+
+// archiveSubDir returns the subdirectory (slash separated, empty for none)
+// of the year directory containing a file in the target tree.
+func (imp *Importer) archiveSubDir(path string) (string, error) {
+	rel, err := imp.relative(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	if _, subDir, found := strings.Cut(rel, "/"); found {
+		return subDir, nil
+	}
+	return "", nil
+}
+
+// archiveBaseName returns the original base name of a file in the target tree.
+func archiveBaseName(path string) string {
+	base := filepath.Base(path)
+	if len(base) > len(fileNameStubFmt) {
+		return base[len(fileNameStubFmt):]
+	}
+	return base
+}
+
+// CaptureTime returns the time at which the media file was captured.
+// The time is returned in the local time zone.
+// Extractors registered for the file extension take precedence over the built-in formats.
+// If the file has no capture time the file name and modification time are tried (see fileNameTime).
+func CaptureTime(path string) (time.Time, error) {
+	when, err := metadataCaptureTime(path)
+	if errors.Is(err, ErrNoCaptureTime) {
+		if fallback, fallbackErr := fileNameTime(path); fallbackErr == nil {
+			log.Debug().Err(err).Str("path", path).Time("when", fallback).Msg("Capture time from file name")
+			return fallback, nil
+		}
+	}
+	return when, err
+}
+
+// metadataCaptureTime returns the capture time from the metadata of the media file.
+func metadataCaptureTime(path string) (time.Time, error) {
+	if extractor := extractor(path); extractor != nil {
+		return extractCaptureTime(extractor, path)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".jpg", ".jpeg", ".heic":
+		return EXIFcaptureTime(path)
+	case ".mp4":
+		return MP4captureTime(path)
+	case ".mov":
+		return MOVcaptureTime(path)
+	case ".avi":
+		return AVIcaptureTime(path)
+	case ".wav":
+		return WAVcaptureTime(path)
+	case ".mp3":
+		return MP3captureTime(path)
+	default:
+		return time.Time{}, fmt.Errorf("%w: extension %s", ErrUnsupportedFormat, ext)
+	}
+}
+
+// verify checks that the media data of a file is intact.
+func verify(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		return JPEGverify(path)
+	case ".mp4", ".mov":
+		return MP4verify(path)
+	case ".avi":
+		return AVIverify(path)
+	case ".wav":
+		return WAVverify(path)
+	}
+	return nil
+}
+
+func isJPEG(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".jpg" || ext == ".jpeg"
+}
+
+// checkTargetDir checks the year directory for the capture time, creating it if necessary,
+// and then creates the target directory beneath it if it is different.
+// Only the year directory is created by itself so that a missing
+// (e.g. unmounted) target root is not silently recreated.
+func (imp *Importer) checkTargetDir(root string, when time.Time, targetDir string) error {
+	yearDir := root + when.Format(targetDirFmt)
+	if err := checkTargetDir(yearDir); err != nil {
+		return err
+	} else if targetDir != yearDir {
+		if err := os.MkdirAll(targetDir, 0766); err != nil {
+			return fmt.Errorf("%w: make target dir: %s", ErrTargetUnavailable, err)
+		}
+	}
+	return nil
+}
+
+func checkTargetDir(targetDir string) error {
+	if stat, err := os.Stat(targetDir); err == nil {
+		if !stat.IsDir() {
+			return fmt.Errorf("%w: target dir is not a directory", ErrTargetUnavailable)
+		}
+	} else if errors.Is(err, os.ErrNotExist) {
+		if err := os.Mkdir(targetDir, 0766); err != nil {
+			return fmt.Errorf("%w: make target dir: %s", ErrTargetUnavailable, err)
+		}
+	} else {
+		return fmt.Errorf("%w: stat target dir: %s", ErrTargetUnavailable, err)
+	}
+	return nil
+}
+
+// copySourceToTarget copies the source file to the target path.
+// If data is not nil it is written instead of the source file contents.
+// The copied contents are also written to the hash.
+// Returns false if an identical target file already exists.
+// The target file is created exclusively so that an existing file is never overwritten,
+// even one created concurrently or with a name that differs only in case
+// on a case-insensitive file system.
+func copySourceToTarget(source, target string, data []byte, h hash.Hash, blockSize int, dog *watchdog) (bool, error) {
+	if _, err := os.Stat(target); err == nil {
+		return false, compareExisting(source, target, data, dog)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("stat target file: %w", err)
+	}
+	var err error
+	if data != nil {
+		if err = writeFileExclusive(target, data); err == nil {
+			_, _ = h.Write(data)
+		}
+	} else {
+		err = copyFileHash(source, target, h, blockSize)
+	}
+	if errors.Is(err, os.ErrExist) {
+		// Created since it was checked, so compare with it instead
+		// (only once, as a dangling link is never found by os.Stat).
+		return false, compareExisting(source, target, data, dog)
+	} else if err != nil {
+		// Don't leave a partial file that would conflict when the import is retried.
+		_ = os.Remove(target)
+		return false, fmt.Errorf("copy file: %w", err)
+	}
+	log.Info().Str("target-path", target).Msg("Copied file")
+	return true, nil
+}
+
+// compareExisting compares an existing target file with the source file or, if not nil, the data,
+// returning ErrConflict if they differ.
+// The watchdog (if not nil) is notified of progress.
+func compareExisting(source, target string, data []byte, dog *watchdog) error {
+	if equal, err := compareTarget(source, target, data, dog); err != nil {
+		return fmt.Errorf("compare files: %w", err)
+	} else if !equal {
+		return fmt.Errorf("%w: pre-existing file not identical", ErrConflict)
+	}
+	log.Info().Str("target-path", target).Msg("Skipping pre-existing identical file")
+	return nil
+}
+
+// compareTarget compares the target file with the source file or, if not nil, the data.
+// The watchdog (if not nil) is notified of progress.
+func compareTarget(source, target string, data []byte, dog *watchdog) (bool, error) {
+	if data == nil {
+		return compareFiles(source, target, dog)
+	}
+	return compareFileData(target, data, dog)
+}
+
+// setCreated sets the creation (birth) time of a file to the capture time where supported
+// (macOS and Windows) so that sorting by creation date matches the archive.
+// Failures are only logged since the file itself is intact.
+func setCreated(path string, when time.Time) {
+	if err := setBirthTime(path, when); err != nil {
+		log.Warn().Err(err).Str("target-path", path).Msg("Set file creation time")
+	}
+}
+
+// writeFileExclusive writes the data to a new file, failing if the file already exists.
+func writeFileExclusive(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func copyFile(source, target string) error {
+	return copyFileHash(source, target, nil, 0)
+}
+
+// copyFileHash copies the source file to a new target file using a buffer of blockSize bytes
+// (DefaultBlockSize if zero), writing the contents to the hash (if not nil) as they are copied.
+// Blocks of zeros are left as holes in the target file (see sparseWriter).
+func copyFileHash(source, target string, h hash.Hash, blockSize int) error {
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("open source file: %w", err)
+	}
+	defer func() { _ = sourceFile.Close() }()
+	targetFile, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return fmt.Errorf("create target file: %w", err)
+	}
+	defer func() { _ = targetFile.Close() }()
+	var reader io.Reader = sourceFile
+	if h != nil {
+		reader = io.TeeReader(sourceFile, h)
+	}
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+	// Hide any ReaderFrom/WriterTo so that the buffer is always used.
+	writer := &sparseWriter{file: targetFile}
+	if _, err = io.CopyBuffer(writer, struct{ io.Reader }{reader}, make([]byte, blockSize)); err != nil {
+		return fmt.Errorf("copy file: %w", err)
+	} else if err = writer.Close(); err != nil {
+		return fmt.Errorf("set file size: %w", err)
+	}
+	return nil
+}
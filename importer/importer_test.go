@@ -0,0 +1,284 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/fixture"
+)
+
+func TestMain(m *testing.M) {
+	// The importer logs each file, which only obscures test failures.
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// testImporter returns an importer for a new target root directory with a catalog,
+// closed when the test ends.
+func testImporter(t *testing.T, options Options) *Importer {
+	t.Helper()
+	target := t.TempDir()
+	cat, err := OpenCatalog(target)
+	if err != nil {
+		t.Fatalf("open catalog: %s", err)
+	}
+	t.Cleanup(func() { _ = cat.Close() })
+	options.Catalog = cat
+	return New(target, options)
+}
+
+// writeFile writes the data into a file with the name beneath the directory, returning its path.
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("make dir: %s", err)
+	} else if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write %s: %s", name, err)
+	}
+	return path
+}
+
+// writeJPEG writes a synthesized JPEG file beneath the directory, returning its path.
+func writeJPEG(t *testing.T, dir, name string, spec fixture.JPEG) string {
+	t.Helper()
+	data, err := spec.Bytes()
+	if err != nil {
+		t.Fatalf("synthesize %s: %s", name, err)
+	}
+	return writeFile(t, dir, name, data)
+}
+
+// importFiles imports the source files, failing the test on any error.
+func importFiles(t *testing.T, imp *Importer, sources ...string) []string {
+	t.Helper()
+	var paths []string
+	for _, source := range sources {
+		path, err := imp.Import(source)
+		if err != nil {
+			t.Fatalf("import %s: %s", source, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// testTime is the capture time of test files, in the local time zone as camera clocks are.
+var testTime = time.Date(2024, time.May, 1, 6, 30, 0, 0, time.Local)
+
+// catalogEntry returns the catalog entry of the archived file, failing the test if there is none.
+func catalogEntry(t *testing.T, imp *Importer, path string) *catalog.File {
+	t.Helper()
+	rel, err := imp.relative(path)
+	if err != nil {
+		t.Fatalf("relative path: %s", err)
+	}
+	file := imp.options.Catalog.File(rel)
+	if file == nil {
+		t.Fatalf("not in catalog: %s", rel)
+	}
+	return file
+}
+
+// standardFixtures writes the standard fixtures (see fixture.Standard) captured on the test date
+// into a new directory, returning it and the fixtures in import order.
+func standardFixtures(t *testing.T) (string, []*fixture.Fixture) {
+	t.Helper()
+	fixtures, err := fixture.Standard(testTime)
+	if err != nil {
+		t.Fatalf("synthesize fixtures: %s", err)
+	}
+	dir := t.TempDir()
+	if err := fixture.Write(dir, fixtures, testTime); err != nil {
+		t.Fatalf("write fixtures: %s", err)
+	}
+	return dir, fixtures
+}
+
+// wallClock formats a time as read from a camera clock, without its time zone.
+func wallClock(when time.Time) string {
+	return when.Format("2006-01-02 15:04:05")
+}
+
+func TestCaptureTime(t *testing.T) {
+	dir, fixtures := standardFixtures(t)
+	for _, fx := range fixtures {
+		t.Run(fx.Path, func(t *testing.T) {
+			when, err := CaptureTime(filepath.Join(dir, filepath.FromSlash(fx.Path)))
+			if fx.Expect == fixture.NoCaptureTime {
+				if !errors.Is(err, ErrNoCaptureTime) {
+					t.Errorf("error %v, want %v (%s)", err, ErrNoCaptureTime, fx.Note)
+				}
+			} else if err != nil {
+				t.Errorf("error %s (%s)", err, fx.Note)
+			} else if wallClock(when) != wallClock(fx.Captured) {
+				t.Errorf("captured %s, want %s (%s)", wallClock(when), wallClock(fx.Captured), fx.Note)
+			}
+		})
+	}
+}
+
+func TestInstant(t *testing.T) {
+	zone := time.FixedZone("camera", -6*60*60)
+	// Camera clock times of JPEG files are parsed as UTC.
+	wall := time.Date(2024, time.May, 1, 6, 30, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name   string
+		source string
+		zone   *time.Location
+		want   time.Time
+	}{
+		{"JPEG in local time", "IMG_0001.JPG", nil, time.Date(2024, time.May, 1, 6, 30, 0, 0, localTimeZone)},
+		{"JPEG in camera zone", "IMG_0001.JPG", zone, time.Date(2024, time.May, 1, 6, 30, 0, 0, zone)},
+		{"MP4 already absolute", "VID_0001.MP4", zone, wall},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			imp := New(t.TempDir(), Options{CameraZone: test.zone})
+			if got := imp.instant(filepath.Join(t.TempDir(), test.source), wall); !got.Equal(test.want) {
+				t.Errorf("instant %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTargetPath(t *testing.T) {
+	imp := New(t.TempDir(), Options{})
+	root := imp.Target()
+	for _, test := range []struct {
+		subDir, baseName, want string
+	}{
+		{"", "IMG_0001.JPG", "/2024/05-01-06:30:00-IMG_0001.JPG"},
+		{"Ridge", "IMG_0001.JPG", "/2024/Ridge/05-01-06:30:00-IMG_0001.JPG"},
+		{"", nameNFD, "/2024/05-01-06:30:00-" + nameNFC},
+	} {
+		path := imp.targetPath(root, testTime, test.subDir, test.baseName)
+		if path != root+test.want {
+			t.Errorf("targetPath(%q, %q) = %q, want %q", test.subDir, test.baseName, path, root+test.want)
+		}
+		if base := archiveBaseName(path); base != normalName(test.baseName) {
+			t.Errorf("archiveBaseName(%q) = %q, want %q", path, base, normalName(test.baseName))
+		}
+		if subDir, err := imp.archiveSubDir(path); err != nil || subDir != test.subDir {
+			t.Errorf("archiveSubDir(%q) = %q, %v, want %q", path, subDir, err, test.subDir)
+		}
+	}
+}
+
+func TestCopySourceToTarget(t *testing.T) {
+	dir := t.TempDir()
+	source := writeFile(t, dir, "source.jpg", []byte("source contents"))
+	modified := []byte("modified contents")
+	for _, test := range []struct {
+		name string
+		// existing is the contents of a pre-existing target file, nil for none.
+		existing []byte
+		data     []byte
+		copied   bool
+		err      error
+		want     []byte
+	}{
+		{"new file", nil, nil, true, nil, []byte("source contents")},
+		{"new file with modified data", nil, modified, true, nil, modified},
+		{"identical file", []byte("source contents"), nil, false, nil, []byte("source contents")},
+		{"identical modified data", modified, modified, false, nil, modified},
+		{"conflicting file", []byte("other contents"), nil, false, ErrConflict, []byte("other contents")},
+		{"conflicting with modified data", []byte("source contents"), modified, false, ErrConflict, []byte("source contents")},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "target.jpg")
+			if test.existing != nil {
+				writeFile(t, filepath.Dir(target), "target.jpg", test.existing)
+			}
+			h, _ := newHash(HashSHA256)
+			copied, err := copySourceToTarget(source, target, test.data, h, 0, nil)
+			if !errors.Is(err, test.err) || test.err == nil && err != nil {
+				t.Fatalf("error %v, want %v", err, test.err)
+			} else if copied != test.copied {
+				t.Errorf("copied %t, want %t", copied, test.copied)
+			}
+			if data, err := os.ReadFile(target); err != nil || !bytes.Equal(data, test.want) {
+				t.Errorf("target contains %q (%v), want %q", data, err, test.want)
+			}
+			if copied {
+				want, _ := hashFile(target, HashSHA256)
+				if got := hashString(h); got != want {
+					t.Errorf("hash %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestCopySourceToTargetCreated checks a target created after it was found missing
+// (simulated by a dangling symbolic link, which os.Stat doesn't find but O_EXCL does):
+// it is compared with, neither overwritten nor removed.
+func TestCopySourceToTargetCreated(t *testing.T) {
+	dir := t.TempDir()
+	source := writeFile(t, dir, "source.jpg", []byte("source contents"))
+	target := filepath.Join(dir, "target.jpg")
+	if err := os.Symlink(filepath.Join(dir, "nowhere"), target); err != nil {
+		t.Skipf("symbolic link: %s", err)
+	}
+	h, _ := newHash(HashSHA256)
+	if copied, err := copySourceToTarget(source, target, nil, h, 0, nil); err == nil || copied {
+		t.Errorf("copied %t, error %v, want a failed comparison", copied, err)
+	}
+	if _, err := os.Lstat(target); err != nil {
+		t.Errorf("existing target removed: %s", err)
+	}
+}
+
+// TestCopySourceToTargetPartial checks that a copy failing partway leaves no partial target file.
+func TestCopySourceToTargetPartial(t *testing.T) {
+	// A directory can be opened but not read, so the copy fails after creating the target.
+	source := t.TempDir()
+	target := filepath.Join(t.TempDir(), "target.jpg")
+	h, _ := newHash(HashSHA256)
+	if _, err := copySourceToTarget(source, target, nil, h, 0, nil); err == nil {
+		t.Fatal("copied a directory")
+	}
+	if _, err := os.Lstat(target); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("partial target left behind (%v)", err)
+	}
+}
+
+// TestImportStandardFixtures imports the standard fixtures in order and checks the outcome of each.
+func TestImportStandardFixtures(t *testing.T) {
+	dir, fixtures := standardFixtures(t)
+	imp := testImporter(t, Options{})
+	for _, fx := range fixtures {
+		path, err := imp.Import(filepath.Join(dir, filepath.FromSlash(fx.Path)))
+		switch fx.Expect {
+		case fixture.Imported, fixture.Duplicate:
+			if err != nil {
+				t.Errorf("%s: %s (%s)", fx.Path, err, fx.Note)
+			} else if file := catalogEntry(t, imp, path); wallClock(file.Captured) != wallClock(fx.Captured) {
+				t.Errorf("%s: cataloged capture time %s, want %s", fx.Path, wallClock(file.Captured), wallClock(fx.Captured))
+			}
+		case fixture.Conflict:
+			if !errors.Is(err, ErrConflict) {
+				t.Errorf("%s: error %v, want %v (%s)", fx.Path, err, ErrConflict, fx.Note)
+			}
+		case fixture.NoCaptureTime:
+			if !errors.Is(err, ErrNoCaptureTime) {
+				t.Errorf("%s: error %v, want %v (%s)", fx.Path, err, ErrNoCaptureTime, fx.Note)
+			}
+		}
+	}
+	var imported int
+	for _, fx := range fixtures {
+		if fx.Expect == fixture.Imported {
+			imported++
+		}
+	}
+	if files := imp.options.Catalog.Files(); len(files) != imported {
+		t.Errorf("%d files cataloged, want %d", len(files), imported)
+	}
+}
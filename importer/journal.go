@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// JournalDir is the directory beneath the target root state directory
+// into which the journal of each import session is written.
+const JournalDir = StateDir + "/sessions"
+
+// Actions taken on source files.
+const (
+	ActionImported    = "imported"    // Copied into the target tree.
+	ActionIdentical   = "identical"   // An identical file was already in the target tree.
+	ActionPresumed    = "presumed"    // A file of the same size was already in the target tree (see Options.QuickSkip).
+	ActionDuplicate   = "duplicate"   // Identical to an earlier source file of the batch.
+	ActionExcluded    = "excluded"    // Excluded by the import filters.
+	ActionQuarantined = "quarantined" // Copied into the quarantine directory.
+	ActionFailed      = "failed"      // Not imported due to an error.
+	ActionAborted     = "aborted"     // Not imported because a strict batch stopped.
+)
+
+// Decision records the action taken on a source file and why.
+type Decision struct {
+	Source   string    `json:"source"`
+	Action   string    `json:"action"`
+	Target   string    `json:"target,omitempty"`
+	Captured time.Time `json:"captured,omitempty"`
+	// Reason is the error for failed and quarantined files
+	// and the earlier source file for duplicates.
+	Reason string    `json:"reason,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Journal records the decision made for each source file of an import session
+// so that the session can later be replayed (see Replay), e.g. to find out why a file was skipped.
+type Journal struct {
+	Session  string           `json:"session,omitempty"`
+	Started  time.Time        `json:"started"`
+	Source   string           `json:"source"`
+	Target   string           `json:"target"`
+	Settings *JournalSettings `json:"settings"`
+	// Decisions in the order they were made.
+	Decisions []*Decision `json:"decisions"`
+
+	mutex sync.Mutex
+}
+
+// JournalSettings are the import options affecting the decisions of a session.
+// GPS tracks (Options.Track) are not recorded.
+type JournalSettings struct {
+	CameraZone        string    `json:"camera-zone,omitempty"`
+	PreserveStructure bool      `json:"preserve-structure,omitempty"`
+	Pool              []string  `json:"pool,omitempty"`
+	PoolPolicy        string    `json:"pool-policy,omitempty"`
+	Hash              string    `json:"hash,omitempty"`
+	FixOrientation    bool      `json:"fix-orientation,omitempty"`
+	Verify            bool      `json:"verify,omitempty"`
+	Strict            bool      `json:"strict,omitempty"`
+	Lenient           bool      `json:"lenient,omitempty"`
+	QuickSkip         bool      `json:"quick-skip,omitempty"`
+	Only              []string  `json:"only,omitempty"`
+	Since             time.Time `json:"since,omitempty"`
+	Until             time.Time `json:"until,omitempty"`
+}
+
+// NewJournal returns a journal for an import session of the source into the target
+// with the options (to which it must then be added).
+// The session is the ID of the catalog session, empty if none.
+func NewJournal(session, source, target string, options *Options) *Journal {
+	settings := &JournalSettings{
+		PreserveStructure: options.PreserveStructure,
+		Pool:              options.Pool,
+		PoolPolicy:        options.PoolPolicy,
+		Hash:              options.Hash,
+		FixOrientation:    options.FixOrientation,
+		Verify:            options.Verify,
+		Strict:            options.Strict,
+		Lenient:           options.Lenient,
+		QuickSkip:         options.QuickSkip,
+		Only:              options.Only,
+		Since:             options.Since,
+		Until:             options.Until,
+	}
+	if options.CameraZone != nil {
+		settings.CameraZone = options.CameraZone.String()
+	}
+	return &Journal{
+		Session:  session,
+		Started:  time.Now(),
+		Source:   plainPath(source),
+		Target:   plainPath(target),
+		Settings: settings,
+	}
+}
+
+// Apply sets the options as they were for the session.
+func (s *JournalSettings) Apply(options *Options) error {
+	if s.CameraZone != "" {
+		zone, err := time.LoadLocation(s.CameraZone)
+		if err != nil {
+			return fmt.Errorf("load camera time zone: %w", err)
+		}
+		options.CameraZone = zone
+	}
+	options.PreserveStructure = s.PreserveStructure
+	options.Pool = s.Pool
+	options.PoolPolicy = s.PoolPolicy
+	options.Hash = s.Hash
+	options.FixOrientation = s.FixOrientation
+	options.Verify = s.Verify
+	options.Strict = s.Strict
+	options.Lenient = s.Lenient
+	options.QuickSkip = s.QuickSkip
+	options.Only = s.Only
+	options.Since = s.Since
+	options.Until = s.Until
+	return nil
+}
+
+// add records a decision.
+func (j *Journal) add(decision *Decision) {
+	decision.Time = time.Now()
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.Decisions = append(j.Decisions, decision)
+}
+
+// Write writes the journal into the JournalDir of the target, named after the session
+// (or the start time if none), and returns the path of the file.
+func (j *Journal) Write() (string, error) {
+	dir := filepath.Join(j.Target, JournalDir)
+	if err := os.MkdirAll(dir, 0766); err != nil {
+		return "", fmt.Errorf("make journal dir: %w", err)
+	}
+	name := j.Session
+	if name == "" {
+		name = j.Started.Format("20060102-150405")
+	}
+	path := filepath.Join(dir, name+".json")
+	j.mutex.Lock()
+	data, err := json.MarshalIndent(j, "", "  ")
+	j.mutex.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("marshal journal: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0666); err != nil {
+		return "", fmt.Errorf("write journal: %w", err)
+	}
+	return path, nil
+}
+
+// ReadJournal reads a journal written by Journal.Write.
+func ReadJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	journal := &Journal{}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("parse journal: %w", err)
+	}
+	if journal.Settings == nil {
+		journal.Settings = &JournalSettings{}
+	}
+	return journal, nil
+}
+
+// decide records the action taken on a source file in the journal (if any).
+func (imp *Importer) decide(source, action, target string, when time.Time, reason string) {
+	if imp.options.Journal != nil {
+		imp.options.Journal.add(&Decision{Source: imp.sourceName(plainPath(source)), Action: action, Target: target, Captured: when, Reason: reason})
+	}
+}
+
+// decideError records the failure to import a source file in the journal (if any).
+func (imp *Importer) decideError(source, target string, err error) {
+	action := ActionFailed
+	if Quarantined(err) {
+		action = ActionQuarantined
+	} else if errors.Is(err, ErrAborted) {
+		action = ActionAborted
+	}
+	reason := err
+	var importErr *Error
+	if errors.As(err, &importErr) {
+		reason = importErr.Err
+	}
+	imp.decide(source, action, target, time.Time{}, reason.Error())
+}
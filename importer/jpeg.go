@@ -0,0 +1,250 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"os"
+
+	"github.com/dsoprea/go-exif/v3"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+)
+
+const (
+	jpegMarkerSOI  = 0xD8
+	jpegMarkerSOS  = 0xDA
+	jpegMarkerEOI  = 0xD9
+	jpegMarkerAPP1 = 0xE1
+)
+
+var (
+	errNoEXIFSegment = errors.New("no EXIF APP1 segment")
+	exifPreamble     = []byte("Exif\x00\x00")
+)
+
+// jpegFindEXIF returns the start and end offsets of the EXIF APP1 segment
+// (including the marker and length bytes) within JPEG data.
+func jpegFindEXIF(data []byte) (int, int, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != jpegMarkerSOI {
+		return 0, 0, fmt.Errorf("missing JPEG start of image marker")
+	}
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF {
+			return 0, 0, fmt.Errorf("bad JPEG marker at offset %d", offset)
+		}
+		marker := data[offset+1]
+		if marker == 0xFF {
+			// Fill byte.
+			offset++
+			continue
+		}
+		if marker == jpegMarkerSOS {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		end := offset + 2 + length
+		if length < 2 || end > len(data) {
+			return 0, 0, fmt.Errorf("bad JPEG segment length at offset %d", offset)
+		}
+		if marker == jpegMarkerAPP1 && bytes.HasPrefix(data[offset+4:end], exifPreamble) {
+			return offset, end, nil
+		}
+		offset = end
+	}
+	return 0, 0, errNoEXIFSegment
+}
+
+// jpegReadEXIF returns the raw EXIF data of a JPEG file from its APP1 segment,
+// reading only the segment headers (seeking past other segments) and the APP1 segment,
+// rather than searching the start of the file for the EXIF header.
+func jpegReadEXIF(file io.ReadSeeker) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(file, header[:2]); err != nil {
+		return nil, fmt.Errorf("read JPEG start of image marker: %w", err)
+	} else if header[0] != 0xFF || header[1] != jpegMarkerSOI {
+		return nil, fmt.Errorf("missing JPEG start of image marker")
+	}
+	for {
+		if _, err := io.ReadFull(file, header[:]); err != nil {
+			return nil, fmt.Errorf("read JPEG segment header: %w", err)
+		} else if header[0] != 0xFF {
+			return nil, fmt.Errorf("bad JPEG marker")
+		}
+		marker := header[1]
+		if marker == 0xFF {
+			// Fill byte, the next marker starts at the second byte.
+			if _, err := file.Seek(-3, io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("seek JPEG marker: %w", err)
+			}
+			continue
+		} else if marker == jpegMarkerSOS || marker == jpegMarkerEOI {
+			return nil, errNoEXIFSegment
+		}
+		length := int64(binary.BigEndian.Uint16(header[2:]))
+		if length < 2 {
+			return nil, fmt.Errorf("bad JPEG segment length")
+		}
+		if marker == jpegMarkerAPP1 {
+			segment := make([]byte, length-2)
+			if _, err := io.ReadFull(file, segment); err != nil {
+				return nil, fmt.Errorf("read JPEG APP1 segment: %w", err)
+			}
+			if bytes.HasPrefix(segment, exifPreamble) {
+				return segment[len(exifPreamble):], nil
+			}
+			// Another APP1 segment, e.g. XMP.
+			continue
+		}
+		if _, err := file.Seek(length-2, io.SeekCurrent); err != nil {
+			return nil, fmt.Errorf("seek JPEG segment: %w", err)
+		}
+	}
+}
+
+// jpegReplaceEXIF returns JPEG data with the EXIF APP1 segment replaced by the raw EXIF data.
+// If there is no EXIF segment the new one is inserted directly after the start of image marker.
+func jpegReplaceEXIF(data, rawExif []byte) ([]byte, error) {
+	start, end, err := jpegFindEXIF(data)
+	if errors.Is(err, errNoEXIFSegment) {
+		start, end = 2, 2
+	} else if err != nil {
+		return nil, err
+	}
+	length := 2 + len(exifPreamble) + len(rawExif)
+	if length > 0xFFFF {
+		return nil, fmt.Errorf("EXIF data too large for APP1 segment: %d bytes", length)
+	}
+	segment := make([]byte, 4, 2+length)
+	segment[0] = 0xFF
+	segment[1] = jpegMarkerAPP1
+	binary.BigEndian.PutUint16(segment[2:], uint16(length))
+	segment = append(segment, exifPreamble...)
+	segment = append(segment, rawExif...)
+
+	result := make([]byte, 0, len(data)-(end-start)+len(segment))
+	result = append(result, data[:start]...)
+	result = append(result, segment...)
+	return append(result, data[end:]...), nil
+}
+
+// EXIFupdate rewrites the EXIF data of a JPEG file.
+// The update function is called with the root IFD builder of the existing EXIF data.
+// The file is rewritten via a temporary file in the same directory.
+func EXIFupdate(path string, update func(rootIb *exif.IfdBuilder) error) error {
+	data, err := readFileBounded(path)
+	if err != nil {
+		return err
+	}
+	if data, err = EXIFupdateData(data, update); err != nil {
+		return err
+	}
+	return writeFileReplace(path, data)
+}
+
+// EXIFupdateData returns a copy of JPEG data with updated EXIF data.
+// The update function is called with the root IFD builder of the existing EXIF data.
+func EXIFupdateData(data []byte, update func(rootIb *exif.IfdBuilder) error) ([]byte, error) {
+	start, end, err := jpegFindEXIF(data)
+	if err != nil {
+		return nil, fmt.Errorf("find EXIF: %w", err)
+	}
+	rawExif := data[start+4+len(exifPreamble) : end]
+	im, err := exifcommon.NewIfdMappingWithStandard()
+	if err != nil {
+		return nil, fmt.Errorf("getting EXIF mapping: %w", err)
+	}
+	_, index, err := exif.Collect(im, exif.NewTagIndex(), rawExif)
+	if err != nil {
+		return nil, fmt.Errorf("getting EXIF index: %w", err)
+	}
+	rootIb := exif.NewIfdBuilderFromExistingChain(index.RootIfd)
+	if err := update(rootIb); err != nil {
+		return nil, fmt.Errorf("update EXIF: %w", err)
+	}
+	if rawExif, err = exif.NewIfdByteEncoder().EncodeToExif(rootIb); err != nil {
+		return nil, fmt.Errorf("encode EXIF: %w", err)
+	}
+	if data, err = jpegReplaceEXIF(data, rawExif); err != nil {
+		return nil, fmt.Errorf("replace EXIF: %w", err)
+	}
+	return data, nil
+}
+
+// writeFileReplace replaces the contents of a file by writing a temporary file and renaming it.
+func writeFileReplace(path string, data []byte) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, stat.Mode().Perm()); err != nil {
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		_ = os.Remove(temp)
+		return fmt.Errorf("rename temporary file: %w", err)
+	}
+	return nil
+}
+
+// jpegMaxPixels is the largest image verified, far beyond any camera.
+const jpegMaxPixels = 200 << 20
+
+// JPEGverify decodes the image data of a JPEG file to check that it is intact.
+// EXIF data can be intact while the image data is damaged, for example by a failing card.
+// The file is streamed to the decoder rather than read into memory.
+func JPEGverify(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	// A damaged frame header can claim dimensions for which the decoder would exhaust memory.
+	if config, err := jpeg.DecodeConfig(bufio.NewReaderSize(file, streamBufferSize)); err != nil {
+		return fmt.Errorf("%w: decode JPEG header: %s", ErrCorrupt, err)
+	} else if pixels := int64(config.Width) * int64(config.Height); pixels > jpegMaxPixels {
+		return fmt.Errorf("%w: JPEG dimensions %dx%d too large", ErrCorrupt, config.Width, config.Height)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind file: %w", err)
+	}
+	if _, err := jpeg.Decode(bufio.NewReaderSize(file, streamBufferSize)); err != nil {
+		return fmt.Errorf("%w: decode JPEG: %s", ErrCorrupt, err)
+	}
+	// The decoder stops at the end of the last scan, so check that
+	// the image was not truncated before the end of image marker.
+	if end, err := jpegEnd(file); err != nil {
+		return fmt.Errorf("read end of file: %w", err)
+	} else if !bytes.HasSuffix(end, []byte{0xFF, jpegMarkerEOI}) {
+		return fmt.Errorf("%w: missing JPEG end of image marker", ErrCorrupt)
+	}
+	return nil
+}
+
+// jpegEnd returns the last bytes of a file before any trailing zero padding.
+func jpegEnd(file *os.File) ([]byte, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, streamBufferSize)
+	for end := stat.Size(); end > 0; {
+		start := end - int64(len(buffer))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buffer[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return nil, err
+		}
+		if chunk = bytes.TrimRight(chunk, "\x00"); len(chunk) > 0 {
+			return chunk, nil
+		}
+		end = start
+	}
+	return nil, nil
+}
@@ -0,0 +1,234 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	exifundefined "github.com/dsoprea/go-exif/v3/undefined"
+)
+
+// An iPhone Live Photo is a still image (HEIC or JPG) and a short MOV video
+// with the same base name (e.g. IMG_1234.HEIC and IMG_1234.MOV) and the same
+// content identifier, written into the Apple maker note of the image and
+// the QuickTime metadata of the video. The video is filed under the capture time
+// of the image so that the pair stays together in the target tree.
+
+const (
+	// tagIDMakerNote is the EXIF MakerNote tag.
+	tagIDMakerNote = 0x927c
+	// appleMakerNoteHeader starts the Apple maker note, followed by a big-endian IFD
+	// whose offsets are relative to the start of the maker note.
+	appleMakerNoteHeader = "Apple iOS\x00\x00\x01MM"
+	// appleTagContentID is the maker note tag holding the Live Photo content identifier.
+	appleTagContentID = 0x0011
+	// quickTimeKeyContentID is the QuickTime metadata key holding the Live Photo content identifier.
+	quickTimeKeyContentID = "com.apple.quicktime.content.identifier"
+)
+
+// livePhotoStillExts are the extensions of Live Photo still images.
+var livePhotoStillExts = []string{".heic", ".HEIC", ".jpg", ".JPG", ".jpeg", ".JPEG"}
+
+// MOVcaptureTime returns the capture time of a QuickTime MOV file (e.g. from an iPhone).
+// The capture time of a Live Photo video is that of its still image.
+func MOVcaptureTime(path string) (time.Time, error) {
+	if still := livePhotoStill(path); still != "" {
+		return CaptureTime(still)
+	}
+	return MP4captureTime(path)
+}
+
+// livePhotoStill returns the path of the still image of a Live Photo video, empty if none.
+// The still image must have the same base name and content identifier as the video.
+// It may already have been renamed (e.g. by the rename command).
+func livePhotoStill(path string) string {
+	dir, name := filepath.Split(strings.TrimSuffix(path, filepath.Ext(path)))
+	var candidates []string
+	for _, ext := range livePhotoStillExts {
+		candidates = append(candidates, filepath.Join(dir, name+ext))
+		if renamed, err := filepath.Glob(filepath.Join(dir, "*-"+name+ext)); err == nil {
+			for _, still := range renamed {
+				if archiveBaseName(still) == name+ext {
+					candidates = append(candidates, still)
+				}
+			}
+		}
+	}
+	var videoID string
+	for _, still := range candidates {
+		if _, err := os.Stat(still); err != nil {
+			continue
+		}
+		if videoID == "" {
+			var err error
+			if videoID, err = quickTimeContentID(path); err != nil || videoID == "" {
+				return ""
+			}
+		}
+		if stillID, err := exifContentID(still); err == nil && stillID == videoID {
+			return still
+		}
+	}
+	return ""
+}
+
+// exifContentID returns the Live Photo content identifier from the Apple maker note of an image.
+func exifContentID(path string) (string, error) {
+	metadata, err := exifRead(path)
+	if err != nil {
+		return "", err
+	}
+	value, err := metadata.value(tagIDMakerNote)
+	if err != nil {
+		return "", err
+	}
+	makerNote, ok := value.(exifundefined.Tag927CMakerNote)
+	if !ok {
+		return "", fmt.Errorf("unexpected maker note %T", value)
+	}
+	note := makerNote.MakerNoteBytes
+	if !bytes.HasPrefix(note, []byte(appleMakerNoteHeader)) {
+		return "", errors.New("not an Apple maker note")
+	}
+	ifd := len(appleMakerNoteHeader)
+	if len(note) < ifd+2 {
+		return "", errors.New("truncated Apple maker note")
+	}
+	count := int(binary.BigEndian.Uint16(note[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if len(note) < entry+12 {
+			return "", errors.New("truncated Apple maker note")
+		}
+		if binary.BigEndian.Uint16(note[entry:]) != appleTagContentID {
+			continue
+		}
+		size := int(binary.BigEndian.Uint32(note[entry+4:]))
+		offset := entry + 8
+		if size > 4 {
+			offset = int(binary.BigEndian.Uint32(note[entry+8:]))
+		}
+		if offset < 0 || size < 0 || offset+size > len(note) {
+			return "", errors.New("bad content identifier offset")
+		}
+		return strings.TrimRight(string(note[offset:offset+size]), "\x00"), nil
+	}
+	return "", nil
+}
+
+// quickTimeContentID returns the Live Photo content identifier from the QuickTime metadata of a video.
+func quickTimeContentID(path string) (string, error) {
+	metadata, err := quickTimeMetadata(path)
+	if err != nil {
+		return "", err
+	}
+	return metadata[quickTimeKeyContentID], nil
+}
+
+// quickTimeMetadata returns the string values of the QuickTime metadata (moov/meta keys and ilst boxes)
+// of a MOV file, keyed by name (e.g. com.apple.quicktime.creationdate).
+func quickTimeMetadata(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+	offset, size, err := quickTimeFindBox(file, 0, stat.Size(), "moov")
+	if err != nil {
+		return nil, err
+	} else if size > maxInMemorySize {
+		return nil, fmt.Errorf("moov box size %d larger than %d bytes", size, maxInMemorySize)
+	}
+	moov := make([]byte, size)
+	if _, err := file.ReadAt(moov, offset); err != nil {
+		return nil, fmt.Errorf("read moov box: %w", err)
+	}
+	meta := quickTimeChild(moov, "meta")
+	if meta == nil {
+		return nil, errors.New("no moov/meta box")
+	}
+	if len(meta) >= 8 && string(meta[4:8]) != "hdlr" {
+		// ISO style meta box with version and flags.
+		meta = meta[4:]
+	}
+	keys, ilst := quickTimeChild(meta, "keys"), quickTimeChild(meta, "ilst")
+	if len(keys) < 8 || ilst == nil {
+		return nil, errors.New("no moov/meta/keys or ilst box")
+	}
+	var names []string
+	for entry := keys[8:]; len(entry) >= 8; {
+		size := int(binary.BigEndian.Uint32(entry))
+		if size < 8 || size > len(entry) {
+			break
+		}
+		names = append(names, string(entry[8:size]))
+		entry = entry[size:]
+	}
+	metadata := make(map[string]string)
+	for item := ilst; len(item) >= 8; {
+		size := int(binary.BigEndian.Uint32(item))
+		if size < 8 || size > len(item) {
+			break
+		}
+		index := int(binary.BigEndian.Uint32(item[4:]))
+		if data := quickTimeChild(item[8:size], "data"); index >= 1 && index <= len(names) && len(data) >= 8 {
+			metadata[names[index-1]] = string(data[8:])
+		}
+		item = item[size:]
+	}
+	return metadata, nil
+}
+
+// quickTimeFindBox returns the offset and size of the payload of the first box of the specified type
+// between the start and end offsets of a file.
+func quickTimeFindBox(file io.ReaderAt, start, end int64, boxType string) (int64, int64, error) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := file.ReadAt(header[:8], offset); err != nil {
+			return 0, 0, fmt.Errorf("read box header at offset %d: %w", offset, err)
+		}
+		size, headerSize := int64(binary.BigEndian.Uint32(header)), int64(8)
+		switch size {
+		case 0:
+			size = end - offset
+		case 1:
+			if _, err := file.ReadAt(header[8:16], offset+8); err != nil {
+				return 0, 0, fmt.Errorf("read box size at offset %d: %w", offset, err)
+			}
+			size, headerSize = int64(binary.BigEndian.Uint64(header[8:16])), 16
+		}
+		if size < headerSize || offset+size > end {
+			return 0, 0, fmt.Errorf("bad box size %d at offset %d", size, offset)
+		}
+		if string(header[4:8]) == boxType {
+			return offset + headerSize, size - headerSize, nil
+		}
+		offset += size
+	}
+	return 0, 0, fmt.Errorf("no %s box", boxType)
+}
+
+// quickTimeChild returns the payload of the first child box of the specified type in the box payload, nil if none.
+func quickTimeChild(payload []byte, boxType string) []byte {
+	for len(payload) >= 8 {
+		size := int(binary.BigEndian.Uint32(payload))
+		if size < 8 || size > len(payload) {
+			return nil
+		}
+		if string(payload[4:8]) == boxType {
+			return payload[8:size]
+		}
+		payload = payload[size:]
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package importer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abema/go-mp4"
+)
+
+var localTimeZone = time.Now().Location()
+
+// MP4captureTime returns the capture time of an MP4 file from its mvhd box.
+func MP4captureTime(path string) (time.Time, error) {
+	if header, err := mp4ReadMvhd(path); err == nil {
+		return mp4Time(header.creation), nil
+	}
+	if metadata, err := MP4getMetadata(path); err != nil {
+		return time.Time{}, fmt.Errorf("%w: get MP4 metadata: %s", ErrNoCaptureTime, err)
+	} else if len(metadata) != 1 {
+		return time.Time{}, fmt.Errorf("%w: wrong number of metadata results: %d", ErrNoCaptureTime, len(metadata))
+	} else if payload, ok := metadata[0].Payload.(*mp4.Mvhd); !ok {
+		return time.Time{}, fmt.Errorf("%w: convert metadata payload to mvhd: %v", ErrNoCaptureTime, metadata[0].Payload)
+	} else {
+		return mp4Time(uint64(payload.CreationTimeV0)), nil
+	}
+}
+
+// mp4Time converts an mvhd time to the local time zone.
+func mp4Time(seconds uint64) time.Time {
+	// Mvhd/CreationTimeV0 is seconds since Jan 1, 1904 for some reason.
+	return time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC).
+		Add(time.Second * time.Duration(seconds)).
+		// It's also in UTC so convert it to the local time zone.
+		In(localTimeZone)
+}
+
+// MP4duration returns the duration of an MP4 or MOV file from its mvhd box.
+func MP4duration(path string) (time.Duration, error) {
+	if header, err := mp4ReadMvhd(path); err == nil && header.timescale != 0 {
+		return time.Duration(header.duration) * time.Second / time.Duration(header.timescale), nil
+	}
+	metadata, err := MP4getMetadata(path)
+	if err != nil {
+		return 0, fmt.Errorf("get MP4 metadata: %w", err)
+	} else if len(metadata) != 1 {
+		return 0, fmt.Errorf("wrong number of metadata results: %d", len(metadata))
+	}
+	payload, ok := metadata[0].Payload.(*mp4.Mvhd)
+	if !ok || payload.Timescale == 0 {
+		return 0, fmt.Errorf("bad mvhd payload: %v", metadata[0].Payload)
+	}
+	duration := uint64(payload.DurationV0)
+	if payload.GetVersion() == 1 {
+		duration = payload.DurationV1
+	}
+	return time.Duration(duration) * time.Second / time.Duration(payload.Timescale), nil
+}
+
+// mvhdHeader is the start of an mvhd box payload.
+type mvhdHeader struct {
+	// offset of the payload in the file.
+	offset    int64
+	version   byte
+	creation  uint64
+	timescale uint32
+	duration  uint64
+}
+
+// mp4ReadMvhd reads the mvhd box of an MP4 or MOV file by following the box size headers
+// to the moov box and within it to the mvhd box, reading nothing else.
+// This is much faster than a generic box traversal (see MP4getMetadata)
+// which may read past the (huge) mdat box and any boxes after the moov box.
+func mp4ReadMvhd(path string) (*mvhdHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+	start, end, err := mp4FindBox(file, 0, stat.Size(), "moov")
+	if err != nil {
+		return nil, err
+	}
+	if start, _, err = mp4FindBox(file, start, end, "mvhd"); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, 32)
+	if _, err := file.ReadAt(payload, start); err != nil {
+		return nil, fmt.Errorf("read mvhd payload: %w", err)
+	}
+	header := &mvhdHeader{offset: start, version: payload[0]}
+	switch header.version {
+	case 0:
+		header.creation = uint64(binary.BigEndian.Uint32(payload[4:]))
+		header.timescale = binary.BigEndian.Uint32(payload[12:])
+		header.duration = uint64(binary.BigEndian.Uint32(payload[16:]))
+	case 1:
+		header.creation = binary.BigEndian.Uint64(payload[4:])
+		header.timescale = binary.BigEndian.Uint32(payload[20:])
+		header.duration = binary.BigEndian.Uint64(payload[24:])
+	default:
+		return nil, fmt.Errorf("unknown mvhd version %d", header.version)
+	}
+	return header, nil
+}
+
+// mp4FindBox returns the payload start and end offsets of the first box of a type
+// among the boxes between the start and end offsets, reading only the box headers.
+func mp4FindBox(file *os.File, start, end int64, boxType string) (int64, int64, error) {
+	var found bool
+	if err := mp4WalkBoxes(file, start, end, func(walked string, payloadStart, payloadEnd int64) bool {
+		if walked == boxType {
+			found, start, end = true, payloadStart, payloadEnd
+		}
+		return found
+	}); err != nil {
+		return 0, 0, err
+	} else if !found {
+		return 0, 0, fmt.Errorf("no %s box", boxType)
+	}
+	return start, end, nil
+}
+
+// mp4CheckBoxes checks that the sizes of the boxes between the start and end offsets fit between them.
+// go-mp4 loops forever on some damaged sizes (e.g. a 64-bit size of 0),
+// so the boxes it is to traverse are checked first.
+func mp4CheckBoxes(file *os.File, start, end int64) error {
+	return mp4WalkBoxes(file, start, end, func(string, int64, int64) bool { return false })
+}
+
+// mp4WalkBoxes calls the function with the type and payload start and end offsets of each box
+// between the start and end offsets, reading only the box headers, until it returns true.
+func mp4WalkBoxes(file *os.File, start, end int64, fn func(boxType string, start, end int64) bool) error {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := file.ReadAt(header[:8], offset); err != nil {
+			return fmt.Errorf("read box header at offset %d: %w", offset, err)
+		}
+		size, headerSize := int64(binary.BigEndian.Uint32(header)), int64(8)
+		switch size {
+		case 0:
+			// The box extends to the end.
+			size = end - offset
+		case 1:
+			if _, err := file.ReadAt(header[8:16], offset+8); err != nil {
+				return fmt.Errorf("read box size at offset %d: %w", offset, err)
+			}
+			size, headerSize = int64(binary.BigEndian.Uint64(header[8:])), 16
+		}
+		if size < headerSize || offset+size > end {
+			return fmt.Errorf("bad %s box size %d at offset %d", header[4:8], size, offset)
+		}
+		if fn(string(header[4:8]), offset+headerSize, offset+size) {
+			return nil
+		}
+		offset += size
+	}
+	return nil
+}
+
+// MP4getMetadata returns the mvhd box of an MP4 file by generic box traversal.
+// The boxes up to the moov box must fit in the file, go-mp4 allocates
+// whatever a box size claims and a damaged size can exhaust memory.
+func MP4getMetadata(path string) ([]*mp4.BoxInfoWithPayload, error) {
+	if file, err := os.Open(path); err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	} else {
+		defer func() { _ = file.Close() }()
+		if stat, err := file.Stat(); err != nil {
+			return nil, fmt.Errorf("stat file: %w", err)
+		} else if start, end, err := mp4FindBox(file, 0, stat.Size(), "moov"); err != nil {
+			return nil, err
+		} else if err := mp4CheckBoxes(file, start, end); err != nil {
+			return nil, err
+		}
+		return mp4.ExtractBoxWithPayload(file, nil,
+			mp4.BoxPath{mp4.BoxTypeMoov(), mp4.BoxTypeMvhd()})
+	}
+}
+
+// MP4shiftCreationTime adds the offset to the mvhd creation time of an MP4 file.
+// The file is modified in place.
+func MP4shiftCreationTime(path string, offset time.Duration) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	mvhd, err := mp4ReadMvhd(path)
+	if err != nil {
+		return fmt.Errorf("find mvhd box: %w", err)
+	}
+
+	// The mvhd payload starts with a version byte and three flag bytes
+	// followed by the creation time (32 bits for version 0, 64 bits for version 1).
+	payload := mvhd.offset
+	header := make([]byte, 12)
+	if _, err := file.ReadAt(header, payload); err != nil {
+		return fmt.Errorf("read mvhd header: %w", err)
+	}
+	seconds := int64(offset / time.Second)
+	switch header[0] {
+	case 0:
+		binary.BigEndian.PutUint32(header[4:], uint32(int64(binary.BigEndian.Uint32(header[4:]))+seconds))
+		_, err = file.WriteAt(header[4:8], payload+4)
+	case 1:
+		binary.BigEndian.PutUint64(header[4:], uint64(int64(binary.BigEndian.Uint64(header[4:]))+seconds))
+		_, err = file.WriteAt(header[4:12], payload+4)
+	default:
+		return fmt.Errorf("unknown mvhd version %d", header[0])
+	}
+	if err != nil {
+		return fmt.Errorf("write mvhd creation time: %w", err)
+	}
+	return nil
+}
+
+// MP4verify checks the box structure of an MP4 file.
+// Clips truncated by power failure may be missing the moov box (which
+// the cameras write after the media data) or may end partway through a box.
+func MP4verify(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+
+	var hasMdat, hasMoov bool
+	header := make([]byte, 16)
+	for offset := int64(0); offset < stat.Size(); {
+		if _, err := file.ReadAt(header[:8], offset); err != nil {
+			return fmt.Errorf("%w: truncated box header at offset %d", ErrCorrupt, offset)
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		boxType := string(header[4:8])
+		switch size {
+		case 0:
+			// Box extends to end of file.
+			size = stat.Size() - offset
+		case 1:
+			if _, err := file.ReadAt(header[8:16], offset+8); err != nil {
+				return fmt.Errorf("%w: truncated %s box header at offset %d", ErrCorrupt, boxType, offset)
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		if size < 8 {
+			return fmt.Errorf("%w: bad %s box size %d at offset %d", ErrCorrupt, boxType, size, offset)
+		} else if offset+size > stat.Size() {
+			return fmt.Errorf("%w: %s box at offset %d truncated by %d bytes",
+				ErrCorrupt, boxType, offset, offset+size-stat.Size())
+		}
+		switch boxType {
+		case "mdat":
+			hasMdat = true
+		case "moov":
+			hasMoov = true
+		}
+		offset += size
+	}
+
+	if !hasMoov {
+		if hasMdat {
+			return fmt.Errorf("%w: no moov box, only media data (recording probably interrupted by power failure)", ErrCorrupt)
+		}
+		return fmt.Errorf("%w: no moov box", ErrCorrupt)
+	}
+	if start, end, err := mp4FindBox(file, 0, stat.Size(), "moov"); err != nil {
+		return fmt.Errorf("%w: find moov box: %s", ErrCorrupt, err)
+	} else if err := mp4CheckBoxes(file, start, end); err != nil {
+		return fmt.Errorf("%w: moov box: %s", ErrCorrupt, err)
+	}
+	for _, path := range []mp4.BoxPath{
+		{mp4.BoxTypeMoov(), mp4.BoxTypeMvhd()},
+		{mp4.BoxTypeMoov(), mp4.BoxTypeTrak()},
+	} {
+		if boxes, err := mp4.ExtractBox(file, nil, path); err != nil {
+			return fmt.Errorf("%w: read moov box: %s", ErrCorrupt, err)
+		} else if len(boxes) == 0 {
+			return fmt.Errorf("%w: moov box has no %s box", ErrCorrupt, path[1])
+		}
+	}
+	return nil
+}
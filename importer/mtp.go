@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// mtpPrefix starts the source of a device (e.g. an Android phone running the GardePro app)
+// accessed through gphoto2, followed by the folder on the device to import.
+const mtpPrefix = "mtp:"
+
+// IsMTP returns true if the source is a folder on a device accessed through gphoto2,
+// e.g. mtp:/store_00010001/DCIM/GardePro (see ImportMTP).
+func IsMTP(source string) bool {
+	return strings.HasPrefix(source, mtpPrefix)
+}
+
+// mtpFile is a file listed by gphoto2.
+type mtpFile struct {
+	// folder is the folder on the device and number the number of the file in the folder.
+	folder string
+	number int
+	name   string
+	// modified is the modification time of the file, zero if not listed.
+	modified time.Time
+}
+
+var (
+	// mtpFolderPattern matches the line starting the files of a folder in a gphoto2 --list-files listing.
+	mtpFolderPattern = regexp.MustCompile(`^There (?:is|are) (?:no|\d+) files? in folder '(.*)'`)
+	// mtpFilePattern matches the line of a file in a gphoto2 --list-files listing, e.g.
+	// #1     IMG_0001.JPG               rd  2345 KB  4000x3000 image/jpeg 1714567890
+	mtpFilePattern = regexp.MustCompile(`^#\d+\s+(\S+)(?:.*\s(\d{9,}))?\s*$`)
+)
+
+// ImportMTP imports the supported media files beneath a folder on a device accessed through gphoto2
+// (MTP or PTP, e.g. an Android phone running the GardePro app), continuing after errors.
+// The source is mtp: followed by the folder (the whole device if empty),
+// and gphoto2 is the path of the gphoto2 executable. The first device found by gphoto2 is used.
+// With Options.Strict the remaining files are aborted once one fails.
+// The results are in listing order, with the source of each file being mtp: followed by its path
+// on the device (e.g. mtp:/store_00010001/DCIM/GardePro/IMG_0001.JPG),
+// which is also recorded in the catalog and journal.
+// Each file (with its sidecars) is downloaded into a temporary directory, imported, and removed before the next.
+// Folder paths beneath the source folder are kept when preserving structure.
+func (imp *Importer) ImportMTP(source, gphoto2 string) ([]Result, error) {
+	folder := strings.TrimPrefix(source, mtpPrefix)
+	if folder == "" {
+		folder = "/"
+	}
+	files, err := listMTP(gphoto2, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]*mtpFile)
+	var media []*mtpFile
+	var names []string
+	for _, file := range files {
+		name := path.Join(file.folder, file.name)
+		listed[name] = file
+		if Supported(file.name) {
+			media = append(media, file)
+			names = append(names, mtpPrefix+name)
+		}
+	}
+	return imp.importStaged(names, func(i int, staging string) (string, error) {
+		source, err := getMTP(gphoto2, folder, media[i], staging)
+		if err != nil {
+			return "", err
+		}
+		stem := strings.TrimSuffix(path.Join(media[i].folder, media[i].name), path.Ext(media[i].name))
+		for _, ext := range sidecarExts {
+			if sidecar := listed[stem+ext]; sidecar != nil {
+				if _, err := getMTP(gphoto2, folder, sidecar, staging); err != nil {
+					log.Warn().Err(err).Str("sidecar", stem+ext).Msg("Download sidecar")
+				}
+			}
+		}
+		return source, nil
+	})
+}
+
+// listMTP returns the files beneath the folder on the device, in listing order.
+func listMTP(gphoto2, folder string) ([]*mtpFile, error) {
+	output, err := runGphoto2(gphoto2, "--list-files", "--folder", folder)
+	if err != nil {
+		return nil, err
+	}
+	var files []*mtpFile
+	var current string
+	var number int
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := mtpFolderPattern.FindStringSubmatch(line); match != nil {
+			current, number = match[1], 0
+		} else if match := mtpFilePattern.FindStringSubmatch(line); match != nil && current != "" {
+			// Files are numbered from one within each folder (see getMTP).
+			number++
+			file := &mtpFile{folder: current, number: number, name: match[1]}
+			if seconds, err := strconv.ParseInt(match[2], 10, 64); err == nil {
+				file.modified = time.Unix(seconds, 0)
+			}
+			files = append(files, file)
+		}
+	}
+	return files, scanner.Err()
+}
+
+// getMTP downloads the file from the device into the staging directory,
+// at its path relative to the source folder, returning its path.
+func getMTP(gphoto2, folder string, file *mtpFile, staging string) (string, error) {
+	rel := strings.TrimPrefix(strings.TrimPrefix(file.folder, folder), "/")
+	if strings.Contains(file.name, "/") || strings.HasPrefix(path.Clean("/"+rel), "/..") {
+		return "", fmt.Errorf("bad file name %q", path.Join(file.folder, file.name))
+	}
+	target := filepath.Join(staging, filepath.FromSlash(rel), file.name)
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return "", fmt.Errorf("make download dir: %w", err)
+	}
+	if _, err := runGphoto2(gphoto2, "--quiet", "--folder", file.folder, "--no-recurse",
+		"--get-file", strconv.Itoa(file.number), "--filename", target, "--force-overwrite"); err != nil {
+		return "", err
+	} else if _, err := os.Stat(target); err != nil {
+		return "", fmt.Errorf("downloaded file: %w", err)
+	}
+	// The modification time is used for files without capture times if enabled (see UseModTime).
+	if !file.modified.IsZero() {
+		_ = os.Chtimes(target, file.modified, file.modified)
+	}
+	return target, nil
+}
+
+// runGphoto2 runs gphoto2, returning its output or its error output with any error.
+func runGphoto2(gphoto2 string, args ...string) ([]byte, error) {
+	cmd := exec.Command(gphoto2, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gphoto2: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
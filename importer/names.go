@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// File names are compared and created in Unicode normalization form C (composed).
+// macOS file systems may return names in form D (decomposed), so the same name
+// copied from a Mac to Linux can be a different sequence of bytes.
+
+// normalName returns the name in Unicode normalization form C.
+func normalName(name string) string {
+	return norm.NFC.String(name)
+}
+
+// existingPath returns the path of an existing file whose name differs from the path
+// only in Unicode normalization, or only in case on a case-insensitive file system,
+// or the path itself if there is none.
+// On a case-insensitive file system the file is found by the path
+// but the returned path has the actual name of the file (e.g. for the catalog).
+func existingPath(path string) string {
+	dir, base := filepath.Split(path)
+	var match func(name string) bool
+	if _, err := os.Lstat(path); err == nil {
+		if !caseInsensitive(dir) {
+			return path
+		}
+		match = func(name string) bool {
+			return strings.EqualFold(normalName(name), base)
+		}
+	} else if errors.Is(err, os.ErrNotExist) && !isASCII(base) {
+		match = func(name string) bool {
+			return normalName(name) == base
+		}
+	} else {
+		return path
+	}
+	base = normalName(base)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return path
+	}
+	for _, entry := range entries {
+		if entry.Name() == base {
+			return filepath.Join(dir, entry.Name())
+		}
+	}
+	for _, entry := range entries {
+		if match(entry.Name()) {
+			return filepath.Join(dir, entry.Name())
+		}
+	}
+	return path
+}
+
+var (
+	caseInsensitiveDirs  = make(map[string]bool)
+	caseInsensitiveMutex sync.Mutex
+)
+
+// caseInsensitive returns true if the file system containing the directory
+// treats names that differ only in case as the same (e.g. exFAT, NTFS, and APFS by default).
+// The directory is tested by creating a temporary file with a lower case name
+// and looking for it by the upper case name. Results are cached by directory.
+func caseInsensitive(dir string) bool {
+	caseInsensitiveMutex.Lock()
+	defer caseInsensitiveMutex.Unlock()
+	if insensitive, found := caseInsensitiveDirs[dir]; found {
+		return insensitive
+	}
+	file, err := os.CreateTemp(dir, ".gardepro-case-*")
+	if err != nil {
+		return false
+	}
+	_ = file.Close()
+	defer func() { _ = os.Remove(file.Name()) }()
+	_, err = os.Lstat(filepath.Join(dir, strings.ToUpper(filepath.Base(file.Name()))))
+	caseInsensitiveDirs[dir] = err == nil
+	return err == nil
+}
+
+// isASCII returns true if the name has no multi-byte characters, which have only one normal form.
+func isASCII(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Windows extended-length path prefixes.
+const (
+	longPathPrefix    = `\\?\`
+	longPathUNCPrefix = `\\?\UNC\`
+)
+
+// plainPath removes any Windows extended-length path prefix (\\?\) and returns an absolute path.
+// Paths with the prefix are not normalized by Windows, so they must not be built with
+// forward slashes. Go adds the prefix itself when accessing long absolute paths.
+func plainPath(path string) string {
+	if strings.HasPrefix(path, longPathUNCPrefix) {
+		path = `\\` + path[len(longPathUNCPrefix):]
+	} else {
+		path = strings.TrimPrefix(path, longPathPrefix)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
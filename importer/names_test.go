@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Names that differ only in Unicode normalization, as copied from macOS (form D) and elsewhere (form C).
+const (
+	nameNFC = "Ch\u00e8vre_0001.JPG"
+	nameNFD = "Che\u0300vre_0001.JPG"
+)
+
+func TestNormalName(t *testing.T) {
+	for _, test := range []struct {
+		name, want string
+	}{
+		{"IMG_0001.JPG", "IMG_0001.JPG"},
+		{nameNFC, nameNFC},
+		{nameNFD, nameNFC},
+		{"Ho\u0308he/" + nameNFD, "H\u00f6he/" + nameNFC},
+	} {
+		if got := normalName(test.name); got != test.want {
+			t.Errorf("normalName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestExistingPath(t *testing.T) {
+	dir := t.TempDir()
+	nfd := writeFile(t, dir, nameNFD, []byte("data"))
+	for _, test := range []struct {
+		path, want string
+	}{
+		{nfd, nfd},
+		{filepath.Join(dir, nameNFC), nfd},
+		{filepath.Join(dir, "IMG_0001.JPG"), filepath.Join(dir, "IMG_0001.JPG")},
+		{filepath.Join(dir, "missing", nameNFC), filepath.Join(dir, "missing", nameNFC)},
+	} {
+		if got := existingPath(test.path); got != test.want {
+			t.Errorf("existingPath(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestPlainPath(t *testing.T) {
+	for _, test := range []struct {
+		path, plain string
+	}{
+		{`\\?\C:\Cards\DCIM`, `C:\Cards\DCIM`},
+		{`\\?\UNC\nas\share\DCIM`, `\\nas\share\DCIM`},
+		{`C:\Cards\DCIM`, `C:\Cards\DCIM`},
+		{"cards/DCIM", "cards/DCIM"},
+	} {
+		want, err := filepath.Abs(test.plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := plainPath(test.path); got != want {
+			t.Errorf("plainPath(%q) = %q, want %q", test.path, got, want)
+		}
+	}
+}
+
+// TestImportNormalizedName checks that a source file whose name differs from an archived file
+// only in Unicode normalization is the same file: a duplicate if identical, a conflict if not.
+func TestImportNormalizedName(t *testing.T) {
+	imp := testImporter(t, Options{})
+	mp4NFC, mp4NFD := strings.TrimSuffix(nameNFC, ".JPG")+".MP4", strings.TrimSuffix(nameNFD, ".JPG")+".MP4"
+	created := time.Date(2024, time.May, 1, 6, 30, 0, 0, time.UTC)
+	archived, err := imp.Import(writeFile(t, t.TempDir(), mp4NFC, testMP4(created)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The archive is renamed to form D as if it had been copied from a Mac.
+	nfd := filepath.Join(filepath.Dir(archived), strings.TrimSuffix(filepath.Base(archived), mp4NFC)+mp4NFD)
+	if err := os.Rename(archived, nfd); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := imp.Import(writeFile(t, t.TempDir(), mp4NFD, testMP4(created)))
+	if err != nil {
+		t.Fatalf("import identical file: %s", err)
+	} else if path != nfd {
+		t.Errorf("identical file imported to %q, want existing %q", path, nfd)
+	} else if _, err := os.Stat(archived); err == nil {
+		t.Errorf("identical file copied beside the existing file as %q", archived)
+	}
+	if rel, err := imp.relative(nfd); err != nil {
+		t.Fatal(err)
+	} else if imp.options.Catalog.File(rel) == nil {
+		t.Errorf("form D path %s not found in catalog", rel)
+	}
+
+	different := testMP4(created)
+	different[len(different)-1] = 1
+	if _, err := imp.Import(writeFile(t, t.TempDir(), mp4NFC, different)); !errors.Is(err, ErrConflict) {
+		t.Errorf("import different file: %v, want %v", err, ErrConflict)
+	}
+}
+
+// testMP4 returns a minimal MP4 file, just an ftyp box and a moov box with an mvhd box
+// with the creation time, which is all that importing reads.
+func testMP4(created time.Time) []byte {
+	mvhd := make([]byte, 108)
+	binary.BigEndian.PutUint32(mvhd, uint32(len(mvhd)))
+	copy(mvhd[4:], "mvhd")
+	seconds := created.Sub(time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)) / time.Second
+	binary.BigEndian.PutUint32(mvhd[12:], uint32(seconds))
+	binary.BigEndian.PutUint32(mvhd[16:], uint32(seconds))
+	binary.BigEndian.PutUint32(mvhd[20:], 1000)       // timescale
+	binary.BigEndian.PutUint32(mvhd[28:], 0x00010000) // rate 1.0
+	binary.BigEndian.PutUint16(mvhd[32:], 0x0100)     // volume 1.0
+	// Identity matrix.
+	binary.BigEndian.PutUint32(mvhd[44:], 0x00010000)
+	binary.BigEndian.PutUint32(mvhd[60:], 0x00010000)
+	binary.BigEndian.PutUint32(mvhd[76:], 0x40000000)
+	binary.BigEndian.PutUint32(mvhd[104:], 2) // next track ID
+	moov := make([]byte, 8, 8+len(mvhd))
+	binary.BigEndian.PutUint32(moov, uint32(cap(moov)))
+	copy(moov[4:], "moov")
+	ftyp := []byte{0, 0, 0, 20, 'f', 't', 'y', 'p', 'i', 's', 'o', 'm', 0, 0, 2, 0, 'i', 's', 'o', 'm'}
+	return append(ftyp, append(moov, mvhd...)...)
+}
@@ -0,0 +1,49 @@
+package importer
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// NAS media indexes updated with each archived file.
+const (
+	// IndexSynology adds files to the Synology media index (used by Photo Station, Moments,
+	// and Video Station, which then make their own thumbnails) with synoindex.
+	IndexSynology = "synology"
+)
+
+// nasDirs are directories of thumbnails, metadata, and deleted files that NAS
+// operating systems keep in shared folders, which contain JPEG files that aren't media.
+var nasDirs = map[string]bool{
+	"@eaDir":             true, // Synology thumbnails and metadata
+	"#recycle":           true, // Synology recycle bin
+	"#snapshot":          true, // Synology snapshots
+	".@__thumb":          true, // QNAP thumbnails
+	"@Recycle":           true, // QNAP recycle bin
+	"@Recently-Snapshot": true, // QNAP snapshots
+}
+
+// skipDir returns true if a directory with the name is skipped when looking for media files:
+// the state directory and NAS thumbnail, recycle bin, and snapshot directories.
+func skipDir(name string) bool {
+	return name == StateDir || nasDirs[name]
+}
+
+// index adds an archived file to the NAS media index (Options.Index), if any,
+// so that it appears in the NAS photo apps without waiting for a rescan.
+// Failures are logged but don't affect the import.
+func (imp *Importer) index(path string) {
+	switch imp.options.Index {
+	case "":
+		return
+	case IndexSynology:
+		if output, err := exec.Command("synoindex", "-a", path).CombinedOutput(); err != nil {
+			log.Warn().Err(err).Str("target-path", path).Str("output", strings.TrimSpace(string(output))).
+				Msg("Add file to Synology index")
+		}
+	default:
+		log.Warn().Str("index", imp.options.Index).Msg("Unknown NAS index")
+	}
+}
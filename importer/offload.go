@@ -0,0 +1,217 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/remote"
+)
+
+// StubExt is appended to the path of an offloaded file to name its stub placeholder.
+const StubExt = ".offloaded.json"
+
+// ErrRestorePending means an offloaded file is still being restored from cold storage.
+var ErrRestorePending = errors.New("restore from cold storage pending")
+
+// Stub is the placeholder left in place of an offloaded file.
+type Stub struct {
+	URL          string    `json:"url"`
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	StorageClass string    `json:"storageClass"`
+	Offloaded    time.Time `json:"offloaded"`
+}
+
+// Offload uploads archived files captured before the specified date (YYYY-MM-DD)
+// to remote storage and replaces them with stub placeholder files.
+// Files must match their catalog entries, and uploads are checked against the checksums
+// computed by the storage service. Returns the number of files offloaded.
+func (imp *Importer) Offload(ctx context.Context, store *remote.S3, before, storageClass string) (int, error) {
+	var selected []string
+	for _, root := range imp.roots() {
+		if err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			} else if entry.IsDir() {
+				if skipDir(entry.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			} else if !Supported(path) {
+				return nil
+			}
+			if when, err := CaptureTime(path); err != nil {
+				log.Warn().Err(err).Str("path", path).Msg("Skipping file")
+			} else if when.Format(dateFmt) < before {
+				selected = append(selected, path)
+			}
+			return nil
+		}); err != nil {
+			return 0, fmt.Errorf("walk %s: %w", root, err)
+		}
+	}
+
+	var offloaded int
+	for _, path := range selected {
+		if err := imp.offload(ctx, store, path, storageClass); err != nil {
+			return offloaded, fmt.Errorf("offload %s: %w", path, err)
+		}
+		offloaded++
+	}
+	return offloaded, nil
+}
+
+func (imp *Importer) offload(ctx context.Context, store *remote.S3, path, storageClass string) error {
+	rel, err := imp.relative(path)
+	if err != nil {
+		return err
+	}
+	hash, err := hashFile(path, HashSHA256)
+	if err != nil {
+		return err
+	}
+	// Only intact files are offloaded, as the local copy is removed.
+	if err := imp.checkCataloged(rel, path, hash); err != nil {
+		return err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	key := store.Key(rel)
+	// Large files are uploaded in parts so that an interrupted offload resumes where it stopped.
+	uploadState := filepath.Join(imp.target, StateDir, "uploads", hash+".json")
+	if err := store.PutResumable(ctx, key, path, storageClass, hash, uploadState); err != nil {
+		return err
+	}
+	if object, err := store.Head(ctx, key); err != nil {
+		return fmt.Errorf("check upload: %w", err)
+	} else if object.Size != stat.Size() || object.ChecksumSHA256 != "" && object.ChecksumSHA256 != hash {
+		// Multipart uploads were verified by their part checksums instead (see remote.S3.PutResumable).
+		return fmt.Errorf("uploaded object does not match file")
+	}
+
+	stub := &Stub{
+		URL:          store.URL(key),
+		Size:         stat.Size(),
+		SHA256:       hash,
+		StorageClass: storageClass,
+		Offloaded:    time.Now(),
+	}
+	if data, err := json.MarshalIndent(stub, "", "  "); err != nil {
+		return fmt.Errorf("marshal stub: %w", err)
+	} else if err := os.WriteFile(path+StubExt, data, 0666); err != nil {
+		return fmt.Errorf("write stub: %w", err)
+	} else if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove offloaded file: %w", err)
+	}
+	log.Info().Str("path", path).Str("url", stub.URL).Msg("Offloaded file")
+	return imp.catalogOffloaded(rel, stub.URL)
+}
+
+// Restore downloads an offloaded file from remote storage, replacing its stub placeholder.
+// The path may be that of the stub or of the original file.
+// Files in cold storage must first be restored by the storage service,
+// in which case the restore is requested (for the specified number of days and tier)
+// and ErrRestorePending is returned until it has completed.
+func (imp *Importer) Restore(ctx context.Context, path string, days int, tier string) error {
+	path = plainPath(strings.TrimSuffix(path, StubExt))
+	var stub Stub
+	if data, err := os.ReadFile(path + StubExt); err != nil {
+		return fmt.Errorf("read stub: %w", err)
+	} else if err := json.Unmarshal(data, &stub); err != nil {
+		return fmt.Errorf("parse stub: %w", err)
+	}
+	store, err := remote.NewS3(ctx, stub.URL)
+	if err != nil {
+		return err
+	}
+	key, err := store.KeyFromURL(stub.URL)
+	if err != nil {
+		return err
+	}
+	object, err := store.Head(ctx, key)
+	if err != nil {
+		return err
+	}
+	if object.Archived {
+		if !object.Restoring {
+			if err := store.Restore(ctx, key, days, tier); err != nil {
+				return err
+			}
+			log.Info().Str("url", stub.URL).Str("tier", tier).Msg("Requested restore")
+		}
+		return fmt.Errorf("%w: %s", ErrRestorePending, stub.URL)
+	}
+
+	temp := path + ".download"
+	if err := store.Get(ctx, key, temp); err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+	if hash, err := hashFile(temp, HashSHA256); err != nil {
+		return err
+	} else if hash != stub.SHA256 {
+		_ = os.Remove(temp)
+		return fmt.Errorf("%w: restored file hash does not match stub", ErrCorrupt)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		return fmt.Errorf("rename restored file: %w", err)
+	} else if err := os.Remove(path + StubExt); err != nil {
+		return fmt.Errorf("remove stub: %w", err)
+	}
+	log.Info().Str("path", path).Str("url", stub.URL).Msg("Restored file")
+	rel, err := imp.relative(path)
+	if err != nil {
+		return err
+	}
+	return imp.catalogOffloaded(rel, "")
+}
+
+// checkCataloged checks that the file at the path (relative path rel) with the SHA-256 hash (hex)
+// matches its catalog entry, if any.
+func (imp *Importer) checkCataloged(rel, path, hash string) error {
+	if imp.options.Catalog == nil {
+		return nil
+	}
+	file := imp.options.Catalog.File(rel)
+	if file == nil || file.Hash == "" {
+		return nil
+	}
+	algorithm, expected := splitHash(file.Hash)
+	sum := hash
+	if algorithm != HashSHA256 {
+		var err error
+		if sum, err = hashFile(path, algorithm); err != nil {
+			return err
+		}
+	}
+	if sum != expected {
+		return fmt.Errorf("%w: file differs from catalog", ErrCorrupt)
+	}
+	return nil
+}
+
+// catalogOffloaded records the remote URL of an offloaded file (empty when restored).
+func (imp *Importer) catalogOffloaded(rel, url string) error {
+	if imp.options.Catalog == nil {
+		return nil
+	}
+	if file := imp.options.Catalog.File(rel); file != nil {
+		updated := *file
+		updated.Offloaded = url
+		if err := imp.options.Catalog.AddFile(&updated); err != nil {
+			return fmt.Errorf("catalog file: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package importer
+
+import (
+	"fmt"
+
+	"github.com/dsoprea/go-exif/v3"
+)
+
+// EXIF Orientation values 1 through 8 describe how the pixels are to be rotated and flipped for display,
+// 1 being upright. Some cameras write other values (e.g. 0 or 9) which viewers handle inconsistently.
+const (
+	tagIDOrientation     = 0x112
+	tagNameOrientation   = "Orientation"
+	orientationUpright   = 1
+	orientationTransform = 8
+)
+
+// EXIForientation returns the EXIF Orientation of an image, zero if it has none.
+func EXIForientation(path string) (int, error) {
+	metadata, err := exifRead(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := metadata.value(tagIDOrientation)
+	if err != nil {
+		return 0, nil
+	}
+	if shorts, ok := value.([]uint16); ok && len(shorts) == 1 {
+		return int(shorts[0]), nil
+	}
+	return 0, fmt.Errorf("unexpected orientation value %v", value)
+}
+
+// validOrientation returns true if the orientation is one of the standard values.
+func validOrientation(orientation int) bool {
+	return orientation >= orientationUpright && orientation <= orientationTransform
+}
+
+// EXIFsetOrientation sets the Orientation tag.
+func EXIFsetOrientation(rootIb *exif.IfdBuilder, orientation int) error {
+	if err := rootIb.SetStandardWithName(tagNameOrientation, []uint16{uint16(orientation)}); err != nil {
+		return fmt.Errorf("set %s: %w", tagNameOrientation, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// CameraOverride replaces import options for the files of one camera,
+// identified by its card or by the EXIF Model of its JPEG files.
+type CameraOverride struct {
+	// Name identifies the override in logs.
+	Name string
+	// Card matches the volume ID or label of the card containing the files (see CardIdentity),
+	// empty to match any card.
+	Card string
+	// Model matches the EXIF Model of JPEG files, empty to match any model.
+	// Other files (e.g. videos) only match overrides without a model.
+	Model string
+	// Zone replaces Options.CameraZone, nil to keep it.
+	Zone *time.Location
+	// ClockOffset is added to the capture time of each file to correct a camera clock that is off.
+	// The archived files are named and cataloged by the corrected time and the EXIF capture time
+	// of JPEG files is corrected, but other files keep their recorded time.
+	ClockOffset time.Duration
+	// Prefix is prepended to the archived names of files (e.g. the camera name).
+	Prefix string
+	// MinConfidence is the classifier confidence (0 to 1) below which tags are not recorded,
+	// zero to record all tags.
+	MinConfidence float64
+}
+
+// match returns true if the override applies to the source file, whose card is found with cardOf.
+// An override with neither a card nor a model applies to no files.
+func (o *CameraOverride) match(source string, cardOf func(dir string) *catalog.Card) bool {
+	if o.Card == "" && o.Model == "" {
+		return false
+	}
+	if o.Card != "" {
+		card := cardOf(filepath.Dir(source))
+		if card == nil || o.Card != card.Volume && o.Card != card.Label {
+			return false
+		}
+	}
+	if o.Model != "" {
+		if !isJPEG(source) {
+			return false
+		}
+		metadata, err := exifRead(source)
+		if err != nil {
+			return false
+		}
+		model, err := metadata.value(tagIDModel)
+		if err != nil {
+			return false
+		}
+		if modelStr, ok := model.(string); !ok || strings.TrimSpace(modelStr) != o.Model {
+			return false
+		}
+	}
+	return true
+}
+
+// override returns the first camera override applying to the source file, nil if none.
+func (imp *Importer) override(source string) *CameraOverride {
+	for _, override := range imp.options.Cameras {
+		if override.match(source, imp.cardOf) {
+			return override
+		}
+	}
+	return nil
+}
+
+// cardOf returns the identity of the card containing the directory (see CardIdentity),
+// cached by directory since all the files of a directory are on the same card.
+// The cache is cleared by each ImportBatch, since another card may be mounted at the same path.
+func (imp *Importer) cardOf(dir string) *catalog.Card {
+	imp.cardsMutex.Lock()
+	defer imp.cardsMutex.Unlock()
+	if card, found := imp.cards[dir]; found {
+		return card
+	}
+	if imp.cards == nil {
+		imp.cards = make(map[string]*catalog.Card)
+	}
+	card := CardIdentity(dir)
+	imp.cards[dir] = card
+	return card
+}
+
+// forgetCards clears the cache of card identities.
+func (imp *Importer) forgetCards() {
+	imp.cardsMutex.Lock()
+	defer imp.cardsMutex.Unlock()
+	imp.cards = nil
+}
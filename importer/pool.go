@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Policies for choosing the target root directory of a file when there is a pool of roots.
+const (
+	// PoolFillFirst uses the first root (starting with the target root)
+	// with enough free space for the file.
+	PoolFillFirst = "fill-first"
+	// PoolMonth rotates through the roots by capture month
+	// so that each month is kept together on one root.
+	PoolMonth = "month"
+)
+
+// poolReserve is the free space to be left on each root by the fill-first policy.
+const poolReserve = 1 << 30
+
+// poolMarker is the name of the file in the state directory of each pool root
+// that contains the path of the target root (where the catalog is kept).
+const poolMarker = "pool"
+
+// roots returns the target root followed by any pool roots.
+func (imp *Importer) roots() []string {
+	return append([]string{imp.target}, imp.options.Pool...)
+}
+
+// rootOf returns the root directory containing the path.
+func (imp *Importer) rootOf(path string) string {
+	for _, root := range imp.options.Pool {
+		if strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return root
+		}
+	}
+	return imp.target
+}
+
+// chooseRoot returns the root directory into which a file is to be copied.
+// If the file already exists in any root that root is returned
+// so that identical files are detected and conflicts are not hidden.
+func (imp *Importer) chooseRoot(source string, when time.Time, subDir string) (string, error) {
+	roots := imp.roots()
+	if len(roots) == 1 {
+		return imp.target, nil
+	}
+	base := filepath.Base(source)
+	for _, root := range roots {
+		if _, err := os.Stat(existingPath(imp.targetPath(root, when, subDir, base))); err == nil {
+			return root, nil
+		}
+	}
+
+	var root string
+	switch imp.options.PoolPolicy {
+	case PoolMonth:
+		root = roots[(when.Year()*12+int(when.Month())-1)%len(roots)]
+	case PoolFillFirst, "":
+		stat, err := os.Stat(source)
+		if err != nil {
+			return "", fmt.Errorf("stat source file: %w", err)
+		}
+		for _, candidate := range roots {
+			if free, err := freeSpace(candidate); err != nil {
+				return "", fmt.Errorf("%w: free space of %s: %s", ErrTargetUnavailable, candidate, err)
+			} else if free > uint64(stat.Size())+poolReserve {
+				root = candidate
+				break
+			}
+		}
+		if root == "" {
+			return "", fmt.Errorf("%w: no free space in target pool", ErrTargetUnavailable)
+		}
+	default:
+		return "", fmt.Errorf("unknown pool policy '%s'", imp.options.PoolPolicy)
+	}
+	if root != imp.target {
+		if err := imp.markPoolRoot(root); err != nil {
+			return "", err
+		}
+	}
+	return root, nil
+}
+
+// markPoolRoot writes the pool marker file into the state directory of a pool root
+// so that FindTarget can find the catalog from files in the pool root.
+func (imp *Importer) markPoolRoot(root string) error {
+	marker := filepath.Join(root, StateDir, poolMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+	target, err := filepath.Abs(imp.target)
+	if err != nil {
+		return fmt.Errorf("absolute target path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(marker), 0766); err != nil {
+		return fmt.Errorf("%w: make pool state dir: %s", ErrTargetUnavailable, err)
+	} else if err := os.WriteFile(marker, []byte(target+"\n"), 0666); err != nil {
+		return fmt.Errorf("%w: write pool marker: %s", ErrTargetUnavailable, err)
+	}
+	return nil
+}
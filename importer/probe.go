@@ -0,0 +1,42 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Delays between checks for an unavailable target to return, doubling each time.
+const (
+	retryMinDelay = time.Second
+	retryMaxDelay = 30 * time.Second
+)
+
+// Probe checks that the target root and any pool roots are present and writable,
+// for example that a NAS share is mounted and responding.
+// Errors wrap ErrTargetUnavailable.
+func (imp *Importer) Probe() error {
+	for _, root := range imp.roots() {
+		if err := probeRoot(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func probeRoot(root string) error {
+	if stat, err := os.Stat(root); err != nil {
+		return fmt.Errorf("%w: stat root %s: %s", ErrTargetUnavailable, root, err)
+	} else if !stat.IsDir() {
+		return fmt.Errorf("%w: root %s is not a directory", ErrTargetUnavailable, root)
+	}
+	file, err := os.CreateTemp(root, ".gardepro-probe-*")
+	if err != nil {
+		return fmt.Errorf("%w: write to root %s: %s", ErrTargetUnavailable, root, err)
+	}
+	_ = file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		return fmt.Errorf("%w: remove probe file from root %s: %s", ErrTargetUnavailable, root, err)
+	}
+	return nil
+}
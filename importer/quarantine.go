@@ -0,0 +1,58 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// QuarantineDir is the directory beneath the target root state directory
+// into which damaged source files are copied instead of being archived.
+const QuarantineDir = StateDir + "/quarantine"
+
+// quarantine copies a damaged source file into the quarantine directory
+// along with a text file containing the diagnosis.
+// Returns the path of the quarantined copy.
+func (imp *Importer) quarantine(source string, diagnosis error) (string, error) {
+	dir := filepath.Join(imp.target, QuarantineDir)
+	if err := os.MkdirAll(dir, 0766); err != nil {
+		return "", fmt.Errorf("make quarantine dir: %w", err)
+	}
+	base := filepath.Base(source)
+	ext := filepath.Ext(base)
+	path := filepath.Join(dir, base)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("stat quarantine file: %w", err)
+		} else if equal, err := compareFiles(source, path, nil); err != nil {
+			return "", fmt.Errorf("compare quarantine file: %w", err)
+		} else if equal {
+			return path, nil
+		}
+		path = filepath.Join(dir, strings.TrimSuffix(base, ext)+"-"+strconv.Itoa(i)+ext)
+	}
+	if err := copyFile(source, path); err != nil {
+		return "", fmt.Errorf("copy to quarantine: %w", err)
+	}
+	if err := os.WriteFile(path+".txt", []byte(source+"\n"+diagnosis.Error()+"\n"), 0666); err != nil {
+		return "", fmt.Errorf("write quarantine diagnosis: %w", err)
+	}
+	log.Warn().Err(diagnosis).Str("quarantine-path", path).Msg("Quarantined file")
+	return path, nil
+}
+
+// quarantineError quarantines a source file that failed to import
+// and returns the path of the quarantined copy and the import error.
+func (imp *Importer) quarantineError(source string, err error) (string, error) {
+	path, qErr := imp.quarantine(source, err)
+	if qErr != nil {
+		return "", &Error{Source: source, Err: fmt.Errorf("%w (quarantine: %s)", err, qErr)}
+	}
+	return path, &Error{Source: source, Target: path, Err: err, Quarantined: true}
+}
@@ -0,0 +1,45 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Trail camera brands disagree on where and how the capture time is recorded:
+//   - GardePro writes EXIF DateTime in IFD0.
+//   - Some Browning and Bushnell models leave DateTime empty or zeroed
+//     and only write DateTimeOriginal or DateTimeDigitized in the EXIF IFD.
+//   - Some cameras separate the date with slashes or dashes instead of colons,
+//     pad the value with spaces, or omit the seconds.
+//   - Browning and older Bushnell models record AVI rather than MP4 video,
+//     with the time in an IDIT chunk formatted like C asctime().
+//   - Reconyx writes standard EXIF times along with a maker note.
+
+// cameraTimeLayouts are the formats in which cameras write capture times.
+var cameraTimeLayouts = []string{
+	exifTimeFmt,
+	"2006/01/02 15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006:01:02 15:04",
+	"2006/01/02 15:04",
+	time.ANSIC,
+	"Mon Jan 2 15:04:05 2006",
+	"2006-01-02",
+}
+
+// parseCameraTime parses a capture time in any of the cameraTimeLayouts.
+// The time is parsed as UTC since cameras don't record the time zone.
+func parseCameraTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(strings.Trim(value, "\x00"))
+	if value == "" || strings.HasPrefix(value, "0000") {
+		return time.Time{}, fmt.Errorf("empty time '%s'", value)
+	}
+	for _, layout := range cameraTimeLayouts {
+		if when, err := time.Parse(layout, value); err == nil {
+			return when, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time format '%s'", value)
+}
@@ -0,0 +1,28 @@
+package importer
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/trace"
+)
+
+// importFileIsolated imports the source file as importFileOnce does, recovering from panics.
+// The parsers (some third-party) can panic on damaged files, which must not abort a whole batch,
+// so the file is quarantined with the panic as its diagnosis and ErrCorrupt is returned.
+func (imp *Importer) importFileIsolated(source, subDir string, dog *watchdog, span *trace.Span) (targetPath string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Str("source", source).Interface("panic", r).Str("stack", string(debug.Stack())).
+				Msg("Import panicked")
+			if !dog.claim() {
+				targetPath, err = "", abandoned(plainPath(source))
+				return
+			}
+			targetPath, err = imp.quarantineError(plainPath(source), fmt.Errorf("%w: import panicked: %v", ErrCorrupt, r))
+		}
+	}()
+	return imp.importFileOnce(source, subDir, dog, span)
+}
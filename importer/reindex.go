@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// yearDir matches the names of the year directories in a target root.
+var yearDir = regexp.MustCompile(`^[0-9]{4}$`)
+
+// ReindexOptions specifies which archived files are reindexed.
+type ReindexOptions struct {
+	// Year limits reindexing to a single year directory, zero for all years.
+	Year int
+}
+
+// Reindex rebuilds the catalog entries of the media files in the year directories of all roots
+// by re-reading their contents and embedded metadata (see describeFile),
+// e.g. after the catalog is lost or a feature such as exposure checking is enabled late.
+// Existing entries keep their source, import time, session, labels, and rating.
+// Files without entries are added without a source.
+// Entries of files that are missing (and not offloaded) are logged.
+// Returns the number of files reindexed.
+func (imp *Importer) Reindex(options *ReindexOptions) (int, error) {
+	if imp.options.Catalog == nil {
+		return 0, errors.New("reindexing requires a catalog")
+	}
+	var count int
+	found := make(map[string]bool)
+	err := imp.walkArchive(options.Year, func(path string) error {
+		rel, err := imp.relative(path)
+		if err != nil {
+			return err
+		}
+		existing := imp.options.Catalog.File(rel)
+		if existing != nil {
+			found[existing.Path] = true
+		}
+		when, err := CaptureTime(path)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Reindex: no capture time")
+			return nil
+		}
+		if err := imp.indexFile(path, rel, when, existing); err != nil {
+			return err
+		}
+		found[rel] = true
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	for _, file := range imp.options.Catalog.Files() {
+		if year, _, _ := strings.Cut(file.Path, "/"); !found[file.Path] && file.Offloaded == "" &&
+			(options.Year == 0 || year == strconv.Itoa(options.Year)) {
+			log.Warn().Str("path", file.Path).Msg("Reindex: cataloged file missing")
+		}
+	}
+	return count, nil
+}
+
+// indexFile records an archived file in the catalog, updating the existing entry (if not nil).
+// The file's hash and other fields read from its contents and sidecars (see describeFile)
+// are updated, its source, import time, session, labels, and rating are kept.
+func (imp *Importer) indexFile(path, rel string, when time.Time, existing *catalog.File) error {
+	algorithm := imp.options.Hash
+	if algorithm == "" {
+		algorithm = HashSHA256
+	}
+	sum, err := hashFile(path, algorithm)
+	if err != nil {
+		return fmt.Errorf("hash %s: %w", path, err)
+	}
+	file := &catalog.File{Path: rel, Imported: time.Now()}
+	if existing != nil {
+		updated := *existing
+		file = &updated
+	}
+	if root := imp.rootOf(path); root != imp.target {
+		if file.Root, err = filepath.Abs(root); err != nil {
+			file.Root = root
+		}
+	} else {
+		file.Root = ""
+	}
+	file.Hash, file.Captured = algorithm+":"+sum, when
+	imp.describeFile(file, path)
+	if err := imp.options.Catalog.AddFile(file); err != nil {
+		return fmt.Errorf("catalog %s: %w", path, err)
+	}
+	return nil
+}
+
+// walkArchive calls the function with the path of each media file
+// in the year directories of all roots (or just the specified year, if not zero).
+// The state directory, event videos, and files being written are skipped.
+func (imp *Importer) walkArchive(year int, fn func(path string) error) error {
+	for _, root := range imp.roots() {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return fmt.Errorf("read root %s: %w", root, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || !yearDir.MatchString(entry.Name()) ||
+				year != 0 && entry.Name() != strconv.Itoa(year) {
+				continue
+			}
+			if err := filepath.WalkDir(filepath.Join(root, entry.Name()), func(path string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				} else if entry.IsDir() {
+					if skipDir(entry.Name()) {
+						return filepath.SkipDir
+					}
+					return nil
+				} else if !Supported(path) ||
+					strings.HasSuffix(strings.TrimSuffix(path, filepath.Ext(path)), eventSuffix) {
+					return nil
+				}
+				return fn(path)
+			}); err != nil {
+				return fmt.Errorf("walk %s: %w", root, err)
+			}
+		}
+	}
+	return nil
+}
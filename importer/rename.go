@@ -0,0 +1,34 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RenameInPlace renames a media file within its own directory to the dated file name
+// convention used in the target tree (without the year directory).
+// Files already named for their capture time are left alone.
+// The new name is normalized (see normalName). Any sidecar file is renamed to match.
+// Returns the new path of the file.
+func RenameInPlace(path string) (string, error) {
+	when, err := CaptureTime(path)
+	if err != nil {
+		return path, &Error{Source: path, Err: err}
+	}
+	stub := when.Format(fileNameStubFmt)
+	base := filepath.Base(path)
+	if strings.HasPrefix(base, stub) {
+		return path, nil
+	}
+	newPath := filepath.Join(filepath.Dir(path), stub+normalName(base))
+	if _, err := os.Stat(existingPath(newPath)); err == nil {
+		return path, &Error{Source: path, Target: newPath, Err: fmt.Errorf("%w: file exists", ErrConflict)}
+	} else if err := os.Rename(path, newPath); err != nil {
+		return path, &Error{Source: path, Target: newPath, Err: fmt.Errorf("rename: %w", err)}
+	} else if err := moveSidecars(path, newPath); err != nil {
+		return newPath, &Error{Source: path, Target: newPath, Err: err}
+	}
+	return newPath, nil
+}
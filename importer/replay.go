@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Replayed is a source file of a journaled session with the decision made then
+// and the decision that would be made now.
+type Replayed struct {
+	Source string
+	// Then is the journaled decision, nil if there was none (e.g. the session was interrupted).
+	Then *Decision
+	// Now is the decision importing the file again would make.
+	Now *Decision
+}
+
+// Changed returns true if the file would now be treated differently.
+// A file archived then (imported, or identical to or presumed to be a file already archived)
+// and to the same target now is not treated differently.
+func (r *Replayed) Changed() bool {
+	if r.Then == nil {
+		return true
+	} else if archived(r.Then.Action) && archived(r.Now.Action) {
+		return r.Then.Target != r.Now.Target
+	}
+	return r.Then.Action != r.Now.Action
+}
+
+// archived returns true if the action leaves the file archived.
+func archived(action string) bool {
+	return action == ActionImported || action == ActionIdentical || action == ActionPresumed
+}
+
+// Replay decides again what to do with each source file of a journaled session
+// against the current state of the archive, as importing the files again would,
+// without changing anything. Source files that no longer exist fail.
+// The Importer should have the options of the session (see JournalSettings.Apply).
+// Files are in the order of the journal, each once (its last decision).
+func (imp *Importer) Replay(journal *Journal) []*Replayed {
+	var replayed []*Replayed
+	bySource := make(map[string]*Replayed)
+	var sources []string
+	for _, decision := range journal.Decisions {
+		if r, found := bySource[decision.Source]; found {
+			r.Then = decision
+			continue
+		}
+		r := &Replayed{Source: decision.Source, Then: decision}
+		bySource[decision.Source] = r
+		replayed = append(replayed, r)
+		if _, err := os.Stat(decision.Source); err == nil {
+			sources = append(sources, decision.Source)
+		}
+	}
+	root := journal.Source
+	if stat, err := os.Stat(root); err != nil || !stat.IsDir() {
+		root = filepath.Dir(root)
+	}
+	var included []string
+	for _, source := range sources {
+		if !imp.Excluded(source) {
+			included = append(included, source)
+		}
+	}
+	duplicates := findDuplicates(included, imp.options.Hash)
+	// Files that would be imported to the same target conflict (duplicates are already excluded).
+	planned := make(map[string]string)
+	for _, r := range replayed {
+		if duplicate := duplicates[r.Source]; duplicate != "" {
+			r.Now = &Decision{Source: r.Source, Action: ActionDuplicate, Reason: duplicate}
+		} else {
+			r.Now = imp.plan(r.Source, imp.sourceSubDir(root, r.Source))
+			if r.Now.Action == ActionImported {
+				if earlier, found := planned[r.Now.Target]; found {
+					r.Now.Action = ActionFailed
+					r.Now.Reason = fmt.Sprintf("%s: %s would be imported first", ErrConflict, earlier)
+				} else {
+					planned[r.Now.Target] = r.Source
+				}
+			}
+		}
+		r.Now.Time = time.Now()
+	}
+	return replayed
+}
+
+// plan returns the decision importing the source file would make, as importFileOnce does,
+// without copying or quarantining it.
+func (imp *Importer) plan(source, subDir string) *Decision {
+	decision := &Decision{Source: source}
+	fail := func(action string, err error) *Decision {
+		decision.Action, decision.Reason = action, err.Error()
+		return decision
+	}
+	if _, err := os.Stat(source); err != nil {
+		return fail(ActionFailed, err)
+	}
+	if imp.Excluded(source) {
+		decision.Action = ActionExcluded
+		return decision
+	}
+	if imp.options.Verify {
+		if err := verify(source); err != nil {
+			return fail(ActionQuarantined, err)
+		}
+	}
+	when, err := CaptureTime(source)
+	if err != nil {
+		if imp.options.Lenient && (errors.Is(err, ErrNoCaptureTime) || errors.Is(err, ErrUnsupportedFormat)) {
+			return fail(ActionQuarantined, err)
+		}
+		return fail(ActionFailed, err)
+	}
+	decision.Captured = when
+	root, err := imp.chooseRoot(source, when, subDir)
+	if err != nil {
+		return fail(ActionFailed, err)
+	}
+	decision.Target = existingPath(imp.targetPath(root, when, subDir, filepath.Base(source)))
+	if _, err := os.Stat(decision.Target); errors.Is(err, os.ErrNotExist) {
+		decision.Action = ActionImported
+		return decision
+	} else if err != nil {
+		return fail(ActionFailed, err)
+	}
+	if imp.options.QuickSkip && imp.presumeImported(source, decision.Target) {
+		decision.Action = ActionPresumed
+		return decision
+	}
+	data, err := imp.targetData(source, when)
+	if err != nil {
+		return fail(ActionFailed, err)
+	}
+	if equal, err := compareTarget(source, decision.Target, data, nil); err != nil {
+		return fail(ActionFailed, err)
+	} else if !equal {
+		return fail(ActionFailed, fmt.Errorf("%w: pre-existing file not identical", ErrConflict))
+	}
+	decision.Action = ActionIdentical
+	return decision
+}
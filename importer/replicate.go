@@ -0,0 +1,249 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// Peer is another installation reached over SSH, e.g. the archive at a cabin.
+// The peer only needs a POSIX shell, the program itself is not run there.
+type Peer struct {
+	// SSH is the path of the ssh executable, "ssh" (found in the PATH) if empty.
+	// Keys and host options are taken from the usual ssh configuration.
+	SSH string
+	// Host is the ssh destination, e.g. pi@cabin.
+	Host string
+	// Target is the target root directory on the peer.
+	Target string
+}
+
+// ReplicateResult counts the files copied by Replicate.
+type ReplicateResult struct {
+	Pulled, Pushed int
+}
+
+// Replicate mirrors the archive with a peer by copying each cataloged file that
+// the other side's catalog lacks, along with its catalog entry, in both directions.
+// Pulled files are verified against their catalog hashes and pushed files by size.
+// Files are copied into the target root on either side (not into pool roots).
+// Moves and removals are not replicated, nor are offloaded files or sidecars.
+func (imp *Importer) Replicate(peer *Peer) (*ReplicateResult, error) {
+	if imp.options.Catalog == nil {
+		return nil, errors.New("replication requires a catalog")
+	}
+	work, err := os.MkdirTemp("", "gardepro-replicate-")
+	if err != nil {
+		return nil, fmt.Errorf("make work dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(work) }()
+	journal, err := os.Create(filepath.Join(work, catalog.FileName))
+	if err != nil {
+		return nil, fmt.Errorf("create peer catalog: %w", err)
+	}
+	err = peer.run(nil, journal, "cat "+shellQuote(path.Join(peer.Target, StateDir, catalog.FileName)))
+	if closeErr := journal.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read peer catalog: %w", err)
+	}
+	remote, err := catalog.Open(work)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = remote.Close() }()
+
+	result := &ReplicateResult{}
+	for _, file := range remote.Files() {
+		if file.Offloaded != "" || imp.options.Catalog.File(file.Path) != nil {
+			continue
+		}
+		if err := imp.pull(peer, remote, file); err != nil {
+			return result, &Error{Source: peer.Host + ":" + peer.path(file), Err: err}
+		}
+		result.Pulled++
+	}
+	for _, file := range imp.options.Catalog.Files() {
+		if file.Offloaded != "" || remote.File(file.Path) != nil {
+			continue
+		}
+		if err := imp.push(peer, remote, file); err != nil {
+			return result, &Error{Source: imp.catalogPath(file), Err: err}
+		}
+		result.Pushed++
+	}
+	return result, nil
+}
+
+// pull copies a file from the peer into the target tree and catalogs it.
+// The file is created exclusively, so nothing is overwritten.
+func (imp *Importer) pull(peer *Peer, remote *catalog.Catalog, file *catalog.File) error {
+	rel, err := pullPath(file.Path)
+	if err != nil {
+		return err
+	}
+	for _, root := range imp.roots() {
+		if _, err := os.Lstat(existingPath(filepath.Join(root, rel))); err == nil {
+			return fmt.Errorf("%w: %s exists but is not cataloged", ErrConflict, file.Path)
+		}
+	}
+	target := filepath.Join(imp.target, rel)
+	if err := imp.checkTargetDir(imp.target, file.Captured, filepath.Dir(target)); err != nil {
+		return err
+	}
+	algorithm, sum := splitHash(file.Hash)
+	h, err := newHash(algorithm)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if errors.Is(err, os.ErrExist) {
+		// Created since it was checked.
+		return fmt.Errorf("%w: %s exists but is not cataloged", ErrConflict, file.Path)
+	} else if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	err = peer.run(nil, io.MultiWriter(out, h), "cat "+shellQuote(peer.path(file)))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && file.Hash != "" && hashString(h) != sum {
+		err = fmt.Errorf("%w: pulled file does not match its hash", ErrCorrupt)
+	}
+	if err != nil {
+		// Don't leave a partial file that would conflict when replication is retried.
+		_ = os.Remove(target)
+		return err
+	}
+	setCreated(target, imp.instant(target, file.Captured))
+
+	pulled := *file
+	pulled.Root = ""
+	if file.Hash == "" {
+		pulled.Hash = algorithm + ":" + hashString(h)
+	}
+	if session := remote.Session(file.Session); session != nil {
+		if err := imp.options.Catalog.AddSession(session); err != nil {
+			return fmt.Errorf("catalog pulled session: %w", err)
+		}
+	}
+	if err := imp.options.Catalog.AddFile(&pulled); err != nil {
+		return fmt.Errorf("catalog pulled file: %w", err)
+	}
+	log.Info().Str("target-path", target).Str("peer", peer.Host).Msg("Pulled file")
+	return nil
+}
+
+// pullPath returns the local relative path of a file cataloged by the peer,
+// in normalization form C (see normalName).
+// The path comes from the peer, so paths outside the target are rejected.
+func pullPath(slashPath string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(normalName(slashPath)))
+	if filepath.IsAbs(rel) || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) ||
+		filepath.VolumeName(rel) != "" {
+		return "", fmt.Errorf("bad peer catalog path %q", slashPath)
+	}
+	return rel, nil
+}
+
+// push copies a file to the peer's target root and appends its entry to the peer's catalog.
+func (imp *Importer) push(peer *Peer, remote *catalog.Catalog, file *catalog.File) error {
+	source := imp.catalogPath(file)
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+	stat, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	pushed := *file
+	pushed.Root = ""
+	target := peer.path(&pushed)
+	var size bytes.Buffer
+	if err := peer.run(in, &size, fmt.Sprintf("mkdir -p %s && cat > %s && mv %s %s && wc -c < %s",
+		shellQuote(path.Dir(target)), shellQuote(target+".tmp"), shellQuote(target+".tmp"),
+		shellQuote(target), shellQuote(target))); err != nil {
+		return err
+	}
+	if n, err := strconv.ParseInt(strings.TrimSpace(size.String()), 10, 64); err != nil || n != stat.Size() {
+		return fmt.Errorf("%w: pushed file size %q does not match %d", ErrCorrupt, strings.TrimSpace(size.String()), stat.Size())
+	}
+
+	// The catalog is an append-only journal so records can be appended by the peer's shell.
+	var records bytes.Buffer
+	session := imp.options.Catalog.Session(file.Session)
+	if session != nil && remote.Session(session.ID) == nil {
+		if err := appendRecord(&records, &catalog.Record{Session: session}); err != nil {
+			return err
+		}
+	}
+	if err := appendRecord(&records, &catalog.Record{File: &pushed}); err != nil {
+		return err
+	}
+	if err := peer.run(&records, nil, "cat >> "+shellQuote(path.Join(peer.Target, StateDir, catalog.FileName))); err != nil {
+		return fmt.Errorf("catalog pushed file: %w", err)
+	}
+	// Keep the copy of the peer's catalog up to date so that sessions are only sent once.
+	if session != nil {
+		if err := remote.AddSession(session); err != nil {
+			return err
+		}
+	}
+	log.Info().Str("source", source).Str("peer", peer.Host).Msg("Pushed file")
+	return nil
+}
+
+func appendRecord(buffer *bytes.Buffer, record *catalog.Record) error {
+	record.Time = time.Now()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal catalog record: %w", err)
+	}
+	buffer.Write(append(data, '\n'))
+	return nil
+}
+
+// path returns the path of a cataloged file on the peer.
+func (peer *Peer) path(file *catalog.File) string {
+	root := file.Root
+	if root == "" {
+		root = peer.Target
+	}
+	return path.Join(filepath.ToSlash(root), file.Path)
+}
+
+// run a shell command on the peer.
+func (peer *Peer) run(stdin io.Reader, stdout io.Writer, command string) error {
+	ssh := peer.SSH
+	if ssh == "" {
+		ssh = "ssh"
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(ssh, peer.Host, command)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", ssh, peer.Host, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// shellQuote quotes a string for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
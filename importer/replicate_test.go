@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// testPeer returns a peer whose target is a new directory with a catalog of the files (by slash separated path),
+// reached by a fake ssh that runs commands with the local shell.
+func testPeer(t *testing.T, files map[string][]byte) *Peer {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("no POSIX shell")
+	}
+	dir := t.TempDir()
+	ssh := writeFile(t, dir, "ssh", []byte("#!/bin/sh\n# ssh HOST COMMAND\nexec sh -c \"$2\"\n"))
+	if err := os.Chmod(ssh, 0755); err != nil {
+		t.Fatal(err)
+	}
+	peer := &Peer{SSH: ssh, Host: "cabin", Target: filepath.Join(dir, "target")}
+	cat, err := OpenCatalog(peer.Target)
+	if err != nil {
+		t.Fatalf("open peer catalog: %s", err)
+	}
+	defer func() { _ = cat.Close() }()
+	for name, data := range files {
+		path := writeFile(t, peer.Target, name, data)
+		sum, err := hashFile(path, HashSHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cat.AddFile(&catalog.File{Path: name, Hash: HashSHA256 + ":" + sum, Captured: testTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return peer
+}
+
+func TestReplicatePull(t *testing.T) {
+	name := "2024/05-01-06:30:00-IMG_0001.JPG"
+	imp := testImporter(t, Options{})
+	result, err := imp.Replicate(testPeer(t, map[string][]byte{name: []byte("pulled")}))
+	if err != nil {
+		t.Fatal(err)
+	} else if result.Pulled != 1 || result.Pushed != 0 {
+		t.Errorf("pulled %d and pushed %d, want 1 and 0", result.Pulled, result.Pushed)
+	}
+	if data, err := os.ReadFile(filepath.Join(imp.Target(), filepath.FromSlash(name))); err != nil || string(data) != "pulled" {
+		t.Errorf("pulled file contains %q (%v)", data, err)
+	}
+	if imp.options.Catalog.File(name) == nil {
+		t.Errorf("pulled file not cataloged")
+	}
+}
+
+// TestReplicatePullPaths checks that peer catalog paths outside the target are rejected
+// and existing files which aren't cataloged are not overwritten.
+func TestReplicatePullPaths(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		path string
+		// existing is the name of an uncataloged file in the target, if any.
+		existing string
+		err      string
+	}{
+		{"parent", "../../escape.JPG", "", "bad peer catalog path"},
+		{"parent after cleaning", "2024/../../escape.JPG", "", "bad peer catalog path"},
+		{"absolute", "/tmp/escape.JPG", "", "bad peer catalog path"},
+		{"root", ".", "", "bad peer catalog path"},
+		{"uncataloged file", "2024/05-01-06:30:00-IMG_0001.JPG", "2024/05-01-06:30:00-IMG_0001.JPG", ErrConflict.Error()},
+		{"uncataloged file in form D", "2024/05-01-06:30:00-" + nameNFC, "2024/05-01-06:30:00-" + nameNFD, ErrConflict.Error()},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			imp := testImporter(t, Options{})
+			var existing string
+			if test.existing != "" {
+				existing = writeFile(t, imp.Target(), test.existing, []byte("existing"))
+			}
+			peer := testPeer(t, nil)
+			// The file is put in the peer catalog by hand as the path may not be valid there either.
+			if err := appendPeerFile(peer, test.path); err != nil {
+				t.Fatal(err)
+			}
+			_, err := imp.Replicate(peer)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("error %v, want %q", err, test.err)
+			}
+			if test.err == ErrConflict.Error() && !errors.Is(err, ErrConflict) {
+				t.Errorf("error %v is not %v", err, ErrConflict)
+			}
+			if existing != "" {
+				if data, err := os.ReadFile(existing); err != nil || string(data) != "existing" {
+					t.Errorf("existing file contains %q (%v)", data, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(imp.Target())), "escape.JPG")); err == nil {
+				t.Errorf("file written outside the target")
+			}
+			if len(imp.options.Catalog.Files()) != 0 {
+				t.Errorf("rejected file cataloged")
+			}
+		})
+	}
+}
+
+// appendPeerFile catalogs a file with the path in the peer catalog.
+func appendPeerFile(peer *Peer, path string) error {
+	cat, err := OpenCatalog(peer.Target)
+	if err != nil {
+		return err
+	}
+	if err := cat.AddFile(&catalog.File{Path: path, Hash: HashSHA256 + ":00", Captured: testTime}); err != nil {
+		_ = cat.Close()
+		return err
+	}
+	return cat.Close()
+}
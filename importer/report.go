@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// thumbnailWidth is the width in pixels of the thumbnails of notable events in reports.
+const thumbnailWidth = 320
+
+// MonthReport summarizes the captures of a month.
+type MonthReport struct {
+	// Month is the first day of the month.
+	Month time.Time
+	Files int
+	// Cameras is the activity of each camera with captures in the month, in camera order.
+	Cameras []*CameraActivity
+	// Tags counts the files with each tag (e.g. species from a classifier plugin) or label,
+	// most frequent first.
+	Tags []*TagCount
+	// Notable events are tagged or labeled files and the best frames of bursts, in capture order.
+	Notable []*Event
+}
+
+// TagCount is the number of files with a tag.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// Event is a notable capture.
+type Event struct {
+	File *catalog.File
+	// Thumbnail is a small JPEG of the file, nil if the file isn't a readable JPEG.
+	Thumbnail []byte
+}
+
+// Report summarizes the captures in the catalog during the month containing the specified time,
+// with thumbnails of up to the specified number of notable events.
+// Tagged or labeled files are preferred to the best frames of bursts as notable events.
+func (imp *Importer) Report(month time.Time, notable int) (*MonthReport, error) {
+	if imp.options.Catalog == nil {
+		return nil, errors.New("reports require a catalog")
+	}
+	report := &MonthReport{Month: time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)}
+	end := report.Month.AddDate(0, 1, 0)
+	report.Cameras = Activity(imp.options.Catalog, report.Month, end, "")
+	tags := make(map[string]int)
+	var tagged, best []*catalog.File
+	for _, file := range imp.options.Catalog.Originals() {
+		if file.Captured.Before(report.Month) || !file.Captured.Before(end) {
+			continue
+		}
+		report.Files++
+		for _, tag := range file.AllTags() {
+			tags[tag]++
+		}
+		if isJPEG(file.Path) && file.Offloaded == "" {
+			if len(file.AllTags()) > 0 {
+				tagged = append(tagged, file)
+			} else if file.Best {
+				best = append(best, file)
+			}
+		}
+	}
+	for tag, count := range tags {
+		report.Tags = append(report.Tags, &TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(report.Tags, func(i, j int) bool {
+		if report.Tags[i].Count != report.Tags[j].Count {
+			return report.Tags[i].Count > report.Tags[j].Count
+		}
+		return report.Tags[i].Tag < report.Tags[j].Tag
+	})
+
+	events := append(spread(tagged, notable), spread(best, notable-len(tagged))...)
+	sort.Slice(events, func(i, j int) bool {
+		return captureBefore(events[i], events[j])
+	})
+	for _, file := range events {
+		thumbnail, err := Thumbnail(imp.catalogPath(file), thumbnailWidth)
+		if err != nil {
+			log.Warn().Err(err).Str("path", file.Path).Msg("Thumbnail")
+		}
+		report.Notable = append(report.Notable, &Event{File: file, Thumbnail: thumbnail})
+	}
+	return report, nil
+}
+
+// spread returns up to the specified number of files evenly spread through the files by capture time.
+func spread(files []*catalog.File, count int) []*catalog.File {
+	if count <= 0 {
+		return nil
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return captureBefore(files[i], files[j])
+	})
+	if len(files) <= count {
+		return files
+	}
+	picked := make([]*catalog.File, count)
+	for i := range picked {
+		picked[i] = files[i*len(files)/count]
+	}
+	return picked
+}
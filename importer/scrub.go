@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// ScrubOptions limits the files verified by a scrub.
+type ScrubOptions struct {
+	// Budget is how long the scrub may take, zero for no limit.
+	// The file being verified when the budget runs out is finished.
+	Budget time.Duration
+	// Period is how often each file is to be verified:
+	// files verified more recently are skipped, zero to verify all files.
+	Period time.Duration
+}
+
+// ScrubResult summarizes a scrub.
+type ScrubResult struct {
+	// Verified is the number of files whose contents match their hashes.
+	Verified int
+	// Bytes is the total size of the files hashed.
+	Bytes int64
+	// Damaged files don't match their hashes, Missing files don't exist.
+	Damaged []*catalog.File
+	Missing []*catalog.File
+	// Remaining is the number of files due for verification that were not reached within the budget.
+	Remaining int
+	// Orphans are derivatives (e.g. low resolution copies) whose originals are no longer cataloged,
+	// which may be the only remaining copies of their captures.
+	Orphans []*catalog.File
+}
+
+// Scrub verifies archived files against their cataloged hashes, least recently verified first
+// (never verified files before all others), recording when each matching file was verified
+// in the catalog. Run regularly with a budget, successive scrubs rotate through the archive
+// so that all files are verified once per period. Offloaded files and files without hashes are skipped.
+// The derivatives of damaged or missing files are logged, as are derivatives without originals.
+func (imp *Importer) Scrub(options *ScrubOptions) (*ScrubResult, error) {
+	if imp.options.Catalog == nil {
+		return nil, errors.New("scrubbing requires a catalog")
+	}
+	start := time.Now()
+	result := &ScrubResult{}
+	var due []*catalog.File
+	for _, file := range imp.options.Catalog.Files() {
+		if file.Derivative() && imp.options.Catalog.File(file.Original) == nil {
+			log.Warn().Str("path", file.Path).Str("original", file.Original).Msg("Scrub: original of derivative not cataloged")
+			result.Orphans = append(result.Orphans, file)
+		}
+		if file.Offloaded != "" || file.Hash == "" {
+			continue
+		}
+		if options.Period > 0 && !file.Verified.IsZero() && start.Sub(file.Verified) < options.Period {
+			continue
+		}
+		due = append(due, file)
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].Verified.Before(due[j].Verified)
+	})
+	for i, file := range due {
+		if options.Budget > 0 && time.Since(start) >= options.Budget {
+			result.Remaining = len(due) - i
+			break
+		}
+		path := imp.catalogPath(file)
+		stat, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			log.Error().Str("path", file.Path).Strs("derivatives", derivativePaths(imp.options.Catalog, file)).
+				Msg("Scrub: archived file missing")
+			result.Missing = append(result.Missing, file)
+			continue
+		} else if err != nil {
+			return result, fmt.Errorf("stat %s: %w", path, err)
+		}
+		algorithm, expected := splitHash(file.Hash)
+		sum, err := hashFile(path, algorithm)
+		if err != nil {
+			// Most likely unreadable sectors.
+			log.Error().Err(err).Str("path", file.Path).Strs("derivatives", derivativePaths(imp.options.Catalog, file)).
+				Msg("Scrub: archived file unreadable")
+			result.Damaged = append(result.Damaged, file)
+			continue
+		}
+		result.Bytes += stat.Size()
+		if sum != expected {
+			log.Error().Str("path", file.Path).Str("hash", file.Hash).Strs("derivatives", derivativePaths(imp.options.Catalog, file)).
+				Msg("Scrub: archived file differs from catalog")
+			result.Damaged = append(result.Damaged, file)
+			continue
+		}
+		updated := *file
+		updated.Verified = time.Now()
+		if err := imp.options.Catalog.AddFile(&updated); err != nil {
+			return result, fmt.Errorf("catalog %s: %w", file.Path, err)
+		}
+		result.Verified++
+	}
+	return result, nil
+}
+
+// derivativePaths returns the paths of the derivatives of the file (e.g. a low resolution copy
+// from which a damaged capture can at least be viewed).
+func derivativePaths(cat *catalog.Catalog, file *catalog.File) []string {
+	var paths []string
+	for _, derivative := range cat.Derivatives(file.Path) {
+		paths = append(paths, derivative.Path)
+	}
+	return paths
+}
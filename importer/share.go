@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/remote"
+)
+
+// ShareOptions specifies which archived files are shared and for how long.
+type ShareOptions struct {
+	// From and Until limit the capture times of shared files, zero for no limit. Until is exclusive.
+	From, Until time.Time
+	// Tag limits the shared files to those with the tag (or label), empty for all.
+	Tag string
+	// Hide excludes files with any of these tags (or labels), e.g. human and vehicle
+	// captures that guests shouldn't see, even when they are specified in Paths.
+	Hide []string
+	// Paths are archived files (e.g. a stitched event video) shared instead of
+	// selecting files by capture time and tag. Derivatives (e.g. low resolution copies)
+	// are only shared when specified here.
+	Paths []string
+	// Expires is how long the links to the shared files are valid, at most remote.MaxExpires.
+	Expires time.Duration
+}
+
+// SharedFile is an archived file uploaded for sharing.
+type SharedFile struct {
+	File *catalog.File
+	// Key of the uploaded copy.
+	Key string
+	// URL from which the uploaded copy can be read until the link expires.
+	URL string
+}
+
+// Video returns true if the shared file is a video.
+func (shared *SharedFile) Video() bool {
+	return strings.HasPrefix(mime.TypeByExtension(filepath.Ext(shared.Key)), "video/")
+}
+
+// Share uploads the selected archived files beneath a new unguessable prefix
+// (the share token) in remote storage and returns them, in capture order,
+// with links that expire after ShareOptions.Expires.
+// Clips stitched into an event video (see Stitch) are shared as the event video
+// and only the best frame of each burst is shared. Offloaded files are skipped.
+func (imp *Importer) Share(ctx context.Context, store *remote.S3, options *ShareOptions) (string, []*SharedFile, error) {
+	files, err := imp.shareFiles(options)
+	if err != nil {
+		return "", nil, err
+	} else if len(files) == 0 {
+		return "", nil, errors.New("no files to share")
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", nil, fmt.Errorf("make share token: %w", err)
+	}
+	prefix := store.Key("share-" + hex.EncodeToString(token) + "/")
+	shared := make([]*SharedFile, 0, len(files))
+	for _, file := range files {
+		item := &SharedFile{File: file, Key: prefix + file.Path}
+		if err := imp.shareFile(ctx, store, item); err != nil {
+			return prefix, shared, fmt.Errorf("share %s: %w", file.Path, err)
+		}
+		if item.URL, err = store.Presign(ctx, item.Key, options.Expires); err != nil {
+			return prefix, shared, err
+		}
+		shared = append(shared, item)
+		log.Info().Str("path", file.Path).Msg("Shared file")
+	}
+	return prefix, shared, nil
+}
+
+// shareFile uploads an archived file for sharing.
+func (imp *Importer) shareFile(ctx context.Context, store *remote.S3, shared *SharedFile) error {
+	file, err := os.Open(imp.catalogPath(shared.File))
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(shared.Key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return store.PutContent(ctx, shared.Key, file, stat.Size(), contentType)
+}
+
+// shareFiles returns the catalog entries of the archived files to share in capture order.
+// Event videos and other files not in the catalog are described by their capture time.
+func (imp *Importer) shareFiles(options *ShareOptions) ([]*catalog.File, error) {
+	var files []*catalog.File
+	if len(options.Paths) > 0 {
+		for _, path := range options.Paths {
+			path = plainPath(path)
+			rel, err := imp.relative(path)
+			if err != nil {
+				return nil, err
+			}
+			var file *catalog.File
+			if imp.options.Catalog != nil {
+				file = imp.options.Catalog.File(rel)
+			}
+			if file == nil {
+				when, err := CaptureTime(path)
+				if err != nil {
+					return nil, fmt.Errorf("capture time of %s: %w", path, err)
+				}
+				file = &catalog.File{Path: rel, Captured: when}
+				if root := imp.rootOf(path); root != imp.target {
+					file.Root = root
+				}
+			}
+			if hidden(file, options.Hide) {
+				log.Warn().Str("path", path).Msg("Not sharing hidden file")
+				continue
+			}
+			files = append(files, file)
+		}
+	} else if imp.options.Catalog == nil {
+		return nil, errors.New("sharing by capture time requires a catalog")
+	} else {
+		events := make(map[string]bool)
+		for _, file := range imp.options.Catalog.Originals() {
+			if !options.From.IsZero() && file.Captured.Before(options.From) ||
+				!options.Until.IsZero() && !file.Captured.Before(options.Until) ||
+				file.Offloaded != "" || file.Burst != "" && !file.Best ||
+				options.Tag != "" && !file.HasTag(options.Tag) || hidden(file, options.Hide) {
+				continue
+			}
+			if file.Event != "" {
+				if events[file.Event] {
+					continue
+				}
+				events[file.Event] = true
+				event := *file
+				event.Path = file.Event
+				file = &event
+			}
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return captureBefore(files[i], files[j])
+	})
+	return files, nil
+}
+
+// hidden returns true if the file has any of the hidden tags (or labels).
+func hidden(file *catalog.File, hide []string) bool {
+	for _, tag := range hide {
+		if file.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+)
+
+const (
+	// sharpnessMaxSize is the largest image dimension (in pixels) scored for sharpness,
+	// larger images are sampled to bound the time taken.
+	sharpnessMaxSize = 1024
+	// clipDark and clipBright bound the luminance of pixels that are not under or over exposed.
+	clipDark   = 5
+	clipBright = 250
+)
+
+// Sharpness returns a sharpness and exposure score for a JPEG file, higher is better.
+// The score is the variance of the Laplacian of the luminance (blurred frames have few edges)
+// reduced by the fraction of pixels that are clipped black or white.
+func Sharpness(path string) (float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	img, err := jpeg.Decode(file)
+	if err != nil {
+		return 0, fmt.Errorf("decode JPEG: %w", err)
+	}
+	return sharpness(img), nil
+}
+
+// sharpness returns the sharpness and exposure score of an image.
+func sharpness(img image.Image) float64 {
+	luma, width, height := sampleLuminance(img)
+	if width < 3 || height < 3 {
+		return 0
+	}
+	var clipped int
+	for _, value := range luma {
+		if value <= clipDark || value >= clipBright {
+			clipped++
+		}
+	}
+	var sum, sumSquares float64
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			i := y*width + x
+			laplacian := float64(luma[i-width]) + float64(luma[i+width]) + float64(luma[i-1]) + float64(luma[i+1]) -
+				4*float64(luma[i])
+			sum += laplacian
+			sumSquares += laplacian * laplacian
+		}
+	}
+	n := float64((width - 2) * (height - 2))
+	mean := sum / n
+	variance := sumSquares/n - mean*mean
+	return variance * (1 - float64(clipped)/float64(len(luma)))
+}
+
+// sampleLuminance returns the luminance of an image sampled so that neither dimension
+// exceeds sharpnessMaxSize, along with the sampled width and height.
+func sampleLuminance(img image.Image) ([]uint8, int, int) {
+	bounds := img.Bounds()
+	step := 1
+	for bounds.Dx()/step > sharpnessMaxSize || bounds.Dy()/step > sharpnessMaxSize {
+		step++
+	}
+	width, height := bounds.Dx()/step, bounds.Dy()/step
+	luma := make([]uint8, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			luma[y*width+x] = luminance(img, bounds.Min.X+x*step, bounds.Min.Y+y*step)
+		}
+	}
+	return luma, width, height
+}
+
+// luminance returns the luminance of a pixel, using the Y plane directly for decoded JPEG images.
+func luminance(img image.Image, x, y int) uint8 {
+	switch img := img.(type) {
+	case *image.YCbCr:
+		return img.Y[img.YOffset(x, y)]
+	case *image.Gray:
+		return img.Pix[img.PixOffset(x, y)]
+	}
+	return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+}
@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// Sidecar files hold data about a media file in a separate file with the same base name,
+// such as the SRT telemetry subtitles that DJI drones write next to each video
+// or XMP metadata (e.g. ratings) for photo management applications.
+// Sidecars are co-filed with their media file, getting the same dated name with their own extension.
+// They are not recorded in the catalog.
+
+// sidecarExts are the extensions of sidecar files.
+var sidecarExts = []string{".SRT", ".srt", ".XMP", ".xmp"}
+
+var (
+	// srtLatitude and srtLongitude match the positions written by newer DJI drones, e.g. "[latitude: 35.1234] [longitude: -80.1234]".
+	srtLatitude  = regexp.MustCompile(`\[lat(?:itude)?\s*:\s*(-?[0-9.]+)\]`)
+	srtLongitude = regexp.MustCompile(`\[lon(?:gitude)?\s*:\s*(-?[0-9.]+)\]`)
+	// srtGPS matches the positions written by older DJI drones, e.g. "GPS(-80.1234,35.1234,100)".
+	srtGPS = regexp.MustCompile(`GPS\s*\(\s*(-?[0-9.]+)\s*,\s*(-?[0-9.]+)`)
+)
+
+// sidecars returns the paths of the sidecar files of a media file.
+// The media file may have been renamed.
+func sidecars(path string) []string {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	var found []string
+	var stats []os.FileInfo
+	for _, ext := range sidecarExts {
+		if stat, err := os.Stat(base + ext); err == nil && !stat.IsDir() && !sameFileAs(stat, stats) {
+			found = append(found, base+ext)
+			stats = append(stats, stat)
+		}
+	}
+	return found
+}
+
+// sameFileAs returns whether the file is one of the other files,
+// e.g. both extension cases of a sidecar on a case-insensitive file system.
+func sameFileAs(stat os.FileInfo, others []os.FileInfo) bool {
+	for _, other := range others {
+		if os.SameFile(stat, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// sidecarPath returns the path for a sidecar file co-filed with a media file.
+func sidecarPath(sidecar, path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + filepath.Ext(sidecar)
+}
+
+// copySidecars copies the sidecar files (if any) of the source media file next to its target path.
+// The watchdog (if not nil) is notified of progress.
+func copySidecars(source, targetPath string, blockSize int, dog *watchdog) error {
+	for _, from := range sidecars(source) {
+		h, err := newHash("")
+		if err != nil {
+			return err
+		}
+		if _, err := copySourceToTarget(from, sidecarPath(from, targetPath), nil, dog.watchHash(h), blockSize, dog); err != nil {
+			return fmt.Errorf("copy sidecar %s: %w", from, err)
+		}
+	}
+	return nil
+}
+
+// moveSidecars renames the sidecar files (if any) of a media file that has been renamed.
+func moveSidecars(from, to string) error {
+	for _, sidecarFrom := range sidecars(from) {
+		sidecarTo := sidecarPath(sidecarFrom, to)
+		if _, err := os.Stat(existingPath(sidecarTo)); err == nil {
+			return fmt.Errorf("%w: sidecar %s exists", ErrConflict, sidecarTo)
+		} else if err := os.Rename(sidecarFrom, sidecarTo); err != nil {
+			return fmt.Errorf("rename sidecar: %w", err)
+		}
+	}
+	return nil
+}
+
+// SRTposition returns the first GPS position in a DJI SRT telemetry file, nil if there is none.
+func SRTposition(path string) (*catalog.Position, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var latitude, longitude string
+		if match := srtGPS.FindStringSubmatch(line); match != nil {
+			latitude, longitude = match[2], match[1]
+		} else if lat, lon := srtLatitude.FindStringSubmatch(line), srtLongitude.FindStringSubmatch(line); lat != nil && lon != nil {
+			latitude, longitude = lat[1], lon[1]
+		} else {
+			continue
+		}
+		position := &catalog.Position{}
+		if position.Latitude, err = strconv.ParseFloat(latitude, 64); err != nil {
+			continue
+		} else if position.Longitude, err = strconv.ParseFloat(longitude, 64); err != nil {
+			continue
+		} else if position.Latitude == 0 && position.Longitude == 0 {
+			// No GPS fix yet.
+			continue
+		}
+		return position, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return nil, nil
+}
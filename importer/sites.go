@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// SitesDir is the directory beneath the state directory holding copies
+// of the catalogs of other installations (sites), one subdirectory per site.
+const SitesDir = "sites"
+
+var siteName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// MergeSite copies the catalog of another installation (e.g. a cabin Raspberry Pi)
+// into the target's state directory so that its files can be included in activity
+// and statistics without their media being present. The source is either the target
+// root directory of the other installation or its catalog journal (e.g. copied with scp).
+// The catalogs are append-only journals so the copy replaces any earlier one.
+// Each site's files are kept in their own catalog so they can't conflict with local files.
+// Returns the number of files in the site catalog.
+func MergeSite(target, site, source string) (int, error) {
+	if !siteName.MatchString(site) {
+		return 0, fmt.Errorf("bad site name %q (letters, digits, - and _)", site)
+	}
+	if stat, err := os.Stat(source); err != nil {
+		return 0, fmt.Errorf("stat source: %w", err)
+	} else if stat.IsDir() {
+		source = filepath.Join(source, StateDir, catalog.FileName)
+	}
+	sites := filepath.Join(target, StateDir, SitesDir)
+	temp := filepath.Join(sites, "."+site+".tmp")
+	if err := os.RemoveAll(temp); err != nil {
+		return 0, fmt.Errorf("remove old temporary site: %w", err)
+	} else if err := os.MkdirAll(temp, 0766); err != nil {
+		return 0, fmt.Errorf("make site dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(temp) }()
+	if err := copyJournal(source, filepath.Join(temp, catalog.FileName)); err != nil {
+		return 0, err
+	}
+	cat, err := catalog.Open(temp)
+	if err != nil {
+		return 0, err
+	}
+	files := len(cat.Files())
+	if err := cat.Close(); err != nil {
+		return 0, fmt.Errorf("close site catalog: %w", err)
+	}
+	dir := filepath.Join(sites, site)
+	if err := os.RemoveAll(dir); err != nil {
+		return 0, fmt.Errorf("remove old site: %w", err)
+	} else if err := os.Rename(temp, dir); err != nil {
+		return 0, fmt.Errorf("rename site: %w", err)
+	}
+	return files, nil
+}
+
+func copyJournal(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("open site catalog: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("create site catalog: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("copy site catalog: %w", err)
+	}
+	return out.Close()
+}
+
+// Sites returns the names of the sites merged into the target (see MergeSite) in order.
+func Sites(target string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(target, StateDir, SitesDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read sites dir: %w", err)
+	}
+	var sites []string
+	for _, entry := range entries {
+		if entry.IsDir() && siteName.MatchString(entry.Name()) {
+			sites = append(sites, entry.Name())
+		}
+	}
+	sort.Strings(sites)
+	return sites, nil
+}
+
+// OpenSite opens the catalog of a site merged into the target.
+func OpenSite(target, site string) (*catalog.Catalog, error) {
+	return catalog.Open(filepath.Join(target, StateDir, SitesDir, site))
+}
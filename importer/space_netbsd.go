@@ -0,0 +1,12 @@
+package importer
+
+import "golang.org/x/sys/unix"
+
+// freeSpace returns the number of bytes available to the user on the file system containing the path.
+func freeSpace(path string) (uint64, error) {
+	var stat unix.Statvfs_t
+	if err := unix.Statvfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Frsize), nil
+}
@@ -0,0 +1,12 @@
+package importer
+
+import "syscall"
+
+// freeSpace returns the number of bytes available to the user on the file system containing the path.
+func freeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.F_bavail) * uint64(stat.F_bsize), nil
+}
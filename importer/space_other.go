@@ -0,0 +1,14 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+
+package importer
+
+import (
+	"errors"
+	"runtime"
+)
+
+// freeSpace returns the number of bytes available to the user on the file system containing the path,
+// which isn't known on this system, so the fill-first pool policy isn't available.
+func freeSpace(path string) (uint64, error) {
+	return 0, errors.New("free space unknown on " + runtime.GOOS)
+}
@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd || linux
+
+package importer
+
+import "syscall"
+
+// freeSpace returns the number of bytes available to the user on the file system containing the path.
+func freeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	// The field types differ between systems (e.g. Bavail is signed on FreeBSD).
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package importer
+
+import "golang.org/x/sys/windows"
+
+// freeSpace returns the number of bytes available to the user on the file system containing the path.
+func freeSpace(path string) (uint64, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var available uint64
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &available, nil, nil); err != nil {
+		return 0, err
+	}
+	return available, nil
+}
@@ -0,0 +1,47 @@
+package importer
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// sparseBlockSize is the size of the zero regions skipped by sparseWriter,
+// the usual file system block size.
+const sparseBlockSize = 4096
+
+var zeroBlock [sparseBlockSize]byte
+
+// sparseWriter writes to a file, seeking over blocks of zeros instead of writing them
+// so that the file system can leave holes (e.g. in recovered files) rather than allocate them.
+// Sparse source files need no special handling since their holes read as zeros.
+// Close must be called to set the final file size in case the file ends with zeros.
+type sparseWriter struct {
+	file *os.File
+	size int64
+}
+
+func (w *sparseWriter) Write(p []byte) (int, error) {
+	for written := 0; written < len(p); {
+		n := len(p) - written
+		if n > sparseBlockSize {
+			n = sparseBlockSize
+		}
+		block := p[written : written+n]
+		if n == sparseBlockSize && bytes.Equal(block, zeroBlock[:]) {
+			if _, err := w.file.Seek(int64(n), io.SeekCurrent); err != nil {
+				return written, err
+			}
+		} else if _, err := w.file.Write(block); err != nil {
+			return written, err
+		}
+		written += n
+		w.size += int64(n)
+	}
+	return len(p), nil
+}
+
+// Close sets the file size, which is only needed if the last block was skipped.
+func (w *sparseWriter) Close() error {
+	return w.file.Truncate(w.size)
+}
@@ -0,0 +1,94 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// importStaged imports files which are not available by path (e.g. in an archive or on a phone),
+// continuing after errors. Each file is staged by the stage function (with its sidecars)
+// beneath the staging directory, imported, and removed before the next.
+// The stage function returns the path of the staged file.
+// The names are those by which the files are recorded in the catalog and journal (see sourceName),
+// and are the sources of the results, which are in the same order.
+// With Options.Strict the remaining files are aborted once one fails.
+func (imp *Importer) importStaged(names []string, stage func(i int, staging string) (string, error)) ([]Result, error) {
+	staging, err := os.MkdirTemp("", "gardepro-stage-")
+	if err != nil {
+		return nil, fmt.Errorf("make staging dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+	imp.forgetCards()
+
+	results := make([]Result, 0, len(names))
+	var aborted bool
+	for i, name := range names {
+		result := Result{Source: name}
+		if aborted {
+			result.Err = &Error{Source: name, Err: ErrAborted}
+			imp.decideError(name, "", result.Err)
+		} else if source, err := stage(i, staging); err != nil {
+			result.Err = &Error{Source: name, Err: err}
+			log.Error().Err(result.Err).Str("source", name).Msg("Stage file")
+			imp.decideError(name, "", result.Err)
+		} else {
+			imp.aliasSource(source, name)
+			if imp.Excluded(source) {
+				log.Info().Str("source", name).Msg("Skipping file excluded by import filters")
+				imp.decide(source, ActionExcluded, "", time.Time{}, "")
+				result.Excluded = true
+			} else if result.Target, result.Err = imp.importFile(source, imp.sourceSubDir(staging, source)); result.Err != nil {
+				var importErr *Error
+				if errors.As(result.Err, &importErr) {
+					importErr.Source = name
+				}
+				log.Error().Err(result.Err).Str("source", name).Msg("Import file")
+			}
+			imp.aliasSource(source, "")
+		}
+		if err := os.RemoveAll(staging); err != nil {
+			return results, fmt.Errorf("clear staging dir: %w", err)
+		} else if err := os.Mkdir(staging, 0700); err != nil {
+			return results, fmt.Errorf("make staging dir: %w", err)
+		}
+		if result.Err != nil && imp.options.Strict && !aborted {
+			log.Warn().Str("source", name).Msg("Strict import stopped after failure")
+			aborted = true
+		}
+		results = append(results, result)
+		if imp.options.Progress != nil {
+			imp.options.Progress(i+1, len(names))
+		}
+	}
+	return results, nil
+}
+
+// aliasSource records the name under which the source file is recorded in the catalog and journal
+// (see sourceName), or removes it if the name is empty.
+func (imp *Importer) aliasSource(source, name string) {
+	imp.aliasesMutex.Lock()
+	defer imp.aliasesMutex.Unlock()
+	if name == "" {
+		delete(imp.aliases, source)
+		return
+	}
+	if imp.aliases == nil {
+		imp.aliases = make(map[string]string)
+	}
+	imp.aliases[source] = name
+}
+
+// sourceName returns the name under which the source file is recorded in the catalog and journal,
+// which differs from its path for staged files (see importStaged).
+func (imp *Importer) sourceName(source string) string {
+	imp.aliasesMutex.Lock()
+	defer imp.aliasesMutex.Unlock()
+	if name, found := imp.aliases[source]; found {
+		return name
+	}
+	return source
+}
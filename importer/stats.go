@@ -0,0 +1,115 @@
+package importer
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// CameraStats summarizes the files captured by a camera.
+type CameraStats struct {
+	// Camera is the source directory of the camera's files (i.e. its card).
+	Camera string
+	Files  int
+	First  time.Time
+	Last   time.Time
+	// Battery is the most recent battery level reported by the camera, nil if none.
+	Battery *catalog.Battery
+	// BatteryTrend is the change in battery level (percent or volts, as reported) per day
+	// over the trend period, zero if unknown.
+	BatteryTrend float64
+	// BatteryEmpty is when the battery percentage is projected to reach zero, zero if unknown.
+	BatteryEmpty time.Time
+}
+
+// cameraOf returns the identity of the camera that captured a file.
+// Cameras are identified by the source directory of their files (i.e. their card)
+// unless the file was recognized as another camera's (see Fingerprint).
+func cameraOf(file *catalog.File) string {
+	if file.Camera != "" {
+		return file.Camera
+	}
+	return filepath.Dir(file.Source)
+}
+
+// Stats returns statistics for each camera in the catalog, in camera order.
+// Battery trends are computed over the battery levels reported in the trend period
+// before each camera's most recent report.
+func Stats(cat *catalog.Catalog, trend time.Duration) []*CameraStats {
+	cameras := make(map[string][]*catalog.File)
+	for _, file := range cat.Originals() {
+		camera := cameraOf(file)
+		cameras[camera] = append(cameras[camera], file)
+	}
+	var stats []*CameraStats
+	for camera, files := range cameras {
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Captured.Before(files[j].Captured)
+		})
+		stat := &CameraStats{
+			Camera: camera,
+			Files:  len(files),
+			First:  files[0].Captured,
+			Last:   files[len(files)-1].Captured,
+		}
+		var readings []*catalog.File
+		for _, file := range files {
+			if file.Battery != nil {
+				readings = append(readings, file)
+			}
+		}
+		if len(readings) > 0 {
+			latest := readings[len(readings)-1]
+			stat.Battery = latest.Battery
+			var recent []*catalog.File
+			for _, reading := range readings {
+				if latest.Captured.Sub(reading.Captured) <= trend {
+					recent = append(recent, reading)
+				}
+			}
+			stat.BatteryTrend = batteryTrend(recent)
+			if latest.Battery.Percent > 0 && stat.BatteryTrend < 0 {
+				days := latest.Battery.Percent / -stat.BatteryTrend
+				stat.BatteryEmpty = latest.Captured.Add(time.Duration(days * float64(24*time.Hour)))
+			}
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Camera < stats[j].Camera
+	})
+	return stats
+}
+
+// batteryTrend returns the least squares slope of the battery levels per day, zero if unknown.
+// Percentages are used if the latest reading is a percentage, otherwise volts.
+func batteryTrend(readings []*catalog.File) float64 {
+	if len(readings) < 2 {
+		return 0
+	}
+	percent := readings[len(readings)-1].Battery.Percent > 0
+	start := readings[0].Captured
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, reading := range readings {
+		y := reading.Battery.Volts
+		if percent {
+			y = reading.Battery.Percent
+		}
+		if y == 0 {
+			continue
+		}
+		x := reading.Captured.Sub(start).Hours() / 24
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
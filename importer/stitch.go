@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// DefaultStitchGap is the longest time between the end of a clip and the start of the next clip
+// of the same event unless specified.
+const DefaultStitchGap = 10 * time.Second
+
+// eventSuffix is added to the base name of the first clip of an event to name its event video.
+const eventSuffix = "-EVENT"
+
+// StitchOptions specifies how consecutive clips are stitched into event videos.
+type StitchOptions struct {
+	// Gap is the longest time between the end of a clip and the start of the next, DefaultStitchGap if zero.
+	Gap time.Duration
+	// FFmpeg is the path of the ffmpeg executable, "ffmpeg" (found in the PATH) if empty.
+	FFmpeg string
+}
+
+// Stitch finds runs of MP4 and MOV clips in the catalog from the same source directory
+// that start within the gap of the end of the previous clip (e.g. a single animal
+// triggering several clips) and concatenates each run into an event video next to its first clip,
+// without re-encoding. The clips are kept and the event video is recorded in their catalog entries.
+// Runs already stitched are skipped. Returns the number of event videos made.
+func (imp *Importer) Stitch(options *StitchOptions) (int, error) {
+	if imp.options.Catalog == nil {
+		return 0, errors.New("stitching requires a catalog")
+	}
+	gap := options.Gap
+	if gap <= 0 {
+		gap = DefaultStitchGap
+	}
+	ffmpeg := options.FFmpeg
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	var count int
+	for _, clips := range imp.events(gap) {
+		if allEvent(clips, eventPath(clips[0].Path)) {
+			if _, err := os.Stat(eventPath(imp.catalogPath(clips[0]))); err == nil {
+				continue
+			}
+		}
+		if err := imp.stitch(ffmpeg, clips); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// events groups the clips in the catalog by source directory (i.e. camera card)
+// and returns the runs of two or more clips that start within the gap of the end of the previous clip.
+func (imp *Importer) events(gap time.Duration) [][]*catalog.File {
+	cameras := make(map[string][]*catalog.File)
+	for _, file := range imp.options.Catalog.Originals() {
+		if ext := strings.ToLower(filepath.Ext(file.Path)); (ext == ".mp4" || ext == ".mov") && file.Offloaded == "" {
+			camera := cameraOf(file)
+			cameras[camera] = append(cameras[camera], file)
+		}
+	}
+	var result [][]*catalog.File
+	for _, clips := range cameras {
+		sort.Slice(clips, func(i, j int) bool {
+			return captureBefore(clips[i], clips[j])
+		})
+		start := 0
+		var end time.Time
+		for i := 0; i <= len(clips); i++ {
+			if i == len(clips) || i > start && clips[i].Captured.Sub(end) > gap ||
+				i > start && !strings.EqualFold(filepath.Ext(clips[i].Path), filepath.Ext(clips[start].Path)) {
+				if i-start > 1 {
+					result = append(result, clips[start:i])
+				}
+				start = i
+			}
+			if i < len(clips) {
+				duration, err := MP4duration(imp.catalogPath(clips[i]))
+				if err != nil {
+					log.Warn().Err(err).Str("path", clips[i].Path).Msg("Clip duration")
+				}
+				end = clips[i].Captured.Add(duration)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i][0].Captured.Before(result[j][0].Captured)
+	})
+	return result
+}
+
+// allEvent returns whether all the clips are recorded as part of the event.
+func allEvent(clips []*catalog.File, event string) bool {
+	for _, clip := range clips {
+		if clip.Event != event {
+			return false
+		}
+	}
+	return true
+}
+
+// eventPath returns the path of the event video starting with the clip at the path.
+func eventPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + eventSuffix + ext
+}
+
+// stitch concatenates the clips into an event video with ffmpeg and records it in the catalog.
+// The event video is in the same root as the first clip.
+func (imp *Importer) stitch(ffmpeg string, clips []*catalog.File) error {
+	event := eventPath(imp.catalogPath(clips[0]))
+	list, err := os.CreateTemp("", "gardepro-stitch-*.txt")
+	if err != nil {
+		return fmt.Errorf("create clip list: %w", err)
+	}
+	defer func() { _ = os.Remove(list.Name()) }()
+	for _, clip := range clips {
+		path := strings.ReplaceAll(imp.catalogPath(clip), "'", `'\''`)
+		if _, err := fmt.Fprintf(list, "file '%s'\n", filepath.ToSlash(path)); err != nil {
+			_ = list.Close()
+			return fmt.Errorf("write clip list: %w", err)
+		}
+	}
+	if err := list.Close(); err != nil {
+		return fmt.Errorf("close clip list: %w", err)
+	}
+
+	temp := event + ".tmp"
+	if err := runFFmpeg(ffmpeg, "", "-f", "concat", "-safe", "0", "-i", list.Name(),
+		"-c", "copy", "-map_metadata", "0", "-f", strings.TrimPrefix(strings.ToLower(filepath.Ext(event)), "."),
+		temp); err != nil {
+		_ = os.Remove(temp)
+		return fmt.Errorf("stitch %s: %w", event, err)
+	} else if err := os.Rename(temp, event); err != nil {
+		_ = os.Remove(temp)
+		return fmt.Errorf("rename event video: %w", err)
+	}
+	for _, clip := range clips {
+		updated := *clip
+		updated.Event = eventPath(clips[0].Path)
+		if err := imp.options.Catalog.AddFile(&updated); err != nil {
+			return fmt.Errorf("catalog clip: %w", err)
+		}
+	}
+	log.Info().Str("event", event).Int("clips", len(clips)).Msg("Stitched event")
+	return nil
+}
+
+// runFFmpeg runs ffmpeg in the directory (the current directory if empty),
+// returning its error output with any error.
+func runFFmpeg(ffmpeg, dir string, args ...string) error {
+	cmd := exec.Command(ffmpeg, append([]string{"-hide_banner", "-loglevel", "error", "-y"}, args...)...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dsoprea/go-exif/v3"
+	"github.com/udhos/equalfile"
+)
+
+// Media files are streamed through fixed size buffers rather than read into memory,
+// since videos can be several gigabytes and the application may run on a small computer.
+// Only JPEG files whose EXIF data is rewritten are read into memory, up to maxInMemorySize.
+// Memory use is then bounded by roughly -jobs times (-blocksize + maxInMemorySize + decoded image).
+const (
+	// streamBufferSize is the size of the buffers used to compare files.
+	streamBufferSize = 64 << 10
+	// exifSearchLimit is how far into a file the EXIF data is searched for.
+	// The EXIF APP1 segment is limited to 64 KiB and is near the start of a JPEG file.
+	exifSearchLimit = 1 << 20
+	// maxInMemorySize is the largest file that is read into memory.
+	maxInMemorySize = 64 << 20
+	// maxCompareSize is the largest file that is compared.
+	maxCompareSize = 1 << 40
+)
+
+// readFileBounded reads an entire file into memory if it is no larger than maxInMemorySize.
+func readFileBounded(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	if stat, err := file.Stat(); err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	} else if stat.Size() > maxInMemorySize {
+		return nil, fmt.Errorf("file size %d larger than %d bytes", stat.Size(), maxInMemorySize)
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxInMemorySize))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return data, nil
+}
+
+// exifExtract returns the raw EXIF data near the start of a file.
+// The EXIF APP1 segment of a JPEG file is read directly (see jpegReadEXIF).
+// Other files, and JPEG files whose segments can't be followed, are searched for EXIF data
+// within exifSearchLimit, since the EXIF library would otherwise read the rest of the file into memory.
+func exifExtract(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	if isJPEG(path) {
+		if rawExif, err := jpegReadEXIF(file); err == nil {
+			return rawExif, nil
+		} else if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("seek file: %w", err)
+		}
+	}
+	return exif.SearchAndExtractExifWithReader(io.LimitReader(file, exifSearchLimit))
+}
+
+// newCompare returns a file comparison with its own bounded buffer,
+// so that concurrent imports don't share one.
+func newCompare() *equalfile.Cmp {
+	return equalfile.New(make([]byte, streamBufferSize), equalfile.Options{MaxSize: maxCompareSize})
+}
+
+// compareFiles returns true if the files have the same contents.
+// The watchdog (if not nil) is notified of progress as the files are read.
+func compareFiles(path1, path2 string, dog *watchdog) (bool, error) {
+	file1, err := os.Open(path1)
+	if err != nil {
+		return false, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file1.Close() }()
+	file2, err := os.Open(path2)
+	if err != nil {
+		return false, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file2.Close() }()
+	// The same shortcuts as equalfile.Cmp.CompareFile.
+	if stat1, err := file1.Stat(); err != nil {
+		return false, fmt.Errorf("stat file: %w", err)
+	} else if stat2, err := file2.Stat(); err != nil {
+		return false, fmt.Errorf("stat file: %w", err)
+	} else if os.SameFile(stat1, stat2) {
+		return true, nil
+	} else if stat1.Mode().IsRegular() && stat2.Mode().IsRegular() && stat1.Size() != stat2.Size() {
+		return false, nil
+	}
+	return newCompare().CompareReader(dog.watchReader(file1), dog.watchReader(file2))
+}
+
+// compareFileData returns true if the file contents are the same as the data.
+// The watchdog (if not nil) is notified of progress as the file is read.
+func compareFileData(path string, data []byte, dog *watchdog) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	return newCompare().CompareReader(dog.watchReader(file), bytes.NewReader(data))
+}
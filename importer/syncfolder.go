@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Sync folder presets (see SyncFolder), for phones uploading camera files
+// (e.g. with the GardePro app) to a cloud drive synced to this computer.
+const (
+	// SyncGoogleDrive is the GardePro folder in My Drive of Google Drive for desktop
+	// (or the older Backup and Sync).
+	SyncGoogleDrive = "google-drive"
+	// SyncICloud is the GardePro folder in iCloud Drive.
+	SyncICloud = "icloud"
+)
+
+// syncDirs are directories in which sync clients keep partial transfers,
+// which contain files that aren't complete media files.
+var syncDirs = map[string]bool{
+	".tmp.drivedownload": true, // Google Drive downloads in progress
+	".tmp.driveupload":   true, // Google Drive uploads in progress
+}
+
+// SyncFolder returns the GardePro folder of the sync client of the preset on this computer.
+func SyncFolder(preset string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("home dir: %w", err)
+	}
+	var candidates []string
+	switch preset {
+	case SyncGoogleDrive:
+		// Google Drive for desktop has a My Drive per account (on macOS beneath CloudStorage,
+		// on Windows on drive G:), Backup and Sync had a single folder in the home directory.
+		candidates, _ = filepath.Glob(filepath.Join(home, "Library", "CloudStorage", "GoogleDrive-*", "My Drive", "GardePro"))
+		if runtime.GOOS == "windows" {
+			candidates = append(candidates, `G:\My Drive\GardePro`)
+		}
+		candidates = append(candidates, filepath.Join(home, "My Drive", "GardePro"), filepath.Join(home, "Google Drive", "GardePro"))
+	case SyncICloud:
+		candidates = []string{
+			filepath.Join(home, "Library", "Mobile Documents", "com~apple~CloudDocs", "GardePro"),
+			filepath.Join(home, "iCloudDrive", "GardePro"),
+		}
+	default:
+		return "", fmt.Errorf("unknown sync preset %s", preset)
+	}
+	for _, candidate := range candidates {
+		if stat, err := os.Stat(candidate); err == nil && stat.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no %s GardePro folder (tried %s)", preset, strings.Join(candidates, ", "))
+}
+
+// SyncFiles returns the media files beneath the sync folder which have been downloaded
+// and those which are still placeholders for files not yet downloaded (by the paths they will have).
+// Partial transfers are skipped. Downloaded files may still be incomplete (or, with Google Drive streaming,
+// be fetched on first read), so callers should wait for them to stop changing before importing them.
+func SyncFiles(folder string) ([]string, []string, error) {
+	var ready, pending []string
+	err := filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if entry.IsDir() {
+			if path != folder && (skipDir(entry.Name()) || syncDirs[entry.Name()]) {
+				return filepath.SkipDir
+			}
+		} else if media := iCloudPlaceholder(entry.Name()); media != "" {
+			if Supported(media) {
+				pending = append(pending, filepath.Join(filepath.Dir(path), media))
+			}
+		} else if Supported(path) {
+			ready = append(ready, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("walk sync folder: %w", err)
+	}
+	return ready, pending, nil
+}
+
+// iCloudPlaceholder returns the name of the file for which an iCloud Drive placeholder
+// with the name stands (e.g. IMG_0001.JPG for .IMG_0001.JPG.icloud), empty if it isn't one.
+func iCloudPlaceholder(name string) string {
+	if !strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".icloud") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(name, "."), ".icloud")
+}
+
+// RequestDownload asks the sync client to download a file not yet downloaded (see SyncFiles),
+// which is only possible for iCloud Drive on macOS. Other clients download files by themselves.
+func RequestDownload(path string) error {
+	if runtime.GOOS != "darwin" {
+		return nil
+	}
+	if output, err := exec.Command("brctl", "download", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("brctl: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
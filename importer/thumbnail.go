@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+)
+
+// thumbnailQuality is the JPEG quality of thumbnails.
+const thumbnailQuality = 75
+
+// Thumbnail returns a JPEG thumbnail of a JPEG file scaled to the specified width.
+// Each thumbnail pixel is the average of the image pixels it covers.
+// The EXIF orientation is not applied.
+func Thumbnail(path string, width int) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	img, err := jpeg.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("decode JPEG: %w", err)
+	}
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, scale(img, width), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, fmt.Errorf("encode JPEG: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// scale returns the image scaled down to the specified width, keeping its aspect ratio.
+// Images no wider than the width are returned unchanged.
+func scale(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if width <= 0 || bounds.Dx() <= width {
+		return img
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	return resize(img, width, height)
+}
+
+// resize returns the image scaled down to the specified width and height,
+// which are no larger than those of the image.
+func resize(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := bounds.Min.Y+y*bounds.Dy()/height, bounds.Min.Y+(y+1)*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			x0, x1 := bounds.Min.X+x*bounds.Dx()/width, bounds.Min.X+(x+1)*bounds.Dx()/width
+			var r, g, b, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, _ := img.At(sx, sy).RGBA()
+					r, g, b, n = r+pr>>8, g+pg>>8, b+pb>>8, n+1
+				}
+			}
+			i := scaled.PixOffset(x, y)
+			scaled.Pix[i], scaled.Pix[i+1], scaled.Pix[i+2], scaled.Pix[i+3] =
+				uint8(r/n), uint8(g/n), uint8(b/n), 0xff
+		}
+	}
+	return scaled
+}
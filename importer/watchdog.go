@@ -0,0 +1,138 @@
+package importer
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/trace"
+)
+
+// Watchdog states: the import is running, has claimed its outcome, or has been given up on.
+const (
+	watchRunning int32 = iota
+	watchClaimed
+	watchAbandoned
+)
+
+// watchdog tracks the progress of a file import.
+// A nil watchdog ignores progress.
+type watchdog struct {
+	last  int64
+	state int32
+}
+
+// touch records progress.
+func (w *watchdog) touch() {
+	if w != nil {
+		atomic.StoreInt64(&w.last, time.Now().UnixNano())
+	}
+}
+
+// idle returns the time since the last progress.
+func (w *watchdog) idle() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&w.last)))
+}
+
+// watchHash returns a hash that records progress as data is written to it.
+// Since files are hashed as they are copied this tracks the progress of the copy.
+func (w *watchdog) watchHash(h hash.Hash) hash.Hash {
+	if w == nil {
+		return h
+	}
+	return &watchedHash{Hash: h, dog: w}
+}
+
+// watchReader returns a reader that records progress as data is read from it.
+// This tracks the progress of reads that aren't hashed as they are copied,
+// such as comparing with an existing target file or hashing it for the catalog.
+func (w *watchdog) watchReader(r io.Reader) io.Reader {
+	if w == nil {
+		return r
+	}
+	return &watchedReader{Reader: r, dog: w}
+}
+
+type watchedReader struct {
+	io.Reader
+	dog *watchdog
+}
+
+func (r *watchedReader) Read(p []byte) (int, error) {
+	r.dog.touch()
+	return r.Reader.Read(p)
+}
+
+// claim returns true if the import may record its outcome in the catalog, journal, or quarantine,
+// after which the watchdog no longer gives up on it.
+// Returns false if the watchdog has already given up on the import.
+func (w *watchdog) claim() bool {
+	if w == nil || atomic.CompareAndSwapInt32(&w.state, watchRunning, watchClaimed) {
+		return true
+	}
+	return atomic.LoadInt32(&w.state) == watchClaimed
+}
+
+// abandon gives up on the import, returning false if it has already claimed its outcome.
+func (w *watchdog) abandon() bool {
+	return atomic.CompareAndSwapInt32(&w.state, watchRunning, watchAbandoned)
+}
+
+// abandoned returns the error for an import that the watchdog gave up on.
+// The error is only returned by the abandoned import, which nothing waits for any longer.
+func abandoned(source string) error {
+	return &Error{Source: source, Err: fmt.Errorf("%w: abandoned", ErrTimeout)}
+}
+
+type watchedHash struct {
+	hash.Hash
+	dog *watchdog
+}
+
+func (h *watchedHash) Write(p []byte) (int, error) {
+	h.dog.touch()
+	return h.Hash.Write(p)
+}
+
+// importFileWatched imports the source file, giving up if the import makes no progress
+// for Options.Timeout. Blocked system calls can't be interrupted so an import that is
+// given up on may still complete (or stay blocked) in the background,
+// but once given up on it records nothing in the catalog, journal, or quarantine (see watchdog.claim).
+// An import that has claimed its outcome is waited for as it only has that left to record.
+func (imp *Importer) importFileWatched(source, subDir string, span *trace.Span) (string, error) {
+	if imp.options.Timeout <= 0 {
+		return imp.importFileIsolated(source, subDir, nil, span)
+	}
+	type result struct {
+		targetPath string
+		err        error
+	}
+	dog := &watchdog{}
+	dog.touch()
+	done := make(chan result, 1)
+	go func() {
+		targetPath, err := imp.importFileIsolated(source, subDir, dog, span)
+		done <- result{targetPath: targetPath, err: err}
+	}()
+	interval := imp.options.Timeout / 10
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case r := <-done:
+			return r.targetPath, r.err
+		case <-ticker.C:
+			if idle := dog.idle(); idle > imp.options.Timeout && dog.abandon() {
+				log.Error().Str("source", source).Dur("idle", idle).Msg("Import hung, skipping file")
+				return "", &Error{Source: source, Err: fmt.Errorf("%w: no progress for %s", ErrTimeout, idle)}
+			}
+		}
+	}
+}
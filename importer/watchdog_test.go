@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/madkins23/gardepro/catalog"
+	"github.com/madkins23/gardepro/fixture"
+)
+
+func TestWatchdogClaim(t *testing.T) {
+	var none *watchdog
+	if !none.claim() {
+		t.Error("nil watchdog not claimed")
+	}
+	claimed := &watchdog{}
+	if !claimed.claim() || !claimed.claim() {
+		t.Error("running import not claimed")
+	} else if claimed.abandon() {
+		t.Error("claimed import abandoned")
+	}
+	abandoned := &watchdog{}
+	if !abandoned.abandon() {
+		t.Error("running import not abandoned")
+	} else if abandoned.claim() {
+		t.Error("abandoned import claimed")
+	}
+}
+
+func TestWatchdogProgress(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 3*streamBufferSize)
+	source := writeFile(t, dir, "source.jpg", data)
+	target := writeFile(t, dir, "target.jpg", data)
+	tests := []struct {
+		name string
+		read func(dog *watchdog) error
+	}{
+		{"compare files", func(dog *watchdog) error {
+			_, err := compareFiles(source, target, dog)
+			return err
+		}},
+		{"compare data", func(dog *watchdog) error {
+			_, err := compareFileData(target, data, dog)
+			return err
+		}},
+		{"hash", func(dog *watchdog) error {
+			_, err := hashFileWatched(target, HashSHA256, dog)
+			return err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Never touched, so idle since the epoch.
+			dog := &watchdog{}
+			if err := test.read(dog); err != nil {
+				t.Fatalf("read: %s", err)
+			} else if idle := dog.idle(); idle > time.Minute {
+				t.Errorf("no progress, idle %s", idle)
+			}
+		})
+	}
+}
+
+// TestImportAbandoned checks that an import the watchdog has given up on
+// records nothing in the catalog, journal, or quarantine.
+func TestImportAbandoned(t *testing.T) {
+	spec := fixture.JPEG{Captured: testTime}
+	data, err := spec.Bytes()
+	if err != nil {
+		t.Fatalf("synthesize: %s", err)
+	}
+	tests := []struct {
+		name    string
+		options Options
+		file    string
+		data    []byte
+		archive bool
+		catalog bool
+	}{
+		{name: "copied", file: "copied.jpg", data: data},
+		{name: "identical", file: "identical.jpg", data: data, archive: true},
+		{name: "presumed", options: Options{QuickSkip: true}, file: "presumed.jpg", data: data, archive: true, catalog: true},
+		{name: "quarantined", options: Options{Lenient: true}, file: "damaged.jpg", data: []byte("not a JPEG")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			journal := &Journal{}
+			test.options.Journal = journal
+			imp := testImporter(t, test.options)
+			source := writeFile(t, t.TempDir(), test.file, test.data)
+			if test.archive {
+				root, err := imp.chooseRoot(source, testTime, "")
+				if err != nil {
+					t.Fatalf("choose root: %s", err)
+				}
+				target := imp.targetPath(root, testTime, "", test.file)
+				writeFile(t, filepath.Dir(target), filepath.Base(target), test.data)
+				if test.catalog {
+					rel, err := imp.relative(target)
+					if err != nil {
+						t.Fatalf("relative path: %s", err)
+					} else if err := imp.options.Catalog.AddFile(&catalog.File{Path: rel, Source: imp.sourceName(source)}); err != nil {
+						t.Fatalf("catalog file: %s", err)
+					}
+				}
+			}
+			cataloged := len(imp.options.Catalog.Files())
+			dog := &watchdog{}
+			dog.abandon()
+			if _, err := imp.importFileOnce(source, "", dog, nil); !errors.Is(err, ErrTimeout) {
+				t.Errorf("error %v, want %v", err, ErrTimeout)
+			}
+			if files := imp.options.Catalog.Files(); len(files) > cataloged {
+				t.Errorf("cataloged %d files", len(files)-cataloged)
+			}
+			if len(journal.Decisions) > 0 {
+				t.Errorf("journaled %s", journal.Decisions[0].Action)
+			}
+			if _, err := os.Stat(filepath.Join(imp.target, QuarantineDir)); err == nil {
+				t.Error("quarantined")
+			}
+		})
+	}
+}
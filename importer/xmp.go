@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// XMP sidecars hold metadata for photo management applications such as Lightroom and digiKam,
+// which read (and write) ratings and keywords from BaseName.xmp next to each file.
+// Sidecars written by this application are marked with xmp:CreatorTool and are rewritten
+// as required, only the rating of other sidecars is changed.
+
+// xmpCreatorTool marks XMP sidecars written by this application.
+const xmpCreatorTool = "gardepro"
+
+// xmpTemplate is an XMP sidecar, the arguments are the rdf:Description attributes and elements.
+const xmpTemplate = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:lr="http://ns.adobe.com/lightroom/1.0/"
+    xmlns:digiKam="http://www.digikam.org/ns/1.0/"
+    xmp:CreatorTool="` + xmpCreatorTool + `"%s>%s
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>
+`
+
+var (
+	// xmpRating matches an xmp:Rating attribute or element.
+	xmpRating = regexp.MustCompile(`xmp:Rating="[^"]*"|<xmp:Rating>[^<]*</xmp:Rating>`)
+	// xmpDescription matches the start of the first rdf:Description element.
+	xmpDescription = regexp.MustCompile(`<rdf:Description\b`)
+	// xmpOurs matches the creator tool of sidecars written by this application.
+	xmpOurs = regexp.MustCompile(`xmp:CreatorTool="` + xmpCreatorTool + `"`)
+)
+
+// xmpPath returns the path of the XMP sidecar of a media file.
+// An existing sidecar is used whatever the case of its extension.
+func xmpPath(path string) string {
+	for _, sidecar := range sidecars(path) {
+		if strings.EqualFold(filepath.Ext(sidecar), ".xmp") {
+			return sidecar
+		}
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".xmp"
+}
+
+// XMPwrite writes the capture time, camera, tags, labels, and rating of a media file
+// in the catalog to its XMP sidecar. Tags and labels are written as keywords (dc:subject)
+// and as hierarchical keywords for Lightroom (lr:hierarchicalSubject) and digiKam (digiKam:TagsList)
+// beneath Camera, Tags, and Labels. Returns false if the sidecar was written by another application,
+// in which case only the rating is written (if any).
+func XMPwrite(path string, file *catalog.File) (bool, error) {
+	sidecar := xmpPath(path)
+	if data, err := os.ReadFile(sidecar); err == nil && !xmpOurs.Match(data) {
+		if file.Rating > 0 {
+			return false, XMPsetRating(path, file.Rating)
+		}
+		return false, nil
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("read XMP sidecar: %w", err)
+	}
+
+	attributes := "\n    xmp:CreateDate=\"" + file.Captured.Format("2006-01-02T15:04:05") + "\""
+	if file.Rating > 0 {
+		attributes += "\n    xmp:Rating=\"" + strconv.Itoa(file.Rating) + "\""
+	}
+	keywords := file.AllTags()
+	hierarchy := []string{"Camera|" + filepath.Base(cameraOf(file))}
+	for _, tag := range file.Tags {
+		hierarchy = append(hierarchy, "Tags|"+tag)
+	}
+	for _, label := range file.Labels {
+		hierarchy = append(hierarchy, "Labels|"+label)
+	}
+	var elements string
+	if len(keywords) > 0 {
+		elements += xmpBag("dc:subject", keywords)
+	}
+	elements += xmpBag("lr:hierarchicalSubject", hierarchy)
+	digiKam := make([]string, len(hierarchy))
+	for i, keyword := range hierarchy {
+		digiKam[i] = strings.ReplaceAll(keyword, "|", "/")
+	}
+	elements += xmpBag("digiKam:TagsList", digiKam)
+	if err := os.WriteFile(sidecar, []byte(fmt.Sprintf(xmpTemplate, attributes, elements)), 0644); err != nil {
+		return false, fmt.Errorf("write XMP sidecar: %w", err)
+	}
+	return true, nil
+}
+
+// xmpBag returns an XMP element with an unordered list of values.
+func xmpBag(name string, values []string) string {
+	var bag strings.Builder
+	bag.WriteString("\n   <" + name + ">\n    <rdf:Bag>")
+	for _, value := range values {
+		bag.WriteString("\n     <rdf:li>")
+		_ = xml.EscapeText(&bag, []byte(value))
+		bag.WriteString("</rdf:li>")
+	}
+	bag.WriteString("\n    </rdf:Bag>\n   </" + name + ">")
+	return bag.String()
+}
+
+// XMPsetRating sets the star rating (xmp:Rating, 1 to 5, 0 for none) in the XMP sidecar of a media file,
+// creating the sidecar if there isn't one. Other metadata in an existing sidecar is kept.
+func XMPsetRating(path string, rating int) error {
+	if rating < 0 || rating > 5 {
+		return fmt.Errorf("rating must be 0 to 5: %d", rating)
+	}
+	sidecar := xmpPath(path)
+	attribute := ` xmp:Rating="` + strconv.Itoa(rating) + `"`
+	data, err := os.ReadFile(sidecar)
+	if errors.Is(err, os.ErrNotExist) {
+		data = []byte(fmt.Sprintf(xmpTemplate, "\n    "+strings.TrimSpace(attribute), ""))
+	} else if err != nil {
+		return fmt.Errorf("read XMP sidecar: %w", err)
+	} else if xmpRating.Match(data) {
+		data = xmpRating.ReplaceAllFunc(data, func(match []byte) []byte {
+			if match[0] == '<' {
+				return []byte("<xmp:Rating>" + strconv.Itoa(rating) + "</xmp:Rating>")
+			}
+			return []byte(strings.TrimSpace(attribute))
+		})
+	} else if location := xmpDescription.FindIndex(data); location != nil {
+		insert := attribute
+		if !strings.Contains(string(data), "xmlns:xmp=") {
+			insert = ` xmlns:xmp="http://ns.adobe.com/xap/1.0/"` + insert
+		}
+		data = []byte(string(data[:location[1]]) + insert + string(data[location[1]:]))
+	} else {
+		return fmt.Errorf("no rdf:Description in XMP sidecar %s", sidecar)
+	}
+	if err := os.WriteFile(sidecar, data, 0644); err != nil {
+		return fmt.Errorf("write XMP sidecar: %w", err)
+	}
+	return nil
+}
+
+// ExportXMP writes the XMP sidecar of each file in the catalog (see XMPwrite),
+// except offloaded files. Returns the number of sidecars written in full
+// and the number written by other applications, of which only the rating was written.
+func (imp *Importer) ExportXMP() (int, int, error) {
+	if imp.options.Catalog == nil {
+		return 0, 0, errors.New("XMP export requires a catalog")
+	}
+	var written, others int
+	for _, file := range imp.options.Catalog.Files() {
+		if file.Offloaded != "" {
+			continue
+		}
+		if ours, err := XMPwrite(imp.catalogPath(file), file); err != nil {
+			return written, others, fmt.Errorf("export %s: %w", file.Path, err)
+		} else if ours {
+			written++
+		} else {
+			log.Debug().Str("path", file.Path).Msg("XMP sidecar from another application, only rating exported")
+			others++
+		}
+	}
+	return written, others, nil
+}
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// IsZip returns true if the source is a ZIP archive (e.g. exported by the GardePro mobile app).
+func IsZip(source string) bool {
+	return strings.EqualFold(filepath.Ext(source), ".zip")
+}
+
+// ImportZip imports the supported media files in a ZIP archive, continuing after errors.
+// With Options.Strict the remaining files are aborted once one fails.
+// The results are in archive order, with the source of each file being the path of the archive
+// joined with the name of the entry (e.g. /exports/photos.zip/DCIM/IMG_0001.JPG),
+// which is also recorded in the catalog and journal.
+// The importer reads files by path, so each entry (with its sidecars) is extracted
+// into a temporary directory, imported, and removed before the next,
+// rather than extracting the whole archive. Entry paths are kept when preserving structure.
+func (imp *Importer) ImportZip(archive string) ([]Result, error) {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+	if abs, err := filepath.Abs(archive); err == nil {
+		archive = abs
+	}
+
+	entries := make(map[string]*zip.File)
+	var media []*zip.File
+	var names []string
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		entries[entry.Name] = entry
+		if Supported(entry.Name) {
+			media = append(media, entry)
+			names = append(names, archive+string(filepath.Separator)+filepath.FromSlash(entry.Name))
+		}
+	}
+	return imp.importStaged(names, func(i int, staging string) (string, error) {
+		source, err := extractEntry(media[i], staging)
+		if err != nil {
+			return "", err
+		}
+		stem := strings.TrimSuffix(media[i].Name, path.Ext(media[i].Name))
+		for _, ext := range sidecarExts {
+			if sidecar := entries[stem+ext]; sidecar != nil {
+				if _, err := extractEntry(sidecar, staging); err != nil {
+					log.Warn().Err(err).Str("sidecar", sidecar.Name).Msg("Extract sidecar")
+				}
+			}
+		}
+		return source, nil
+	})
+}
+
+// extractEntry writes an archive entry beneath the staging directory with its modification time,
+// returning its path. Entries with names leading out of the staging directory are refused.
+func extractEntry(entry *zip.File, staging string) (string, error) {
+	name := filepath.Clean(filepath.FromSlash(entry.Name))
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) ||
+		filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("bad entry name %q", entry.Name)
+	}
+	path := filepath.Join(staging, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("make entry dir: %w", err)
+	}
+	reader, err := entry.Open()
+	if err != nil {
+		return "", fmt.Errorf("open entry: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("create entry file: %w", err)
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("extract entry: %w", err)
+	}
+	// The modification time is used for files without capture times if enabled (see UseModTime).
+	_ = os.Chtimes(path, entry.Modified, entry.Modified)
+	return path, nil
+}
@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madkins23/gardepro/fixture"
+)
+
+// zipEntry is an entry of a test archive.
+type zipEntry struct {
+	name string
+	data []byte
+}
+
+// writeZip writes an archive of the entries, modified at the test time, into the directory,
+// returning its path.
+func writeZip(t *testing.T, dir, name string, entries []zipEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: testTime})
+		if err != nil {
+			t.Fatalf("create entry %s: %s", entry.name, err)
+		} else if _, err := w.Write(entry.data); err != nil {
+			t.Fatalf("write entry %s: %s", entry.name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close archive: %s", err)
+	}
+	return writeFile(t, dir, name, buf.Bytes())
+}
+
+// jpegData returns a synthesized JPEG file captured at the time.
+func jpegData(t *testing.T, captured time.Time) []byte {
+	t.Helper()
+	spec := fixture.JPEG{Captured: captured, Model: "GardePro E6"}
+	data, err := spec.Bytes()
+	if err != nil {
+		t.Fatalf("synthesize JPEG: %s", err)
+	}
+	return data
+}
+
+func TestExtractEntry(t *testing.T) {
+	tests := []struct {
+		name string
+		// path is the extracted path relative to the staging directory, empty if refused.
+		path string
+		// windows names are only special on Windows, elsewhere being plain file names.
+		windows bool
+	}{
+		{name: "IMG_0001.JPG", path: "IMG_0001.JPG"},
+		{name: "DCIM/100MEDIA/IMG_0001.JPG", path: "DCIM/100MEDIA/IMG_0001.JPG"},
+		{name: "DCIM/../IMG_0001.JPG", path: "IMG_0001.JPG"},
+		{name: "../x.jpg"},
+		{name: ".."},
+		{name: "DCIM/../../x.jpg"},
+		{name: "/tmp/x.jpg"},
+		{name: `..\x.jpg`, windows: true},
+		{name: "C:/x.jpg", windows: true},
+		{name: "C:x.jpg", windows: true},
+		{name: `\\server\share\x.jpg`, windows: true},
+	}
+	var entries []zipEntry
+	for _, test := range tests {
+		entries = append(entries, zipEntry{name: test.name, data: []byte(test.name)})
+	}
+	reader, err := zip.OpenReader(writeZip(t, t.TempDir(), "photos.zip", entries))
+	if err != nil {
+		t.Fatalf("open archive: %s", err)
+	}
+	defer func() { _ = reader.Close() }()
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.windows && runtime.GOOS != "windows" {
+				t.Skip("not a special name here")
+			}
+			parent := t.TempDir()
+			staging := filepath.Join(parent, "staging")
+			path, err := extractEntry(reader.File[i], staging)
+			if test.path == "" {
+				if err == nil || !strings.Contains(err.Error(), "bad entry name") {
+					t.Errorf("extracted to %s, %v, want the name refused", path, err)
+				}
+				if found, _ := filepath.Glob(filepath.Join(parent, "*")); len(found) > 0 {
+					t.Errorf("files written: %v", found)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("extract: %s", err)
+			}
+			if want := filepath.Join(staging, filepath.FromSlash(test.path)); path != want {
+				t.Errorf("extracted to %s, want %s", path, want)
+			}
+			if data, err := os.ReadFile(path); err != nil || string(data) != test.name {
+				t.Errorf("extracted %q, %v", data, err)
+			}
+			if stat, err := os.Stat(path); err != nil || !stat.ModTime().Equal(testTime) {
+				t.Errorf("modified %v, %v, want %s", stat, err, testTime)
+			}
+		})
+	}
+}
+
+func TestImportZip(t *testing.T) {
+	imp := testImporter(t, Options{})
+	archive := writeZip(t, t.TempDir(), "photos.zip", []zipEntry{
+		{name: "DCIM/IMG_0001.JPG", data: jpegData(t, testTime)},
+		{name: "DCIM/IMG_0001.XMP", data: []byte("<x:xmpmeta/>")},
+		{name: "DCIM/notes.txt", data: []byte("not media")},
+		{name: "../IMG_0002.JPG", data: jpegData(t, testTime.Add(time.Minute))},
+		{name: "DCIM/IMG_0003.JPG", data: jpegData(t, testTime.Add(2*time.Minute))},
+		{name: "DCIM/IMG_0003.srt", data: []byte("1\n00:00:00,000 --> 00:00:01,000\n")},
+		{name: "IMG_0003.XMP", data: []byte("<x:xmpmeta/> of another file")},
+	})
+	results, err := imp.ImportZip(archive)
+	if err != nil {
+		t.Fatalf("import archive: %s", err)
+	}
+	abs, _ := filepath.Abs(archive)
+	want := []string{"DCIM/IMG_0001.JPG", "../IMG_0002.JPG", "DCIM/IMG_0003.JPG"}
+	if len(results) != len(want) {
+		t.Fatalf("%d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if source := abs + string(filepath.Separator) + filepath.FromSlash(want[i]); result.Source != source {
+			t.Errorf("source %s, want %s", result.Source, source)
+		}
+	}
+	if err := results[1].Err; err == nil || !strings.Contains(err.Error(), "bad entry name") {
+		t.Errorf("error %v, want the name refused", err)
+	}
+
+	tests := []struct {
+		result   Result
+		sidecars []string
+	}{
+		{result: results[0], sidecars: []string{".XMP"}},
+		{result: results[2], sidecars: []string{".srt"}},
+	}
+	for _, test := range tests {
+		t.Run(filepath.Base(test.result.Source), func(t *testing.T) {
+			if test.result.Err != nil {
+				t.Fatalf("import: %s", test.result.Err)
+			}
+			if file := catalogEntry(t, imp, test.result.Target); file.Source != test.result.Source {
+				t.Errorf("cataloged source %s, want %s", file.Source, test.result.Source)
+			}
+			stem := strings.TrimSuffix(test.result.Target, filepath.Ext(test.result.Target))
+			found, _ := filepath.Glob(stem + ".*")
+			if len(found) != 1+len(test.sidecars) {
+				t.Errorf("archived %v, want the file and sidecars %v", found, test.sidecars)
+			}
+			for _, ext := range test.sidecars {
+				if _, err := os.Stat(stem + ext); err != nil {
+					t.Errorf("sidecar not co-filed: %s", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Package mqtt publishes messages to an MQTT 3.1.1 broker (e.g. Mosquitto for Home Assistant).
+//
+// Only what is needed to publish retained status messages is implemented:
+// connecting (with an optional user name and password), publishing at QoS 0,
+// and disconnecting.
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+// DefaultPort is the MQTT port used when a broker URL doesn't specify one.
+const DefaultPort = "1883"
+
+// dialTimeout bounds connecting to the broker and each write.
+const dialTimeout = 10 * time.Second
+
+// Packet types (shifted into the upper nibble of the fixed header).
+const (
+	packetConnect    = 1 << 4
+	packetConnAck    = 2 << 4
+	packetPublish    = 3 << 4
+	packetDisconnect = 14 << 4
+)
+
+// Client is a connection to an MQTT broker. It is not safe for concurrent use.
+type Client struct {
+	conn net.Conn
+}
+
+// Dial connects to the broker at a URL of the form mqtt://[user:password@]host[:port].
+func Dial(brokerURL, clientID string) (*Client, error) {
+	parsed, err := url.Parse(brokerURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse broker URL: %w", err)
+	} else if parsed.Scheme != "mqtt" || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("not an mqtt://host:port URL: %s", brokerURL)
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = DefaultPort
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(parsed.Hostname(), port), dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("connect to broker: %w", err)
+	}
+	client := &Client{conn: conn}
+	if err := client.connect(clientID, parsed.User); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// connect sends the CONNECT packet and waits for the broker to accept it.
+func (c *Client) connect(clientID string, user *url.Userinfo) error {
+	flags := byte(0x02) // Clean session.
+	payload := appendString(nil, clientID)
+	if user != nil {
+		flags |= 0x80
+		payload = appendString(payload, user.Username())
+		if password, set := user.Password(); set {
+			flags |= 0x40
+			payload = appendString(payload, password)
+		}
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags, 0, 60) // Protocol level 4 (3.1.1), 60 second keep alive.
+	if err := c.write(packetConnect, append(body, payload...)); err != nil {
+		return fmt.Errorf("send connect: %w", err)
+	}
+	_ = c.conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(c.conn, ack); err != nil {
+		return fmt.Errorf("read connect acknowledgement: %w", err)
+	} else if ack[0] != packetConnAck {
+		return errors.New("unexpected response to connect")
+	} else if ack[3] != 0 {
+		return fmt.Errorf("broker refused connection (code %d)", ack[3])
+	}
+	return nil
+}
+
+// Publish a message to a topic at QoS 0.
+// Retained messages are kept by the broker and sent to later subscribers.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	header := byte(packetPublish)
+	if retain {
+		header |= 0x01
+	}
+	if err := c.write(header, append(appendString(nil, topic), payload...)); err != nil {
+		return fmt.Errorf("publish %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Close disconnects from the broker.
+func (c *Client) Close() error {
+	err := c.write(packetDisconnect, nil)
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// write a packet with the fixed header byte and body.
+func (c *Client) write(header byte, body []byte) error {
+	packet := []byte{header}
+	// The remaining length is encoded in seven bits per byte, least significant first.
+	for length := len(body); ; {
+		digit := byte(length % 128)
+		if length /= 128; length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+	_, err := c.conn.Write(append(packet, body...))
+	return err
+}
+
+// appendString appends a length prefixed UTF-8 string.
+func appendString(data []byte, s string) []byte {
+	return append(append(data, byte(len(s)>>8), byte(len(s))), s...)
+}
@@ -0,0 +1,175 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// packet is an MQTT control packet received by a test broker.
+type packet struct {
+	header byte
+	body   []byte
+}
+
+// readPacket reads a control packet, decoding its remaining length.
+func readPacket(reader *bufio.Reader) (*packet, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, shift := 0, 0
+	for {
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		length |= int(digit&0x7F) << shift
+		if shift += 7; digit&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+	return &packet{header: header, body: body}, nil
+}
+
+// readString reads a length prefixed string from the start of the data, returning it and the rest of the data.
+func readString(t *testing.T, data []byte) (string, []byte) {
+	t.Helper()
+	if len(data) < 2 || len(data) < 2+int(binary.BigEndian.Uint16(data)) {
+		t.Fatalf("truncated string in % x", data)
+	}
+	length := 2 + int(binary.BigEndian.Uint16(data))
+	return string(data[2:length]), data[length:]
+}
+
+// testBroker runs a broker for a single connection which acknowledges the connection with the return code,
+// returning its URL (with the user information) and a channel on which the packets received
+// are sent when the connection ends.
+func testBroker(t *testing.T, user string, code byte) (string, chan []*packet) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	packets := make(chan []*packet, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		var received []*packet
+		defer func() { packets <- received }()
+		reader := bufio.NewReader(conn)
+		for {
+			p, err := readPacket(reader)
+			if err != nil {
+				return
+			}
+			received = append(received, p)
+			switch p.header & 0xF0 {
+			case packetConnect:
+				_, _ = conn.Write([]byte{packetConnAck, 2, 0, code})
+			case packetDisconnect:
+				return
+			}
+		}
+	}()
+	return "mqtt://" + user + listener.Addr().String(), packets
+}
+
+func TestPublish(t *testing.T) {
+	brokerURL, packets := testBroker(t, "ha:secret@", 0)
+	client, err := Dial(brokerURL, "gardepro-test")
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+	large := bytes.Repeat([]byte("x"), 20000)
+	if err := client.Publish("gardepro/status", []byte("online"), true); err != nil {
+		t.Fatalf("publish: %s", err)
+	} else if err := client.Publish("gardepro/report", large, false); err != nil {
+		t.Fatalf("publish: %s", err)
+	} else if err := client.Close(); err != nil {
+		t.Fatalf("close: %s", err)
+	}
+	received := <-packets
+	if len(received) != 4 {
+		t.Fatalf("%d packets, want connect, 2 publish, disconnect", len(received))
+	}
+
+	connect := received[0]
+	protocol, rest := readString(t, connect.body)
+	if connect.header != packetConnect || protocol != "MQTT" || len(rest) < 4 || rest[0] != 4 {
+		t.Fatalf("connect % x", connect.body)
+	} else if flags := rest[1]; flags != 0x80|0x40|0x02 {
+		t.Errorf("connect flags %#x, want user, password, and clean session", flags)
+	}
+	clientID, rest := readString(t, rest[4:])
+	user, rest := readString(t, rest)
+	password, _ := readString(t, rest)
+	if clientID != "gardepro-test" || user != "ha" || password != "secret" {
+		t.Errorf("connect as %q %q %q", clientID, user, password)
+	}
+
+	for i, want := range []struct {
+		header  byte
+		topic   string
+		payload []byte
+	}{
+		{packetPublish | 0x01, "gardepro/status", []byte("online")},
+		{packetPublish, "gardepro/report", large},
+	} {
+		publish := received[1+i]
+		topic, payload := readString(t, publish.body)
+		if publish.header != want.header || topic != want.topic || !bytes.Equal(payload, want.payload) {
+			t.Errorf("publish %#x %s with %d bytes, want %#x %s with %d", publish.header, topic, len(payload),
+				want.header, want.topic, len(want.payload))
+		}
+	}
+	if received[3].header != packetDisconnect {
+		t.Errorf("last packet %#x, want disconnect", received[3].header)
+	}
+}
+
+func TestDialAnonymous(t *testing.T) {
+	brokerURL, packets := testBroker(t, "", 0)
+	client, err := Dial(brokerURL, "gardepro-test")
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+	_ = client.Close()
+	received := <-packets
+	_, rest := readString(t, received[0].body)
+	if flags := rest[1]; flags != 0x02 {
+		t.Errorf("connect flags %#x, want clean session only", flags)
+	}
+}
+
+func TestDialErrors(t *testing.T) {
+	refused, _ := testBroker(t, "ha:wrong@", 5)
+	tests := []struct {
+		url string
+		err string
+	}{
+		{url: refused, err: "refused connection (code 5)"},
+		{url: "tcp://broker:1883", err: "not an mqtt://"},
+		{url: "mqtt://:1883", err: "not an mqtt://"},
+		{url: "mqtt://%zz", err: "parse broker URL"},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			if _, err := Dial(test.url, "gardepro-test"); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
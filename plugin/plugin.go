@@ -0,0 +1,192 @@
+// Package plugin runs external plugin executables that extend the application
+// without recompiling it, for example to read capture times from another camera brand.
+//
+// Plugins are executables in a plugin directory. Each call runs the plugin once,
+// writing a single JSON request to its standard input and reading a single JSON
+// response from its standard output. Anything written to standard error is logged.
+//
+// Every plugin must answer the describe request:
+//
+//	{"type": "describe"}
+//
+// with its kind and, for extractors, the file extensions it handles:
+//
+//	{"kind": "extractor", "name": "heic", "extensions": [".heic"]}
+//
+// The kinds and their requests are:
+//
+//	extractor   {"type": "capture-time", "path": "..."}
+//	            => {"captured": "2006-01-02T15:04:05Z"}
+//	classifier  {"type": "classify", "path": "...", "captured": "..."}
+//	            => {"tags": ["deer", "night"], "confidence": 0.9}
+//	notifier    {"type": "notify", "event": "file-imported", "source": "...", "target": "..."}
+//	            {"type": "notify", "event": "file-failed", "source": "...", "target": "...", "error": "..."}
+//	            {"type": "notify", "event": "camera-fault", "source": "camera source dir", "error": "reason"}
+//	            {"type": "notify", "event": "battery-low", "source": "camera source dir", "error": "battery level"}
+//	            {"type": "notify", "event": "camera-gap", "source": "camera source dir", "error": "no captures since date"}
+//	            => {}
+//
+// No file-imported event is sent for a copy of a capture already imported at another resolution
+// (e.g. the full resolution file from the card after the low resolution copy uploaded by the phone app).
+//
+// Capture times are camera clock times (e.g. from EXIF), any time zone is ignored.
+// Any response may instead contain an error: {"error": "message"}.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Plugin kinds.
+const (
+	KindExtractor  = "extractor"
+	KindClassifier = "classifier"
+	KindNotifier   = "notifier"
+)
+
+// Notification events.
+const (
+	EventFileImported = "file-imported"
+	EventFileFailed   = "file-failed"
+	EventCameraFault  = "camera-fault"
+	EventBatteryLow   = "battery-low"
+	EventCameraGap    = "camera-gap"
+)
+
+// Timeout is how long a plugin may take to answer a request.
+const Timeout = 30 * time.Second
+
+// Request is sent to a plugin.
+type Request struct {
+	Type     string     `json:"type"`
+	Path     string     `json:"path,omitempty"`
+	Captured *time.Time `json:"captured,omitempty"`
+	Event    string     `json:"event,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	Target   string     `json:"target,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// Response is received from a plugin.
+type Response struct {
+	Error string `json:"error,omitempty"`
+	// Describe response.
+	Kind       string   `json:"kind,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	Extensions []string `json:"extensions,omitempty"`
+	// Capture time response.
+	Captured time.Time `json:"captured,omitempty"`
+	// Classify response, confidence is from 0 to 1 (optional).
+	Tags       []string `json:"tags,omitempty"`
+	Confidence float64  `json:"confidence,omitempty"`
+}
+
+// Plugin is an external plugin executable.
+type Plugin struct {
+	Path       string
+	Kind       string
+	Name       string
+	Extensions []string
+}
+
+// Load describes all executables in the directory.
+// A missing directory has no plugins. Executables that can't describe themselves are logged and skipped.
+func Load(dir string) ([]*Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read plugin dir: %w", err)
+	}
+	var plugins []*Plugin
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		plugin := &Plugin{Path: filepath.Join(dir, entry.Name())}
+		response, err := plugin.Call(&Request{Type: "describe"})
+		if err != nil {
+			log.Warn().Err(err).Str("plugin", plugin.Path).Msg("Describe plugin")
+			continue
+		}
+		plugin.Kind, plugin.Name, plugin.Extensions = response.Kind, response.Name, response.Extensions
+		if plugin.Name == "" {
+			plugin.Name = entry.Name()
+		}
+		plugins = append(plugins, plugin)
+		log.Debug().Str("plugin", plugin.Path).Str("kind", plugin.Kind).Msg("Loaded plugin")
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Path < plugins[j].Path
+	})
+	return plugins, nil
+}
+
+// Call sends a request to the plugin and returns its response.
+// An error in the response is returned as an error.
+func (p *Plugin) Call(request *Request) (*Response, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if stderr.Len() > 0 {
+		log.Debug().Str("plugin", p.Path).Bytes("stderr", stderr.Bytes()).Msg("Plugin output")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("run plugin %s: %w", p.Path, err)
+	}
+	response := &Response{}
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return nil, fmt.Errorf("unmarshal plugin %s response: %w", p.Path, err)
+	} else if response.Error != "" {
+		return response, fmt.Errorf("plugin %s: %s", p.Name, response.Error)
+	}
+	return response, nil
+}
+
+// CaptureTime asks an extractor plugin for the capture time of a file.
+func (p *Plugin) CaptureTime(path string) (time.Time, error) {
+	response, err := p.Call(&Request{Type: "capture-time", Path: path})
+	if err != nil {
+		return time.Time{}, err
+	} else if response.Captured.IsZero() {
+		return time.Time{}, fmt.Errorf("plugin %s: no capture time", p.Name)
+	}
+	return response.Captured, nil
+}
+
+// Classify asks a classifier plugin for tags describing a file and its confidence in them,
+// zero if unknown.
+func (p *Plugin) Classify(path string, captured time.Time) ([]string, float64, error) {
+	response, err := p.Call(&Request{Type: "classify", Path: path, Captured: &captured})
+	if err != nil {
+		return nil, 0, err
+	}
+	return response.Tags, response.Confidence, nil
+}
+
+// Notify sends an event to a notifier plugin.
+func (p *Plugin) Notify(request *Request) error {
+	request.Type = "notify"
+	_, err := p.Call(request)
+	return err
+}
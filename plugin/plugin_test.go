@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMain(m *testing.M) {
+	// Plugins that fail to describe themselves are logged, which only obscures test failures.
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// writePlugin writes a shell script plugin into the directory which saves each request in $PLUGIN_REQUEST
+// and answers with the response for the request type (JSON without the outer braces).
+func writePlugin(t *testing.T, dir, name string, responses map[string]string) *Plugin {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("no POSIX shell")
+	}
+	script := "#!/bin/sh\nrequest=$(cat)\nprintf '%s' \"$request\" > \"$PLUGIN_REQUEST\"\ncase \"$request\" in\n"
+	for kind, response := range responses {
+		script += `*'"type":"` + kind + `"'*) echo '{` + response + "}';;\n"
+	}
+	script += "*) exit 1;;\nesac\n"
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("write plugin: %s", err)
+	}
+	return &Plugin{Path: path, Name: name}
+}
+
+// lastRequest returns the last request received by a plugin.
+func lastRequest(t *testing.T) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(os.Getenv("PLUGIN_REQUEST"))
+	if err != nil {
+		t.Fatalf("read request: %s", err)
+	}
+	request := make(map[string]string)
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatalf("unmarshal request %s: %s", data, err)
+	}
+	return request
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("PLUGIN_REQUEST", filepath.Join(t.TempDir(), "request.json"))
+	if plugins, err := Load(filepath.Join(t.TempDir(), "missing")); err != nil || plugins != nil {
+		t.Errorf("missing dir loaded %v, %v", plugins, err)
+	}
+	dir := t.TempDir()
+	writePlugin(t, dir, "b-heic", map[string]string{"describe": `"kind":"extractor","name":"heic","extensions":[".heic"]`})
+	writePlugin(t, dir, "a-notify", map[string]string{"describe": `"kind":"notifier"`})
+	writePlugin(t, dir, "c-broken", map[string]string{"describe": `"error":"no model"`})
+	writePlugin(t, dir, "d-silent", nil)
+	if err := os.WriteFile(filepath.Join(dir, "e-garbage"), []byte("#!/bin/sh\necho not JSON\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "f-dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	plugins, err := Load(dir)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	var loaded []string
+	for _, plugin := range plugins {
+		loaded = append(loaded, filepath.Base(plugin.Path)+" "+plugin.Kind+" "+plugin.Name+" "+strings.Join(plugin.Extensions, ","))
+	}
+	if want := []string{"a-notify notifier a-notify ", "b-heic extractor heic .heic"}; strings.Join(loaded, "|") != strings.Join(want, "|") {
+		t.Errorf("loaded %q, want %q", loaded, want)
+	}
+}
+
+func TestCaptureTime(t *testing.T) {
+	t.Setenv("PLUGIN_REQUEST", filepath.Join(t.TempDir(), "request.json"))
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		response string
+		captured time.Time
+		err      string
+	}{
+		{name: "captured", response: `"captured":"2024-05-01T06:30:00Z"`, captured: time.Date(2024, time.May, 1, 6, 30, 0, 0, time.UTC)},
+		{name: "none", response: ``, err: "no capture time"},
+		{name: "error", response: `"error":"not a HEIC file"`, err: "not a HEIC file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plugin := writePlugin(t, dir, test.name, map[string]string{"capture-time": test.response})
+			captured, err := plugin.CaptureTime("/cards/IMG_0001.HEIC")
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error %v, want %q", err, test.err)
+				}
+			} else if err != nil {
+				t.Errorf("capture time: %s", err)
+			} else if !captured.Equal(test.captured) {
+				t.Errorf("captured %s, want %s", captured, test.captured)
+			}
+			if request := lastRequest(t); request["path"] != "/cards/IMG_0001.HEIC" {
+				t.Errorf("request %v", request)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	t.Setenv("PLUGIN_REQUEST", filepath.Join(t.TempDir(), "request.json"))
+	plugin := writePlugin(t, t.TempDir(), "classifier", map[string]string{"classify": `"tags":["deer","night"],"confidence":0.9`})
+	captured := time.Date(2024, time.May, 1, 22, 15, 0, 0, time.UTC)
+	tags, confidence, err := plugin.Classify("/archive/2024/IMG_0001.JPG", captured)
+	if err != nil {
+		t.Fatalf("classify: %s", err)
+	} else if strings.Join(tags, ",") != "deer,night" || confidence != 0.9 {
+		t.Errorf("classified %v with confidence %g", tags, confidence)
+	}
+	if request := lastRequest(t); request["path"] != "/archive/2024/IMG_0001.JPG" || request["captured"] != "2024-05-01T22:15:00Z" {
+		t.Errorf("request %v", request)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	t.Setenv("PLUGIN_REQUEST", filepath.Join(t.TempDir(), "request.json"))
+	plugin := writePlugin(t, t.TempDir(), "notifier", map[string]string{"notify": ``})
+	if err := plugin.Notify(&Request{Event: EventCameraFault, Source: "/cards/ridge", Error: "all dark"}); err != nil {
+		t.Fatalf("notify: %s", err)
+	}
+	want := map[string]string{"type": "notify", "event": EventCameraFault, "source": "/cards/ridge", "error": "all dark"}
+	if request := lastRequest(t); len(request) != len(want) {
+		t.Errorf("request %v, want %v", request, want)
+	} else {
+		for key, value := range want {
+			if request[key] != value {
+				t.Errorf("request %s %q, want %q", key, request[key], value)
+			}
+		}
+	}
+	failing := writePlugin(t, t.TempDir(), "failing", nil)
+	if err := failing.Notify(&Request{Event: EventFileImported}); err == nil || !strings.Contains(err.Error(), "run plugin") {
+		t.Errorf("error %v, want the plugin to fail", err)
+	}
+}
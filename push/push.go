@@ -0,0 +1,134 @@
+// Package push sends short notifications (e.g. "import finished" or "camera offline")
+// to phones through the ntfy (https://ntfy.sh) and Pushover (https://pushover.net) services,
+// as lighter-weight alternatives to notifier plugins.
+//
+// Services are specified as:
+//
+//	ntfy:TOPIC                     a topic on ntfy.sh
+//	ntfy:https://host/TOPIC        a topic on another (e.g. self-hosted) ntfy server
+//	pushover:APP_TOKEN:USER_KEY    a Pushover application token and user (or group) key
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Service kinds.
+const (
+	KindNtfy     = "ntfy"
+	KindPushover = "pushover"
+)
+
+// DefaultNtfy is the ntfy server used for topics without a server URL.
+const DefaultNtfy = "https://ntfy.sh/"
+
+// PushoverURL is the Pushover message API endpoint.
+var PushoverURL = "https://api.pushover.net/1/messages.json"
+
+// Timeout is how long sending a notification may take.
+const Timeout = 30 * time.Second
+
+// Service is a push notification service.
+type Service struct {
+	Kind string
+	// URL of the ntfy topic.
+	URL string
+	// Token and User are the Pushover application token and user key.
+	Token string
+	User  string
+}
+
+// Parse parses a comma separated list of services.
+func Parse(specs string) ([]*Service, error) {
+	var services []*Service
+	for _, spec := range strings.Split(specs, ",") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		service, err := parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+func parse(spec string) (*Service, error) {
+	kind, value, _ := strings.Cut(spec, ":")
+	switch kind {
+	case KindNtfy:
+		if value == "" {
+			return nil, fmt.Errorf("no ntfy topic in %s", spec)
+		} else if !strings.Contains(value, "://") {
+			value = DefaultNtfy + value
+		} else if parsed, err := url.Parse(value); err != nil {
+			return nil, fmt.Errorf("parse ntfy URL: %w", err)
+		} else if strings.Trim(parsed.Path, "/") == "" {
+			return nil, fmt.Errorf("no ntfy topic in %s", spec)
+		}
+		return &Service{Kind: kind, URL: value}, nil
+	case KindPushover:
+		token, user, _ := strings.Cut(value, ":")
+		if token == "" || user == "" {
+			return nil, errors.New("pushover requires pushover:APP_TOKEN:USER_KEY")
+		}
+		return &Service{Kind: kind, Token: token, User: user}, nil
+	}
+	return nil, fmt.Errorf("unknown push service %q (ntfy or pushover)", kind)
+}
+
+// String returns the service without its secrets, e.g. for logging.
+func (s *Service) String() string {
+	if s.Kind == KindNtfy {
+		return s.URL
+	}
+	return s.Kind
+}
+
+// Send a notification with a title and message.
+// Urgent notifications are sent at high priority (e.g. for failures).
+func (s *Service) Send(title, message string, urgent bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	var request *http.Request
+	var err error
+	switch s.Kind {
+	case KindNtfy:
+		if request, err = http.NewRequestWithContext(ctx, http.MethodPost, s.URL, strings.NewReader(message)); err != nil {
+			return fmt.Errorf("make ntfy request: %w", err)
+		}
+		request.Header.Set("Title", title)
+		if urgent {
+			request.Header.Set("Priority", "high")
+		}
+	case KindPushover:
+		form := url.Values{"token": {s.Token}, "user": {s.User}, "title": {title}, "message": {message}}
+		if urgent {
+			form.Set("priority", "1")
+		}
+		if request, err = http.NewRequestWithContext(ctx, http.MethodPost, PushoverURL, strings.NewReader(form.Encode())); err != nil {
+			return fmt.Errorf("make pushover request: %w", err)
+		}
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	default:
+		return fmt.Errorf("unknown push service %q", s.Kind)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("send %s notification: %w", s.Kind, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("send %s notification: %s: %s", s.Kind, response.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package push
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		specs    string
+		services []Service
+		err      string
+	}{
+		{specs: "", services: nil},
+		{specs: "ntfy:trailcam", services: []Service{{Kind: KindNtfy, URL: DefaultNtfy + "trailcam"}}},
+		{specs: "ntfy:https://ntfy.example.com/trailcam", services: []Service{{Kind: KindNtfy, URL: "https://ntfy.example.com/trailcam"}}},
+		{specs: "pushover:app:user", services: []Service{{Kind: KindPushover, Token: "app", User: "user"}}},
+		{specs: " ntfy:a , pushover:app:user ,", services: []Service{
+			{Kind: KindNtfy, URL: DefaultNtfy + "a"}, {Kind: KindPushover, Token: "app", User: "user"}}},
+		{specs: "ntfy:", err: "no ntfy topic"},
+		{specs: "ntfy:https://ntfy.example.com/", err: "no ntfy topic"},
+		{specs: "ntfy:https://ntfy.example.com/%zz", err: "parse ntfy URL"},
+		{specs: "pushover:app", err: "pushover requires"},
+		{specs: "pushover::user", err: "pushover requires"},
+		{specs: "slack:channel", err: "unknown push service"},
+	}
+	for _, test := range tests {
+		t.Run(test.specs, func(t *testing.T) {
+			services, err := Parse(test.specs)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error %v, want %q", err, test.err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("parse: %s", err)
+			}
+			if len(services) != len(test.services) {
+				t.Fatalf("%d services, want %d", len(services), len(test.services))
+			}
+			for i, service := range services {
+				if *service != test.services[i] {
+					t.Errorf("service %+v, want %+v", *service, test.services[i])
+				}
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	if service := (&Service{Kind: KindPushover, Token: "secret", User: "key"}); service.String() != KindPushover {
+		t.Errorf("pushover shown as %q", service.String())
+	}
+}
+
+// received is a request received by a test server.
+type received struct {
+	header http.Header
+	body   string
+}
+
+// testServer returns the URL of a server which answers with the status
+// and sends the requests it receives on the channel.
+func testServer(t *testing.T, status int) (string, chan received) {
+	t.Helper()
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: string(body)}
+		if status != http.StatusOK {
+			http.Error(w, "invalid topic", status)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, requests
+}
+
+func TestSendNtfy(t *testing.T) {
+	for _, urgent := range []bool{false, true} {
+		serverURL, requests := testServer(t, http.StatusOK)
+		service := &Service{Kind: KindNtfy, URL: serverURL + "/trailcam"}
+		if err := service.Send("Import finished", "12 files", urgent); err != nil {
+			t.Fatalf("send: %s", err)
+		}
+		request := <-requests
+		if request.body != "12 files" || request.header.Get("Title") != "Import finished" {
+			t.Errorf("sent %q titled %q", request.body, request.header.Get("Title"))
+		}
+		if priority := request.header.Get("Priority"); (priority == "high") != urgent {
+			t.Errorf("urgent %t sent with priority %q", urgent, priority)
+		}
+	}
+}
+
+func TestSendPushover(t *testing.T) {
+	serverURL, requests := testServer(t, http.StatusOK)
+	saved := PushoverURL
+	PushoverURL = serverURL
+	defer func() { PushoverURL = saved }()
+	service := &Service{Kind: KindPushover, Token: "app", User: "user"}
+	if err := service.Send("Camera offline", "No captures from ridge", true); err != nil {
+		t.Fatalf("send: %s", err)
+	}
+	request := <-requests
+	form, err := url.ParseQuery(request.body)
+	if err != nil {
+		t.Fatalf("parse form: %s", err)
+	}
+	want := url.Values{"token": {"app"}, "user": {"user"}, "title": {"Camera offline"},
+		"message": {"No captures from ridge"}, "priority": {"1"}}
+	if form.Encode() != want.Encode() {
+		t.Errorf("sent %s, want %s", form.Encode(), want.Encode())
+	}
+}
+
+func TestSendError(t *testing.T) {
+	serverURL, _ := testServer(t, http.StatusBadRequest)
+	service := &Service{Kind: KindNtfy, URL: serverURL + "/trailcam"}
+	if err := service.Send("title", "message", false); err == nil || !strings.Contains(err.Error(), "invalid topic") {
+		t.Errorf("error %v, want the response", err)
+	}
+	if err := (&Service{Kind: "slack"}).Send("title", "message", false); err == nil {
+		t.Error("no error for unknown service")
+	}
+}
@@ -0,0 +1,400 @@
+// Package release finds, verifies, and installs gardepro releases published on GitHub
+// so that installations at remote sites (e.g. a headless Raspberry Pi) can update themselves.
+//
+// A release has an executable asset for each platform named per AssetName,
+// a SumsName file of SHA-256 checksums of the assets in sha256sum format,
+// and SumsName plus SignatureSuffix, the Ed25519 signature of the checksums file
+// by the private key of the maintainer (see Sign), whose public key is PublicKey.
+// The checksums file also has a line naming the version of the release
+// (VersionPrefix followed by the tag), so that the signature covers the version
+// and an older signed release can't be offered under a newer tag.
+package release
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRepository is the GitHub repository of gardepro releases.
+const DefaultRepository = "madkins23/gardepro"
+
+// SumsName is the name of the checksums asset of a release.
+const SumsName = "SHA256SUMS"
+
+// VersionPrefix starts the line of the checksums file naming the version of the release.
+const VersionPrefix = "version "
+
+// SignatureSuffix is appended to the checksums asset name for its signature.
+const SignatureSuffix = ".sig"
+
+// PublicKey is the base64 encoded Ed25519 public key of the maintainer, whose private key
+// signs the checksums of every release. Release builds set it with
+// -ldflags "-X github.com/madkins23/gardepro/release.PublicKey=KEY" (see EncodePublicKey).
+var PublicKey = ""
+
+// API is the base URL of the GitHub API.
+var API = "https://api.github.com"
+
+// Timeout for each request.
+var Timeout = 5 * time.Minute
+
+// maxAssetSize is the largest asset downloaded.
+const maxAssetSize = 256 << 20
+
+// Release is a published release.
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file of a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Asset returns the release asset with the name, nil if none.
+func (r *Release) Asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// AssetName returns the name of the executable asset for the current platform,
+// e.g. gardepro-linux-arm64 or gardepro-windows-amd64.exe.
+func AssetName() string {
+	name := "gardepro-" + runtime.GOOS + "-" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Latest returns the latest release of the GitHub repository (owner/name).
+func Latest(repository string) (*Release, error) {
+	data, err := get(API+"/repos/"+repository+"/releases/latest", 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("get latest release: %w", err)
+	}
+	release := &Release{}
+	if err := json.Unmarshal(data, release); err != nil {
+		return nil, fmt.Errorf("parse release: %w", err)
+	}
+	return release, nil
+}
+
+// EmbeddedKey returns the decoded PublicKey, nil if none was set.
+func EmbeddedKey() (ed25519.PublicKey, error) {
+	if PublicKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode release key: %w", err)
+	} else if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("release key is %d bytes, not %d", len(key), ed25519.PublicKeySize)
+	}
+	return key, nil
+}
+
+// EncodePublicKey returns the public key encoded for PublicKey.
+func EncodePublicKey(public ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(public)
+}
+
+// Download returns the executable asset of the release for the current platform
+// and the signed version of the release, after checking the asset against the checksums of the release
+// and the signature of the checksums by the private key of the public key.
+// Unsigned releases and releases whose signed version isn't their tag are refused.
+// The signed version, not the tag, is the one to compare with the running version.
+func (r *Release) Download(public ed25519.PublicKey) ([]byte, string, error) {
+	if len(public) != ed25519.PublicKeySize {
+		return nil, "", errors.New("no public key to check the release signature")
+	}
+	name := AssetName()
+	asset, sums := r.Asset(name), r.Asset(SumsName)
+	if asset == nil {
+		return nil, "", fmt.Errorf("release %s has no %s", r.Tag, name)
+	} else if sums == nil {
+		return nil, "", fmt.Errorf("release %s has no %s", r.Tag, SumsName)
+	}
+	sumsData, err := get(sums.URL, 1<<20)
+	if err != nil {
+		return nil, "", fmt.Errorf("download %s: %w", SumsName, err)
+	}
+	signature := r.Asset(SumsName + SignatureSuffix)
+	if signature == nil {
+		return nil, "", fmt.Errorf("release %s is not signed", r.Tag)
+	}
+	sigData, err := get(signature.URL, 1024)
+	if err != nil {
+		return nil, "", fmt.Errorf("download signature: %w", err)
+	}
+	if !ed25519.Verify(public, sumsData, sigData) {
+		return nil, "", fmt.Errorf("%s of release %s: bad signature", SumsName, r.Tag)
+	}
+	signed, err := sumsVersion(sumsData)
+	if err != nil {
+		return nil, "", fmt.Errorf("release %s: %w", r.Tag, err)
+	} else if signed != r.Tag {
+		return nil, "", fmt.Errorf("release %s is signed as version %s", r.Tag, signed)
+	}
+	expected, err := checksum(sumsData, name)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := get(asset.URL, maxAssetSize)
+	if err != nil {
+		return nil, "", fmt.Errorf("download %s: %w", name, err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != expected {
+		return nil, "", fmt.Errorf("%s of release %s: checksum mismatch", name, r.Tag)
+	}
+	return data, signed, nil
+}
+
+// CompareVersions compares two semantic versions (e.g. v1.2.3 or v1.3.0-rc.1, the v optional),
+// returning -1, 0, or +1 as a is older than, the same as, or newer than b.
+// Pre-releases are older than their release, build metadata (after +) is ignored.
+func CompareVersions(a, b string) (int, error) {
+	av, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bv, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < 3; i++ {
+		if av.numbers[i] != bv.numbers[i] {
+			return compareInts(av.numbers[i], bv.numbers[i]), nil
+		}
+	}
+	switch {
+	case len(av.pre) == 0 && len(bv.pre) == 0:
+		return 0, nil
+	case len(av.pre) == 0:
+		return 1, nil
+	case len(bv.pre) == 0:
+		return -1, nil
+	}
+	for i := 0; i < len(av.pre) && i < len(bv.pre); i++ {
+		if c := comparePrerelease(av.pre[i], bv.pre[i]); c != 0 {
+			return c, nil
+		}
+	}
+	return compareInts(uint64(len(av.pre)), uint64(len(bv.pre))), nil
+}
+
+// version is a parsed semantic version.
+type version struct {
+	numbers [3]uint64
+	pre     []string
+}
+
+// parseVersion parses a semantic version.
+func parseVersion(text string) (*version, error) {
+	core := strings.TrimPrefix(text, "v")
+	if i := strings.IndexByte(core, '+'); i >= 0 {
+		core = core[:i]
+	}
+	v := &version{}
+	if i := strings.IndexByte(core, '-'); i >= 0 {
+		core, v.pre = core[:i], strings.Split(core[i+1:], ".")
+		for _, identifier := range v.pre {
+			if identifier == "" {
+				return nil, fmt.Errorf("version %q: empty pre-release identifier", text)
+			}
+		}
+	}
+	fields := strings.Split(core, ".")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("version %q is not MAJOR.MINOR.PATCH", text)
+	}
+	for i, field := range fields {
+		n, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("version %q: bad number %q", text, field)
+		}
+		v.numbers[i] = n
+	}
+	return v, nil
+}
+
+// comparePrerelease compares pre-release identifiers: numbers numerically and before other identifiers,
+// which are compared as text.
+func comparePrerelease(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInts(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// checksum returns the checksum of the named file from a sha256sum format checksums file.
+func checksum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(sums)))
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), VersionPrefix) {
+			continue
+		}
+		// Binary mode files are marked with an asterisk.
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", name, SumsName)
+}
+
+// errNoVersion is returned for a checksums file without a version line.
+var errNoVersion = fmt.Errorf("no version in %s", SumsName)
+
+// sumsVersion returns the version named by the VersionPrefix line of a checksums file,
+// which must have exactly one.
+func sumsVersion(sums []byte) (string, error) {
+	var found string
+	scanner := bufio.NewScanner(strings.NewReader(string(sums)))
+	for scanner.Scan() {
+		if !strings.HasPrefix(scanner.Text(), VersionPrefix) {
+			continue
+		} else if found != "" {
+			return "", fmt.Errorf("more than one version in %s", SumsName)
+		}
+		found = strings.TrimSpace(strings.TrimPrefix(scanner.Text(), VersionPrefix))
+		if found == "" {
+			return "", fmt.Errorf("empty version in %s", SumsName)
+		}
+	}
+	if found == "" {
+		return "", errNoVersion
+	}
+	return found, nil
+}
+
+// get returns the body of a URL, failing if larger than the limit.
+func get(url string, limit int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json, application/octet-stream")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(response.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(response.Body, limit+1))
+	if err != nil {
+		return nil, err
+	} else if int64(len(data)) > limit {
+		return nil, fmt.Errorf("larger than %d bytes", limit)
+	}
+	return data, nil
+}
+
+// Install replaces the executable file with the data.
+// The new file is written beside it and renamed over it, so the executable is never partly written.
+// Windows doesn't allow a running executable to be replaced, but it can be renamed,
+// so there it is first renamed with the suffix .old (removed by the next Install).
+func Install(executable string, data []byte) error {
+	stat, err := os.Stat(executable)
+	if err != nil {
+		return fmt.Errorf("stat executable: %w", err)
+	}
+	temp := executable + ".new"
+	if err := os.WriteFile(temp, data, stat.Mode().Perm()); err != nil {
+		return fmt.Errorf("write new executable: %w", err)
+	}
+	if runtime.GOOS == "windows" {
+		old := executable + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(executable, old); err != nil {
+			_ = os.Remove(temp)
+			return fmt.Errorf("rename old executable: %w", err)
+		}
+	}
+	if err := os.Rename(temp, executable); err != nil {
+		_ = os.Remove(temp)
+		return fmt.Errorf("replace executable: %w", err)
+	}
+	return nil
+}
+
+// Sign writes the signature of the checksums file by the private key beside it
+// (the path plus SignatureSuffix), to be published with the release with the tag.
+// The version line naming the tag is appended to the checksums file if it has none,
+// and a checksums file naming another version is refused.
+func Sign(sumsPath, tag string, private ed25519.PrivateKey) error {
+	if tag == "" || strings.ContainsAny(tag, " \t\r\n") {
+		return fmt.Errorf("bad release tag %q", tag)
+	}
+	data, err := os.ReadFile(sumsPath)
+	if err != nil {
+		return fmt.Errorf("read checksums: %w", err)
+	}
+	if signed, err := sumsVersion(data); err == nil && signed != tag {
+		return fmt.Errorf("%s names version %s, not %s", sumsPath, signed, tag)
+	} else if err != nil && !errors.Is(err, errNoVersion) {
+		return err
+	} else if err != nil {
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		data = append(data, VersionPrefix+tag+"\n"...)
+		if err := os.WriteFile(sumsPath, data, 0644); err != nil {
+			return fmt.Errorf("write checksums: %w", err)
+		}
+	}
+	if err := os.WriteFile(sumsPath+SignatureSuffix, ed25519.Sign(private, data), 0644); err != nil {
+		return fmt.Errorf("write signature: %w", err)
+	}
+	return nil
+}
+
+// Executable returns the path of the running executable with symbolic links resolved.
+func Executable() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
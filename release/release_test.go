@@ -0,0 +1,213 @@
+package release
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"1.2.3", "v1.2.3", 0},
+		{"v1.2.4", "v1.2.3", 1},
+		{"v1.2.3", "v1.2.4", -1},
+		{"v1.10.0", "v1.9.9", 1},
+		{"v2.0.0", "v1.99.99", 1},
+		{"v0.9.0", "v1.0.0", -1},
+		{"v1.0.0-rc.1", "v1.0.0", -1},
+		{"v1.0.0", "v1.0.0-rc.1", 1},
+		{"v1.0.0-alpha", "v1.0.0-alpha.1", -1},
+		{"v1.0.0-alpha.1", "v1.0.0-alpha.beta", -1},
+		{"v1.0.0-beta.2", "v1.0.0-beta.11", -1},
+		{"v1.0.0-rc.1", "v1.0.0-beta.11", 1},
+		{"v1.0.0+build.5", "v1.0.0", 0},
+	} {
+		if got, err := CompareVersions(test.a, test.b); err != nil {
+			t.Errorf("CompareVersions(%q, %q): %s", test.a, test.b, err)
+		} else if got != test.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestCompareVersionsInvalid(t *testing.T) {
+	for _, bad := range []string{"dev", "", "v1.2", "v1.2.3.4", "v1.x.3", "v1.2.3-", "v1.2.3-rc..1", "v-1.2.3"} {
+		if _, err := CompareVersions(bad, "v1.2.3"); err == nil {
+			t.Errorf("CompareVersions(%q, ...) accepted", bad)
+		}
+		if _, err := CompareVersions("v1.2.3", bad); err == nil {
+			t.Errorf("CompareVersions(..., %q) accepted", bad)
+		}
+	}
+}
+
+func TestEmbeddedKey(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(saved string) { PublicKey = saved }(PublicKey)
+	PublicKey = ""
+	if key, err := EmbeddedKey(); key != nil || err != nil {
+		t.Errorf("no key: %v, %v", key, err)
+	}
+	PublicKey = EncodePublicKey(public)
+	if key, err := EmbeddedKey(); err != nil || !key.Equal(public) {
+		t.Errorf("key %v, %v, want %v", key, err, public)
+	}
+	PublicKey = EncodePublicKey(public[:16])
+	if _, err := EmbeddedKey(); err == nil {
+		t.Error("short key accepted")
+	}
+}
+
+func TestDownload(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	executable := []byte("new executable")
+	sum := sha256.Sum256(executable)
+	line := hex.EncodeToString(sum[:]) + " *" + AssetName() + "\n"
+	sums := []byte(line + VersionPrefix + "v1.2.3\n")
+	badSums := []byte(strings.Repeat("0", 64) + " *" + AssetName() + "\n" + VersionPrefix + "v1.2.3\n")
+	oldSums := []byte(line + VersionPrefix + "v1.0.0\n")
+	files := map[string][]byte{
+		"/asset":           executable,
+		"/sums":            sums,
+		"/bad-sums":        badSums,
+		"/old-sums":        oldSums,
+		"/no-version-sums": []byte(line),
+		"/sig":             ed25519.Sign(private, sums),
+		"/bad-sig":         ed25519.Sign(private, badSums),
+		"/old-sig":         ed25519.Sign(private, oldSums),
+		"/no-version-sig":  ed25519.Sign(private, []byte(line)),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if data, ok := files[r.URL.Path]; ok {
+			_, _ = w.Write(data)
+		} else {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	for _, test := range []struct {
+		name      string
+		key       ed25519.PublicKey
+		sums, sig string
+		want      string
+	}{
+		{"signed", public, "/sums", "/sig", ""},
+		{"no key", nil, "/sums", "/sig", "no public key"},
+		{"other key", other, "/sums", "/sig", "bad signature"},
+		{"unsigned", public, "/sums", "", "not signed"},
+		{"signature of other checksums", public, "/sums", "/bad-sig", "bad signature"},
+		{"checksum mismatch", public, "/bad-sums", "/bad-sig", "checksum mismatch"},
+		{"older release under newer tag", public, "/old-sums", "/old-sig", "signed as version v1.0.0"},
+		{"no signed version", public, "/no-version-sums", "/no-version-sig", "no version"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			release := &Release{Tag: "v1.2.3", Assets: []Asset{
+				{Name: AssetName(), URL: server.URL + "/asset"},
+				{Name: SumsName, URL: server.URL + test.sums},
+			}}
+			if test.sig != "" {
+				release.Assets = append(release.Assets, Asset{Name: SumsName + SignatureSuffix, URL: server.URL + test.sig})
+			}
+			data, signed, err := release.Download(test.key)
+			if test.want == "" {
+				if err != nil || string(data) != string(executable) || signed != "v1.2.3" {
+					t.Errorf("downloaded %q version %q, %v", data, signed, err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestSumsVersion(t *testing.T) {
+	tests := []struct {
+		sums    string
+		version string
+		err     string
+	}{
+		{sums: "abc  gardepro-linux-amd64\nversion v1.2.3\n", version: "v1.2.3"},
+		{sums: "version v1.2.3", version: "v1.2.3"},
+		{sums: "abc  gardepro-linux-amd64\n", err: "no version"},
+		{sums: "version \n", err: "empty version"},
+		{sums: "version v1.2.3\nversion v1.2.4\n", err: "more than one version"},
+	}
+	for _, test := range tests {
+		t.Run(test.sums, func(t *testing.T) {
+			version, err := sumsVersion([]byte(test.sums))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error %v, want %q", err, test.err)
+				}
+			} else if err != nil || version != test.version {
+				t.Errorf("version %q, %v, want %q", version, err, test.version)
+			}
+		})
+	}
+	if _, err := checksum([]byte("version v1.2.3\n"), "v1.2.3"); err == nil {
+		t.Error("version line read as a checksum")
+	}
+}
+
+func TestSign(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, sums, tag string
+		signed          string
+		err             string
+	}{
+		{name: "added", sums: "abc *gardepro-linux-amd64", tag: "v1.2.3", signed: "abc *gardepro-linux-amd64\nversion v1.2.3\n"},
+		{name: "present", sums: "version v1.2.3\nabc *gardepro-linux-amd64\n", tag: "v1.2.3", signed: "version v1.2.3\nabc *gardepro-linux-amd64\n"},
+		{name: "other version", sums: "version v1.2.2\n", tag: "v1.2.3", err: "names version v1.2.2"},
+		{name: "no tag", sums: "abc *gardepro-linux-amd64\n", err: "bad release tag"},
+		{name: "bad tag", sums: "abc *gardepro-linux-amd64\n", tag: "v1.2.3 v1.2.4", err: "bad release tag"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), SumsName)
+			if err := os.WriteFile(path, []byte(test.sums), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := Sign(path, test.tag, private)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error %v, want %q", err, test.err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("sign: %s", err)
+			}
+			sums, _ := os.ReadFile(path)
+			signature, _ := os.ReadFile(path + SignatureSuffix)
+			if string(sums) != test.signed {
+				t.Errorf("signed %q, want %q", sums, test.signed)
+			} else if !ed25519.Verify(public, sums, signature) {
+				t.Error("bad signature")
+			}
+		})
+	}
+}
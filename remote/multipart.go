@@ -0,0 +1,253 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	minPartSize = 8 << 20
+	maxParts    = 10000
+)
+
+// uploadState records the progress of a multipart upload so that it can be resumed.
+type uploadState struct {
+	Key      string       `json:"key"`
+	UploadID string       `json:"uploadID"`
+	Size     int64        `json:"size"`
+	ModTime  time.Time    `json:"modTime"`
+	PartSize int64        `json:"partSize"`
+	Parts    []uploadPart `json:"parts"`
+}
+
+type uploadPart struct {
+	Number int32  `json:"number"`
+	ETag   string `json:"etag"`
+	MD5    string `json:"md5"`
+}
+
+// PutResumable uploads a file to the object with the specified key as a multipart upload.
+// The progress of the upload is kept in the state file so that an interrupted upload
+// is resumed from the last completed part by calling PutResumable again with the same state file.
+// The completed object is verified against the MD5 hashes of the uploaded parts
+// (or its size if the service doesn't provide a multipart ETag) and the state file is removed.
+// Files smaller than a single part are uploaded with Put.
+func (s *S3) PutResumable(ctx context.Context, key, path, storageClass, sha256, stateFile string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	if stat.Size() <= minPartSize {
+		return s.Put(ctx, key, path, storageClass, sha256)
+	}
+
+	state, err := s.resumeState(ctx, stateFile, key, stat)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		partSize := int64(minPartSize)
+		for stat.Size()/partSize >= maxParts {
+			partSize *= 2
+		}
+		create, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:       aws.String(s.bucket),
+			Key:          aws.String(key),
+			StorageClass: types.StorageClass(storageClass),
+			Metadata:     map[string]string{"sha256": sha256},
+		})
+		if err != nil {
+			return fmt.Errorf("create multipart upload: %w", err)
+		}
+		state = &uploadState{
+			Key:      key,
+			UploadID: aws.ToString(create.UploadId),
+			Size:     stat.Size(),
+			ModTime:  stat.ModTime(),
+			PartSize: partSize,
+		}
+		if err := state.save(stateFile); err != nil {
+			return err
+		}
+	} else {
+		log.Info().Str("key", key).Int("parts", len(state.Parts)).Msg("Resuming upload")
+	}
+
+	buffer := make([]byte, state.PartSize)
+	for offset := int64(len(state.Parts)) * state.PartSize; offset < state.Size; offset += state.PartSize {
+		n, err := file.ReadAt(buffer, offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("read file: %w", err)
+		}
+		sum := md5.Sum(buffer[:n])
+		number := int32(len(state.Parts) + 1)
+		upload, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(s.bucket),
+			Key:           aws.String(key),
+			UploadId:      aws.String(state.UploadID),
+			PartNumber:    number,
+			Body:          bytes.NewReader(buffer[:n]),
+			ContentLength: int64(n),
+			ContentMD5:    aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+		})
+		if err != nil {
+			return fmt.Errorf("upload part %d: %w", number, err)
+		}
+		state.Parts = append(state.Parts, uploadPart{
+			Number: number,
+			ETag:   aws.ToString(upload.ETag),
+			MD5:    hex.EncodeToString(sum[:]),
+		})
+		if err := state.save(stateFile); err != nil {
+			return err
+		}
+	}
+
+	completed := make([]types.CompletedPart, len(state.Parts))
+	for i, part := range state.Parts {
+		completed[i] = types.CompletedPart{ETag: aws.String(part.ETag), PartNumber: part.Number}
+	}
+	complete, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(state.UploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
+	// Some S3 compatible services don't return the multipart form of ETag
+	// so fall back to checking the size of the completed object.
+	if etag := strings.Trim(aws.ToString(complete.ETag), `"`); strings.Contains(etag, "-") {
+		if expected := state.etag(); etag != expected {
+			return fmt.Errorf("uploaded object ETag %s does not match expected %s", etag, expected)
+		}
+	} else if object, err := s.Head(ctx, key); err != nil {
+		return fmt.Errorf("check upload: %w", err)
+	} else if object.Size != state.Size {
+		return fmt.Errorf("uploaded object size %d does not match file size %d", object.Size, state.Size)
+	}
+	if err := os.Remove(stateFile); err != nil {
+		return fmt.Errorf("remove upload state: %w", err)
+	}
+	return nil
+}
+
+// resumeState loads the upload state if it is for the same key and unchanged file
+// and reconciles it with the parts that the service has actually received.
+// Returns nil if there is no upload to resume.
+func (s *S3) resumeState(ctx context.Context, stateFile, key string, stat os.FileInfo) (*uploadState, error) {
+	state, err := loadState(stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if state.Key != key || state.Size != stat.Size() || !state.ModTime.Equal(stat.ModTime()) {
+		log.Warn().Str("key", key).Msg("Abandoning upload state for different or changed file")
+		s.abort(ctx, state)
+		return nil, nil
+	}
+
+	received := make(map[int32]string)
+	var marker *string
+	for {
+		list, err := s.client.ListParts(ctx, &s3.ListPartsInput{
+			Bucket:           aws.String(s.bucket),
+			Key:              aws.String(key),
+			UploadId:         aws.String(state.UploadID),
+			PartNumberMarker: marker,
+		})
+		if err != nil {
+			// Most likely the upload has expired or been aborted, so start over.
+			log.Warn().Err(err).Str("key", key).Msg("Abandoning upload state")
+			return nil, nil
+		}
+		for _, part := range list.Parts {
+			received[part.PartNumber] = aws.ToString(part.ETag)
+		}
+		if !list.IsTruncated {
+			break
+		}
+		marker = list.NextPartNumberMarker
+	}
+	// Keep the leading parts that were received intact.
+	for i, part := range state.Parts {
+		if received[part.Number] != part.ETag {
+			state.Parts = state.Parts[:i]
+			break
+		}
+	}
+	return state, nil
+}
+
+// abort a multipart upload so that its parts are not kept (and charged for).
+func (s *S3) abort(ctx context.Context, state *uploadState) {
+	if _, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(state.Key),
+		UploadId: aws.String(state.UploadID),
+	}); err != nil {
+		log.Warn().Err(err).Str("key", state.Key).Msg("Abort multipart upload")
+	}
+}
+
+// etag returns the expected ETag of the completed multipart object,
+// which is the MD5 hash of the concatenated part MD5 hashes followed by the number of parts.
+func (state *uploadState) etag() string {
+	hash := md5.New()
+	for _, part := range state.Parts {
+		sum, _ := hex.DecodeString(part.MD5)
+		hash.Write(sum)
+	}
+	return hex.EncodeToString(hash.Sum(nil)) + "-" + strconv.Itoa(len(state.Parts))
+}
+
+func loadState(stateFile string) (*uploadState, error) {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return nil, fmt.Errorf("read upload state: %w", err)
+	}
+	state := new(uploadState)
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("parse upload state: %w", err)
+	}
+	return state, nil
+}
+
+func (state *uploadState) save(stateFile string) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal upload state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0766); err != nil {
+		return fmt.Errorf("make upload state dir: %w", err)
+	}
+	temp := stateFile + ".tmp"
+	if err := os.WriteFile(temp, data, 0666); err != nil {
+		return fmt.Errorf("write upload state: %w", err)
+	}
+	return os.Rename(temp, stateFile)
+}
@@ -0,0 +1,289 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMain(m *testing.M) {
+	// Resumed and abandoned uploads are logged, which only obscures test failures.
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// fakeS3 is the part of an S3 service used by multipart uploads, for a single upload at a time.
+type fakeS3 struct {
+	mutex sync.Mutex
+	// created is the number of uploads created, aborted the number aborted.
+	created, aborted int
+	// parts received for the current upload and uploaded counts the uploads of each part number.
+	parts    map[int32][]byte
+	uploaded map[int32]int
+	// failPart is a part number to reject once.
+	failPart int32
+	// forget drops the parts received when set, as when an upload expires.
+	forget bool
+	// etag replaces the ETag of completed objects if not empty.
+	etag string
+	// truncate drops the last byte of completed objects.
+	truncate bool
+	// object is the completed object.
+	object []byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.created++
+		f.parts = make(map[int32][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>upload-%d</UploadId></InitiateMultipartUploadResult>", f.created)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if int32(number) == f.failPart {
+			f.failPart = 0
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, "<Error><Code>AccessDenied</Code><Message>denied</Message></Error>")
+			return
+		}
+		f.parts[int32(number)] = body
+		f.uploaded[int32(number)]++
+		sum := md5.Sum(body)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	case r.Method == http.MethodGet && query.Has("uploadId"):
+		if f.forget {
+			f.parts = make(map[int32][]byte)
+		}
+		fmt.Fprint(w, "<ListPartsResult><IsTruncated>false</IsTruncated>")
+		for number, part := range f.parts {
+			sum := md5.Sum(part)
+			fmt.Fprintf(w, `<Part><PartNumber>%d</PartNumber><ETag>"%s"</ETag><Size>%d</Size></Part>`, number, hex.EncodeToString(sum[:]), len(part))
+		}
+		fmt.Fprint(w, "</ListPartsResult>")
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		var complete struct {
+			Parts []struct{ PartNumber int32 } `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hash := md5.New()
+		f.object = nil
+		for _, part := range complete.Parts {
+			sum := md5.Sum(f.parts[part.PartNumber])
+			hash.Write(sum[:])
+			f.object = append(f.object, f.parts[part.PartNumber]...)
+		}
+		if f.truncate && len(f.object) > 0 {
+			f.object = f.object[:len(f.object)-1]
+		}
+		etag := hex.EncodeToString(hash.Sum(nil)) + "-" + strconv.Itoa(len(complete.Parts))
+		if f.etag != "" {
+			etag = f.etag
+		}
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><ETag>"%s"</ETag></CompleteMultipartUploadResult>`, etag)
+	case r.Method == http.MethodHead:
+		w.Header().Set("Content-Length", strconv.Itoa(len(f.object)))
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		f.aborted++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, r.Method+" "+r.URL.String(), http.StatusNotImplemented)
+	}
+}
+
+// testS3 returns a store in a fake S3 service.
+func testS3(t *testing.T) (*S3, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{uploaded: make(map[int32]int)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	store, err := NewS3(context.Background(), "s3://bucket/prefix")
+	if err != nil {
+		t.Fatalf("new S3: %s", err)
+	}
+	return store, fake
+}
+
+// testUpload writes a file of two and a half parts, returning its path and contents.
+func testUpload(t *testing.T) (string, []byte) {
+	t.Helper()
+	data := make([]byte, 2*minPartSize+minPartSize/2)
+	rand.New(rand.NewSource(1)).Read(data)
+	path := filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write file: %s", err)
+	}
+	return path, data
+}
+
+// partNumbers returns the part numbers of the upload state, in order.
+func partNumbers(t *testing.T, stateFile string) []int32 {
+	t.Helper()
+	state, err := loadState(stateFile)
+	if err != nil {
+		t.Fatalf("load state: %s", err)
+	}
+	var numbers []int32
+	for _, part := range state.Parts {
+		numbers = append(numbers, part.Number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+func TestPutResumable(t *testing.T) {
+	ctx := context.Background()
+	t.Run("complete", func(t *testing.T) {
+		store, fake := testS3(t)
+		path, data := testUpload(t)
+		stateFile := filepath.Join(t.TempDir(), "upload.json")
+		if err := store.PutResumable(ctx, "prefix/clip.mp4", path, "", "", stateFile); err != nil {
+			t.Fatalf("put: %s", err)
+		}
+		if !bytes.Equal(fake.object, data) {
+			t.Errorf("uploaded %d bytes, want %d", len(fake.object), len(data))
+		} else if len(fake.parts) != 3 {
+			t.Errorf("%d parts, want 3", len(fake.parts))
+		}
+		if _, err := os.Stat(stateFile); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("state file kept: %v", err)
+		}
+	})
+	t.Run("resume", func(t *testing.T) {
+		store, fake := testS3(t)
+		path, data := testUpload(t)
+		stateFile := filepath.Join(t.TempDir(), "upload.json")
+		fake.failPart = 2
+		if err := store.PutResumable(ctx, "prefix/clip.mp4", path, "", "", stateFile); err == nil || !strings.Contains(err.Error(), "upload part 2") {
+			t.Fatalf("error %v, want upload part 2 failure", err)
+		}
+		if numbers := partNumbers(t, stateFile); len(numbers) != 1 || numbers[0] != 1 {
+			t.Fatalf("state parts %v, want [1]", numbers)
+		}
+		if err := store.PutResumable(ctx, "prefix/clip.mp4", path, "", "", stateFile); err != nil {
+			t.Fatalf("resume: %s", err)
+		}
+		if !bytes.Equal(fake.object, data) {
+			t.Errorf("uploaded %d bytes, want %d", len(fake.object), len(data))
+		}
+		if fake.created != 1 || fake.uploaded[1] != 1 {
+			t.Errorf("%d uploads created, part 1 uploaded %d times, want 1 and 1", fake.created, fake.uploaded[1])
+		}
+	})
+	t.Run("parts lost", func(t *testing.T) {
+		store, fake := testS3(t)
+		path, data := testUpload(t)
+		stateFile := filepath.Join(t.TempDir(), "upload.json")
+		fake.failPart = 3
+		if err := store.PutResumable(ctx, "prefix/clip.mp4", path, "", "", stateFile); err == nil {
+			t.Fatal("no error for failed part")
+		}
+		// The state has parts 1 and 2 but the service no longer has them.
+		fake.forget = true
+		if err := store.PutResumable(ctx, "prefix/clip.mp4", path, "", "", stateFile); err != nil {
+			t.Fatalf("resume: %s", err)
+		}
+		if !bytes.Equal(fake.object, data) {
+			t.Errorf("uploaded %d bytes, want %d", len(fake.object), len(data))
+		}
+		if fake.uploaded[1] != 2 {
+			t.Errorf("part 1 uploaded %d times, want 2", fake.uploaded[1])
+		}
+	})
+	t.Run("changed file", func(t *testing.T) {
+		store, fake := testS3(t)
+		path, _ := testUpload(t)
+		stateFile := filepath.Join(t.TempDir(), "upload.json")
+		fake.failPart = 2
+		_ = store.PutResumable(ctx, "prefix/clip.mp4", path, "", "", stateFile)
+		data := bytes.Repeat([]byte{7}, 2*minPartSize)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("write file: %s", err)
+		}
+		if err := store.PutResumable(ctx, "prefix/clip.mp4", path, "", "", stateFile); err != nil {
+			t.Fatalf("put: %s", err)
+		}
+		if !bytes.Equal(fake.object, data) {
+			t.Errorf("uploaded %d bytes, want %d", len(fake.object), len(data))
+		}
+		if fake.created != 2 || fake.aborted != 1 {
+			t.Errorf("%d uploads created, %d aborted, want 2 and 1", fake.created, fake.aborted)
+		}
+	})
+	t.Run("hash mismatch", func(t *testing.T) {
+		store, fake := testS3(t)
+		path, _ := testUpload(t)
+		stateFile := filepath.Join(t.TempDir(), "upload.json")
+		fake.etag = "00000000000000000000000000000000-3"
+		if err := store.PutResumable(ctx, "prefix/clip.mp4", path, "", "", stateFile); err == nil || !strings.Contains(err.Error(), "does not match") {
+			t.Errorf("error %v, want ETag mismatch", err)
+		}
+		if _, err := os.Stat(stateFile); err != nil {
+			t.Errorf("state file not kept: %s", err)
+		}
+	})
+	t.Run("plain ETag", func(t *testing.T) {
+		store, fake := testS3(t)
+		path, data := testUpload(t)
+		stateFile := filepath.Join(t.TempDir(), "upload.json")
+		// A plain ETag, as some compatible services return, is checked by size instead.
+		fake.etag = "0123456789abcdef0123456789abcdef"
+		if err := store.PutResumable(ctx, "prefix/clip.mp4", path, "", "", stateFile); err != nil {
+			t.Fatalf("put: %s", err)
+		} else if !bytes.Equal(fake.object, data) {
+			t.Errorf("uploaded %d bytes, want %d", len(fake.object), len(data))
+		}
+	})
+	t.Run("size mismatch", func(t *testing.T) {
+		store, fake := testS3(t)
+		path, _ := testUpload(t)
+		stateFile := filepath.Join(t.TempDir(), "upload.json")
+		fake.etag = "0123456789abcdef0123456789abcdef"
+		fake.truncate = true
+		if err := store.PutResumable(ctx, "prefix/clip.mp4", path, "", "", stateFile); err == nil || !strings.Contains(err.Error(), "size") {
+			t.Errorf("error %v, want size mismatch", err)
+		}
+	})
+}
+
+func TestUploadStateETag(t *testing.T) {
+	state := &uploadState{}
+	hash := md5.New()
+	for i, part := range [][]byte{[]byte("one"), []byte("two")} {
+		sum := md5.Sum(part)
+		hash.Write(sum[:])
+		state.Parts = append(state.Parts, uploadPart{Number: int32(i + 1), MD5: hex.EncodeToString(sum[:])})
+	}
+	if want := hex.EncodeToString(hash.Sum(nil)) + "-2"; state.etag() != want {
+		t.Errorf("etag %s, want %s", state.etag(), want)
+	}
+}
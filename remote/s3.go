@@ -0,0 +1,217 @@
+// Package remote copies files to and from remote storage.
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Object describes an object in remote storage.
+type Object struct {
+	Size         int64
+	StorageClass string
+	// SHA256 is the hash (hex) stored in the object metadata by Put or PutResumable.
+	SHA256 string
+	// ChecksumSHA256 is the SHA-256 hash (hex) of the object contents computed by the service,
+	// empty if it has none (e.g. for multipart uploads, or services without additional checksums).
+	ChecksumSHA256 string
+	// Archived is true if the object is in a cold storage class
+	// and must be restored before it can be read.
+	Archived bool
+	// Restoring is true if a restore has been requested and is still in progress.
+	Restoring bool
+}
+
+// S3 is a bucket and key prefix in Amazon S3 or a compatible service.
+// Credentials and region are taken from the usual AWS environment variables and files.
+// The AWS_ENDPOINT_URL environment variable specifies the endpoint of a compatible service.
+type S3 struct {
+	client   *s3.Client
+	bucket   string
+	prefix   string
+	throttle *throttledClient
+}
+
+// NewS3 returns an S3 store for a URL of the form s3://bucket/prefix.
+func NewS3(ctx context.Context, s3URL string) (*S3, error) {
+	parsed, err := url.Parse(s3URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse URL: %w", err)
+	} else if parsed.Scheme != "s3" || parsed.Host == "" {
+		return nil, fmt.Errorf("not an s3://bucket/prefix URL: %s", s3URL)
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS configuration: %w", err)
+	}
+	prefix := strings.Trim(parsed.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	throttle := &throttledClient{}
+	client := s3.NewFromConfig(cfg, func(options *s3.Options) {
+		throttle.client = options.HTTPClient
+		options.HTTPClient = throttle
+		// For S3 compatible services (e.g. MinIO on a NAS).
+		if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+			options.EndpointResolver = s3.EndpointResolverFromURL(endpoint)
+			options.UsePathStyle = true
+		}
+	})
+	return &S3{client: client, bucket: parsed.Host, prefix: prefix, throttle: throttle}, nil
+}
+
+// SetRate limits the rate at which files are uploaded in bytes per second, zero for no limit.
+func (s *S3) SetRate(rate int64) {
+	atomic.StoreInt64(&s.throttle.rate, rate)
+}
+
+// URL returns the s3:// URL of the object with the specified key.
+func (s *S3) URL(key string) string {
+	return "s3://" + s.bucket + "/" + key
+}
+
+// Key returns the object key for a path relative to the prefix.
+func (s *S3) Key(rel string) string {
+	return s.prefix + rel
+}
+
+// KeyFromURL returns the object key from an s3:// URL in the same bucket.
+func (s *S3) KeyFromURL(s3URL string) (string, error) {
+	parsed, err := url.Parse(s3URL)
+	if err != nil {
+		return "", fmt.Errorf("parse URL: %w", err)
+	} else if parsed.Host != s.bucket {
+		return "", fmt.Errorf("URL not in bucket %s: %s", s.bucket, s3URL)
+	}
+	return strings.TrimPrefix(parsed.Path, "/"), nil
+}
+
+// Put uploads a file to the object with the specified key.
+// The SHA-256 hash of the file (hex) is stored in the object metadata
+// and sent as the checksum of the contents, which the service verifies and keeps (see Object.ChecksumSHA256).
+func (s *S3) Put(ctx context.Context, key, path, storageClass, sha256 string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	if _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:         aws.String(s.bucket),
+		Key:            aws.String(key),
+		Body:           file,
+		ContentLength:  stat.Size(),
+		StorageClass:   types.StorageClass(storageClass),
+		Metadata:       map[string]string{"sha256": sha256},
+		ChecksumSHA256: checksumSHA256(sha256),
+	}); err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	return nil
+}
+
+// Probe checks that the bucket exists and is accessible.
+func (s *S3) Probe(ctx context.Context) error {
+	if _, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket)}); err != nil {
+		return fmt.Errorf("head bucket: %w", err)
+	}
+	return nil
+}
+
+// Head returns a description of the object with the specified key.
+func (s *S3) Head(ctx context.Context, key string) (*Object, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(key),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("head object: %w", err)
+	}
+	object := &Object{
+		Size:         head.ContentLength,
+		StorageClass: string(head.StorageClass),
+		SHA256:       head.Metadata["sha256"],
+	}
+	// Checksums of multipart uploads are checksums of the part checksums, suffixed with the number of parts.
+	if checksum, err := base64.StdEncoding.DecodeString(aws.ToString(head.ChecksumSHA256)); err == nil && len(checksum) == sha256.Size {
+		object.ChecksumSHA256 = hex.EncodeToString(checksum)
+	}
+	switch head.StorageClass {
+	case types.StorageClassGlacier, types.StorageClassDeepArchive:
+		// The Restore header is absent until a restore is requested
+		// and then contains ongoing-request="true" until it completes.
+		restore := aws.ToString(head.Restore)
+		object.Restoring = strings.Contains(restore, `ongoing-request="true"`)
+		object.Archived = !strings.Contains(restore, `ongoing-request="false"`)
+	}
+	return object, nil
+}
+
+// Restore requests that an archived object be made readable for the specified number of days.
+// The tier is Expedited, Standard, or Bulk (slower and cheaper in that order).
+func (s *S3) Restore(ctx context.Context, key string, days int, tier string) error {
+	if _, err := s.client.RestoreObject(ctx, &s3.RestoreObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		RestoreRequest: &types.RestoreRequest{
+			Days:                 int32(days),
+			GlacierJobParameters: &types.GlacierJobParameters{Tier: types.Tier(tier)},
+		},
+	}); err != nil {
+		var apiErr interface{ ErrorCode() string }
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "RestoreAlreadyInProgress" {
+			return nil
+		}
+		return fmt.Errorf("restore object: %w", err)
+	}
+	return nil
+}
+
+// Get downloads the object with the specified key into a file.
+func (s *S3) Get(ctx context.Context, key, path string) error {
+	get, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("get object: %w", err)
+	}
+	defer func() { _ = get.Body.Close() }()
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	if _, err := io.Copy(file, get.Body); err != nil {
+		return fmt.Errorf("download object: %w", err)
+	}
+	return nil
+}
+
+// checksumSHA256 returns the base64 form of a SHA-256 hash (hex) for a checksum header, nil if it isn't one.
+func checksumSHA256(sum string) *string {
+	raw, err := hex.DecodeString(sum)
+	if err != nil || len(raw) != sha256.Size {
+		return nil
+	}
+	return aws.String(base64.StdEncoding.EncodeToString(raw))
+}
@@ -0,0 +1,45 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MaxExpires is the longest time for which a presigned URL is valid.
+const MaxExpires = 7 * 24 * time.Hour
+
+// PutContent uploads content to the object with the specified key and content type
+// (e.g. image/jpeg) so that it is displayed by browsers.
+func (s *S3) PutContent(ctx context.Context, key string, content io.ReadSeeker, size int64, contentType string) error {
+	if _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          content,
+		ContentLength: size,
+		ContentType:   aws.String(contentType),
+	}); err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	return nil
+}
+
+// Presign returns a URL from which the object with the specified key can be read
+// without credentials until it expires (at most MaxExpires from now).
+func (s *S3) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if expires > MaxExpires {
+		expires = MaxExpires
+	}
+	request, err := s3.NewPresignClient(s.client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", fmt.Errorf("presign object: %w", err)
+	}
+	return request.URL, nil
+}
@@ -0,0 +1,48 @@
+package remote
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// throttledClient limits the rate at which request bodies are sent
+// so that uploads don't saturate a slow connection.
+type throttledClient struct {
+	client s3.HTTPClient
+	// rate in bytes per second, zero for no limit, accessed atomically.
+	rate int64
+}
+
+// Do sends an HTTP request with its body throttled to the current rate.
+func (c *throttledClient) Do(request *http.Request) (*http.Response, error) {
+	if rate := atomic.LoadInt64(&c.rate); rate > 0 && request.Body != nil && request.Body != http.NoBody {
+		request.Body = &throttle{ReadCloser: request.Body, rate: rate, start: time.Now()}
+	}
+	return c.client.Do(request)
+}
+
+// throttle limits reads from a request body to an average rate.
+type throttle struct {
+	io.ReadCloser
+	rate  int64
+	start time.Time
+	read  int64
+}
+
+func (t *throttle) Read(p []byte) (int, error) {
+	// Small reads keep the rate smooth instead of sending bursts at full speed.
+	if chunk := t.rate / 10; chunk > 0 && int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+	n, err := t.ReadCloser.Read(p)
+	t.read += int64(n)
+	due := time.Duration(float64(t.read) / float64(t.rate) * float64(time.Second))
+	if wait := due - time.Since(t.start); wait > 0 {
+		time.Sleep(wait)
+	}
+	return n, err
+}
@@ -0,0 +1,164 @@
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Upload is the record of a resumable background upload of a local file
+// (e.g. an export bundle) kept in an upload directory so that its progress
+// can be reported, and the upload resumed, after the program is restarted.
+type Upload struct {
+	Path     string    `json:"path"`
+	URL      string    `json:"url"`
+	Size     int64     `json:"size"`
+	SHA256   string    `json:"sha256"`
+	Rate     int64     `json:"rate,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Error    string    `json:"error,omitempty"`
+	// Uploaded is the number of bytes uploaded so far (in completed parts).
+	Uploaded int64 `json:"-"`
+	// Updated is when the upload last made progress.
+	Updated time.Time `json:"-"`
+}
+
+// uploadName returns the base name of the record and state files of an upload
+// of a file to a URL, so that uploading the same file to the same URL again resumes it.
+func uploadName(path, url string) string {
+	sum := sha256.Sum256([]byte(path + "\n" + url))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Trickle uploads a file to the object with the specified key at up to the rate (bytes per second,
+// zero for no limit), recording its progress in the upload directory (see Uploads).
+// An interrupted upload is resumed from the last completed part by calling Trickle again.
+func (s *S3) Trickle(ctx context.Context, key, path, storageClass string, rate int64, dir string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("absolute path: %w", err)
+	}
+	upload := &Upload{Path: path, URL: s.URL(key), Rate: rate, Started: time.Now()}
+	name := filepath.Join(dir, uploadName(upload.Path, upload.URL))
+	if previous, err := loadUpload(name + ".json"); err == nil && previous.Finished.IsZero() {
+		upload.Started, upload.SHA256 = previous.Started, previous.SHA256
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	upload.Size = stat.Size()
+	if upload.SHA256 == "" {
+		if upload.SHA256, err = sha256File(path); err != nil {
+			return err
+		}
+	}
+	if err := upload.save(name + ".json"); err != nil {
+		return err
+	}
+
+	s.SetRate(rate)
+	err = s.PutResumable(ctx, key, path, storageClass, upload.SHA256, name+".state.json")
+	if err != nil {
+		upload.Error = err.Error()
+	} else {
+		upload.Finished = time.Now()
+	}
+	if saveErr := upload.save(name + ".json"); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// Uploads returns the uploads recorded in the upload directory ordered by start time.
+// The progress of unfinished uploads is read from their multipart upload state.
+func Uploads(dir string) ([]*Upload, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read upload dir: %w", err)
+	}
+	var uploads []*Upload
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".state.json") {
+			continue
+		}
+		upload, err := loadUpload(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if !upload.Finished.IsZero() {
+			upload.Uploaded, upload.Updated = upload.Size, upload.Finished
+		} else {
+			upload.Updated = upload.Started
+			stateFile := filepath.Join(dir, strings.TrimSuffix(name, ".json")+".state.json")
+			if stat, err := os.Stat(stateFile); err == nil {
+				upload.Updated = stat.ModTime()
+			}
+			if state, err := loadState(stateFile); err == nil {
+				upload.Uploaded = int64(len(state.Parts)) * state.PartSize
+				if upload.Uploaded > upload.Size {
+					upload.Uploaded = upload.Size
+				}
+			}
+		}
+		uploads = append(uploads, upload)
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].Started.Before(uploads[j].Started)
+	})
+	return uploads, nil
+}
+
+func loadUpload(file string) (*Upload, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read upload: %w", err)
+	}
+	upload := new(Upload)
+	if err := json.Unmarshal(data, upload); err != nil {
+		return nil, fmt.Errorf("parse upload %s: %w", file, err)
+	}
+	return upload, nil
+}
+
+func (upload *Upload) save(file string) error {
+	data, err := json.MarshalIndent(upload, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal upload: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0766); err != nil {
+		return fmt.Errorf("make upload dir: %w", err)
+	}
+	temp := file + ".tmp"
+	if err := os.WriteFile(temp, data, 0666); err != nil {
+		return fmt.Errorf("write upload: %w", err)
+	}
+	return os.Rename(temp, file)
+}
+
+// sha256File returns the SHA-256 hash of a file's contents as a hex string.
+func sha256File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("hash file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
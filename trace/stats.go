@@ -0,0 +1,94 @@
+package trace
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Attributes used for stats.
+const (
+	// AttrClass is the class of file (e.g. jpg) of a span and its children.
+	AttrClass = "class"
+	// AttrBytes is the size of the file of a span.
+	AttrBytes = "bytes"
+)
+
+// Stat is the total time spent in a stage (span name) for a class of files.
+// Spans without a class (e.g. finding source files) have an empty class.
+// When files are imported concurrently the total may exceed the elapsed time.
+type Stat struct {
+	Class string
+	Stage string
+	Count int
+	Total time.Duration
+	// Bytes is the total size of the files of the spans, zero if unknown.
+	Bytes int64
+}
+
+type statKey struct {
+	class, stage string
+}
+
+// total adds a finished span to the stats, with the caller holding the mutex.
+// Measured parts are totaled as separate stages and excluded from the span's own stage.
+func (t *Tracer) total(s *Span) {
+	if s == t.root {
+		return
+	}
+	class := s.class()
+	elapsed := s.end.Sub(s.start)
+	for part, duration := range s.parts {
+		t.stat(class, part).add(duration, 0)
+		elapsed -= duration
+	}
+	bytes, _ := strconv.ParseInt(s.attributes[AttrBytes], 10, 64)
+	t.stat(class, s.name).add(elapsed, bytes)
+}
+
+// class returns the class of the span or its closest ancestor with one, with the caller holding the mutex.
+func (s *Span) class() string {
+	for span := s; span != nil; span = span.parent {
+		if class, ok := span.attributes[AttrClass]; ok {
+			return class
+		}
+	}
+	return ""
+}
+
+func (t *Tracer) stat(class, stage string) *Stat {
+	key := statKey{class: class, stage: stage}
+	stat, found := t.stats[key]
+	if !found {
+		stat = &Stat{Class: class, Stage: stage}
+		t.stats[key] = stat
+	}
+	return stat
+}
+
+func (s *Stat) add(elapsed time.Duration, bytes int64) {
+	s.Count++
+	s.Total += elapsed
+	s.Bytes += bytes
+}
+
+// Stats returns the total time spent in each stage by class, ordered by class and stage.
+func (t *Tracer) Stats() []*Stat {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	stats := make([]*Stat, 0, len(t.stats))
+	for _, stat := range t.stats {
+		copied := *stat
+		stats = append(stats, &copied)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Class != stats[j].Class {
+			return stats[i].Class < stats[j].Class
+		}
+		return stats[i].Stage < stats[j].Stage
+	})
+	return stats
+}
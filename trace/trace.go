@@ -0,0 +1,297 @@
+// Package trace records spans of the import pipeline (scan, duplicates, verify, extract, copy, hash, and catalog)
+// and exports them to an OpenTelemetry collector (e.g. Jaeger or Grafana Tempo) using OTLP over HTTP
+// with JSON encoding, so that slow imports can be diagnosed (is it the card, the hash, or the NAS?)
+// without pulling in the OpenTelemetry SDK.
+// Tracers also total the time spent in each stage by file class (see Stats), with or without exporting.
+//
+// A nil *Tracer or *Span does nothing, so instrumented code needn't check whether tracing is enabled.
+package trace
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEndpoint is the OTLP over HTTP endpoint of a local collector.
+const DefaultEndpoint = "http://localhost:4318"
+
+// tracesPath is appended to endpoints without a path.
+const tracesPath = "/v1/traces"
+
+// exportBatch is the number of finished spans exported at once during a trace.
+const exportBatch = 1000
+
+// exportTimeout bounds each export request.
+const exportTimeout = 30 * time.Second
+
+// Tracer records the spans of a single trace (e.g. an import session).
+// It is safe for concurrent use.
+type Tracer struct {
+	endpoint string
+	service  string
+	traceID  string
+	mutex    sync.Mutex
+	root     *Span
+	spans    []*Span
+	exports  sync.WaitGroup
+	err      error
+	stats    map[statKey]*Stat
+}
+
+// Span is a timed operation within a trace.
+type Span struct {
+	tracer     *Tracer
+	id         string
+	parent     *Span
+	name       string
+	start, end time.Time
+	attributes map[string]string
+	err        error
+	// parts are the durations of parts of the span measured by the caller.
+	parts map[string]time.Duration
+}
+
+// New returns a tracer exporting spans of the service to an OTLP over HTTP endpoint
+// (e.g. http://localhost:4318, to which /v1/traces is appended if there is no path).
+// If the endpoint is empty spans are not exported, only totaled.
+func New(endpoint, service string) (*Tracer, error) {
+	if endpoint == "" {
+		return &Tracer{service: service, stats: make(map[statKey]*Stat)}, nil
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse OTLP endpoint: %w", err)
+	} else if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("OTLP endpoint must be an http or https URL: %s", endpoint)
+	}
+	if strings.Trim(parsed.Path, "/") == "" {
+		parsed.Path = tracesPath
+	}
+	return &Tracer{endpoint: parsed.String(), service: service, traceID: randomID(16), stats: make(map[statKey]*Stat)}, nil
+}
+
+// Start starts a span with attributes specified as key, value pairs.
+// The first span started without a parent is the root span of the trace,
+// later spans without a parent are children of the root span.
+func (t *Tracer) Start(parent *Span, name string, attributes ...string) *Span {
+	if t == nil {
+		return nil
+	}
+	span := &Span{tracer: t, id: randomID(8), name: name, start: time.Now(), attributes: make(map[string]string)}
+	for i := 0; i+1 < len(attributes); i += 2 {
+		span.attributes[attributes[i]] = attributes[i+1]
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if parent != nil {
+		span.parent = parent
+	} else if t.root != nil {
+		span.parent = t.root
+	} else {
+		t.root = span
+	}
+	return span
+}
+
+// Set an attribute of the span.
+func (s *Span) Set(key, value string) {
+	if s == nil {
+		return
+	}
+	s.tracer.mutex.Lock()
+	defer s.tracer.mutex.Unlock()
+	s.attributes[key] = value
+}
+
+// Measure records the duration of a part of the span (e.g. hashing while copying)
+// as an attribute (NAME.seconds) and as a separate stage in the stats.
+func (s *Span) Measure(name string, elapsed time.Duration) {
+	if s == nil {
+		return
+	}
+	s.Set(name+".seconds", strconv.FormatFloat(elapsed.Seconds(), 'f', 6, 64))
+	s.tracer.mutex.Lock()
+	defer s.tracer.mutex.Unlock()
+	if s.parts == nil {
+		s.parts = make(map[string]time.Duration)
+	}
+	s.parts[name] += elapsed
+}
+
+// End the span, recording the error (if any) as its status.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	t := s.tracer
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !s.end.IsZero() {
+		return
+	}
+	s.end, s.err = time.Now(), err
+	t.total(s)
+	if t.endpoint == "" {
+		return
+	}
+	if t.spans = append(t.spans, s); len(t.spans) >= exportBatch {
+		spans := t.spans
+		t.spans = nil
+		t.exports.Add(1)
+		go func() {
+			defer t.exports.Done()
+			t.export(spans)
+		}()
+	}
+}
+
+// Flush exports the finished spans, returning the first export error of the trace.
+func (t *Tracer) Flush() error {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mutex.Unlock()
+	if len(spans) > 0 {
+		t.export(spans)
+	}
+	t.exports.Wait()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.err
+}
+
+// export sends spans to the endpoint, recording the first error.
+func (t *Tracer) export(spans []*Span) {
+	if err := t.send(spans); err != nil {
+		t.mutex.Lock()
+		if t.err == nil {
+			t.err = err
+		}
+		t.mutex.Unlock()
+	}
+}
+
+// OTLP JSON encoding of an export request (see opentelemetry-proto).
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID      string          `json:"traceId"`
+		SpanID       string          `json:"spanId"`
+		ParentSpanID string          `json:"parentSpanId,omitempty"`
+		Name         string          `json:"name"`
+		Kind         int             `json:"kind"`
+		Start        string          `json:"startTimeUnixNano"`
+		End          string          `json:"endTimeUnixNano"`
+		Attributes   []otlpAttribute `json:"attributes,omitempty"`
+		Status       otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// OTLP span kind and status codes.
+const (
+	otlpKindInternal = 1
+	otlpStatusOK     = 1
+	otlpStatusError  = 2
+)
+
+// send spans to the endpoint.
+func (t *Tracer) send(spans []*Span) error {
+	scope := otlpScopeSpans{Scope: otlpScope{Name: "github.com/madkins23/gardepro"}}
+	t.mutex.Lock()
+	for _, span := range spans {
+		encoded := otlpSpan{
+			TraceID: t.traceID,
+			SpanID:  span.id,
+			Name:    span.name,
+			Kind:    otlpKindInternal,
+			Start:   strconv.FormatInt(span.start.UnixNano(), 10),
+			End:     strconv.FormatInt(span.end.UnixNano(), 10),
+			Status:  otlpStatus{Code: otlpStatusOK},
+		}
+		if span.parent != nil {
+			encoded.ParentSpanID = span.parent.id
+		}
+		for key, value := range span.attributes {
+			encoded.Attributes = append(encoded.Attributes, otlpAttribute{Key: key, Value: otlpValue{StringValue: value}})
+		}
+		if span.err != nil {
+			encoded.Status = otlpStatus{Code: otlpStatusError, Message: span.err.Error()}
+		}
+		scope.Spans = append(scope.Spans, encoded)
+	}
+	t.mutex.Unlock()
+	body, err := json.Marshal(&otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: otlpValue{StringValue: t.service}},
+		}},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}})
+	if err != nil {
+		return fmt.Errorf("marshal spans: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("make OTLP request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("export spans: %w", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("export spans: %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// randomID returns a random ID of the specified number of bytes in hex.
+func randomID(size int) string {
+	id := make([]byte, size)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
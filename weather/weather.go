@@ -0,0 +1,122 @@
+// Package weather looks up the historical weather at the time and place of a capture.
+//
+// The weather is taken from an hourly historical weather API compatible with
+// the Open-Meteo archive API (https://open-meteo.com/en/docs/historical-weather-api),
+// which is free for non-commercial use and needs no key.
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/madkins23/gardepro/catalog"
+)
+
+// DefaultURL is the Open-Meteo historical weather API.
+const DefaultURL = "https://archive-api.open-meteo.com/v1/archive"
+
+const (
+	// hourFmt is the format of the hourly times in API responses.
+	hourFmt = "2006-01-02T15:04"
+	dateFmt = "2006-01-02"
+	// requestTimeout bounds each API request.
+	requestTimeout = 30 * time.Second
+)
+
+// Client looks up weather from an API, caching a month of hourly weather per location.
+// It is not safe for concurrent use.
+type Client struct {
+	url    string
+	client *http.Client
+	cache  map[string]map[string]*catalog.Weather
+}
+
+// NewClient returns a client for the API at the URL, DefaultURL if empty.
+func NewClient(apiURL string) *Client {
+	if apiURL == "" {
+		apiURL = DefaultURL
+	}
+	return &Client{
+		url:    apiURL,
+		client: &http.Client{Timeout: requestTimeout},
+		cache:  make(map[string]map[string]*catalog.Weather),
+	}
+}
+
+// Lookup returns the weather at the position during the hour of the capture time.
+// The capture time is the camera clock time, which is taken to be local time at the position.
+// Positions are rounded to a hundredth of a degree (about a kilometer).
+func (c *Client) Lookup(ctx context.Context, latitude, longitude float64, when time.Time) (*catalog.Weather, error) {
+	latitude, longitude = math.Round(latitude*100)/100, math.Round(longitude*100)/100
+	month := time.Date(when.Year(), when.Month(), 1, 0, 0, 0, 0, time.UTC)
+	key := fmt.Sprintf("%.2f,%.2f,%s", latitude, longitude, month.Format("2006-01"))
+	hours, found := c.cache[key]
+	if !found {
+		var err error
+		if hours, err = c.fetch(ctx, latitude, longitude, month, month.AddDate(0, 1, -1)); err != nil {
+			return nil, err
+		}
+		c.cache[key] = hours
+	}
+	if weather := hours[when.Format("2006-01-02T15:00")]; weather != nil {
+		return weather, nil
+	}
+	return nil, fmt.Errorf("no weather for %s at %.2f,%.2f", when.Format(hourFmt), latitude, longitude)
+}
+
+// fetch returns the hourly weather at the position between the dates, keyed by local hour.
+func (c *Client) fetch(ctx context.Context, latitude, longitude float64, start, end time.Time) (map[string]*catalog.Weather, error) {
+	query := url.Values{
+		"latitude":   {strconv.FormatFloat(latitude, 'f', 2, 64)},
+		"longitude":  {strconv.FormatFloat(longitude, 'f', 2, 64)},
+		"start_date": {start.Format(dateFmt)},
+		"end_date":   {end.Format(dateFmt)},
+		"hourly":     {"temperature_2m,precipitation,snowfall"},
+		"timezone":   {"auto"},
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("get weather: %w", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	var body struct {
+		Reason string `json:"reason"`
+		Hourly struct {
+			Time          []string   `json:"time"`
+			Temperature   []*float64 `json:"temperature_2m"`
+			Precipitation []*float64 `json:"precipitation"`
+			Snowfall      []*float64 `json:"snowfall"`
+		} `json:"hourly"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode weather (%s): %w", response.Status, err)
+	} else if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get weather: %s: %s", response.Status, body.Reason)
+	}
+	hourly := body.Hourly
+	hours := make(map[string]*catalog.Weather)
+	for i, hour := range hourly.Time {
+		if i >= len(hourly.Temperature) || hourly.Temperature[i] == nil {
+			continue
+		}
+		weather := &catalog.Weather{Temperature: *hourly.Temperature[i]}
+		if i < len(hourly.Precipitation) && hourly.Precipitation[i] != nil {
+			weather.Precipitation = *hourly.Precipitation[i]
+		}
+		if i < len(hourly.Snowfall) && hourly.Snowfall[i] != nil {
+			weather.Snowfall = *hourly.Snowfall[i]
+		}
+		hours[hour] = weather
+	}
+	return hours, nil
+}